@@ -1,9 +1,10 @@
 package accumulator
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"sort"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/FactomProject/factomd/util/atomic"
@@ -24,6 +25,9 @@ import (
 // record entries that might be invalidated by applications after recording.
 type Accumulator struct {
 	DB            *database.DB             // Database to hold and index the data collected by the Accumulator
+	dbMux         sync.RWMutex             // Guards DB, which SwapStore changes
+	swaps         chan storeSwap           // Stores SwapStore wants Run to move onto
+	queries       chan func()              // Reads of the current block, for Run to make between entries
 	chainID       *types.Hash              // Digital ID of the Accumulator.
 	height        types.BlockHeight        // Height of the current block
 	chains        map[types.Hash]*ChainAcc // Chains with new entries in this block
@@ -34,19 +38,336 @@ type Accumulator struct {
 	EntryCnt      atomic.AtomicInt64       // Count of entries written
 	ChainsInBlock atomic.AtomicInt64       // Count of chains written to
 	ChainCnt      atomic.AtomicInt64       // Count of all chains
+
+	// ContinuousChains are chains whose Merkle DAG runs across blocks.  Rather than starting a fresh MD each
+	// block, the chain's MD is seeded with every entry ever added to the chain, so the ListMDRoot of each of
+	// its nodes covers the whole history of the chain.  The EntryList of each node still only holds the entries
+	// added in that block.  Set before calling Run.
+	ContinuousChains map[types.Hash]bool
+	continuous       map[types.Hash]*merkleDag.MD // Accumulated MD state for the continuous chains
+	chainParams      *ChainParams                 // The ChainParams as stored, once read or written
+
+	// MDFeedPolicy decides what happens when a block's MD root is ready but nobody has drained the mdFeed.
+	// The default (DropOnNoReader) logs and drops the root rather than stalling block production.  The
+	// chainRootFeed follows the same policy.
+	MDFeedPolicy  FeedPolicy
+	chainRootFeed chan node.NEList // Every chain's root as its block is sealed, if ChainRootFeed was called
+
+	// DropWhenFeedFull has Submit refuse an entry as FeedFull, rather than wait, when the entryFeed is full.
+	// Entries logged to the WALPath are always waited for, as the log already holds them.  See FeedStats.
+	DropWhenFeedFull bool
+	feed             feedStats // How full the entryFeed has been, and what was dropped for want of a reader
+
+	// PrecomputeReceipts has sealBlock build and store the receipt for every entry in the block, so
+	// GetReceipt is a single read of the database.  This costs a receipt's worth of storage per entry.
+	PrecomputeReceipts bool
+
+	// EntrySequences has the accumulator number the entries of each chain 0, 1, 2 ... in the order they
+	// are added, across blocks and restarts, so consumers can detect gaps.  Doesn't change any MD root.
+	EntrySequences bool
+
+	// CountEntryTypes has sealBlock record how many entries of each type (see node.EntryType) every chain
+	// has in the block, for GetEntryTypeCounts.  AcceptEntryType, if set, has Submit reject entries of the
+	// types it returns false for as a FilteredType.
+	CountEntryTypes bool
+	AcceptEntryType func(entryType byte) bool
+
+	// OnCommit is called with each directory block once the block has been written to the database, before
+	// the next block takes any entries.  It is called from the go routine running the accumulator, so it
+	// holds up block production until it returns.
+	OnCommit func(directoryBlock *node.Node)
+
+	// OnChainCreated is told of each chain the first time it is sealed in a block, once the block has been
+	// committed, like OnCommit.  A chain is new if it had no head in the database, so it is told once per chain
+	// however many times the accumulator restarts.  Blocks a replica applies aren't told of.
+	OnChainCreated func(event ChainCreated)
+
+	// BeforeSeal is called just before each block is sealed, with a BlockBuilder it can use to add entries
+	// of its own to the block.  It is called from the go routine running the accumulator, after the block has
+	// been taken as not empty, so it never stops SkipEmptyBlocks skipping a block.
+	BeforeSeal func(builder *BlockBuilder)
+
+	// Anchorer, if set, anchors each block once it is final, and where it was anchored is recorded so
+	// GetProofToAnchor can follow an entry to the anchor.  Like OnCommit, it holds up block production until
+	// it returns.
+	Anchorer Anchorer
+
+	// FinalizationDepth holds a block back from OnFinal and the Anchorer until this many more blocks have
+	// been sealed on top of it, so a block that an upstream reorg might yet change is never anchored.  Zero
+	// makes each block final as soon as OnCommit has been told of it.  Set it before Init.  The finalized height
+	// is committed with each block, so changing the depth across a restart never makes a block final twice.
+	FinalizationDepth types.BlockHeight
+	OnFinal           func(directoryBlock *node.Node) // Called with each block as it becomes final
+	finalized         atomic.AtomicInt64              // Count of the blocks that are final
+
+	Hasher      merkleDag.Hasher // Combines hashes in the Merkle DAGs; nil for sha256
+	BlockFlags  node.Flags       // Features the directory blocks are built with; see hasher
+	Logger      Logger           // Where to log; nil logs to stdout
+	Metrics     Metrics          // Where to count things; nil for no metrics
+	PanicPolicy PanicPolicy      // Whether Run recovers from panics (the default) or lets them through
+	Clock       Clock            // Where we get the time; nil for the system clock
+	Tracer      Tracer           // Where to trace the sealing of each block (see SpanSeal); nil for no tracing
+	traces      traces           // Trace contexts of entries given to SubmitTraced
+
+	// MaxEntriesPerChainPerSecond caps the rate at which Submit takes entries for any one chain, so a
+	// misbehaving chain can't flood the accumulator.  Excess entries are rejected as RateLimited while
+	// other chains carry on.  Zero means no limit.
+	MaxEntriesPerChainPerSecond int
+	OnReject                    func(entry node.EntryHash, reason RejectReason) // Told of every entry Submit rejects
+	rejects                     rejectWatchers                                  // Subscribers to WatchRejects
+
+	// OnIdle is called by Run once the entry feed has stayed empty for IdleWindow (by the Clock), and again
+	// only after more entries have been taken.  See Idle.
+	OnIdle     func()
+	IdleWindow time.Duration
+	idle       atomic.AtomicBool // Set once OnIdle is due, until the next entry is taken
+	idleSince  time.Time         // When Run first found the feed empty, or zero while it has entries
+
+	// RecentDuplicateBlocks has Submit reject entries already sealed in the last this many blocks as a
+	// RecentDuplicate.  A bloom filter per block keeps this (mostly) off the database.  Older duplicates are
+	// still dropped (quietly) when added.  Zero turns the check off.
+	RecentDuplicateBlocks int
+	recent                recentEntries // Bloom filters over the entries of the last RecentDuplicateBlocks blocks
+	throttle              throttle      // Per chain token buckets
+	schedule              schedule      // Entries held for future blocks
+
+	// TenantResolver groups chains into tenants, each with a quota of entries Submit takes for it in each
+	// QuotaPeriod; entries over a tenant's quota are rejected as QuotaExceeded while other tenants carry on.
+	// A tenant's quota is its entry in TenantQuotas, or DefaultTenantQuota if it has none, zero meaning no
+	// limit.  Periods start at multiples of QuotaPeriod by the Clock (a day's quota starts over at midnight
+	// UTC); with no QuotaPeriod, quotas are per block, starting over as each block is sealed.  Entries are
+	// counted as they are submitted, so an entry still in the feed as a block is sealed counts against that block.
+	TenantResolver     func(chainID types.Hash) (tenantID string)
+	TenantQuotas       map[string]int
+	DefaultTenantQuota int
+	QuotaPeriod        time.Duration
+	quotas             quotas // What each tenant has used of its quota
+
+	// PermanentDedup has the accumulator keep a permanent index of every entry sealed in each chain, one that
+	// is never pruned, and Submit reject any entry already in it as AlreadyRecorded.  This costs a read of
+	// the database for every entry submitted, and a write for every entry sealed.
+	PermanentDedup bool
+
+	// KeepChainStats has sealBlock keep a ChainStat for every chain: how many entries and blocks it has
+	// contributed, and the last block it was in.  This costs a read and a write for every chain in each block.
+	KeepChainStats bool
+
+	// ValidateEntries has Submit and SubmitAtHeight refuse malformed entries (see validateEntry) as
+	// Malformed, before they are queued, rather than leaving the Run loop to build chains out of them.
+	ValidateEntries bool
+
+	// EntryValidator, with ValidateEntries set, is asked about each entry that passes validateEntry's own
+	// checks, and an entry it returns an error for is refused as Malformed.  The accumulator only holds
+	// hashes, so the validator is given the PayloadResolver to fetch the entry's payload from wherever the
+	// validator keeps it (nil if none is set).
+	EntryValidator func(entry node.EntryHash, resolve PayloadResolver) error
+
+	// PayloadResolver is handed to the EntryValidator.  The accumulator never calls it itself.
+	PayloadResolver PayloadResolver
+
+	// EntryTimeStamps has sealBlock record the time of each entry (see Reader.EntryTimeStamp): the submitter's
+	// TimeStamp, if the entry carries one, or else the Clock's time when the entry was added to its block.  An
+	// entry recovered from the write ahead log has lost its submitter's TimeStamp, so gets the Clock's.
+	EntryTimeStamps bool
+	entryTimes      map[types.Hash]types.TimeStamp // The time of each entry in the current block, with EntryTimeStamps
+
+	// MaxTimeSkew has Submit reject, as TimeSkew, an entry whose submitter's TimeStamp is further than this
+	// from the Clock, ahead or behind.  Zero takes any TimeStamp.
+	MaxTimeSkew time.Duration
+
+	// MaxBatchSize caps the entries SubmitBatch takes in one call, so one producer can't hold up the others
+	// for long.  A bigger batch is refused whole with an ErrBatchTooLarge.  Zero means no limit.
+	MaxBatchSize int
+
+	// InternChains has the accumulator hang onto what each chain allocated in a block (the index of its entries,
+	// and its MD's list of hashes) and reuse it when the chain turns up again in the next block, rather than
+	// growing them afresh.  It cuts the allocations of workloads where the same chains fill block after block,
+	// at the cost of holding a block's worth of buffers between blocks.  Doesn't change any MD root.
+	InternChains bool
+	interned     map[types.Hash]chainBuffers // Buffers of the chains in the last block, by ChainID
+	hot          hotChain                    // The chain that had the last block (nearly) to itself
+
+	// MaxEntriesPerBlock seals the block as soon as it holds this many entries, whether or not Run has been
+	// told to end the block, and even while paused.  Zero means no limit.
+	MaxEntriesPerBlock int
+	SkipEmptyBlocks    bool              // Don't seal a block with no entries when Run is told to end it
+	LogChains          bool              // Log each chain's entry count and root as its block is sealed
+	blockEntries       int               // Entries added to the current block
+	paused             atomic.AtomicBool // Set by Pause; Run ignores the end of block signal while set
+
+	// ReuseEmptyRoots seals a block with no chains flagged node.SameRoot: its ListMDRoot is the previous block's,
+	// and isn't stored with it.  Readers fill it in as they load the block (see sameRoot), and anyone else reading
+	// the blocks has to do the same.  It saves space when SkipEmptyBlocks isn't set and the idle blocks mount up.
+	ReuseEmptyRoots bool
+
+	// BlockInterval has Run end each block this long after it was opened, as if told to on the control
+	// channel.  MaxBlockDuration has Run seal a block this long after it was opened, even while paused.  Zero
+	// turns either off.  Times are taken from the Clock.  UpdatePolicy changes these, and MaxEntriesPerBlock,
+	// while Run is running.
+	BlockInterval    time.Duration
+	MaxBlockDuration time.Duration
+	blockStart       time.Time    // When the current block was opened; zero until Run first looks
+	intervalStart    time.Time    // When the current BlockInterval started
+	policyMux        sync.Mutex   // Guards policy
+	policy           *BlockPolicy // Set by UpdatePolicy, to be used from the next block on
+
+	// AwaitAnchors holds back each block until AnchorConfirmed says the block before it has been anchored
+	// externally, tying the blocks produced to the rate they are anchored.  sealBlock leaves the block open
+	// (taking entries, up to the usual limits) while it waits, so it is sealed by whatever ends the block
+	// next once the confirmation has arrived.  Confirmations aren't kept across restarts: after Init, the
+	// head has to be confirmed again.
+	AwaitAnchors bool
+	anchorMux    sync.Mutex        // Guards anchoredTo
+	anchoredTo   types.BlockHeight // One past the highest height AnchorConfirmed has been given
+	anchorLogged bool              // The wait for the current block's anchor has been logged
+
+	epoch     time.Duration // The ScheduledSealing period
+	catchUp   bool          // ScheduledSealing makes up for the boundaries missed
+	nextEpoch time.Time     // The next ScheduledSealing boundary to seal a block at
+
+	// SealWhen, if set, has Run seal the block as soon as the condition holds, as checked after each entry is
+	// added and each time Run finds nothing to do, e.g. SealAny(SealOnEntries(1000), SealOnDuration(2*time.Second)).
+	// The control channel then only ends a block through SealOnControl.  Like being told to end the block, it
+	// waits while paused.  MaxEntriesPerBlock, BlockInterval and MaxBlockDuration still apply.  Set before Run.
+	SealWhen SealCondition
+	told     bool // Run has been told to end the current block, for SealOnControl
+
+	// MaxChainsPerBlock caps the distinct chains in a block.  Once a block has that many, entries for any
+	// other chain are rejected as TooManyChains as they are added (after Submit has accepted them), while
+	// entries for the chains already in the block carry on.  Zero means no limit.
+	MaxChainsPerBlock int
+
+	// Partitions splits the ChainIDs into this many contiguous ranges, and builds the MDs of the chains in
+	// each range in a go routine of its own, so the hashing of a block is spread over that many cores.  The
+	// directory block's ListMDRoot is still the MD over every chain's root in ChainID order, i.e. over the
+	// roots of the first partition, then the second, and so on, so it is the same whatever the number of
+	// partitions.  Zero or one leaves all the hashing to Run.  Set before calling Run.  Since the hashing is
+	// done after the entry has been added, a panic in a partition (from the Hasher, say) drops the whole block
+	// as it is sealed, rather than just the entry.
+	Partitions int
+	partitions partitions
+
+	// Sequencer, if set, orders the entries of each block.  Entries are held as they arrive, then added in
+	// the order of the sequences assigned to them when the block is sealed.  Set before calling Run.
+	Sequencer Sequencer
+	sequenced []sequencedEntry // Entries held for the current block by the Sequencer
+
+	// WALPath, if set, has Submit log every entry it accepts to a write ahead log at this path before
+	// queuing it, and Init add the entries logged but never sealed (say, because we crashed) to the first
+	// block.  Sealing a block trims its entries off the log.  Entries sent straight to the entryFeed aren't
+	// logged, and would upset the trimming, so all entries have to go through Submit.  Set before calling
+	// Init, along with anything else (Hasher, BlockFlags ...) the first block depends on.
+	WALPath string
+	wal     wal
+
+	// RepairOnInit has Init move the head of the directory blocks to the highest block that can be read back,
+	// if SelfCheck finds they disagree.  Otherwise Init panics with the *HeadInconsistent.
+	RepairOnInit bool
+
+	// StrictMode has sealBlock check, before anything is written, that the root each chain gives the
+	// directory block is the root its MD computes afresh, catching a chain node reused with a stale root.  A
+	// block that fails the check is logged and left open.  It costs a second GetMDRoot for every chain.
+	StrictMode bool
+
+	// MaxCommitFailures trips a circuit breaker once this many commits in a row have failed: the accumulator
+	// turns Unhealthy, rejecting entries and sealing no blocks, until Reset.  See Health.  Zero never trips it.
+	MaxCommitFailures int
+	healthMux         sync.Mutex        // Guards commitFailures and health
+	commitFailures    int               // Commits failed since the last one that succeeded (or Reset)
+	health            error             // Why the breaker tripped; nil while healthy
+	unhealthy         atomic.AtomicBool // Set once the breaker trips, until Reset
+	retrying          bool              // The current block failed to commit, and is kept open to try again
+
+	// MaxHeight is the height at which the accumulator stops sealing blocks, failing with ErrHeightExhausted
+	// rather than wrapping around to 0.  Zero means types.MaxBlockHeight; it is only set lower for testing.
+	MaxHeight    types.BlockHeight
+	heightWarned bool // The approach to MaxHeight has been logged
+
+	// VerifyPrevious has sealBlock read the previous directory block back from the database before chaining
+	// the next one off it, refusing with ErrPrevCorrupt if it has been corrupted.  This costs a read per block.
+	VerifyPrevious bool
+
+	// RequireSyncHeight holds back sealing (see checkSynced) until the current block's height has reached it,
+	// so an accumulator joining a network doesn't produce blocks until it has caught up with the network's
+	// height, say by replicating its blocks (see ApplyBlock).  Entries submitted meanwhile wait in the current
+	// block.  Zero doesn't hold anything back.  Change it with SetSyncHeight once Run is running.
+	RequireSyncHeight types.BlockHeight
+	syncMux           sync.Mutex // Guards RequireSyncHeight and syncLogged
+	syncLogged        bool       // The wait for the RequireSyncHeight has been logged
+
+	// MaxDBBytes, if set, is the most the database should take up.  Once a sealed block takes it over, the
+	// oldest blocks are pruned (see Prune) until the bytes they held bring it back under, or only the last
+	// MinKeepBlocks blocks are left unpruned, and the database is then compacted in the background.  The store
+	// has to know its size (see database.Sizer).
+	MaxDBBytes    int64
+	MinKeepBlocks types.BlockHeight
+	budget        budget // How far the pruning for MaxDBBytes has got
+
+	annotationMux sync.Mutex // Guards annotation
+	annotation    []byte     // Set by SetNextBlockAnnotation for the next block sealed
+
+	stopping  atomic.AtomicBool // Set by Stop; Submit rejects entries and Run seals its last block
+	submitMux sync.RWMutex      // Held by Submit while it queues an entry, so Stop can wait them out
+	stopped   chan struct{}     // Closed when Run returns
+
+	watchMux sync.Mutex    // Guards sealed and the acknowledged height
+	sealed   chan struct{} // Closed when the next block is committed, to wake up the BlockWatchers
+	bus      eventBus      // The Observers, told of each block committed
+
+	totalEntries  int64  // We count the entries and chains as we go, but update the atomic counts
+	chainsInBlock int64  //  at the end of each block
+	sealedEntries uint64 // Entries in all the blocks sealed, across restarts
+}
+
+// ErrInvalidConfig
+// What Init panics with, before touching anything, when it is given nothing to work with
+type ErrInvalidConfig struct {
+	Problem string // What is missing
+}
+
+func (e ErrInvalidConfig) Error() string {
+	return "invalid accumulator configuration: " + e.Problem
+}
+
+// checkConfig
+// Make sure Init has a database with a Store underneath, and a ChainID
+func checkConfig(db *database.DB, chainID *types.Hash) error {
+	switch {
+	case db == nil:
+		return ErrInvalidConfig{Problem: "no database"}
+	case db.GetStore() == nil:
+		return ErrInvalidConfig{Problem: "the database has no store; call Init or InitStore on it first"}
+	case chainID == nil:
+		return ErrInvalidConfig{Problem: "no ChainID"}
+	}
+	return nil
 }
 
 // Allocate the HashMap and Channels for this accumulator
 // The ChainID is the Digital Identity of the Accumulator.  We will want to integrate
 // useful digital IDs into the accumulator structure to ensure the integrity of the data
-// collected.
+// collected.  A nil db or chainID, or a db with no Store, panics with an ErrInvalidConfig.
+// Restarting against a database whose ChainParams don't match the Hasher (or the
+// one the BlockFlags pick) panics with a *ParamMismatch, and one that fails SelfCheck (unless
+// RepairOnInit is set) with a *HeadInconsistent.
 func (a *Accumulator) Init(db *database.DB, chainID *types.Hash) (
 	EntryFeed chan node.EntryHash, // Return the EntryFeed channel to send ANode Hashes to the accumulator
 	control chan bool, // The control channel signals End of Block to the accumulator
 	mdFeed chan *types.Hash) { // the Merkle DAG Feed (mdFeed) returns block merkle DAG roots
 
+	if err := checkConfig(db, chainID); err != nil {
+		panic(err)
+	}
 	a.DB = db
 	a.chainID = chainID
+	if err := a.SelfCheck(); err != nil {
+		if a.RepairOnInit {
+			err = a.repairHead()
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
 	headHash := db.Get(types.NodeHead, chainID[:])
 	if headHash != nil {
 		head := db.Get(types.Node, headHash)
@@ -58,141 +379,735 @@ func (a *Accumulator) Init(db *database.DB, chainID *types.Hash) (
 		if err != nil {
 			panic(fmt.Sprintf("error unmarshaling the head of the directory block.\n%v", err))
 		}
+		if err := a.Reader().sameRoot(&headNode); err != nil {
+			panic(fmt.Sprintf("error finding the ListMDRoot of the head of the directory block.\n%v", err))
+		}
 		a.previous = &headNode
 		a.height = headNode.BHeight + 1
 	}
+	if err := a.checkParams(); err != nil {
+		panic(err)
+	}
+	finalized, recorded, err := a.Reader().FinalizedCount()
+	if err != nil {
+		panic(fmt.Sprintf("error reading the finalized height.\n%v", err))
+	}
+	if recorded { // Carry on from the last block made final before we restarted
+		a.finalized.Store(int64(finalized))
+	} else if a.height > a.FinalizationDepth { // An older database; take the blocks deep enough as final
+		a.finalized.Store(int64(a.height - a.FinalizationDepth))
+	}
+	total, err := a.Reader().TotalEntries()
+	if err != nil {
+		panic(fmt.Sprintf("error reading the total entries accumulated.\n%v", err))
+	}
+	a.sealedEntries = total
+	a.schedule.reopen(a.height)
 	a.chains = make(map[types.Hash]*ChainAcc, 1000)
+	a.continuous = make(map[types.Hash]*merkleDag.MD)
 	a.entryFeed = make(chan node.EntryHash, 10000)
 	a.control = make(chan bool, 1)
 	a.mdFeed = make(chan *types.Hash, 1)
+	a.stopped = make(chan struct{})
+	a.swaps = make(chan storeSwap)
+	a.queries = make(chan func())
+	if a.WALPath != "" {
+		entries, err := a.openWAL()
+		if err != nil {
+			panic(fmt.Sprintf("error opening the write ahead log %s.\n%v", a.WALPath, err))
+		}
+		for _, entry := range entries { // Replay what was never sealed into the first block
+			a.processEntry(entry)
+		}
+		a.wal.taken = len(entries)
+	}
 
-	fmt.Sprintf("Starting the Accumulator at height %d\n", a.height)
+	a.logger().Printf("starting the accumulator at height %d", a.height)
 
 	return a.entryFeed, a.control, a.mdFeed
 }
 
+// TotalEntries
+// The count of entries in every block this accumulator has sealed, including those sealed before a restart
+func (a *Accumulator) TotalEntries() (uint64, error) {
+	return a.Reader().TotalEntries()
+}
+
 func (a *Accumulator) GetEntryFeed() chan node.EntryHash {
 	return a.entryFeed
 }
 
+// ChainRootFeed
+// Return a feed that gets the ChainID and ListMDRoot of every chain in each block as the block is sealed,
+// for consumers that follow particular chains rather than the directory block.  The feed is made by the
+// first call, so call it before Run; until then no chain roots are sent.
+func (a *Accumulator) ChainRootFeed() chan node.NEList {
+	if a.chainRootFeed == nil {
+		a.chainRootFeed = make(chan node.NEList, 10000)
+	}
+	return a.chainRootFeed
+}
+
+// Run
+// Build blocks from the entryFeed until Stop is called.  Then every entry already queued is added, the
+// current block is sealed (paused or not), and Run returns.
 func (a *Accumulator) Run() {
-	var totalEntries int64  // We count the entries and chains as we go, but update the atomic counts
-	var ChainsInBlock int64 //  at the end of each block
+	defer close(a.stopped)
+	for !a.stopping.Load() {
+		a.step()
+	}
+	defer a.stopPartitions()
+	a.ProcessPending()
+	if a.SkipEmptyBlocks && a.blockEmpty() {
+		return
+	}
+	a.SealBlock()
+}
 
-	var goWrites atomic.AtomicInt
+// Stop
+// Have Run seal what it has and return, and wait until it has, and for any compaction MaxDBBytes started.
+// Submit rejects entries from the moment Stop is called, as ShuttingDown, so nothing is accepted that won't be
+// committed.  Run has to be running, and Stop can't be called from the go routine running it (from OnCommit,
+// say).
+func (a *Accumulator) Stop() {
+	a.submitMux.Lock() // Wait for any Submit in progress to get its entry onto the entryFeed
+	a.stopping.Store(true)
+	a.submitMux.Unlock()
+	<-a.stopped
+	a.budget.running.Wait()
+}
 
-	for {
-		// While we are processing a block
-	block:
-		for {
-
-			// Block processing involves pulling Entries out of the entryFeed and adding
-			// it to the Merkle DAG (MD)
-			select {
-			case ctl := <-a.control: // Have we been asked to end the block?
-				if ctl {
-					println("Processing EOB ", a.height)
-					a.height++
-					break block // Break block processing
-				}
-			default:
-				select {
-				case entry := <-a.entryFeed: // Get the next ANode
-					chain := a.chains[entry.ChainID] // See if we have a chain for it
-					totalEntries++
-					if chain == nil { // If we don't have a chain for it, then we add one to our tmp state
-						ChainsInBlock++
-						chain = NewChainAcc(*a.DB, entry, a.height) // Create our collector for this chain
-						a.chains[entry.ChainID] = chain             // Add it to our tmp state
-						chain.MD.AddToChain(entry.EntryHash)        // Add this entry to our chain state
-					} else {
-						// This is where we make sure every Entry added to a chain is a non-duplicate to all
-						// entries.  This assumes that the chains for an accumulator are unique to that accumulator,
-						// which is true by design.  So if the entry isn't in the chain right now, and not in the db,
-						// then it is unique.
-						if chain.entries[entry.EntryHash] == 0 { // Added this entry to this chain already?
-							if a.DB.Get(types.EntryNode, entry.EntryHash.Bytes()) == nil { // Have the entry in the DB already?
-								chain.entries[entry.EntryHash] = 1   // No? Then mark it in the chain
-								chain.MD.AddToChain(entry.EntryHash) // Add it to the chain
-							}
-						}
-					}
-				default:
-					time.Sleep(100 * time.Millisecond) // If there is nothing to do, pause a bit
-				}
+// Close
+// Stop the partition go routines of an accumulator driven by ProcessPending and SealBlock rather than Run
+// (Run stops them itself as it returns), and close the write ahead log, which Stop leaves open.  Anything not
+// yet sealed is left in the log for the next Init.  Don't use the accumulator after Close.
+func (a *Accumulator) Close() error {
+	a.stopPartitions()
+	return a.wal.close()
+}
+
+// step
+// One trip through the Run loop.  Block processing involves pulling Entries out of the entryFeed and
+// adding it to the Merkle DAG (MD), until we are told to end the block.
+func (a *Accumulator) step() {
+	if a.timeUp() || a.sealDue() || a.epochDue() {
+		return
+	}
+	select {
+	case ctl := <-a.control: // Have we been asked to end the block?
+		switch {
+		case ctl && a.SealWhen != nil: // Left to SealOnControl; like endOfBlock, we drop it while paused
+			a.told = !a.paused.Load()
+		case ctl:
+			a.endOfBlock(false)
+		}
+	case swap := <-a.swaps:
+		swap.done <- a.swapStore(swap.store)
+	case query := <-a.queries:
+		query()
+	default:
+		select {
+		case entry := <-a.entryFeed: // Get the next ANode
+			a.wal.taken++
+			a.noteBusy()
+			a.processEntry(entry)
+			if a.blockFull() {
+				a.SealBlock()
+			} else {
+				a.sealDue()
 			}
+		default:
+			a.noteEmpty()
+			time.Sleep(100 * time.Millisecond) // If there is nothing to do, pause a bit
+		}
+	}
+}
+
+// endOfBlock
+// Seal the current block, unless we are paused (and not forced to seal anyway) or skipping empty blocks.
+// Returns true if the block was sealed, false if it was left open (or dropped).
+func (a *Accumulator) endOfBlock(force bool) bool {
+	if a.paused.Load() && !force {
+		a.logger().Printf("paused; not ending the block at height %d", a.height)
+		return false
+	}
+	if a.SkipEmptyBlocks && a.blockEmpty() {
+		a.logger().Printf("skipping the empty block at height %d", a.height)
+		return false
+	}
+	println("Processing EOB ", a.height)
+	return a.SealBlock() != nil
+}
+
+// timeUp
+// End the current block if it has been open for MaxBlockDuration, or another BlockInterval has passed.
+// Returns true if either was up, whether or not the block was sealed.  If the BlockInterval is up but we
+// are paused, we wait another BlockInterval before trying again.
+func (a *Accumulator) timeUp() bool {
+	now := a.clock().Now()
+	if a.blockStart.IsZero() {
+		a.blockStart, a.intervalStart = now, now
+	}
+	if a.MaxBlockDuration > 0 && now.Sub(a.blockStart) >= a.MaxBlockDuration {
+		if !a.endOfBlock(true) { // Only an empty block we are skipping is left open
+			a.blockStart = now
 		}
+		return true
+	}
+	if a.BlockInterval > 0 && now.Sub(a.intervalStart) >= a.BlockInterval {
+		if !a.endOfBlock(false) {
+			a.intervalStart = now
+		}
+		return true
+	}
+	return false
+}
+
+// Pause
+// Stop Run from ending blocks when told to.  Entries are still added to the current block, until it
+// reaches MaxEntriesPerBlock.  May be called from any go routine.
+func (a *Accumulator) Pause() {
+	a.paused.Store(true)
+}
 
-		if goWrites.Load() > 0 {
-			fmt.Println("Waiting on", goWrites.Load(), "database updates.")
-			for goWrites.Load() > 0 {
-				time.Sleep(1 * time.Second)
+// Resume
+// Let Run end blocks again.  The next end of block signal seals everything collected while paused.
+func (a *Accumulator) Resume() {
+	a.paused.Store(false)
+}
+
+// UpdatePolicy
+// Change the MaxEntriesPerBlock, BlockInterval and MaxBlockDuration while Run is running.  The new policy
+// takes effect from the next block, so the current block is finished under the rules it was opened with.
+// Returns an error, and changes nothing, if the policy is invalid.  May be called from any go routine.
+func (a *Accumulator) UpdatePolicy(p BlockPolicy) error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	a.policyMux.Lock()
+	defer a.policyMux.Unlock()
+	a.policy = &p
+	return nil
+}
+
+// nextBlock
+// Open the next block, switching to the policy given to UpdatePolicy if there is one
+func (a *Accumulator) nextBlock() {
+	a.policyMux.Lock()
+	if p := a.policy; p != nil {
+		a.MaxEntriesPerBlock = p.MaxEntriesPerBlock
+		a.BlockInterval = p.BlockInterval
+		a.MaxBlockDuration = p.MaxBlockDuration
+		a.policy = nil
+	}
+	a.policyMux.Unlock()
+	a.blockStart = a.clock().Now()
+	a.intervalStart = a.blockStart
+	a.told = false
+	a.retrying = false
+	a.anchorLogged = false
+	a.resetQuotas()
+}
+
+// blockFull
+// True if the current block has reached MaxEntriesPerBlock
+func (a *Accumulator) blockFull() bool {
+	return a.MaxEntriesPerBlock > 0 && a.blockEntries+len(a.sequenced) >= a.MaxEntriesPerBlock
+}
+
+// blockEmpty
+// True if nothing has been added to the current block, nor queued or held for it
+func (a *Accumulator) blockEmpty() bool {
+	return len(a.chains) == 0 && len(a.sequenced) == 0 && !a.schedule.has(a.height)
+}
+
+// processEntry
+// Add an entry pulled from the entryFeed, dropping it if that fails.  With a Sequencer, the entry is held
+// until the block is sealed instead.
+func (a *Accumulator) processEntry(entry node.EntryHash) {
+	if a.Sequencer != nil {
+		a.safely("sequencing an entry", func() { a.sequenceEntry(entry) }, func() { a.dropEntry(entry) })
+		return
+	}
+	a.safely("adding an entry",
+		func() { a.addEntry(entry) },
+		func() { a.dropEntry(entry) })
+}
+
+// ProcessPending
+// Add every entry waiting in the entryFeed to the current block, sealing it if it reaches MaxEntriesPerBlock,
+// then return.  With SealBlock, this lets
+// the accumulator be driven step by step (as tests do) rather than by Run.  Don't call it while Run is running.
+func (a *Accumulator) ProcessPending() {
+	for {
+		select {
+		case entry := <-a.entryFeed:
+			a.wal.taken++
+			a.processEntry(entry)
+			if a.blockFull() {
+				a.SealBlock()
 			}
+		default:
+			return
 		}
+	}
+}
+
+// SealBlock
+// End the current block, as Run does when sent a true on the control channel, and return the directory
+// block.  Returns nil if sealing panicked and the block was dropped, or if the block was left open because
+// its commit failed, it is at MaxHeight or already sealed, it awaits the anchor of the block before it (see
+// AwaitAnchors), or the accumulator is unhealthy (see Health).  Don't call it while Run is running.
+func (a *Accumulator) SealBlock() (directoryBlock *node.Node) {
+	if a.unhealthy.Load() {
+		a.logger().Printf("unhealthy; not sealing the block at height %d", a.height)
+		return nil
+	}
+	a.safely("sealing a block", func() { directoryBlock = a.endBlock() }, a.dropBlock)
+	return directoryBlock
+}
 
-		var chainEntries []node.NEList
-		for _, v := range a.chains {
-			v.Node.ListMDRoot = *v.MD.GetMDRoot()
-			v.Node.EntryList = v.MD.HashList
-			v.Node.IsNode = false
+// hasher
+// The Hasher to build the Merkle DAGs with.  Blocks flagged DomainSeparated use the merkleDag.DomainHasher
+// whatever Hasher is set.
+func (a *Accumulator) hasher() merkleDag.Hasher {
+	if a.BlockFlags.Has(node.DomainSeparated) {
+		return merkleDag.DomainHasher{}
+	}
+	return a.Hasher
+}
 
-			tNode := v.Node
-			go func() {
-				goWrites.Add(1)
-				tNode.Put(a.DB)
-				goWrites.Add(-1)
-			}()
+// safely
+// Do some work in the Run loop.  Unless the PanicPolicy says to let panics through, a panic is logged
+// and counted, and cleanup is called to throw away whatever the work was doing so Run can carry on.
+func (a *Accumulator) safely(what string, work func(), cleanup func()) {
+	if a.PanicPolicy == PropagatePanics {
+		work()
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger().Printf("recovered from a panic while %s at height %d: %v\n%s", what, a.height, r, debug.Stack())
+			a.metrics().Add(MetricPanics, 1)
+			cleanup()
+		}
+	}()
+	work()
+}
 
-			ne := new(node.NEList)
-			ne.ChainID = v.Node.ChainID
-			ne.MDRoot = v.Node.ListMDRoot
-			chainEntries = append(chainEntries, *ne)
+// endBlock
+// Seal the current block and hand its MD root to whoever is reading the mdFeed, and its chain roots to
+// whoever is reading the chainRootFeed.
+func (a *Accumulator) endBlock() *node.Node {
+	directoryBlock := a.sealBlock()
+	if directoryBlock == nil {
+		return nil
+	}
+	a.sendMDRoot(directoryBlock.BHeight, directoryBlock.GetMDRoot())
+	a.sendChainRoots(directoryBlock)
+	return directoryBlock
+}
 
+// sendMDRoot
+// Put a block's MD root on the mdFeed according to the MDFeedPolicy.
+func (a *Accumulator) sendMDRoot(height types.BlockHeight, mdRoot *types.Hash) {
+	if a.MDFeedPolicy == BlockUntilRead {
+		a.mdFeed <- mdRoot
+		return
+	}
+	select {
+	case a.mdFeed <- mdRoot:
+	default:
+		a.logger().Printf("No reader on the mdFeed; dropped the MD root %x for block %d", *mdRoot, height)
+		a.feedDropped(&a.feed.mdRoots, MetricMDRootsDropped, 1)
+	}
+}
+
+// sendChainRoots
+// Put the root of each chain in a block on the chainRootFeed, if there is one, according to the MDFeedPolicy.
+func (a *Accumulator) sendChainRoots(directoryBlock *node.Node) {
+	if a.chainRootFeed == nil {
+		return
+	}
+	roots, err := a.Reader().blockChains(directoryBlock)
+	if err != nil {
+		a.logger().Printf("can't read the chain roots of block %d for the chainRootFeed: %v", directoryBlock.BHeight, err)
+		return
+	}
+	for i, ne := range roots {
+		if a.MDFeedPolicy == BlockUntilRead {
+			a.chainRootFeed <- ne
+			continue
 		}
+		select {
+		case a.chainRootFeed <- ne:
+		default:
+			a.logger().Printf("No reader on the chainRootFeed; dropped %d chain roots for block %d",
+				len(roots)-i, directoryBlock.BHeight)
+			a.feedDropped(&a.feed.chainRoots, MetricChainRootsDropped, int64(len(roots)-i))
+			return
+		}
+	}
+}
 
-		sort.Slice(chainEntries, func(i, j int) bool {
-			return bytes.Compare(chainEntries[i].ChainID[:], chainEntries[j].ChainID[:]) < 0
-		})
+// addEntry
+// Add an entry to the chain it belongs to in the current block.
+func (a *Accumulator) addEntry(entry node.EntryHash) {
+	a.totalEntries++
+	trace := a.takeTrace(entry)
+	// This is where we make sure every Entry added to a chain is a non-duplicate to all
+	// entries.  This assumes that the chains for an accumulator are unique to that accumulator,
+	// which is true by design.  So if the entry isn't in the chain right now, and not in the db,
+	// then it is unique.
+	chain := a.chain(entry.ChainID) // See if we have a chain for it, and if it has this entry already
+	if chain != nil && chain.entries[entry.EntryHash] != 0 {
+		return
+	}
+	if a.DB.Get(types.EntryNode, entry.EntryHash.Bytes()) != nil { // Have the entry in the DB already?
+		return
+	}
+	if chain == nil && a.MaxChainsPerBlock > 0 && len(a.chains) >= a.MaxChainsPerBlock {
+		a.reject(entry, TooManyChains) // No room for another chain in this block
+		return
+	}
+	if chain == nil { // If we don't have a chain for it, then we add one to our tmp state
+		var carried *merkleDag.MD
+		var err error
+		if a.ContinuousChains[entry.ChainID] { // Continuous chains pick up where the last block left off
+			if carried, err = a.continuousMD(entry.ChainID); err != nil {
+				a.logger().Printf("dropping entry %x: %v", entry.EntryHash, err)
+				return
+			}
+		}
+		if chain, err = NewChainAcc(*a.DB, entry, a.height, a.now()); err != nil { // Create our collector for this chain
+			panic(err) // processEntry drops the entry
+		}
+		chain.MD.Hasher = a.hasher()
+		if a.InternChains {
+			a.internChain(chain)
+		}
+		a.heatChain(chain)
+		if carried != nil {
+			chain.Continue(carried)
+		}
+		if a.EntrySequences {
+			chain.FirstSequence = nextSequence(a.Reader(), entry.ChainID)
+		}
+		a.chains[entry.ChainID] = chain // Add it to our tmp state
+		a.chainsInBlock++
+	}
+	chain.entries[entry.EntryHash] = 1 // Mark it in the chain
+	a.addHash(chain, entry.EntryHash)  // Add it to the chain
+	if a.EntryTimeStamps {
+		a.timeEntry(entry)
+	}
+	a.blockEntries++
+	a.traceAdded(entry, trace)
+}
 
-		// Print some statistics
-		var sum int
-		for _, v := range a.chains {
-			sum += len(v.MD.HashList)
+// dropEntry
+// Undo a failed addEntry.  The entry's chain may be left with a half built MD, so we rebuild the MD
+// from the hashes that made it in before this entry.
+func (a *Accumulator) dropEntry(entry node.EntryHash) {
+	a.logger().Printf("dropped entry %x for chain %x", entry.EntryHash, entry.ChainID)
+	chain := a.chains[entry.ChainID]
+	if chain == nil {
+		return
+	}
+	a.settle()
+	hashes := chain.MD.HashList
+	if chain.entries[entry.EntryHash] == 1 && len(hashes) > 0 && hashes[len(hashes)-1] == entry.EntryHash {
+		delete(chain.entries, entry.EntryHash)
+		delete(a.entryTimes, entry.EntryHash)
+		hashes = hashes[:len(hashes)-1]
+		a.blockEntries--
+		delete(a.traces.block, entry.EntryHash)
+	}
+	md := new(merkleDag.MD)
+	md.Hasher = a.hasher()
+	for _, h := range hashes {
+		md.AddToChain(h)
+	}
+	chain.MD = md
+	if a.ContinuousChains[entry.ChainID] {
+		a.continuous[entry.ChainID] = md
+	}
+	if len(md.HashList) == chain.Carried { // No entries left in this block for the chain
+		delete(a.chains, entry.ChainID)
+		if a.hot.chain == chain {
+			a.hot.chain = nil
 		}
+		a.chainsInBlock--
+	}
+}
 
-		a.EntryCnt.Store(totalEntries)
-		a.ChainsInBlock.Store(ChainsInBlock)
-		a.ChainCnt.Add(ChainsInBlock)
-		ChainsInBlock = 0
+// dropBlock
+// Throw away a block that failed to seal.  Nothing was written, so all the entries collected for the block
+// are lost, and the next block is built at the same height.
+func (a *Accumulator) dropBlock() {
+	a.logger().Printf("dropped the block at height %d, losing the entries of %d chains", a.height, len(a.chains))
+	a.settle()
+	a.partitions.failed() // The MDs that failed are thrown away with the rest
+	for chainID := range a.chains {
+		delete(a.continuous, chainID) // Continuous chains will be rebuilt from the database
+	}
+	a.hot = hotChain{}
+	a.chains = make(map[types.Hash]*ChainAcc, 1000)
+	a.entryTimes = nil
+	a.chainsInBlock = 0
+	a.blockEntries = 0
+	a.sequenced = nil
+	a.traces.block = nil
+	a.schedule.reopen(a.height)
+	a.nextBlock()
+}
 
-		// Calculate the ListMDRoot for all the accumulated MDRoots for all the chains
-		MDAcc := new(merkleDag.MD)
-		for _, v := range chainEntries {
-			MDAcc.AddToChain(v.MDRoot)
+// sealBlock
+// End the current block, or return nil if the batch fails to commit, leaving the block open with all its
+// entries to be sealed again.  Every chain with entries in this block gets a node recording the entries added and
+// the chain's ListMDRoot, and the directory block collects the ListMDRoots of all those chains.  BeforeSeal
+// adds its entries first, then the entries queued for this block by SubmitAtHeight are added, then those
+// held by the Sequencer in sequence order.  All the hashing is done before anything is written, then the chain
+// nodes and the directory block are written in one batch.  Once the batch is committed, OnCommit is told, then
+// OnFinal and the Anchorer of whichever block that makes final.  Each attempt to seal the block is traced as a
+// SpanSeal span.
+func (a *Accumulator) sealBlock() (sealed *node.Node) {
+	span, start := a.startSpan(SpanSeal), a.clock().Now()
+	defer func() { // Ended even if sealing panics
+		span.SetAttribute(AttrSealDuration, a.clock().Now().Sub(start))
+		span.SetAttribute(AttrSealed, sealed != nil)
+		span.End()
+	}()
+	if a.checkHeight() != nil || a.checkSynced() != nil || a.checkSealed() != nil || a.checkPrevious() != nil ||
+		a.anchorDue() {
+		return nil
+	}
+	if !a.retrying { // BeforeSeal's entries are already in a block we are trying again
+		a.beforeSeal()
+	}
+	a.addScheduled()
+	a.addSequenced()
+	a.checkPartitions()
+
+	chains := a.chainsInOrder()
+	chainEntries := make([]node.NEList, 0, len(chains))
+	for _, v := range chains {
+		v.Node.ListMDRoot = *v.MD.GetMDRoot()
+		v.Node.EntryList = v.MD.HashList[v.Carried:]
+		v.Node.IsNode = false
+
+		ne := new(node.NEList)
+		ne.ChainID = v.Node.ChainID
+		ne.MDRoot = v.Node.ListMDRoot
+		chainEntries = append(chainEntries, *ne)
+		if a.LogChains {
+			a.logger().Printf("block %d chain %x: %d entries, root %x", a.height, ne.ChainID, len(v.Node.EntryList), ne.MDRoot)
 		}
+	}
 
-		// Populate the directory block with the data collected over the last block period.
-		directoryBlock := new(node.Node)
-		directoryBlock.Version = types.Version
-		directoryBlock.ChainID = *a.chainID
-		directoryBlock.BHeight = a.height
-		if directoryBlock.SequenceNum > 0 {
-			directoryBlock.Previous = *a.previous.GetHash()
+	if a.StrictMode {
+		if err := checkChainRoots(chains, chainEntries); err != nil { // Keep the block open, as for a failed commit
+			a.logger().Printf("not sealing the block at height %d: %v", a.height, err)
+			a.retrying = true
+			return nil
 		}
-		directoryBlock.SequenceNum = types.Sequence(a.height)
-		directoryBlock.TimeStamp = types.TimeStamp(time.Now().UnixNano())
-		directoryBlock.IsNode = true
-		lMDR := MDAcc.GetMDRoot()
-		if lMDR != nil {
-			directoryBlock.ListMDRoot = *lMDR
+	}
+
+	// Calculate the ListMDRoot for all the accumulated MDRoots for all the chains (or their group)
+	list, group := a.groupChains(chains, chainEntries)
+	MDAcc := a.directoryMD(list)
+
+	// Populate the directory block with the data collected over the last block period.
+	directoryBlock := new(node.Node)
+	directoryBlock.Version = types.Version
+	directoryBlock.Flags = a.BlockFlags
+	directoryBlock.ChainID = *a.chainID
+	directoryBlock.BHeight = a.height
+	directoryBlock.SequenceNum = types.Sequence(a.height)
+	if a.previous != nil {
+		directoryBlock.Previous = *a.previous.GetHash()
+	}
+	directoryBlock.TimeStamp = a.now()
+	directoryBlock.IsNode = true
+	directoryBlock.List = list
+	directoryBlock.ListMDRoot = *MDAcc.GetMDRoot() // The merkleDag.EmptyMDRoot if no chains have entries
+	if a.ReuseEmptyRoots && len(list) == 0 && a.previous != nil {
+		directoryBlock.Flags |= node.SameRoot
+		directoryBlock.ListMDRoot = a.previous.ListMDRoot
+	}
+
+	// Write the chain nodes, then the directory, into a batch that is committed all at once
+	batch := a.DB.NewBatch()
+	var writes sync.WaitGroup
+	for _, v := range chains {
+		chainID := v.Node.ChainID
+		if a.ContinuousChains[chainID] { // Hang onto the MD so the next block can extend it
+			a.continuous[chainID] = v.MD
+		}
+		tNode := v.Node
+		writes.Add(1)
+		go func() {
+			tNode.Put(&batch.DB)
+			nodeHash := tNode.GetHash()
+			for _, h := range tNode.EntryList { // Index where each entry is, so later duplicates are caught
+				batch.Put(types.EntryNode, h.Bytes(), nodeHash.Bytes())
+			}
+			writes.Done()
+		}()
+		if a.EntrySequences {
+			a.writeSequences(&batch.DB, &writes, v)
 		}
+		if a.CountEntryTypes {
+			a.writeEntryTypeCounts(&batch.DB, v)
+		}
+		if a.PermanentDedup {
+			a.writeChainEntries(&batch.DB, v)
+		}
+		if a.EntryTimeStamps {
+			a.writeEntryTimeStamps(&batch.DB, v)
+		}
+		if a.KeepChainStats {
+			a.writeChainStats(&batch.DB, v, directoryBlock.TimeStamp)
+		}
+	}
+	if a.PrecomputeReceipts {
+		a.writeReceipts(&batch.DB, &writes, MDAcc, list, group)
+	}
+	a.writeGroup(&batch.DB, group)
+	a.indexRoots(&batch.DB, chains)
+	a.indexChainHeights(&batch.DB, chains)
+	a.indexDirectoryRoots(&batch.DB, directoryBlock)
+	writes.Wait()
+	directoryBlock.Put(&batch.DB)
+	var blockEntries uint32
+	for _, v := range chains {
+		blockEntries += uint32(len(v.Node.EntryList))
+	}
+	sealedEntries := a.sealedEntries + uint64(blockEntries)
+	batch.PutInt32(types.BlockEntryCount, int(a.height), types.Uint32Bytes(blockEntries))
+	batch.Put(types.TotalEntries, a.chainID[:], types.Uint64Bytes(sealedEntries))
+	annotation := a.writeAnnotation(&batch.DB)
+	a.writeFinalized(&batch.DB)
+	params := a.writeParams(&batch.DB)
+	err := batch.Commit()
+	a.recordCommit(err)
+	if err != nil { // Keep the block open, entries and all, so the next attempt to seal it can commit it
+		a.logger().Printf("failed to commit the block at height %d; keeping it open to try again: %v", a.height, err)
+		a.restoreAnnotation(annotation)
+		a.retrying = true
+		return nil
+	}
+	if params != nil {
+		a.chainParams = params
+	}
+	a.previous = directoryBlock
+	a.sealedEntries = sealedEntries
+	a.traceSealed(span, a.height, int(blockEntries), len(chains))
+	if err := a.wal.trim(); err != nil { // The entries are sealed, so at worst they are replayed and dropped
+		a.logger().Printf("failed to trim the write ahead log after the block at height %d: %v", a.height, err)
+	}
+	if a.RecentDuplicateBlocks > 0 {
+		a.rememberRecent()
+	}
+
+	a.EntryCnt.Store(a.totalEntries)
+	a.ChainsInBlock.Store(a.chainsInBlock)
+	a.ChainCnt.Add(a.chainsInBlock)
+	a.chainsInBlock = 0
+
+	// Clear out all the chain heads, to start another round of accumulation in the next block
+	if a.InternChains {
+		a.recycleChains()
+	}
+	a.keepHot()
+	a.chains = make(map[types.Hash]*ChainAcc, 1000)
+	a.entryTimes = nil
+	a.blockEntries = 0
+	a.height++
+	a.nextBlock()
+
+	a.feedSealed()
+	a.signalSealed()
+	a.committed(directoryBlock)
+	a.chainsCreated(chains)
+	a.bus.publish(BlockEvent{Block: directoryBlock, MDRoot: *directoryBlock.GetMDRoot(), Entries: int(blockEntries)})
+	a.finalize(directoryBlock)
+	a.enforceBudget()
+	return directoryBlock
+}
+
+// directoryMD
+// Build the MD over the roots of the chains in a block, in the order given (ChainID order), whose root is
+// the directory block's ListMDRoot
+func (a *Accumulator) directoryMD(chainEntries []node.NEList) *merkleDag.MD {
+	MDAcc := new(merkleDag.MD)
+	MDAcc.Hasher = a.hasher()
+	for _, v := range chainEntries {
+		MDAcc.AddToChain(v.MDRoot)
+	}
+	return MDAcc
+}
 
-		// Write the directory
-		directoryBlock.Put(a.DB)
+// checkChainRoots
+// Check the root each chain gives the directory block is the root of the chain's MD, computed again
+func checkChainRoots(chains []*ChainAcc, chainEntries []node.NEList) error {
+	for i, v := range chains {
+		if root := *v.MD.GetMDRoot(); chainEntries[i].MDRoot != root || chainEntries[i].ChainID != v.Node.ChainID {
+			return errors.New(fmt.Sprintf("chain %x gives the directory block the root %x, but its MD has the root %x",
+				chainEntries[i].ChainID, chainEntries[i].MDRoot, root))
+		}
+	}
+	return nil
+}
 
-		a.mdFeed <- directoryBlock.GetMDRoot()
+// committed
+// Tell OnCommit about a block that has been written to the database.  The block is committed whatever
+// OnCommit does, so a panic in OnCommit is logged rather than dropping the block.
+func (a *Accumulator) committed(directoryBlock *node.Node) {
+	if a.OnCommit == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger().Printf("recovered from a panic in OnCommit for the block at height %d: %v\n%s",
+				directoryBlock.BHeight, r, debug.Stack())
+			a.metrics().Add(MetricPanics, 1)
+		}
+	}()
+	a.OnCommit(directoryBlock)
+}
 
-		// Clear out all the chain heads, to start another round of accumulation in the next block
-		a.chains = make(map[types.Hash]*ChainAcc, 1000)
+// writeReceipts
+// Build the receipt for every entry added in this block and write them to the database.  The chain
+// receipts come from the directory MD (by way of the group's MD for the chains grouped); each chain's entry
+// receipts are written by their own go routine.
+func (a *Accumulator) writeReceipts(db *database.DB, writes *sync.WaitGroup, MDAcc *merkleDag.MD, list, group []node.NEList) {
+	chainReceipts := make(map[types.Hash]*merkleDag.MDReceipt, len(a.chains))
+	for i, listReceipt := range merkleDag.BuildMDReceipts(*MDAcc) {
+		if list[i].ChainID != GroupChainID {
+			chainReceipts[list[i].ChainID] = listReceipt
+			continue
+		}
+		for j, groupReceipt := range merkleDag.BuildMDReceipts(*a.directoryMD(group)) {
+			chainReceipts[group[j].ChainID] = joinPaths(groupReceipt, listReceipt)
+		}
+	}
+	for chainID, chainReceipt := range chainReceipts {
+		chain := a.chains[chainID]
+		chainReceipt := chainReceipt
+		height := a.height
+		writes.Add(1)
+		go func() {
+			for _, entryReceipt := range merkleDag.BuildMDReceipts(*chain.MD)[chain.Carried:] {
+				receipt := new(Receipt)
+				receipt.Height = height
+				receipt.ChainID = chain.Node.ChainID
+				receipt.Flags = a.BlockFlags
+				receipt.EntryReceipt = *entryReceipt
+				receipt.ChainReceipt = *chainReceipt
+				db.Put(types.Receipt, ReceiptKey(receipt.ChainID, entryReceipt.EntryHash, height), receipt.Marshal())
+			}
+			writes.Done()
+		}()
 	}
 }
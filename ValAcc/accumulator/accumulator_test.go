@@ -0,0 +1,394 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// GetTestAccumulator
+// Build an accumulator over a fresh in memory database for use in tests.  The accumulator is not running;
+// tests drive it by adding entries and sealing blocks directly.
+func GetTestAccumulator(t *testing.T) *Accumulator {
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	acc := new(Accumulator)
+	chainID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	acc.Init(db, &chainID)
+	return acc
+}
+
+// GetTestEntry
+// Build an EntryHash for the given chain, unique for each i
+func GetTestEntry(chainID types.Hash, i int) node.EntryHash {
+	var eh node.EntryHash
+	eh.ChainID = chainID
+	eh.EntryHash = sha256.Sum256([]byte(fmt.Sprintf("test entry %x %d", chainID, i)))
+	return eh
+}
+
+// panicHasher
+// A sha256 hasher that panics whenever it is asked to combine a particular hash
+type panicHasher struct {
+	bad types.Hash
+}
+
+func (p panicHasher) Combine(left, right types.Hash) types.Hash {
+	if left == p.bad || right == p.bad {
+		panic("combining the bad hash")
+	}
+	return *left.Combine(right)
+}
+
+// countingMetrics
+// Keeps the counts in a map
+type countingMetrics map[string]int64
+
+func (c countingMetrics) Add(name string, delta int64) {
+	c[name] += delta
+}
+
+// runUntilIdle
+// Step the Run loop until the entry feed and control channel are empty
+func runUntilIdle(acc *Accumulator) {
+	for len(acc.entryFeed) > 0 || len(acc.control) > 0 {
+		acc.step()
+	}
+}
+
+// testClock
+// A clock that only moves when the test moves it
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func TestOnCommit(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("committed")))
+	var heights []types.BlockHeight
+	acc.OnCommit = func(directoryBlock *node.Node) {
+		// The block has to be in the database by the time we hear about it
+		stored, err := acc.Reader().GetDirectoryBlock(directoryBlock.BHeight)
+		if err != nil || *stored.GetHash() != *directoryBlock.GetHash() {
+			t.Errorf("block %d should be committed before OnCommit is called", directoryBlock.BHeight)
+		}
+		heights = append(heights, directoryBlock.BHeight)
+		if directoryBlock.BHeight == 1 {
+			panic("OnCommit panics")
+		}
+	}
+	for i := 0; i < 4; i++ {
+		acc.entryFeed <- GetTestEntry(chainID, i)
+		runUntilIdle(acc)
+		acc.control <- true
+		runUntilIdle(acc)
+	}
+	if len(heights) != 4 {
+		t.Fatalf("expected OnCommit once for each of 4 blocks, got %v", heights)
+	}
+	for i, h := range heights {
+		if h != types.BlockHeight(i) {
+			t.Errorf("OnCommit should be called in height order, got %v", heights)
+		}
+	}
+	if _, err := acc.Reader().GetChainNode(chainID, 1); err != nil {
+		t.Error("the block whose OnCommit panicked should not be lost")
+	}
+}
+
+func TestBlockFlags(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("flagged")))
+	other := types.Hash(sha256.Sum256([]byte("also flagged")))
+	var blocks []*node.Node
+	for i, flags := range []node.Flags{0, node.DomainSeparated, node.Signed} {
+		acc.BlockFlags = flags
+		for j := 0; j < 5; j++ {
+			acc.addEntry(GetTestEntry(chainID, i*5+j))
+			acc.addEntry(GetTestEntry(other, i*5+j))
+		}
+		blocks = append(blocks, acc.sealBlock())
+	}
+	if blocks[0].ListMDRoot == blocks[1].ListMDRoot {
+		t.Fatal("domain separation should change the roots")
+	}
+
+	r := NewReader(acc.DB, *acc.chainID) // A reader knowing nothing of how the blocks were built
+	for i := range blocks {
+		stored, err := r.GetDirectoryBlock(blocks[i].BHeight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Flags != blocks[i].Flags {
+			t.Errorf("block %d should be stored with its flags", i)
+		}
+		if err := r.VerifyDirectoryBlock(stored); err != nil && !stored.Flags.Has(node.Signed) {
+			t.Errorf("block %d should verify: %v", i, err)
+		}
+		receipt, err := r.GetReceipt(chainID, GetTestEntry(chainID, i*5+2).EntryHash, stored.BHeight)
+		if err != nil || receipt.ChainReceipt.MDRoot != stored.ListMDRoot {
+			t.Fatalf("the receipt for block %d should be built the way the block was: %v", i, err)
+		}
+		if err := receipt.Check(); err != nil || receipt.Flags != stored.Flags {
+			t.Errorf("the receipt for block %d should verify with the block's flags: %v", i, err)
+		}
+		var unmarshaled Receipt
+		if err := unmarshaled.Unmarshal(receipt.Marshal()); err != nil || !unmarshaled.Verify() {
+			t.Errorf("the receipt for block %d should still verify once unmarshaled (%v)", i, err)
+		}
+		proof, err := r.GetCrossChainProof(chainID, receipt.EntryReceipt.EntryHash,
+			other, GetTestEntry(other, i*5+3).EntryHash, stored.BHeight)
+		if err != nil || !proof.Verify() {
+			t.Errorf("the cross chain proof for block %d should verify (%v)", i, err)
+		}
+	}
+
+	tampered := *blocks[1]
+	tampered.Flags = 0
+	if r.VerifyDirectoryBlock(&tampered) == nil {
+		t.Error("clearing the DomainSeparated flag should make the block fail to verify")
+	}
+	if r.VerifyDirectoryBlock(blocks[2]) == nil {
+		t.Error("we have no way to verify signed blocks, so that should be an error")
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("paused")))
+	acc.Pause()
+	for i := 0; i < 10; i++ {
+		acc.entryFeed <- GetTestEntry(chainID, i)
+		runUntilIdle(acc)
+		if i%3 == 0 { // End of block signals while paused are ignored
+			acc.control <- true
+			runUntilIdle(acc)
+		}
+	}
+	if acc.height != 0 {
+		t.Fatalf("no blocks should be sealed while paused, the next height is %d", acc.height)
+	}
+	acc.Resume()
+	acc.control <- true
+	runUntilIdle(acc)
+	if acc.height != 1 {
+		t.Fatalf("expected exactly one block after resuming, the next height is %d", acc.height)
+	}
+	chain, err := acc.Reader().GetChainNode(chainID, 0)
+	if err != nil || len(chain.EntryList) != 10 {
+		t.Error("the block should hold every entry submitted while paused")
+	}
+
+	// Paused or not, a block is sealed once it reaches MaxEntriesPerBlock
+	acc.MaxEntriesPerBlock = 4
+	acc.Pause()
+	for i := 10; i < 20; i++ {
+		acc.entryFeed <- GetTestEntry(chainID, i)
+	}
+	runUntilIdle(acc)
+	if acc.height != 3 || acc.blockEntries != 2 {
+		t.Errorf("expected two full blocks sealed while paused, the next height is %d", acc.height)
+	}
+}
+
+func TestEmptyBlocks(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	first, second := acc.sealBlock(), acc.sealBlock()
+	if first.ListMDRoot != merkleDag.EmptyMDRoot || second.ListMDRoot != first.ListMDRoot {
+		t.Errorf("empty blocks should have the EmptyMDRoot, got %x and %x", first.ListMDRoot, second.ListMDRoot)
+	}
+	if err := acc.Reader().VerifyDirectoryBlock(second); err != nil {
+		t.Errorf("an empty block should verify: %v", err)
+	}
+
+	acc.SkipEmptyBlocks = true
+	acc.control <- true
+	runUntilIdle(acc)
+	if acc.height != 2 {
+		t.Errorf("an empty block should be skipped, the next height is %d", acc.height)
+	}
+}
+
+func TestMaxChainsPerBlock(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MaxChainsPerBlock = 3
+	rejected := 0
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) {
+		if reason == TooManyChains {
+			rejected++
+		}
+	}
+	var chains []types.Hash
+	for c := 0; c < 10; c++ {
+		chains = append(chains, types.Hash(sha256.Sum256([]byte(fmt.Sprintf("capped %d", c)))))
+	}
+	for i := 0; i < 4; i++ {
+		for _, chainID := range chains {
+			acc.Submit(GetTestEntry(chainID, i))
+		}
+	}
+	runUntilIdle(acc)
+	block := acc.sealBlock()
+	if len(block.List) != 3 || rejected != 7*4 {
+		t.Errorf("expected 3 chains with the entries of the other 7 rejected, got %d chains and %d rejected", len(block.List), rejected)
+	}
+	for _, chainID := range chains[:3] {
+		if chain, err := acc.Reader().GetChainNode(chainID, block.BHeight); err != nil || len(chain.EntryList) != 4 {
+			t.Error("the chains in the block should get all of their entries")
+		}
+	}
+
+	// The next block has room again
+	acc.Submit(GetTestEntry(chains[9], 100))
+	runUntilIdle(acc)
+	if block := acc.sealBlock(); len(block.List) != 1 || block.List[0].ChainID != chains[9] {
+		t.Error("a chain turned away from a full block should get into the next")
+	}
+}
+
+func TestStop(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	var reasons []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { reasons = append(reasons, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("stopped")))
+	go acc.Run()
+	for i := 0; i < 20; i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	acc.Stop()
+	if acc.Submit(GetTestEntry(chainID, 20)) {
+		t.Error("Submit should refuse entries once stopped")
+	}
+	if err := acc.SubmitAtHeight(GetTestEntry(chainID, 21), 5); err != ErrShuttingDown {
+		t.Errorf("SubmitAtHeight should return ErrShuttingDown once stopped, got %v", err)
+	}
+	if len(reasons) != 2 || reasons[0] != ShuttingDown || reasons[1] != ShuttingDown {
+		t.Errorf("expected both entries to be rejected as shutting down, got %v", reasons)
+	}
+	if count, err := acc.Reader().GetBlockEntryCount(0); err != nil || count != 20 {
+		t.Errorf("the final block should hold the 20 entries submitted before Stop, got %d (%v)", count, err)
+	}
+	if acc.height != 1 {
+		t.Errorf("Run should seal exactly one block on the way out, the next height is %d", acc.height)
+	}
+}
+
+// recordingLogger
+// Keeps every line logged
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestLogChainsInOrder(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	logger := new(recordingLogger)
+	acc.Logger = logger
+	acc.LogChains = true
+	var chainIDs []types.Hash
+	for i := 0; i < 20; i++ {
+		chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("chain %d", i))))
+		chainIDs = append(chainIDs, chainID)
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	block := acc.SealBlock()
+	if len(logger.lines) != len(chainIDs) {
+		t.Fatalf("expected a line for each of %d chains, got %d", len(chainIDs), len(logger.lines))
+	}
+	for i, ne := range block.List {
+		prefix := fmt.Sprintf("block 0 chain %x:", ne.ChainID)
+		if !strings.HasPrefix(logger.lines[i], prefix) {
+			t.Errorf("line %d should be for chain %x, got %q", i, ne.ChainID, logger.lines[i])
+		}
+		if i > 0 && bytes.Compare(block.List[i-1].ChainID[:], ne.ChainID[:]) >= 0 {
+			t.Error("the chains should be in ChainID order")
+		}
+	}
+}
+
+func TestInitInvalidConfig(t *testing.T) {
+	initWith := func(db *database.DB, chainID *types.Hash) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err, _ = r.(error)
+			}
+		}()
+		new(Accumulator).Init(db, chainID)
+		return nil
+	}
+	good := new(database.DB)
+	good.InitStore(database.NewMemStore())
+	chainID := types.Hash(sha256.Sum256([]byte("configured")))
+	for _, c := range []struct {
+		db      *database.DB
+		chainID *types.Hash
+		problem string
+	}{
+		{nil, &chainID, "no database"},
+		{new(database.DB), &chainID, "the database has no store; call Init or InitStore on it first"},
+		{good, nil, "no ChainID"},
+	} {
+		if invalid, ok := initWith(c.db, c.chainID).(ErrInvalidConfig); !ok || invalid.Problem != c.problem {
+			t.Errorf("expected an ErrInvalidConfig for %q, got %v", c.problem, invalid)
+		}
+	}
+	if err := initWith(good, &chainID); err != nil {
+		t.Errorf("a database and ChainID should be all Init needs, got %v", err)
+	}
+}
+
+// driftingHasher
+// A sha256 hasher that, while drifting, never combines the same pair to the same hash twice, so a root
+// computed again doesn't match the root computed before
+type driftingHasher struct {
+	drift bool
+	count int
+}
+
+func (d *driftingHasher) Combine(left, right types.Hash) types.Hash {
+	combined := *left.Combine(right)
+	if d.drift {
+		d.count++
+		combined = sha256.Sum256(append(combined[:], byte(d.count)))
+	}
+	return combined
+}
+
+func TestStrictChainRoots(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	hasher := &driftingHasher{drift: true}
+	acc.Hasher = hasher
+	acc.StrictMode = true
+	chainID := types.Hash(sha256.Sum256([]byte("desynced")))
+	for i := 0; i < 3; i++ { // Three entries, so the chain's root takes combining its peaks
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	if acc.SealBlock() != nil {
+		t.Fatal("a chain whose root doesn't match its MD shouldn't be sealed")
+	}
+	if head, _ := acc.Reader().GetHead(); head != nil {
+		t.Fatal("nothing should be written for the block")
+	}
+
+	hasher.drift = false
+	block := acc.SealBlock()
+	if block == nil || block.BHeight != 0 {
+		t.Fatal("the block should seal once the roots agree")
+	}
+	if count, _ := acc.Reader().GetBlockEntryCount(0); count != 3 {
+		t.Errorf("the block kept open should keep its 3 entries, not %d", count)
+	}
+}
@@ -0,0 +1,57 @@
+package accumulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ErrNoWAL
+// Returned by SubmitWithAck for AckWALDurable when there is no write ahead log to make the entry durable
+var ErrNoWAL = errors.New("entries can't be made durable without a WALPath")
+
+// AckLevel
+// How far along its way into a block an entry has to be before SubmitWithAck returns
+type AckLevel int
+
+const (
+	AckAccepted   AckLevel = iota // The entry is on the feed for Run (and, with a WALPath, logged first, as always)
+	AckWALDurable                 // The entry is synced to the write ahead log, and on the feed
+	AckSealed                     // The block the entry is added to has been committed, as with SubmitAndWait
+)
+
+func (l AckLevel) String() string {
+	switch l {
+	case AckAccepted:
+		return "accepted"
+	case AckWALDurable:
+		return "WAL durable"
+	case AckSealed:
+		return "sealed"
+	}
+	return "unknown"
+}
+
+// SubmitWithAck
+// Submit an entry, returning once it has got as far as the ack level asks.  Returns an error if the entry is
+// rejected, or, for AckWALDurable, if there is no WALPath (in which case the entry isn't submitted at all, as
+// nothing could make it durable).  The height and root of the entry's block are only given for AckSealed,
+// the only level ctx is waited on; the others can still block on a full feed.  May be called from any go
+// routine but the one running Run.
+func (a *Accumulator) SubmitWithAck(ctx context.Context, entry node.EntryHash, ack AckLevel) (height types.BlockHeight, root types.Hash, err error) {
+	switch ack {
+	case AckAccepted:
+		return 0, root, a.SubmitErr(entry)
+	case AckWALDurable:
+		if a.WALPath == "" {
+			return 0, root, ErrNoWAL
+		}
+		return 0, root, a.SubmitErr(entry)
+	case AckSealed:
+		return a.SubmitAndWait(ctx, entry)
+	}
+	return 0, root, errors.New(fmt.Sprintf("unknown ack level %d", int(ack)))
+}
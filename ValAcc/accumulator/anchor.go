@@ -0,0 +1,212 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Anchorer
+// Records a directory block's MD root (the root sent on the mdFeed) on some external chain, and returns
+// what a verifier needs to find it there.
+type Anchorer interface {
+	Anchor(height types.BlockHeight, root types.Hash) (*AnchorRecord, error)
+}
+
+// AnchorRecord
+// Where the MD root of the directory block at Height was anchored.  Network says which external chain
+// (or other store) holds the anchor, and TxID where in it to look; what a TxID means is up to the Network.
+type AnchorRecord struct {
+	Height  types.BlockHeight // Height of the anchored directory block
+	Root    types.Hash        // MD root of the directory block, as given by its GetMDRoot
+	Network string            // The external chain holding the anchor
+	TxID    []byte            // Transaction (or other reference) holding the anchor on the Network
+}
+
+// Marshal
+// Version, height, root, then the network and TxID, each led by its length.
+func (r *AnchorRecord) Marshal() (data []byte) {
+	data = append(data, types.Version.Bytes()...)
+	data = append(data, r.Height.Bytes()...)
+	data = append(data, r.Root.Bytes()...)
+	data = append(data, types.Uint16Bytes(uint16(len(r.Network)))...)
+	data = append(data, r.Network...)
+	data = append(data, types.Uint16Bytes(uint16(len(r.TxID)))...)
+	data = append(data, r.TxID...)
+	return data
+}
+
+// Unmarshal
+// Extract an anchor record from a byte slice.  Returns an error if the unmarshal fails.
+func (r *AnchorRecord) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.New(fmt.Sprintf("AnchorRecord failed to unmarshal %v", rec))
+		}
+	}()
+	var version types.VersionField
+	data = version.Extract(data)
+	if version > types.Version {
+		return errors.New(fmt.Sprintf("unknown anchor record version %d", version))
+	}
+	data = r.Height.Extract(data)
+	data = r.Root.Extract(data)
+	var network, txID types.DataField
+	var length uint16
+	length, data = types.BytesUint16(data)
+	data = network.Extract(length, data)
+	length, data = types.BytesUint16(data)
+	txID.Extract(length, data)
+	r.Network = string(network)
+	r.TxID = txID
+	return nil
+}
+
+// anchor
+// Have the Anchorer anchor a block that has just become final, and record where it went.  The block is
+// committed whatever happens, so a failure is logged and the block is left unanchored.
+func (a *Accumulator) anchor(directoryBlock *node.Node) {
+	if a.Anchorer == nil {
+		return
+	}
+	record, err := a.Anchorer.Anchor(directoryBlock.BHeight, *directoryBlock.GetMDRoot())
+	if err == nil {
+		err = a.RecordAnchor(record)
+	}
+	if err != nil {
+		a.logger().Printf("failed to anchor the block at height %d: %v", directoryBlock.BHeight, err)
+	}
+}
+
+// RecordAnchor
+// Record that a directory block has been anchored, for blocks anchored by a consumer of the mdFeed rather
+// than by the Anchorer.  The record's Root has to be the MD root of the block at its Height.
+func (a *Accumulator) RecordAnchor(record *AnchorRecord) error {
+	directoryBlock, err := a.Reader().GetDirectoryBlock(record.Height)
+	if err != nil {
+		return err
+	}
+	if root := *directoryBlock.GetMDRoot(); root != record.Root {
+		return errors.New(fmt.Sprintf("the anchor for height %d is of the root %x, but the block's root is %x",
+			record.Height, record.Root, root))
+	}
+	return a.DB.PutInt32(types.Anchor, int(record.Height), record.Marshal())
+}
+
+// GetAnchor
+// Return the anchor of the directory block at the given height or, if that block wasn't anchored itself,
+// of the first block after it that was.  Each directory block holds the hash of the one before it, so an
+// anchor covers every block before it too.
+func (r *Reader) GetAnchor(height types.BlockHeight) (*AnchorRecord, error) {
+	head, err := r.GetHead()
+	if err != nil {
+		return nil, err
+	}
+	for h := height; head != nil && h <= head.BHeight; h++ {
+		if data := r.DB.GetInt32(types.Anchor, uint32(h)); data != nil {
+			record := new(AnchorRecord)
+			if err := record.Unmarshal(data); err != nil {
+				return nil, err
+			}
+			return record, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("no anchor at or after height %d", height))
+}
+
+// AnchoredProof
+// Proves an entry all the way to an external anchor.  The Receipt takes the entry to the ListMDRoot of its
+// directory block, the Blocks link that directory block to the anchored one through their Previous hashes,
+// and the Anchor says where the MD root of the last of the Blocks was recorded.
+type AnchoredProof struct {
+	Receipt Receipt      // Entry -> chain ListMDRoot -> directory block ListMDRoot
+	Blocks  []*node.Node // Directory blocks from the receipt's height up to the anchor's height
+	Anchor  AnchorRecord // Where the last block's MD root was anchored
+}
+
+// Check
+// Verify the proof, returning why it fails.  What the proof can't check is that the Anchor really is on
+// its Network; the verifier has to look it up there.
+func (p *AnchoredProof) Check() error {
+	if err := p.Receipt.Check(); err != nil {
+		return err
+	}
+	if len(p.Blocks) == 0 {
+		return errors.New("the proof has no directory blocks")
+	}
+	first, last := p.Blocks[0], p.Blocks[len(p.Blocks)-1]
+	if first.BHeight != p.Receipt.Height || first.ListMDRoot != p.Receipt.ChainReceipt.MDRoot {
+		return errors.New(fmt.Sprintf("the receipt isn't of the directory block at height %d", first.BHeight))
+	}
+	for i := 1; i < len(p.Blocks); i++ {
+		if p.Blocks[i].Previous != *p.Blocks[i-1].GetHash() {
+			return errors.New(fmt.Sprintf("the directory block at height %d doesn't follow the one before it",
+				p.Blocks[i].BHeight))
+		}
+	}
+	if last.BHeight != p.Anchor.Height || *last.GetMDRoot() != p.Anchor.Root {
+		return errors.New(fmt.Sprintf("the anchor at height %d isn't of the directory block at height %d",
+			p.Anchor.Height, last.BHeight))
+	}
+	return nil
+}
+
+// Verify
+// True if the proof checks out
+func (p *AnchoredProof) Verify() bool {
+	return p.Check() == nil
+}
+
+// GetProofToAnchor
+// Return the proof of an entry in a chain at the given height through to the first anchor at or after
+// that height.  Returns an error if the entry isn't there, or no block since has been anchored.
+func (r *Reader) GetProofToAnchor(chainID, entry types.Hash, height types.BlockHeight) (*AnchoredProof, error) {
+	receipt, err := r.GetReceipt(chainID, entry, height)
+	if err != nil {
+		return nil, err
+	}
+	anchor, err := r.GetAnchor(height)
+	if err != nil {
+		return nil, err
+	}
+	proof := &AnchoredProof{Receipt: *receipt, Anchor: *anchor}
+	for h := height; h <= anchor.Height; h++ {
+		directoryBlock, err := r.GetDirectoryBlock(h)
+		if err != nil {
+			return nil, err
+		}
+		proof.Blocks = append(proof.Blocks, directoryBlock)
+	}
+	return proof, nil
+}
+
+// AnchorConfirmed
+// Say the root of the block at the given height has been anchored externally.  With AwaitAnchors set, the block
+// after it can be sealed from now on.  Confirming a height confirms every height below it, and confirming a
+// height lower than one already confirmed does nothing.  May be called from any go routine.
+func (a *Accumulator) AnchorConfirmed(height types.BlockHeight) {
+	a.anchorMux.Lock()
+	defer a.anchorMux.Unlock()
+	if height+1 > a.anchoredTo {
+		a.anchoredTo = height + 1
+	}
+}
+
+// anchorDue
+// With AwaitAnchors set, true while the block before the current one is still waiting for AnchorConfirmed, in
+// which case sealBlock leaves the current block open.  The wait is logged once for each block.
+func (a *Accumulator) anchorDue() bool {
+	if !a.AwaitAnchors || a.height == 0 {
+		return false
+	}
+	a.anchorMux.Lock()
+	waiting := a.anchoredTo < a.height
+	a.anchorMux.Unlock()
+	if waiting && !a.anchorLogged {
+		a.logger().Printf("not sealing the block at height %d until the block at height %d is anchored", a.height, a.height-1)
+		a.anchorLogged = true
+	}
+	return waiting
+}
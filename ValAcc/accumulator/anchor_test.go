@@ -0,0 +1,201 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// evenAnchorer
+// Only anchors the blocks at even heights, so some blocks are covered by the anchor of a later block
+type evenAnchorer struct {
+	FileAnchorer
+}
+
+func (e *evenAnchorer) Anchor(height types.BlockHeight, root types.Hash) (*AnchorRecord, error) {
+	if height%2 != 0 {
+		return nil, errors.New(fmt.Sprintf("not anchoring height %d", height))
+	}
+	return e.FileAnchorer.Anchor(height, root)
+}
+
+func TestProofToAnchor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anchors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "anchors.txt")
+
+	acc := GetTestAccumulator(t)
+	acc.Anchorer = &evenAnchorer{FileAnchorer{Path: path}}
+	chainID := types.Hash(sha256.Sum256([]byte("anchored")))
+	for b := 0; b < 5; b++ {
+		acc.addEntry(GetTestEntry(chainID, b))
+		acc.sealBlock()
+	}
+	anchors, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for b := 0; b < 4; b++ {
+		height := types.BlockHeight(b)
+		proof, err := acc.Reader().GetProofToAnchor(chainID, GetTestEntry(chainID, b).EntryHash, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := proof.Check(); err != nil {
+			t.Errorf("the proof for height %d should verify: %v", b, err)
+		}
+		expected := height + height%2 // Odd heights are covered by the anchor of the next block
+		if proof.Anchor.Height != expected || len(proof.Blocks) != int(expected-height)+1 {
+			t.Errorf("the proof for height %d should go to the anchor at %d, went to %d", b, expected, proof.Anchor.Height)
+		}
+
+		// The TxID is where the anchor's line starts in the file
+		offset, _ := types.BytesUint64(proof.Anchor.TxID)
+		line := fmt.Sprintf("%d %x\n", proof.Anchor.Height, proof.Anchor.Root)
+		if int(offset)+len(line) > len(anchors) || string(anchors[offset:int(offset)+len(line)]) != line {
+			t.Errorf("the anchor for height %d doesn't reference its record in the file", b)
+		}
+	}
+
+	// A tampered proof doesn't check out
+	proof, _ := acc.Reader().GetProofToAnchor(chainID, GetTestEntry(chainID, 1).EntryHash, 1)
+	proof.Anchor.Root[0] ^= 1
+	if proof.Verify() {
+		t.Error("a proof to the wrong root should not verify")
+	}
+
+	// Nothing after the last anchored block
+	acc.addEntry(GetTestEntry(chainID, 5))
+	acc.sealBlock()
+	if _, err := acc.Reader().GetProofToAnchor(chainID, GetTestEntry(chainID, 5).EntryHash, 5); err == nil {
+		t.Error("a block with no anchor at or after it should have no proof")
+	}
+	if err := acc.RecordAnchor(&AnchorRecord{Height: 5, Root: chainID}); err == nil {
+		t.Error("an anchor of the wrong root should not be recorded")
+	}
+}
+
+// heightAnchorer
+// Anchors nowhere, but keeps the heights it is asked to anchor
+type heightAnchorer struct {
+	heights []types.BlockHeight
+}
+
+func (h *heightAnchorer) Anchor(height types.BlockHeight, root types.Hash) (*AnchorRecord, error) {
+	h.heights = append(h.heights, height)
+	return &AnchorRecord{Height: height, Root: root, Network: "test"}, nil
+}
+
+func TestFinalizationDepth(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	anchorer := new(heightAnchorer)
+	acc.Anchorer = anchorer
+	acc.FinalizationDepth = 2
+	var finals []types.BlockHeight
+	acc.OnFinal = func(directoryBlock *node.Node) { finals = append(finals, directoryBlock.BHeight) }
+	chainID := types.Hash(sha256.Sum256([]byte("finalized")))
+
+	for b := 0; b < 5; b++ {
+		acc.addEntry(GetTestEntry(chainID, b))
+		acc.sealBlock()
+		if b < 2 {
+			if len(anchorer.heights) != 0 || len(finals) != 0 || acc.FinalizedHeight() != 0 {
+				t.Fatalf("nothing should be final with %d blocks sealed, anchored %v", b+1, anchorer.heights)
+			}
+			continue
+		}
+		// Block b-2 is anchored only now that block b exists
+		final := types.BlockHeight(b - 2)
+		if len(anchorer.heights) != b-1 || anchorer.heights[b-2] != final || finals[b-2] != final {
+			t.Fatalf("with %d blocks sealed, blocks up to %d should be anchored, anchored %v", b+1, final, anchorer.heights)
+		}
+		if acc.FinalizedHeight() != final {
+			t.Errorf("expected the finalized height to be %d, got %d", final, acc.FinalizedHeight())
+		}
+		if _, err := acc.Reader().GetAnchor(final); err != nil {
+			t.Errorf("the anchor for height %d should be recorded: %v", final, err)
+		}
+	}
+
+	// A restart carries on where the last run left off, anchoring block 3 once block 5 is sealed
+	restarted := new(Accumulator)
+	restarted.Anchorer = anchorer
+	restarted.FinalizationDepth = 2
+	restarted.Init(acc.DB, acc.chainID)
+	if restarted.FinalizedHeight() != 2 {
+		t.Errorf("after a restart the finalized height should still be 2, got %d", restarted.FinalizedHeight())
+	}
+	restarted.addEntry(GetTestEntry(chainID, 5))
+	restarted.sealBlock()
+	if len(anchorer.heights) != 4 || anchorer.heights[3] != 3 {
+		t.Errorf("after a restart block 3 should be anchored next, anchored %v", anchorer.heights)
+	}
+
+	// Raising the depth across a restart doesn't take back the blocks already final, and lowering it
+	// makes final every block it now buries deep enough, none of them twice
+	deeper := new(Accumulator)
+	deeper.FinalizationDepth = 4
+	deeper.Init(acc.DB, acc.chainID)
+	if deeper.FinalizedHeight() != 3 {
+		t.Errorf("raising the depth should leave the finalized height at 3, got %d", deeper.FinalizedHeight())
+	}
+	shallower := new(Accumulator)
+	shallower.Anchorer = anchorer
+	shallower.Init(acc.DB, acc.chainID)
+	if shallower.FinalizedHeight() != 3 {
+		t.Errorf("lowering the depth should leave the finalized height at 3 until a block is sealed, got %d",
+			shallower.FinalizedHeight())
+	}
+	shallower.addEntry(GetTestEntry(chainID, 6))
+	shallower.sealBlock()
+	if fmt.Sprint(anchorer.heights) != "[0 1 2 3 4 5 6]" {
+		t.Errorf("blocks 4 to 6 should be anchored once each, anchored %v", anchorer.heights)
+	}
+}
+
+func TestAwaitAnchors(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.AwaitAnchors = true
+	chainID := types.Hash(sha256.Sum256([]byte("awaiting anchors")))
+	acc.addEntry(GetTestEntry(chainID, 0))
+	if acc.SealBlock() == nil {
+		t.Fatal("the first block has nothing to wait for")
+	}
+
+	for i := 1; i < 4; i++ { // Entries keep going into the block while it waits
+		acc.addEntry(GetTestEntry(chainID, i))
+		if acc.SealBlock() != nil {
+			t.Fatal("block 1 shouldn't seal before block 0 is anchored")
+		}
+	}
+	acc.AnchorConfirmed(0)
+	sealed := acc.SealBlock()
+	if sealed == nil || sealed.BHeight != 1 {
+		t.Fatal("block 1 should seal once block 0 is anchored")
+	}
+	if count, err := acc.Reader().GetBlockEntryCount(1); err != nil || count != 3 {
+		t.Errorf("block 1 should hold the 3 entries added while it waited, not %d (%v)", count, err)
+	}
+
+	acc.AnchorConfirmed(3) // Confirms every height up to 3
+	acc.AnchorConfirmed(1)
+	for height := 2; height < 5; height++ {
+		if acc.SealBlock() == nil {
+			t.Errorf("block %d should seal", height)
+		}
+	}
+	if acc.SealBlock() != nil {
+		t.Error("block 5 shouldn't seal before block 4 is anchored")
+	}
+}
@@ -0,0 +1,50 @@
+package accumulator
+
+import (
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// SetNextBlockAnnotation
+// Annotate the next block sealed with whatever an operator wants to say about it (a software upgrade at this
+// height, a build version, a config epoch ...).  Setting it again before the block is sealed replaces it, and
+// a nil or empty annotation clears it.  May be called from any go routine.
+//
+// Annotations are stored out of band, keyed by height, rather than in the directory block.  So they are not
+// covered by the block's hash and no receipt proves them, but annotating a block never changes its hash or
+// the format of directory blocks, and blocks built with or without annotations verify the same way.
+func (a *Accumulator) SetNextBlockAnnotation(annotation []byte) {
+	a.annotationMux.Lock()
+	defer a.annotationMux.Unlock()
+	a.annotation = append([]byte{}, annotation...)
+}
+
+// writeAnnotation
+// Write the annotation set for the block being sealed (if any) into its batch, and clear it for the next block.
+// Returns the annotation written, so it can be put back if the batch fails to commit.
+func (a *Accumulator) writeAnnotation(db *database.DB) []byte {
+	a.annotationMux.Lock()
+	defer a.annotationMux.Unlock()
+	annotation := a.annotation
+	if len(annotation) > 0 {
+		db.PutInt32(types.BlockAnnotation, int(a.height), annotation)
+	}
+	a.annotation = nil
+	return annotation
+}
+
+// restoreAnnotation
+// Put back the annotation of a block that failed to commit, unless SetNextBlockAnnotation has been called since
+func (a *Accumulator) restoreAnnotation(annotation []byte) {
+	a.annotationMux.Lock()
+	defer a.annotationMux.Unlock()
+	if a.annotation == nil {
+		a.annotation = annotation
+	}
+}
+
+// GetBlockAnnotation
+// Return the annotation the operator set for the directory block at the given height, or nil if it has none
+func (r *Reader) GetBlockAnnotation(height types.BlockHeight) []byte {
+	return r.DB.GetInt32(types.BlockAnnotation, uint32(height))
+}
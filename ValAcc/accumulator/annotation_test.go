@@ -0,0 +1,34 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestBlockAnnotation(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("annotated")))
+	acc.addEntry(GetTestEntry(chainID, 0))
+	plain := acc.sealBlock()
+
+	annotation := []byte("software upgrade to v2")
+	acc.SetNextBlockAnnotation([]byte("replaced before the block is sealed"))
+	acc.SetNextBlockAnnotation(annotation)
+	annotation[0] = 'S' // The accumulator keeps its own copy
+	acc.addEntry(GetTestEntry(chainID, 1))
+	annotated := acc.sealBlock()
+	next := acc.sealBlock()
+
+	r := acc.Reader()
+	if got := r.GetBlockAnnotation(annotated.BHeight); string(got) != "software upgrade to v2" {
+		t.Errorf("expected the annotation to read back, got %q", got)
+	}
+	if r.GetBlockAnnotation(plain.BHeight) != nil || r.GetBlockAnnotation(next.BHeight) != nil {
+		t.Error("only the block sealed after SetNextBlockAnnotation should be annotated")
+	}
+	if err := r.VerifyDirectoryBlock(annotated); err != nil {
+		t.Errorf("an annotated block should verify like any other: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestBackupRestore(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chain1 := types.Hash(sha256.Sum256([]byte("backed up 1")))
+	chain2 := types.Hash(sha256.Sum256([]byte("backed up 2")))
+	for b := 0; b < 5; b++ {
+		for i := 0; i < 8; i++ {
+			acc.addEntry(GetTestEntry(chain1, b*8+i))
+			if i%2 == 0 {
+				acc.addEntry(GetTestEntry(chain2, b*8+i))
+			}
+		}
+		acc.sealBlock()
+	}
+
+	var backup bytes.Buffer
+	if err := acc.DB.Backup(&backup); err != nil {
+		t.Fatal(err)
+	}
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	if err := db.Restore(bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Restore(bytes.NewReader(backup.Bytes())); err == nil {
+		t.Error("restoring over a database that isn't empty should fail")
+	}
+
+	r := NewReader(db, *acc.chainID)
+	head, err := r.GetHead()
+	if err != nil || head == nil || *head.GetHash() != *acc.previous.GetHash() {
+		t.Fatal("the restored head should match the accumulator's")
+	}
+	walker := r.WalkBack(head.BHeight)
+	for block, ok := walker.Next(); ok; block, ok = walker.Next() {
+		if err := r.VerifyDirectoryBlock(block); err != nil {
+			t.Error(err)
+		}
+		receipt, err := r.GetReceipt(chain2, GetTestEntry(chain2, int(block.BHeight)*8+4).EntryHash, block.BHeight)
+		if err != nil || !receipt.Verify() {
+			t.Errorf("the receipt at height %d should verify from the restored database", block.BHeight)
+		}
+	}
+	if walker.Err() != nil || head.BHeight != 4 {
+		t.Error("the restored blocks should walk back to genesis")
+	}
+
+	// The restored accumulator carries on where the original left off
+	restored := new(Accumulator)
+	restored.Init(db, acc.chainID)
+	if restored.height != acc.height {
+		t.Errorf("the restored accumulator should be at height %d, is at %d", acc.height, restored.height)
+	}
+
+	// A corrupted or truncated backup is caught
+	for _, bad := range [][]byte{backup.Bytes()[:backup.Len()-10], append(append([]byte{}, backup.Bytes()[:100]...), backup.Bytes()[101:]...)} {
+		db := new(database.DB)
+		db.InitStore(database.NewMemStore())
+		if err := db.Restore(bytes.NewReader(bad)); err == nil {
+			t.Error("expected an error restoring a damaged backup")
+		}
+	}
+}
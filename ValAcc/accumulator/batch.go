@@ -0,0 +1,67 @@
+package accumulator
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+)
+
+// batchChunk
+// SubmitBatch queues a batch this many entries at a time, letting other go routines in between
+const batchChunk = 256
+
+// ErrBatchTooLarge
+// Returned by SubmitBatch for a batch of more than MaxBatchSize entries
+type ErrBatchTooLarge struct {
+	Size int // Entries in the batch
+	Max  int // MaxBatchSize when the batch was refused
+}
+
+func (e ErrBatchTooLarge) Error() string {
+	return fmt.Sprintf("batch has %d entries, more than the limit of %d", e.Size, e.Max)
+}
+
+// SubmitBatch
+// Submit each of the entries, in order, returning why each was rejected, or zero for those queued.  A batch
+// of more than MaxBatchSize entries is refused whole, with an ErrBatchTooLarge, before any are submitted.
+// The batch is queued batchChunk entries at a time, yielding between chunks so other producers (and the Run
+// loop) get their turn rather than waiting out the whole batch.  SubmitBatch may be called from any go routine.
+func (a *Accumulator) SubmitBatch(entries []node.EntryHash) ([]RejectReason, error) {
+	reasons := make([]RejectReason, len(entries))
+	err := a.submitBatch(entries, func(i int, reason RejectReason, _ error) { reasons[i] = reason })
+	if err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+// SubmitBatchErr
+// Submit the entries as SubmitBatch does, but return the error SubmitErr would for each entry rejected (so
+// validateEntry's error for a Malformed one), or nil for those queued
+func (a *Accumulator) SubmitBatchErr(entries []node.EntryHash) ([]error, error) {
+	errs := make([]error, len(entries))
+	err := a.submitBatch(entries, func(i int, reason RejectReason, err error) {
+		errs[i] = rejectError(entries[i], reason, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// submitBatch
+// Submit the entries a chunk at a time, telling submitted what became of each
+func (a *Accumulator) submitBatch(entries []node.EntryHash, submitted func(i int, reason RejectReason, err error)) error {
+	if a.MaxBatchSize > 0 && len(entries) > a.MaxBatchSize {
+		return ErrBatchTooLarge{Size: len(entries), Max: a.MaxBatchSize}
+	}
+	for i, entry := range entries {
+		if i > 0 && i%batchChunk == 0 {
+			runtime.Gosched()
+		}
+		reason, err := a.submit(entry)
+		submitted(i, reason, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestSubmitBatch(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MaxBatchSize = 2*batchChunk + 10 // Over more than one chunk
+	chainID := types.Hash(sha256.Sum256([]byte("batched")))
+	var batch []node.EntryHash
+	for i := 0; i <= acc.MaxBatchSize; i++ {
+		batch = append(batch, GetTestEntry(chainID, i))
+	}
+
+	_, err := acc.SubmitBatch(batch)
+	if tooLarge, ok := err.(ErrBatchTooLarge); !ok || tooLarge.Size != len(batch) || tooLarge.Max != acc.MaxBatchSize {
+		t.Fatalf("a batch over the limit should get an ErrBatchTooLarge, not %v", err)
+	}
+	if len(acc.entryFeed) != 0 {
+		t.Fatalf("%d entries of a batch over the limit were queued", len(acc.entryFeed))
+	}
+
+	batch = batch[:acc.MaxBatchSize]
+	reasons, err := acc.SubmitBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, reason := range reasons {
+		if reason != 0 {
+			t.Fatalf("entry %d of a batch at the limit was rejected as %v", i, reason)
+		}
+	}
+	runUntilIdle(acc)
+	acc.SealBlock()
+	chainNode, err := acc.Reader().GetChainNode(chainID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chainNode.EntryList) != len(batch) {
+		t.Fatalf("the block holds %d entries of the batch, not %d", len(chainNode.EntryList), len(batch))
+	}
+	for i, entry := range batch {
+		if chainNode.EntryList[i] != entry.EntryHash {
+			t.Fatalf("entry %d of the batch is out of place", i)
+		}
+	}
+
+	acc.ValidateEntries = true
+	mixed := []node.EntryHash{GetTestEntry(chainID, 9000), GetTestEntry(types.Hash{}, 9001), {ChainID: chainID}}
+	errs, err := acc.SubmitBatchErr(mixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[1] != ErrZeroChainID || errs[2] != ErrZeroEntryHash {
+		t.Errorf("expected each entry's error from validateEntry, got %v", errs)
+	}
+	if _, err := acc.SubmitBatchErr(make([]node.EntryHash, acc.MaxBatchSize+1)); err == nil {
+		t.Error("SubmitBatchErr should refuse a batch over the limit")
+	}
+}
@@ -0,0 +1,47 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// recordCommit
+// Note how committing a block went.  A success starts the count of failures in a row over; the
+// MaxCommitFailures'th failure in a row trips the breaker, leaving the accumulator Unhealthy until Reset.
+func (a *Accumulator) recordCommit(err error) {
+	a.healthMux.Lock()
+	defer a.healthMux.Unlock()
+	if err == nil {
+		a.commitFailures = 0
+		return
+	}
+	a.commitFailures++
+	if a.MaxCommitFailures > 0 && a.commitFailures >= a.MaxCommitFailures && !a.unhealthy.Load() {
+		a.health = errors.New(fmt.Sprintf("%d commits in a row failed, the last at height %d: %v",
+			a.commitFailures, a.height, err))
+		a.unhealthy.Store(true)
+		a.logger().Printf("the accumulator is unhealthy; no more blocks are sealed until Reset: %v", a.health)
+	}
+}
+
+// Health
+// Returns nil while the accumulator is healthy, or why it isn't once MaxCommitFailures commits in a row have
+// failed.  While unhealthy, Submit and SubmitAtHeight reject every entry as Unhealthy, and blocks are no
+// longer sealed; the entries already in the current block are held until it can be.  May be called from any
+// go routine.
+func (a *Accumulator) Health() error {
+	a.healthMux.Lock()
+	defer a.healthMux.Unlock()
+	return a.health
+}
+
+// Reset
+// Re-arm the breaker once whatever was failing the commits (most likely the database) has been fixed, so the
+// accumulator takes entries and seals blocks again.  May be called from any go routine.
+func (a *Accumulator) Reset() {
+	a.healthMux.Lock()
+	defer a.healthMux.Unlock()
+	a.commitFailures = 0
+	a.health = nil
+	a.unhealthy.Store(false)
+}
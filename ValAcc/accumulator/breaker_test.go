@@ -0,0 +1,94 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// failingStore
+// Fails every Put while fails is above zero, counting it down
+type failingStore struct {
+	database.Store
+	fails int
+}
+
+func (f *failingStore) Put(key []byte, value []byte) error {
+	if f.fails > 0 {
+		f.fails--
+		return errors.New("the disk is full")
+	}
+	return f.Store.Put(key, value)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MaxCommitFailures = 3
+	store := &failingStore{Store: acc.DB.GetStore()}
+	acc.DB.InitStore(store)
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("breaker")))
+
+	store.fails = 1 // A failure followed by a success doesn't count towards the breaker
+	acc.SetNextBlockAnnotation([]byte("retried"))
+	acc.addEntry(GetTestEntry(chainID, 0))
+	if acc.SealBlock() != nil || acc.height != 0 {
+		t.Fatal("the block should be left open when its commit fails")
+	}
+	acc.addEntry(GetTestEntry(chainID, 1))
+	if block := acc.SealBlock(); block == nil || block.BHeight != 0 {
+		t.Fatal("the block should be sealed once the store works again")
+	}
+	chain, err := acc.Reader().GetChainNode(chainID, 0)
+	if err != nil || len(chain.EntryList) != 2 {
+		t.Errorf("the entries of the block that failed to commit should survive into it (%v)", err)
+	}
+	if string(acc.Reader().GetBlockAnnotation(0)) != "retried" {
+		t.Error("the annotation of the block that failed to commit should survive into it")
+	}
+
+	store.fails = 3
+	for i := 0; i < 3; i++ {
+		if acc.Health() != nil {
+			t.Fatalf("the breaker tripped after only %d failures", i)
+		}
+		acc.addEntry(GetTestEntry(chainID, 2+i))
+		acc.SealBlock()
+	}
+	if acc.Health() == nil {
+		t.Fatal("the breaker should trip after 3 failed commits in a row")
+	}
+	entry := GetTestEntry(chainID, 10)
+	if acc.Submit(entry) || len(rejected) != 1 || rejected[0] != Unhealthy {
+		t.Errorf("Submit should reject entries as Unhealthy, got %v", rejected)
+	}
+	if acc.SubmitAtHeight(entry, acc.height+1) == nil {
+		t.Error("SubmitAtHeight should reject entries while unhealthy")
+	}
+	acc.addEntry(GetTestEntry(chainID, 11))
+	if acc.SealBlock() != nil || acc.height != 1 {
+		t.Error("no block should be sealed while unhealthy")
+	}
+
+	acc.Reset()
+	if acc.Health() != nil {
+		t.Errorf("Reset should make the accumulator healthy again, got %v", acc.Health())
+	}
+	if !acc.Submit(entry) {
+		t.Error("Submit should take entries again after Reset")
+	}
+	acc.ProcessPending()
+	block := acc.SealBlock()
+	if block == nil || block.BHeight != 1 || len(block.List) != 1 {
+		t.Fatal("the block should be sealed after Reset")
+	}
+	chain, err = acc.Reader().GetChainNode(chainID, block.BHeight)
+	if err != nil || len(chain.EntryList) != 5 {
+		t.Errorf("the entries of the failed commits, and those held while unhealthy, should be sealed after Reset (%v)", err)
+	}
+}
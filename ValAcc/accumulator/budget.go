@@ -0,0 +1,108 @@
+package accumulator
+
+import "sync"
+
+// budget
+// Where enforceBudget has got to.  The store's Size doesn't drop as blocks are pruned, only once the dead
+// space is compacted, and may lag behind (Badger only measures itself every minute or so).  So the bytes
+// pruned are counted off every Size until a compaction has finished and the Size has moved since.
+type budget struct {
+	mux        sync.Mutex
+	measured   int64          // The Size last measured
+	freed      int64          // Bytes pruned that the Size may still hold, as pruneBlock reckons them
+	compacting bool           // A compaction is running
+	again      bool           // Blocks were pruned while compacting, so compact again once done
+	compacted  bool           // A compaction has finished since the last blocks were pruned
+	failed     bool           // A failure to measure the database against MaxDBBytes has been logged
+	running    sync.WaitGroup // The compaction running
+}
+
+// enforceBudget
+// With MaxDBBytes set, prune the oldest blocks, a block at a time, until the bytes they hold bring the database
+// back under MaxDBBytes or only MinKeepBlocks blocks are left unpruned, then compact once, off the go routine
+// running Run.  The directory blocks are kept, as Prune keeps them.  A store that can't be measured is logged
+// once and otherwise left alone.
+func (a *Accumulator) enforceBudget() {
+	if a.MaxDBBytes <= 0 {
+		return
+	}
+	b := &a.budget
+	size, err := a.DB.Size()
+	if err != nil {
+		if !b.failed {
+			a.logger().Printf("can't keep the database under %d bytes: %v", a.MaxDBBytes, err)
+			b.failed = true
+		}
+		return
+	}
+	b.mux.Lock()
+	if b.compacted && size != b.measured { // Measured since the compaction, so the Size has caught up
+		b.freed = 0
+	}
+	b.measured = size
+	before := size - b.freed
+	b.mux.Unlock()
+	if before <= a.MaxDBBytes {
+		return
+	}
+	limit := int64(a.height) - int64(a.MinKeepBlocks) // Prune below this height at most
+	from, err := a.Reader().PrunedHeight()
+	if err != nil {
+		a.logger().Printf("can't keep the database under %d bytes: %v", a.MaxDBBytes, err)
+		return
+	}
+	estimate, pruned := before, from
+	for estimate > a.MaxDBBytes && int64(pruned) < limit {
+		freed, err := a.prune(pruned + 1)
+		b.mux.Lock()
+		b.freed += freed
+		b.compacted = false
+		b.mux.Unlock()
+		if err != nil {
+			a.logger().Printf("failed to prune the block at height %d: %v", pruned, err)
+			break
+		}
+		estimate -= freed
+		pruned++
+	}
+	if pruned > from {
+		a.logger().Printf("pruned the blocks at heights %d to %d, taking the database from about %d to %d bytes of its %d",
+			from, pruned-1, before, estimate, a.MaxDBBytes)
+		a.compactBudget()
+	}
+}
+
+// compactBudget
+// Compact the database on a go routine of its own, unless a compaction is running already, in which case it
+// runs again once done, to pick up what has been pruned since it started
+func (a *Accumulator) compactBudget() {
+	b := &a.budget
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.compacting {
+		b.again = true
+		return
+	}
+	b.compacting = true
+	b.running.Add(1)
+	db := a.DB // Run may swap the store meanwhile; the one pruned is the one to compact
+	go func() {
+		defer b.running.Done()
+		for {
+			_, err := db.Compact()
+			if err != nil {
+				a.logger().Printf("failed to compact the database: %v", err)
+			}
+			b.mux.Lock()
+			if b.again {
+				b.again = false
+				b.mux.Unlock()
+				continue
+			}
+			b.compacting = false
+			b.compacted = err == nil // Until compacted, the Size still holds what was pruned
+			b.mux.Unlock()
+			return
+		}
+	}()
+}
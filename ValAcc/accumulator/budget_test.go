@@ -0,0 +1,94 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestMaxDBBytes(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MaxDBBytes = 1 // Always over, so everything but the last MinKeepBlocks blocks is pruned
+	acc.MinKeepBlocks = 3
+	chainID := types.Hash(sha256.Sum256([]byte("budgeted")))
+	for b := 0; b < 10; b++ {
+		for i := 0; i < 10; i++ {
+			acc.addEntry(GetTestEntry(chainID, b*10+i))
+		}
+		acc.sealBlock()
+	}
+	r := acc.Reader()
+	if height, _ := r.PrunedHeight(); height != 7 {
+		t.Errorf("expected the blocks below 7 to be pruned, not those below %d", height)
+	}
+	for height := types.BlockHeight(0); height < 10; height++ {
+		if _, err := r.GetDirectoryBlock(height); err != nil {
+			t.Errorf("directory block %d should be kept: %v", height, err)
+		}
+		if _, err := r.GetChainNode(chainID, height); (err == nil) != (height >= 7) {
+			t.Errorf("chain node at height %d: %v", height, err)
+		}
+	}
+	entry := GetTestEntry(chainID, 83).EntryHash
+	if receipt, err := r.GetReceipt(chainID, entry, 8); err != nil || !receipt.Verify() {
+		t.Errorf("the entries of the recent blocks should still be proved (%v)", err)
+	}
+
+	roomy := GetTestAccumulator(t)
+	roomy.MaxDBBytes = 1 << 30
+	for b := 0; b < 5; b++ {
+		roomy.addEntry(GetTestEntry(chainID, b))
+		roomy.sealBlock()
+	}
+	if height, _ := roomy.Reader().PrunedHeight(); height != 0 {
+		t.Errorf("nothing should be pruned under the budget, but the blocks below %d were", height)
+	}
+}
+
+// laggingStore
+// A MemStore whose Size only moves when refreshed, as Badger's only moves every minute or so
+type laggingStore struct {
+	*database.MemStore
+	size int64
+}
+
+func (l *laggingStore) Size() (int64, error) {
+	return l.size, nil
+}
+
+func (l *laggingStore) refresh() {
+	l.size, _ = l.MemStore.Size()
+}
+
+func TestMaxDBBytesLaggingSize(t *testing.T) {
+	store := &laggingStore{MemStore: database.NewMemStore()}
+	db := new(database.DB)
+	db.InitStore(store)
+	acc := new(Accumulator)
+	acc.Init(db, &types.Hash{1})
+	chainID := types.Hash(sha256.Sum256([]byte("lagging")))
+	seal := func(b int) {
+		for i := 0; i < 10; i++ {
+			acc.addEntry(GetTestEntry(chainID, b*10+i))
+		}
+		acc.sealBlock()
+	}
+	for b := 0; b < 10; b++ {
+		seal(b)
+	}
+	store.refresh()
+	acc.MaxDBBytes = store.size - 1 // Pruning any one block is enough
+	acc.MinKeepBlocks = 2
+	for b := 10; b < 15; b++ { // The Size stays put, as though it hasn't caught up with the pruning
+		seal(b)
+	}
+	acc.budget.running.Wait()
+	if height, _ := acc.Reader().PrunedHeight(); height != 1 {
+		t.Errorf("expected the block at height 0 alone to be pruned, not those below %d", height)
+	}
+	if _, err := acc.Reader().GetChainNode(chainID, 1); err != nil {
+		t.Errorf("the blocks a Size that lags still counts shouldn't be pruned: %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package accumulator
 
 import (
-	"time"
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
 
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
@@ -15,26 +18,63 @@ type ChainAcc struct {
 	entries map[types.Hash]int // list of entry hashes we are collecting
 	Node    node.Node          // The node we are building
 	MD      *merkleDag.MD      // The class for creating the MD and MD Roots
+	Carried int                // Number of hashes in MD carried over from previous blocks (continuous chains)
+
+	FirstSequence uint64 // Sequence number of the first entry added to the chain in this block
 }
 
-func NewChainAcc(DB database.DB, eHash node.EntryHash, bHeight types.BlockHeight) *ChainAcc {
+// NewChainAcc
+// Start collecting the chain of the given entry, following on from the chain's head if it has one.  Returns an
+// error if the chain has a head that can't be read back, rather than starting the chain over.
+func NewChainAcc(DB database.DB, eHash node.EntryHash, bHeight types.BlockHeight, timeStamp types.TimeStamp) (*ChainAcc, error) {
 	chainAcc := new(ChainAcc)
 	chainAcc.entries = make(map[types.Hash]int)
 	previousHash := DB.Get(types.NodeHead, eHash.ChainID[:])
 	if previousHash != nil {
 		previousBytes := DB.Get(types.Node, previousHash[:])
+		if previousBytes == nil {
+			return nil, errors.New(fmt.Sprintf("the head %x of chain %x is missing", previousHash, eHash.ChainID))
+		}
 		var previous node.Node
-		previous.Unmarshal(previousBytes)
-		chainAcc.Node.SequenceNum = previous.SequenceNum
-		chainAcc.Node.MarshalCache = previousBytes
+		if _, err := previous.Unmarshal(previousBytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("the head %x of chain %x won't unmarshal: %v", previousHash, eHash.ChainID, err))
+		}
+		chainAcc.Node.SequenceNum = previous.SequenceNum + 1
 		chainAcc.Node.Previous = *previous.GetHash()
 	}
 	chainAcc.Node.Version = types.Version
 	chainAcc.Node.SubChainIDs = eHash.SubChains
 	chainAcc.Node.ChainID = eHash.ChainID
-	chainAcc.Node.TimeStamp = types.TimeStamp(time.Now().UnixNano())
+	chainAcc.Node.TimeStamp = timeStamp
 	chainAcc.Node.BHeight = bHeight
 	chainAcc.Node.IsNode = false
 	chainAcc.MD = new(merkleDag.MD)
-	return chainAcc
+	return chainAcc, nil
+}
+
+// Continue
+// Seed this chain's MD with the MD built over previous blocks, so the ListMDRoot computed for this block
+// covers every entry ever added to the chain.  Only the hashes added after this call are recorded in the
+// node's EntryList.
+func (c *ChainAcc) Continue(md *merkleDag.MD) {
+	c.MD = md
+	c.Carried = len(md.HashList)
+}
+
+// chainsInOrder
+// The chains with entries in the current block, in ChainID order.  Anything done a chain at a time that
+// can be seen from outside (logging, writes, hooks) goes through this rather than ranging over the map, so
+// it happens in the same order every time the same block is built.
+func (a *Accumulator) chainsInOrder() []*ChainAcc {
+	if c := a.hot.chain; c != nil && len(a.chains) == 1 { // Nothing to sort
+		return []*ChainAcc{c}
+	}
+	chains := make([]*ChainAcc, 0, len(a.chains))
+	for _, v := range a.chains {
+		chains = append(chains, v)
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return bytes.Compare(chains[i].Node.ChainID[:], chains[j].Node.ChainID[:]) < 0
+	})
+	return chains
 }
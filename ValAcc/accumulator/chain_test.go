@@ -3,11 +3,14 @@ package accumulator
 import (
 	"crypto/sha256"
 	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
 )
 
 func TestMerkleBuilding(t *testing.T) {
 	hash := sha256.Sum256([]byte("testdata"))
-	chain := new(ChainAcc)
+	chain := new(merkleDag.MD)
 
 	// This test depends on the observation that the non blank entries in c.MD must be non-zero
 	// for every set bit in the count of the entries added to c.MD.  So all we have to do to check the algorithm
@@ -33,7 +36,7 @@ func TestMerkleBuilding(t *testing.T) {
 
 func TestMerkleInclusion(t *testing.T) {
 	hash := sha256.Sum256([]byte("testdata"))
-	chain := new(ChainAcc)
+	chain := new(merkleDag.MD)
 
 	// This test leverages the fact that GetMDRoot() is non-destructive.  So we build up a
 	// a MDRoot up to our limit, but after each additional entry, we redo the process with the entries
@@ -47,7 +50,7 @@ func TestMerkleInclusion(t *testing.T) {
 
 		MDRoot := chain.GetMDRoot()
 
-		copyChain := new(ChainAcc)
+		copyChain := new(merkleDag.MD)
 		for _, v := range chain.HashList {
 			copyChain.AddToChain(v)
 		}
@@ -70,7 +73,7 @@ func TestMerkleInclusion(t *testing.T) {
 
 		for i := 0; i < eCnt; i++ { // Run eCnt tests (one for every entry in chain
 			for j := 0; j < eCnt; j++ { // Modify each of the entries in chain and compute a MDRoot
-				modChain := new(ChainAcc)
+				modChain := new(merkleDag.MD)
 				for i, v := range chain.HashList {
 					if i == j {
 						v[0] ^= 1 // Flip one bit only in the inputs into the new ChainAcc
@@ -86,3 +89,17 @@ func TestMerkleInclusion(t *testing.T) {
 		}
 	}
 }
+
+func TestNewChainAccMissingHead(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("lost its head")))
+	missing := sha256.Sum256([]byte("no such node"))
+	acc.DB.Put(types.NodeHead, chainID[:], missing[:])
+	if _, err := NewChainAcc(*acc.DB, GetTestEntry(chainID, 0), 0, 0); err == nil {
+		t.Fatal("a chain whose head is missing should not be started over")
+	}
+	acc.processEntry(GetTestEntry(chainID, 0))
+	if len(acc.chains) != 0 {
+		t.Error("the entry of a chain whose head is missing should be dropped")
+	}
+}
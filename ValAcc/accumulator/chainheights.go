@@ -0,0 +1,45 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// indexChainHeights
+// Add this block's height to the heights of every chain in it.  A height already there (written by an
+// earlier attempt to commit this block) isn't added again.
+func (a *Accumulator) indexChainHeights(db *database.DB, chains []*ChainAcc) {
+	for _, v := range chains {
+		chainID := v.Node.ChainID
+		heights := a.DB.Get(types.ChainHeights, chainID[:])
+		if n := len(heights); n >= 4 {
+			var last types.BlockHeight
+			last.Extract(heights[n-4:])
+			if last == a.height {
+				continue
+			}
+		}
+		db.Put(types.ChainHeights, chainID[:], append(append([]byte{}, heights...), a.height.Bytes()...))
+	}
+}
+
+// ChainBlockHeights
+// Return the heights of the directory blocks the chain has a node in, lowest first, without walking the
+// directory blocks.  Pruning keeps them, as it keeps the directory blocks.  Blocks sealed before the heights
+// were kept (by an older version of ValAcc) aren't listed.
+func (r *Reader) ChainBlockHeights(chainID types.Hash) ([]types.BlockHeight, error) {
+	data := r.DB.Get(types.ChainHeights, chainID[:])
+	if len(data)%4 != 0 {
+		return nil, errors.New(fmt.Sprintf("the heights of chain %x take %d bytes", chainID, len(data)))
+	}
+	heights := make([]types.BlockHeight, 0, len(data)/4)
+	for len(data) > 0 {
+		var height types.BlockHeight
+		data = height.Extract(data)
+		heights = append(heights, height)
+	}
+	return heights, nil
+}
@@ -0,0 +1,33 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestChainBlockHeights(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("now and then")))
+	other := types.Hash(sha256.Sum256([]byte("every block")))
+	for height := 0; height < 9; height++ {
+		if height == 1 || height == 3 || height == 7 {
+			acc.addEntry(GetTestEntry(chainID, height*10))
+			acc.addEntry(GetTestEntry(chainID, height*10+1))
+		}
+		acc.addEntry(GetTestEntry(other, height))
+		acc.sealBlock()
+	}
+	heights, err := acc.Reader().ChainBlockHeights(chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(heights) != "[1 3 7]" {
+		t.Errorf("the chain should have nodes at heights 1, 3 and 7, not %v", heights)
+	}
+	if heights, _ := acc.Reader().ChainBlockHeights(types.Hash{}); len(heights) != 0 {
+		t.Errorf("a chain never sealed has no heights, not %v", heights)
+	}
+}
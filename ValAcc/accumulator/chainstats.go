@@ -0,0 +1,106 @@
+package accumulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ChainStat
+// How much a chain has contributed to the blocks sealed with KeepChainStats set, and when it last did
+type ChainStat struct {
+	ChainID       types.Hash
+	Entries       uint64            // Entries added to the chain
+	Blocks        uint64            // Blocks the chain has a node in
+	LastHeight    types.BlockHeight // Height of the last block the chain has a node in
+	LastTimeStamp types.TimeStamp   // TimeStamp of that block
+}
+
+// Marshal
+// Entries, blocks, last height, then last timestamp.  The ChainID is the key it is stored under.
+func (s *ChainStat) Marshal() (data []byte) {
+	data = append(data, types.Uint64Bytes(s.Entries)...)
+	data = append(data, types.Uint64Bytes(s.Blocks)...)
+	data = append(data, s.LastHeight.Bytes()...)
+	data = append(data, s.LastTimeStamp.Bytes()...)
+	return data
+}
+
+// Unmarshal
+// Extract the stats of a chain from a byte slice.  Returns an error if the unmarshal fails.
+func (s *ChainStat) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.New(fmt.Sprintf("ChainStat failed to unmarshal %v", rec))
+		}
+	}()
+	s.Entries, data = types.BytesUint64(data)
+	s.Blocks, data = types.BytesUint64(data)
+	data = s.LastHeight.Extract(data)
+	s.LastTimeStamp.Extract(data)
+	return nil
+}
+
+// writeChainStats
+// Add the chain's node in this block to its stats
+func (a *Accumulator) writeChainStats(db *database.DB, chain *ChainAcc, timeStamp types.TimeStamp) {
+	stat, err := a.Reader().ChainStats(chain.Node.ChainID)
+	if err != nil || stat == nil { // Stats that can't be read are started again
+		stat = &ChainStat{ChainID: chain.Node.ChainID}
+	}
+	stat.Entries += uint64(len(chain.Node.EntryList))
+	stat.Blocks++
+	stat.LastHeight = a.height
+	stat.LastTimeStamp = timeStamp
+	db.Put(types.ChainStats, chain.Node.ChainID[:], stat.Marshal())
+}
+
+// ChainStats
+// Return the stats of the chain, or nil if it has no node in any block sealed with KeepChainStats set
+func (r *Reader) ChainStats(chainID types.Hash) (*ChainStat, error) {
+	data := r.DB.Get(types.ChainStats, chainID[:])
+	if data == nil {
+		return nil, nil
+	}
+	stat := &ChainStat{ChainID: chainID}
+	if err := stat.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// TopChainsByEntries
+// Return the stats of the n chains with the most entries, most first (and in ChainID order for the same
+// count).  Every chain's stats are read, so this is for the occasional report rather than every block.
+func (r *Reader) TopChainsByEntries(n int) ([]ChainStat, error) {
+	var stats []ChainStat
+	err := r.DB.IterateBucket(types.ChainStats, func(key, value []byte) error {
+		var stat ChainStat
+		if err := stat.Unmarshal(value); err != nil {
+			return err
+		}
+		copy(stat.ChainID[:], key)
+		stats = append(stats, stat)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Entries != stats[j].Entries {
+			return stats[i].Entries > stats[j].Entries
+		}
+		return bytes.Compare(stats[i].ChainID[:], stats[j].ChainID[:]) < 0
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(stats) {
+		stats = stats[:n]
+	}
+	return stats, nil
+}
@@ -0,0 +1,54 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestChainStats(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	acc.KeepChainStats = true
+	busy := types.Hash(sha256.Sum256([]byte("busy")))
+	quiet := types.Hash(sha256.Sum256([]byte("quiet")))
+	idle := types.Hash(sha256.Sum256([]byte("idle")))
+	for b := 0; b < 4; b++ {
+		for i := 0; i < 10; i++ {
+			acc.addEntry(GetTestEntry(busy, b*10+i))
+		}
+		if b%2 == 0 {
+			acc.addEntry(GetTestEntry(quiet, b))
+		}
+		clock.now = clock.now.Add(time.Second)
+		acc.sealBlock()
+	}
+	acc.KeepChainStats = false // Blocks sealed without stats don't count
+	acc.addEntry(GetTestEntry(idle, 0))
+	acc.addEntry(GetTestEntry(busy, 40))
+	acc.sealBlock()
+
+	for _, expected := range []ChainStat{
+		{ChainID: busy, Entries: 40, Blocks: 4, LastHeight: 3, LastTimeStamp: types.TimeStamp(time.Unix(1004, 0).UnixNano())},
+		{ChainID: quiet, Entries: 2, Blocks: 2, LastHeight: 2, LastTimeStamp: types.TimeStamp(time.Unix(1003, 0).UnixNano())},
+	} {
+		stat, err := acc.Reader().ChainStats(expected.ChainID)
+		if err != nil || stat == nil || *stat != expected {
+			t.Errorf("expected %+v, got %+v (%v)", expected, stat, err)
+		}
+	}
+	if stat, err := acc.Reader().ChainStats(idle); stat != nil || err != nil {
+		t.Errorf("a chain never sealed with KeepChainStats should have no stats, got %+v (%v)", stat, err)
+	}
+
+	top, err := acc.Reader().TopChainsByEntries(5)
+	if err != nil || len(top) != 2 || top[0].ChainID != busy || top[1].ChainID != quiet {
+		t.Errorf("expected the busy chain then the quiet one, got %+v (%v)", top, err)
+	}
+	if top, _ := acc.Reader().TopChainsByEntries(1); len(top) != 1 || top[0].ChainID != busy {
+		t.Errorf("expected just the busy chain, got %+v", top)
+	}
+}
@@ -0,0 +1,32 @@
+package accumulator
+
+import (
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Clock
+// Where the accumulator gets the time from.  Tests can provide their own to control time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// clock
+// The Clock to use; if none was set, we use the system's clock
+func (a *Accumulator) clock() Clock {
+	if a.Clock == nil {
+		return systemClock{}
+	}
+	return a.Clock
+}
+
+// now
+// The time to stamp on the nodes we build
+func (a *Accumulator) now() types.TimeStamp {
+	return types.TimeStamp(a.clock().Now().UnixNano())
+}
@@ -0,0 +1,146 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ConsistencyProof
+// Links the root of a chain's MD over its first FromSize entries to the root over its first ToSize
+// entries, so a client caching the old root can move up to the new one.  The MD over the history of a
+// chain is built as a continuous chain's is: over every entry the chain ever added, in order.
+//
+// The OldPeaks are the roots of the perfect trees the MD is made of at FromSize, one for each bit set in
+// FromSize from the lowest, i.e. the smallest (newest) tree first.  Combined they give the old root.
+// Adding the new Entries on top of them gives the new root.
+type ConsistencyProof struct {
+	ChainID  types.Hash   // Chain the proof is over
+	Flags    node.Flags   // Flags of the blocks the chain was built in, which say what Hasher to verify with
+	FromSize int          // Entries covered by the old root
+	ToSize   int          // Entries covered by the new root
+	OldPeaks []types.Hash // Roots of the perfect trees making up the MD at FromSize
+	Entries  []types.Hash // The entries FromSize up to ToSize
+}
+
+// oldMD
+// Rebuild the MD as it stood at FromSize from the OldPeaks
+func (p *ConsistencyProof) oldMD(hasher merkleDag.Hasher) (*merkleDag.MD, error) {
+	md := new(merkleDag.MD)
+	md.Hasher = hasher
+	peaks := p.OldPeaks
+	for size := p.FromSize; size > 0; size >>= 1 {
+		if size&1 == 0 {
+			md.MD = append(md.MD, nil)
+			continue
+		}
+		if len(peaks) == 0 {
+			return nil, errors.New(fmt.Sprintf("a size of %d needs more than the %d peaks given", p.FromSize, len(p.OldPeaks)))
+		}
+		md.MD = append(md.MD, peaks[0].Copy())
+		peaks = peaks[1:]
+	}
+	if len(peaks) != 0 {
+		return nil, errors.New(fmt.Sprintf("a size of %d needs fewer than the %d peaks given", p.FromSize, len(p.OldPeaks)))
+	}
+	return md, nil
+}
+
+// Verify
+// Check the proof takes oldRoot to newRoot, combining hashes with the given Hasher (nil for sha256).
+func (p *ConsistencyProof) Verify(hasher merkleDag.Hasher, oldRoot, newRoot types.Hash) error {
+	if p.ToSize < p.FromSize || len(p.Entries) != p.ToSize-p.FromSize {
+		return errors.New(fmt.Sprintf("going from %d to %d entries takes %d entries, not %d",
+			p.FromSize, p.ToSize, p.ToSize-p.FromSize, len(p.Entries)))
+	}
+	md, err := p.oldMD(hasher)
+	if err != nil {
+		return err
+	}
+	if root := *md.GetMDRoot(); root != oldRoot {
+		return errors.New(fmt.Sprintf("the peaks give the old root %x, not %x", root, oldRoot))
+	}
+	for _, h := range p.Entries {
+		md.AddToChain(h)
+	}
+	if root := *md.GetMDRoot(); root != newRoot {
+		return errors.New(fmt.Sprintf("the new entries give the root %x, not %x", root, newRoot))
+	}
+	return nil
+}
+
+// GetConsistencyProof
+// Return the proof linking the root over the first fromSize entries of the chain to the root over the
+// first toSize.  The roots are those of a continuous chain (see ContinuousChains), so this returns an error
+// for a chain whose nodes' ListMDRoots don't cover its whole history, as well as one that doesn't have toSize
+// entries, or has had some pruned.  The proof's Flags are those of the blocks the chain was built in, and say
+// which Hasher verifies it.
+func (r *Reader) GetConsistencyProof(chainID types.Hash, fromSize, toSize int) (*ConsistencyProof, error) {
+	if fromSize < 0 || toSize < fromSize {
+		return nil, errors.New(fmt.Sprintf("can't prove %d entries are consistent with %d", toSize, fromSize))
+	}
+	history, flags, err := r.continuousHistory(chainID, toSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(history.HashList) < toSize {
+		return nil, errors.New(fmt.Sprintf("chain %x has %d entries, not %d", chainID, len(history.HashList), toSize))
+	}
+	old := r.forFlags(flags).newMD()
+	for _, h := range history.HashList[:fromSize] {
+		old.AddToChain(h)
+	}
+	proof := new(ConsistencyProof)
+	proof.ChainID = chainID
+	proof.Flags = flags
+	proof.FromSize = fromSize
+	proof.ToSize = toSize
+	for _, peak := range old.MD {
+		if peak != nil {
+			proof.OldPeaks = append(proof.OldPeaks, *peak)
+		}
+	}
+	proof.Entries = append(proof.Entries, history.HashList[fromSize:toSize]...)
+	return proof, nil
+}
+
+// continuousHistory
+// Build the MD over the history of a chain, node by node, until it covers at least the given number of
+// entries (or the chain runs out), hashing as the flags of the directory block holding its first node say.
+// Each node's ListMDRoot has to be the root over the history up to and including it, as it is for a
+// continuous chain built with the same Hasher throughout; returns an error for the first node whose isn't.
+func (r *Reader) continuousHistory(chainID types.Hash, size int) (*merkleDag.MD, node.Flags, error) {
+	var md *merkleDag.MD
+	var flags node.Flags
+	for hash := r.DB.Get(types.NodeFirst, chainID[:]); hash != nil; hash = r.DB.Get(types.NodeNext, hash) {
+		n, err := r.GetNode(hash)
+		if err != nil {
+			return nil, 0, err
+		}
+		if md == nil {
+			directoryBlock, err := r.GetDirectoryBlock(n.BHeight)
+			if err != nil {
+				return nil, 0, err
+			}
+			flags = directoryBlock.Flags
+			md = r.forFlags(flags).newMD()
+		}
+		for _, h := range n.EntryList {
+			md.AddToChain(h)
+		}
+		if root := *md.GetMDRoot(); root != n.ListMDRoot {
+			return nil, 0, errors.New(fmt.Sprintf("chain %x isn't continuous; its node at height %d has the root %x, "+
+				"but its history gives %x", chainID, n.BHeight, n.ListMDRoot, root))
+		}
+		if len(md.HashList) >= size {
+			break
+		}
+	}
+	if md == nil {
+		md = r.newMD()
+	}
+	return md, flags, nil
+}
@@ -0,0 +1,98 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestConsistencyProof(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("consistent")))
+	acc.ContinuousChains = map[types.Hash]bool{chainID: true}
+	var roots []types.Hash // Root of the chain's history at each size
+	md := new(merkleDag.MD)
+	roots = append(roots, *md.GetMDRoot())
+	for b := 0; b < 5; b++ {
+		for i := 0; i < 7+b; i++ {
+			entry := GetTestEntry(chainID, len(roots))
+			acc.addEntry(entry)
+			md.AddToChain(entry.EntryHash)
+			roots = append(roots, *md.GetMDRoot())
+		}
+		acc.sealBlock()
+	}
+	size := len(roots) - 1
+
+	for from := 0; from <= size; from++ {
+		for to := from; to <= size; to++ {
+			proof, err := acc.Reader().GetConsistencyProof(chainID, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := proof.Verify(nil, roots[from], roots[to]); err != nil {
+				t.Fatalf("the proof from %d to %d entries should verify: %v", from, to, err)
+			}
+		}
+	}
+
+	proof, _ := acc.Reader().GetConsistencyProof(chainID, 13, 40)
+	if proof.Verify(nil, roots[12], roots[40]) == nil {
+		t.Error("the proof should not verify from the wrong old root")
+	}
+	if proof.Verify(nil, roots[13], roots[39]) == nil {
+		t.Error("the proof should not verify to the wrong new root")
+	}
+	proof.Entries[5][0] ^= 1
+	if proof.Verify(nil, roots[13], roots[40]) == nil {
+		t.Error("a proof with a tampered entry should not verify")
+	}
+	if _, err := acc.Reader().GetConsistencyProof(chainID, 3, size+1); err == nil {
+		t.Error("there is no proof to more entries than the chain has")
+	}
+
+	other := types.Hash(sha256.Sum256([]byte("not continuous")))
+	for b := 0; b < 2; b++ {
+		acc.addEntry(GetTestEntry(other, b))
+		acc.sealBlock()
+	}
+	if _, err := acc.Reader().GetConsistencyProof(other, 1, 2); err == nil {
+		t.Error("there is no proof between the roots of a chain that isn't continuous")
+	}
+}
+
+func TestConsistencyProofFlags(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.BlockFlags = node.DomainSeparated
+	chainID := types.Hash(sha256.Sum256([]byte("domain separated")))
+	acc.ContinuousChains = map[types.Hash]bool{chainID: true}
+	var roots []types.Hash // The chain's ListMDRoot after each block
+	for b := 0; b < 3; b++ {
+		for i := 0; i < 5; i++ {
+			acc.addEntry(GetTestEntry(chainID, b*5+i))
+		}
+		acc.sealBlock()
+		chain, err := acc.Reader().GetChainNode(chainID, types.BlockHeight(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, chain.ListMDRoot)
+	}
+
+	proof, err := acc.Reader().GetConsistencyProof(chainID, 5, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Flags != node.DomainSeparated {
+		t.Errorf("the proof should carry the flags of the chain's blocks, got %v", proof.Flags)
+	}
+	if err := proof.Verify(merkleDag.DomainHasher{}, roots[0], roots[2]); err != nil {
+		t.Errorf("the proof should verify with the DomainHasher: %v", err)
+	}
+	if proof.Verify(nil, roots[0], roots[2]) == nil {
+		t.Error("the proof should not verify with sha256")
+	}
+}
@@ -0,0 +1,25 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// continuousMD
+// Return the MD holding everything accumulated so far for a continuous chain.  If we don't have it in
+// memory (i.e. we have restarted) then it is rebuilt from the chain's nodes in the database, which returns an
+// error if any of them can't be read.
+func (a *Accumulator) continuousMD(chainID types.Hash) (*merkleDag.MD, error) {
+	md := a.continuous[chainID]
+	if md == nil {
+		var err error
+		if md, err = a.Reader().chainMDTo(chainID, a.height); err != nil {
+			return nil, errors.New(fmt.Sprintf("could not rebuild the continuous chain %x from the database: %v", chainID, err))
+		}
+		a.continuous[chainID] = md
+	}
+	return md, nil
+}
@@ -0,0 +1,53 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestContinuousChain(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("continuous")))
+	acc.ContinuousChains = map[types.Hash]bool{chainID: true}
+
+	fresh := new(merkleDag.MD) // Build the MD from scratch over all the entries, blocks 1 and 2
+	for i := 0; i < 5; i++ {
+		entry := GetTestEntry(chainID, i)
+		acc.addEntry(entry)
+		fresh.AddToChain(entry.EntryHash)
+	}
+	acc.sealBlock()
+	var added []types.Hash // The entries added in block 2 only
+	for i := 5; i < 12; i++ {
+		entry := GetTestEntry(chainID, i)
+		acc.addEntry(entry)
+		fresh.AddToChain(entry.EntryHash)
+		added = append(added, entry.EntryHash)
+	}
+	acc.sealBlock()
+
+	var head node.Node
+	if _, err := head.Unmarshal(acc.DB.Get(types.Node, acc.DB.Get(types.NodeHead, chainID[:]))); err != nil {
+		t.Fatal(err)
+	}
+	if head.ListMDRoot != *fresh.GetMDRoot() {
+		t.Errorf("continuous chain root %x should match an MD over both blocks %x", head.ListMDRoot, *fresh.GetMDRoot())
+	}
+	if len(head.EntryList) != len(added) {
+		t.Fatalf("expected %d entries recorded in block 2, got %d", len(added), len(head.EntryList))
+	}
+	for i, h := range added {
+		if head.EntryList[i] != h {
+			t.Errorf("entry %d in block 2 is %x, expected %x", i, head.EntryList[i], h)
+		}
+	}
+
+	// After a restart, the chain's MD has to be rebuilt from the database
+	if md, err := acc.Reader().chainMDTo(chainID, acc.height); err != nil || *md.GetMDRoot() != *fresh.GetMDRoot() {
+		t.Error("rebuilding the continuous chain from the database should give the same root")
+	}
+}
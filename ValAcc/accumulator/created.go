@@ -0,0 +1,41 @@
+package accumulator
+
+import (
+	"runtime/debug"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ChainCreated
+// A chain sealed for the first time
+type ChainCreated struct {
+	ChainID types.Hash
+	Height  types.BlockHeight // Height of the block the chain was first sealed in
+}
+
+// chainsCreated
+// Tell OnChainCreated of the chains in the block just committed that have no node before it, in ChainID order.
+// The first node of a chain is the one with no head to follow on from in the database, so has the sequence
+// number zero.
+func (a *Accumulator) chainsCreated(chains []*ChainAcc) {
+	if a.OnChainCreated == nil {
+		return
+	}
+	for _, v := range chains {
+		if v.Node.SequenceNum == 0 {
+			a.chainCreated(ChainCreated{ChainID: v.Node.ChainID, Height: v.Node.BHeight})
+		}
+	}
+}
+
+// chainCreated
+// Call OnChainCreated, recovering from a panic in it
+func (a *Accumulator) chainCreated(event ChainCreated) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger().Printf("recovered from a panic in OnChainCreated for chain %x: %v\n%s", event.ChainID, r, debug.Stack())
+			a.metrics().Add(MetricPanics, 1)
+		}
+	}()
+	a.OnChainCreated(event)
+}
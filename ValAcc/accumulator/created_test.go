@@ -0,0 +1,45 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestOnChainCreated(t *testing.T) {
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	accID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	var created []ChainCreated
+	start := func() *Accumulator {
+		acc := new(Accumulator)
+		acc.OnChainCreated = func(event ChainCreated) { created = append(created, event) }
+		acc.Init(db, &accID)
+		return acc
+	}
+	first := types.Hash(sha256.Sum256([]byte("created first")))
+	second := types.Hash(sha256.Sum256([]byte("created second")))
+	third := types.Hash(sha256.Sum256([]byte("created after the restart")))
+
+	acc := start()
+	acc.addEntry(GetTestEntry(first, 0))
+	acc.addEntry(GetTestEntry(first, 1))
+	acc.sealBlock()
+	acc.addEntry(GetTestEntry(first, 2))
+	acc.addEntry(GetTestEntry(second, 0))
+	acc.sealBlock()
+
+	acc = start() // After a restart, only the chain never sealed is new
+	acc.addEntry(GetTestEntry(first, 3))
+	acc.addEntry(GetTestEntry(second, 1))
+	acc.addEntry(GetTestEntry(third, 0))
+	acc.sealBlock()
+
+	want := []ChainCreated{{first, 0}, {second, 1}, {third, 2}}
+	if fmt.Sprint(created) != fmt.Sprint(want) {
+		t.Errorf("expected each chain to be created once, in the block it was first sealed in, got %x", created)
+	}
+}
@@ -0,0 +1,36 @@
+package accumulator
+
+import (
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ChainEntryKey
+// Key of the record that an entry was sealed in a chain, in the ChainEntry bucket
+func ChainEntryKey(chainID, entry types.Hash) (key []byte) {
+	key = append(key, chainID.Bytes()...)
+	key = append(key, entry.Bytes()...)
+	return key
+}
+
+// writeChainEntries
+// Record that every entry the chain added in this block was sealed at this height
+func (a *Accumulator) writeChainEntries(db *database.DB, chain *ChainAcc) {
+	height := a.height.Bytes()
+	for _, h := range chain.Node.EntryList {
+		db.Put(types.ChainEntry, ChainEntryKey(chain.Node.ChainID, h), height)
+	}
+}
+
+// Recorded
+// Report, for each of the entries, whether it has been sealed in its chain by an accumulator running with
+// PermanentDedup.  Checking a batch of entries at once lets a submitter filter out repeats before it
+// submits them.
+func (r *Reader) Recorded(entries ...node.EntryHash) []bool {
+	recorded := make([]bool, len(entries))
+	for i, entry := range entries {
+		recorded[i] = r.DB.Get(types.ChainEntry, ChainEntryKey(entry.ChainID, entry.EntryHash)) != nil
+	}
+	return recorded
+}
@@ -0,0 +1,52 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestPermanentDedup(t *testing.T) {
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	accID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	chainID := types.Hash(sha256.Sum256([]byte("idempotent")))
+	start := func() (*Accumulator, *[]RejectReason) {
+		acc := new(Accumulator)
+		acc.PermanentDedup = true
+		acc.Init(db, &accID)
+		reasons := new([]RejectReason)
+		acc.OnReject = func(entry node.EntryHash, reason RejectReason) { *reasons = append(*reasons, reason) }
+		return acc, reasons
+	}
+
+	acc, _ := start()
+	for i := 0; i < 5; i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	runUntilIdle(acc)
+	acc.sealBlock()
+	if err := acc.Prune(1); err != nil { // Pruning doesn't touch the permanent index
+		t.Fatal(err)
+	}
+
+	restarted, reasons := start()
+	if restarted.Submit(GetTestEntry(chainID, 2)) {
+		t.Error("an entry recorded before the restart should be rejected")
+	}
+	if !restarted.Submit(GetTestEntry(chainID, 5)) {
+		t.Error("a new entry should be accepted")
+	}
+	if len(*reasons) != 1 || (*reasons)[0] != AlreadyRecorded {
+		t.Errorf("expected one entry rejected as already recorded, got %v", *reasons)
+	}
+	other := GetTestEntry(chainID, 3)
+	other.ChainID = types.Hash(sha256.Sum256([]byte("another chain")))
+	recorded := restarted.Reader().Recorded(GetTestEntry(chainID, 0), GetTestEntry(chainID, 4), GetTestEntry(chainID, 5), other)
+	if !recorded[0] || !recorded[1] || recorded[2] || recorded[3] {
+		t.Errorf("only the sealed entries should be recorded, and only in their own chain, got %v", recorded)
+	}
+}
@@ -0,0 +1,74 @@
+package accumulator
+
+import (
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// EntryDetails
+// Everything an explorer shows of a sealed entry, from EntryDetails
+type EntryDetails struct {
+	ChainID     types.Hash
+	Entry       types.Hash
+	Height      types.BlockHeight // Height of the directory block the entry was sealed in
+	TimeStamp   types.TimeStamp   // TimeStamp of that directory block
+	Sequence    uint64            // The entry's sequence number in its chain, if HasSequence
+	HasSequence bool              // The entry was sealed with EntrySequences set
+	Final       bool              // The block is final (see FinalizationDepth), so no reorg can change it
+	Receipt     *Receipt          // Proves the entry is in the directory block
+}
+
+// ErrEntryNotFound
+// Returned by EntryDetails for an entry that hasn't been sealed in the chain
+type ErrEntryNotFound struct {
+	ChainID types.Hash
+	Entry   types.Hash
+}
+
+func (e ErrEntryNotFound) Error() string {
+	return fmt.Sprintf("entry %x has not been sealed in chain %x", e.Entry, e.ChainID)
+}
+
+// EntryDetails
+// Return where an entry was sealed in its chain, with a receipt ready to verify, in one call.  The entry's
+// chain node is found through its index rather than by walking the chain, and the receipt is built from the
+// chain node and directory block already loaded.  Returns an ErrEntryNotFound for an entry the chain doesn't
+// hold.
+func (r *Reader) EntryDetails(chainID, entry types.Hash) (*EntryDetails, error) {
+	nodeHash := r.DB.Get(types.EntryNode, entry[:])
+	if nodeHash == nil {
+		return nil, ErrEntryNotFound{ChainID: chainID, Entry: entry}
+	}
+	chainNode, err := r.GetNode(nodeHash)
+	if err != nil {
+		return nil, err
+	}
+	if chainNode.ChainID != chainID {
+		return nil, ErrEntryNotFound{ChainID: chainID, Entry: entry}
+	}
+	directoryBlock, err := r.GetDirectoryBlock(chainNode.BHeight)
+	if err != nil {
+		return nil, err
+	}
+	finalized, known, err := r.FinalizedCount()
+	if err != nil {
+		return nil, err
+	}
+	details := &EntryDetails{
+		ChainID:   chainID,
+		Entry:     entry,
+		Height:    directoryBlock.BHeight,
+		TimeStamp: directoryBlock.TimeStamp,
+		Final:     known && directoryBlock.BHeight < finalized,
+	}
+	details.Sequence, details.HasSequence = r.GetEntrySequence(chainID, entry)
+	chainReceipt, entryMD, flags, err := r.proveChain(directoryBlock, chainID, chainNode)
+	if err != nil {
+		return nil, err
+	}
+	if details.Receipt, err = r.buildReceipt(chainID, entry, details.Height, chainReceipt, entryMD, flags); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
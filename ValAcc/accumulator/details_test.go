@@ -0,0 +1,64 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestEntryDetails(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.Clock = &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	acc.EntrySequences = true
+	acc.FinalizationDepth = 2
+	chainID := types.Hash(sha256.Sum256([]byte("explored")))
+	other := types.Hash(sha256.Sum256([]byte("not explored")))
+	var blocks []*node.Node
+	for height := 0; height < 3; height++ {
+		for i := 0; i < 4; i++ {
+			acc.addEntry(GetTestEntry(chainID, height*10+i))
+		}
+		acc.addEntry(GetTestEntry(other, height))
+		blocks = append(blocks, acc.sealBlock())
+	}
+
+	for _, c := range []struct {
+		height   int
+		sequence uint64
+		final    bool
+	}{{0, 2, true}, {2, 10, false}} {
+		entry := GetTestEntry(chainID, c.height*10+2).EntryHash
+		details, err := acc.Reader().EntryDetails(chainID, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := blocks[c.height]
+		if details.ChainID != chainID || details.Entry != entry || details.Height != block.BHeight ||
+			details.TimeStamp != block.TimeStamp {
+			t.Errorf("the entry should be found in the block at height %d, not %d", c.height, details.Height)
+		}
+		if !details.HasSequence || details.Sequence != c.sequence {
+			t.Errorf("the entry should have the sequence %d, not %d (%v)", c.sequence, details.Sequence, details.HasSequence)
+		}
+		if details.Final != c.final {
+			t.Errorf("the block at height %d should be final %v", c.height, c.final)
+		}
+		if details.Receipt == nil || !details.Receipt.VerifyAgainstRoot(block.ListMDRoot) ||
+			details.Receipt.EntryReceipt.EntryHash != entry {
+			t.Errorf("the receipt for the entry at height %d should verify against its block", c.height)
+		}
+	}
+
+	missing := types.Hash(sha256.Sum256([]byte("never submitted")))
+	for _, c := range []struct{ chainID, entry types.Hash }{
+		{chainID, missing},
+		{chainID, GetTestEntry(other, 1).EntryHash}, // Sealed, but in another chain
+	} {
+		if _, err := acc.Reader().EntryDetails(c.chainID, c.entry); err != (ErrEntryNotFound{ChainID: c.chainID, Entry: c.entry}) {
+			t.Errorf("expected an ErrEntryNotFound, got %v", err)
+		}
+	}
+}
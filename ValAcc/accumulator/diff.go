@@ -0,0 +1,121 @@
+package accumulator
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// DiffReport
+// Where the directory blocks of two accumulators (a replica and its primary, say) part ways
+type DiffReport struct {
+	BlocksA  int               // Directory blocks the first accumulator has sealed
+	BlocksB  int               // Directory blocks the second has sealed
+	Diverged bool              // Some height both have sealed holds different directory blocks
+	Height   types.BlockHeight // The first such height
+	Chains   []types.Hash      // Chains with a node in only one of the blocks at Height, or different roots, in ChainID order
+}
+
+// Diff
+// Compare the directory blocks of two accumulators over the heights both have sealed.  Each directory block
+// hashes in the one before it, so once the blocks at a height differ so do all those above it, and the first
+// height they differ at is found by a binary search.  One accumulator being ahead of the other isn't a
+// divergence; the block counts say so.
+func Diff(a, b *Reader) (*DiffReport, error) {
+	report := new(DiffReport)
+	var err error
+	if report.BlocksA, err = sealedBlocks(a); err != nil {
+		return nil, err
+	}
+	if report.BlocksB, err = sealedBlocks(b); err != nil {
+		return nil, err
+	}
+	common := report.BlocksA
+	if report.BlocksB < common {
+		common = report.BlocksB
+	}
+	var failed error
+	first := sort.Search(common, func(height int) bool {
+		same, err := sameBlock(a, b, types.BlockHeight(height))
+		if err != nil && failed == nil {
+			failed = err
+		}
+		return !same
+	})
+	if failed != nil {
+		return nil, failed
+	}
+	if first == common {
+		return report, nil
+	}
+	report.Diverged = true
+	report.Height = types.BlockHeight(first)
+	report.Chains, err = diffChains(a, b, report.Height)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// sealedBlocks
+// The number of directory blocks sealed
+func sealedBlocks(r *Reader) (int, error) {
+	head, err := r.GetHead()
+	if err != nil || head == nil {
+		return 0, err
+	}
+	return int(head.BHeight) + 1, nil
+}
+
+// sameBlock
+// Whether the directory blocks at the height have the same hash
+func sameBlock(a, b *Reader, height types.BlockHeight) (bool, error) {
+	blockA, err := a.GetDirectoryBlock(height)
+	if err != nil {
+		return false, err
+	}
+	blockB, err := b.GetDirectoryBlock(height)
+	if err != nil {
+		return false, err
+	}
+	return *blockA.GetHash() == *blockB.GetHash(), nil
+}
+
+// diffChains
+// The chains whose roots differ between the directory blocks at the height, the chains of a Grouped block's
+// group included
+func diffChains(a, b *Reader, height types.BlockHeight) ([]types.Hash, error) {
+	blockA, err := a.GetDirectoryBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	blockB, err := b.GetDirectoryBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	chainsA, err := a.blockChains(blockA)
+	if err != nil {
+		return nil, err
+	}
+	chainsB, err := b.blockChains(blockB)
+	if err != nil {
+		return nil, err
+	}
+	roots := make(map[types.Hash]types.Hash, len(chainsA))
+	for _, ne := range chainsA {
+		roots[ne.ChainID] = ne.MDRoot
+	}
+	var differ []types.Hash
+	for _, ne := range chainsB {
+		if root, ok := roots[ne.ChainID]; !ok || root != ne.MDRoot {
+			differ = append(differ, ne.ChainID)
+		}
+		delete(roots, ne.ChainID)
+	}
+	for chainID := range roots {
+		differ = append(differ, chainID)
+	}
+	sort.Slice(differ, func(i, j int) bool { return bytes.Compare(differ[i][:], differ[j][:]) < 0 })
+	return differ, nil
+}
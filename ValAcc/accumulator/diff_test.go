@@ -0,0 +1,56 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestDiff(t *testing.T) {
+	accID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	newAccumulator := func() *Accumulator {
+		db := new(database.DB)
+		db.InitStore(database.NewMemStore())
+		acc := new(Accumulator)
+		acc.Clock = &testClock{now: time.Unix(1000, 0)} // Blocks sealed at the same time hash the same
+		acc.Init(db, &accID)
+		return acc
+	}
+	a, b := newAccumulator(), newAccumulator()
+	steady := types.Hash(sha256.Sum256([]byte("steady")))
+	diverging := types.Hash(sha256.Sum256([]byte("diverging")))
+	for height := 0; height < 7; height++ {
+		for _, acc := range []*Accumulator{a, b} {
+			acc.addEntry(GetTestEntry(steady, height))
+			acc.addEntry(GetTestEntry(diverging, height))
+		}
+		if height == 4 {
+			b.addEntry(GetTestEntry(diverging, 100))
+		}
+		a.sealBlock()
+		if height < 6 { // b falls a block behind
+			b.sealBlock()
+		}
+	}
+	report, err := Diff(a.Reader(), b.Reader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.BlocksA != 7 || report.BlocksB != 6 {
+		t.Errorf("expected 7 and 6 blocks, got %d and %d", report.BlocksA, report.BlocksB)
+	}
+	if !report.Diverged || report.Height != 4 {
+		t.Fatalf("the accumulators should diverge at height 4, not %d (diverged %v)", report.Height, report.Diverged)
+	}
+	if len(report.Chains) != 1 || report.Chains[0] != diverging {
+		t.Errorf("only the diverging chain's roots differ, not %x", report.Chains)
+	}
+
+	report, err = Diff(a.Reader(), a.Reader())
+	if err != nil || report.Diverged || report.BlocksA != 7 {
+		t.Errorf("an accumulator shouldn't diverge from itself (%v)", err)
+	}
+}
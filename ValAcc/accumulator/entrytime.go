@@ -0,0 +1,63 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// skewed
+// With MaxTimeSkew set, whether the entry carries a submitter's TimeStamp too far from the Clock
+func (a *Accumulator) skewed(entry node.EntryHash) bool {
+	if a.MaxTimeSkew <= 0 || entry.TimeStamp == 0 {
+		return false
+	}
+	skew := time.Duration(int64(entry.TimeStamp) - a.clock().Now().UnixNano())
+	return skew > a.MaxTimeSkew || -skew > a.MaxTimeSkew
+}
+
+// timeEntry
+// Note the time of an entry added to the current block: the submitter's, or else the Clock's
+func (a *Accumulator) timeEntry(entry node.EntryHash) {
+	if a.entryTimes == nil {
+		a.entryTimes = make(map[types.Hash]types.TimeStamp)
+	}
+	timeStamp := entry.TimeStamp
+	if timeStamp == 0 {
+		timeStamp = a.now()
+	}
+	a.entryTimes[entry.EntryHash] = timeStamp
+}
+
+// writeEntryTimeStamps
+// Write the time of each entry the chain added in this block.  An entry added before EntryTimeStamps was set
+// gets the chain node's TimeStamp.
+func (a *Accumulator) writeEntryTimeStamps(db *database.DB, chain *ChainAcc) {
+	for _, h := range chain.Node.EntryList {
+		timeStamp, ok := a.entryTimes[h]
+		if !ok {
+			timeStamp = chain.Node.TimeStamp
+		}
+		db.Put(types.EntryTimeStamp, h[:], timeStamp.Bytes())
+	}
+}
+
+// EntryTimeStamp
+// The time of an entry sealed with EntryTimeStamps set, the submitter's if it gave one.  Returns false if
+// the entry has no time recorded.
+func (r *Reader) EntryTimeStamp(entry types.Hash) (types.TimeStamp, bool, error) {
+	data := r.DB.Get(types.EntryTimeStamp, entry[:])
+	if data == nil {
+		return 0, false, nil
+	}
+	if len(data) != 8 {
+		return 0, false, errors.New(fmt.Sprintf("entry timestamp should be 8 bytes, found %d", len(data)))
+	}
+	var timeStamp types.TimeStamp
+	timeStamp.Extract(data)
+	return timeStamp, true, nil
+}
@@ -0,0 +1,47 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestEntryTimeStamps(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	acc.EntryTimeStamps = true
+	acc.MaxTimeSkew = time.Minute
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("timed by the submitter")))
+
+	inRange := GetTestEntry(chainID, 1)
+	inRange.TimeStamp = types.TimeStamp(clock.now.Add(-30 * time.Second).UnixNano())
+	skewed := GetTestEntry(chainID, 2)
+	skewed.TimeStamp = types.TimeStamp(clock.now.Add(2 * time.Minute).UnixNano())
+	untimed := GetTestEntry(chainID, 3)
+	if !acc.Submit(inRange) || acc.Submit(skewed) || !acc.Submit(untimed) {
+		t.Fatal("only the entry with a submitter's time too far from the clock should be rejected")
+	}
+	if fmt.Sprint(rejected) != "[time skew]" {
+		t.Errorf("the skewed entry should be rejected as TimeSkew, not %v", rejected)
+	}
+	runUntilIdle(acc)
+	acc.sealBlock()
+
+	r := acc.Reader()
+	if ts, ok, err := r.EntryTimeStamp(inRange.EntryHash); err != nil || !ok || ts != inRange.TimeStamp {
+		t.Errorf("the submitter's time should be recorded, not %d (%v)", ts, err)
+	}
+	if ts, ok, err := r.EntryTimeStamp(untimed.EntryHash); err != nil || !ok || ts != types.TimeStamp(clock.now.UnixNano()) {
+		t.Errorf("an entry without a submitter's time should get the clock's, not %d (%v)", ts, err)
+	}
+	if _, ok, _ := r.EntryTimeStamp(skewed.EntryHash); ok {
+		t.Error("the skewed entry shouldn't be recorded")
+	}
+}
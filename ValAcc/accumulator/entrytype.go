@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// EntryTypeCountKey
+// Key of the count of each type of entry a chain has in a block
+func EntryTypeCountKey(chainID types.Hash, height types.BlockHeight) (key []byte) {
+	key = append(key, chainID.Bytes()...)
+	key = append(key, height.Bytes()...)
+	return key
+}
+
+// writeEntryTypeCounts
+// Count the entries of each type (by node.EntryType) the chain has in this block, and write the counts as
+// a type byte and a uint32 count for each type present, in type order.
+func (a *Accumulator) writeEntryTypeCounts(db *database.DB, chain *ChainAcc) {
+	counts := make(map[byte]uint32)
+	for _, h := range chain.Node.EntryList {
+		counts[node.EntryType(node.EntryHash{EntryHash: h})]++
+	}
+	var entryTypes []byte
+	for entryType := range counts {
+		entryTypes = append(entryTypes, entryType)
+	}
+	sort.Slice(entryTypes, func(i, j int) bool { return entryTypes[i] < entryTypes[j] })
+	var value []byte
+	for _, entryType := range entryTypes {
+		value = append(value, entryType)
+		value = append(value, types.Uint32Bytes(counts[entryType])...)
+	}
+	db.Put(types.EntryTypeCount, EntryTypeCountKey(chain.Node.ChainID, a.height), value)
+}
+
+// GetEntryTypeCounts
+// Return how many entries of each type the chain has in the block at the given height.  Only blocks
+// sealed with CountEntryTypes set have the counts.
+func (r *Reader) GetEntryTypeCounts(chainID types.Hash, height types.BlockHeight) (map[byte]uint32, error) {
+	data := r.DB.Get(types.EntryTypeCount, EntryTypeCountKey(chainID, height))
+	if data == nil {
+		return nil, errors.New(fmt.Sprintf("no entry type counts for chain %x at height %d", chainID, height))
+	}
+	if len(data)%5 != 0 {
+		return nil, errors.New(fmt.Sprintf("entry type counts should be 5 bytes a type, found %d bytes", len(data)))
+	}
+	counts := make(map[byte]uint32)
+	for len(data) > 0 {
+		entryType := data[0]
+		counts[entryType], data = types.BytesUint32(data[1:])
+	}
+	return counts, nil
+}
@@ -0,0 +1,54 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestEntryTypes(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.CountEntryTypes = true
+	acc.AcceptEntryType = func(entryType byte) bool { return entryType != 9 }
+	filtered := 0
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) {
+		if reason == FilteredType {
+			filtered++
+		}
+	}
+	chainID := types.Hash(sha256.Sum256([]byte("typed")))
+	expected := map[byte]uint32{}
+	for i := 0; i < 30; i++ {
+		entry := GetTestEntry(chainID, i)
+		entryType := byte(i % 4)
+		if i%10 == 0 {
+			entryType = 9
+		}
+		entry.EntryHash = node.TagEntryHash(entryType, entry.EntryHash)
+		if node.EntryType(entry) != entryType {
+			t.Fatal("the tag should be read back as the entry's type")
+		}
+		if acc.Submit(entry) {
+			expected[entryType]++
+		}
+	}
+	runUntilIdle(acc)
+	block := acc.sealBlock()
+	if filtered != 3 {
+		t.Errorf("expected the 3 entries of type 9 to be filtered, got %d", filtered)
+	}
+	counts, err := acc.Reader().GetEntryTypeCounts(chainID, block.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != len(expected) {
+		t.Errorf("expected %d types, got %d", len(expected), len(counts))
+	}
+	for entryType, count := range expected {
+		if counts[entryType] != count {
+			t.Errorf("expected %d entries of type %d, got %d", count, entryType, counts[entryType])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package accumulator
+
+import (
+	"time"
+)
+
+// ScheduledSealing
+// Have Run seal a block at every boundary of the given period, the times Clock().Now().Truncate(period)
+// comes to (on the minute, for a period of a minute), whether or not the block has entries.  With catchUp,
+// boundaries missed (while the process was down, or while paused) are made up for with empty blocks, so the
+// height keeps pace with the schedule: as Run starts, every boundary since the last block was sealed gets a
+// block.  Without it, the schedule picks up from the next boundary.  Nothing is sealed while paused.  Call
+// before Run; a period of zero turns scheduled sealing off.
+func (a *Accumulator) ScheduledSealing(period time.Duration, catchUp bool) {
+	a.epoch, a.catchUp, a.nextEpoch = period, catchUp, time.Time{}
+}
+
+// epochDue
+// Seal a block for each boundary of the ScheduledSealing period that has passed.  Returns true if any had,
+// whether or not the blocks were sealed.
+func (a *Accumulator) epochDue() bool {
+	if a.epoch <= 0 {
+		return false
+	}
+	now := a.clock().Now()
+	if a.nextEpoch.IsZero() {
+		a.nextEpoch = now.Truncate(a.epoch).Add(a.epoch)
+		if a.catchUp && a.previous != nil { // Pick up from the boundary after the last block was sealed
+			sealed := time.Unix(0, int64(a.previous.TimeStamp))
+			a.nextEpoch = sealed.Truncate(a.epoch).Add(a.epoch)
+		}
+	}
+	if now.Before(a.nextEpoch) || a.paused.Load() {
+		return false
+	}
+	blocks := 1
+	if a.catchUp {
+		blocks += int(now.Sub(a.nextEpoch) / a.epoch)
+	}
+	for ; blocks > 0; blocks-- {
+		if a.SealBlock() == nil { // Try again the next time through
+			return true
+		}
+		a.nextEpoch = a.nextEpoch.Add(a.epoch)
+	}
+	a.nextEpoch = now.Truncate(a.epoch).Add(a.epoch)
+	return true
+}
@@ -0,0 +1,58 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestScheduledSealing(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 30, 0, time.UTC)
+	for _, catchUp := range []bool{false, true} {
+		acc := GetTestAccumulator(t)
+		clock := &testClock{now: start}
+		acc.Clock = clock
+		acc.ScheduledSealing(time.Minute, catchUp)
+		chainID := types.Hash(sha256.Sum256([]byte("scheduled")))
+		acc.Submit(GetTestEntry(chainID, 1))
+		runUntilIdle(acc)
+		if acc.epochDue() || acc.height != 0 {
+			t.Fatal("no block should be sealed before the first boundary")
+		}
+
+		clock.now = start.Add(2 * time.Minute) // Across the boundaries at 10:01 and 10:02
+		acc.epochDue()
+		expected := types.BlockHeight(1)
+		if catchUp {
+			expected = 2
+		}
+		if acc.height != expected {
+			t.Errorf("catchUp %v: crossing two boundaries sealed %d blocks, not %d", catchUp, acc.height, expected)
+		}
+		if count, _ := acc.Reader().GetBlockEntryCount(0); count != 1 {
+			t.Errorf("catchUp %v: the first block has %d entries, not the one submitted", catchUp, count)
+		}
+
+		clock.now = start.Add(2*time.Minute + 20*time.Second) // Still before 10:03
+		if acc.epochDue() {
+			t.Errorf("catchUp %v: a block was sealed between boundaries", catchUp)
+		}
+	}
+
+	// An accumulator restarted after being down across three boundaries makes up for them
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: start}
+	acc.Clock = clock
+	acc.SealBlock() // Sealed at 10:00:30
+	restarted := new(Accumulator)
+	restarted.Clock = clock
+	restarted.Init(acc.DB, acc.chainID)
+	restarted.ScheduledSealing(time.Minute, true)
+	clock.now = start.Add(3*time.Minute + 10*time.Second) // Past 10:01, 10:02 and 10:03
+	restarted.epochDue()
+	if restarted.height != 4 {
+		t.Errorf("restarting across three boundaries should catch up to height 4, not %d", restarted.height)
+	}
+}
@@ -0,0 +1,118 @@
+package accumulator
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ExportFormat
+// How ExportEntries writes its rows
+type ExportFormat int
+
+const (
+	ExportCSV    ExportFormat = iota // A header line, then height,chain_id,entry_hash,sequence; hashes in hex
+	ExportBinary                     // Each row the height (4 bytes), ChainID, entry hash, then sequence (8 bytes)
+)
+
+// NoSequence
+// The sequence ExportBinary writes for an entry with none (one sealed without EntrySequences).  ExportCSV
+// leaves the field empty.
+const NoSequence = ^uint64(0)
+
+// ExportEntries
+// Write a row for every entry sealed from fromHeight to toHeight (inclusive): its height, ChainID, entry hash
+// and sequence in its chain.  Rows come in height order, then in the order of the chains in the directory
+// block, then in the order the entries were added to the chain.  Each chain's node is found by walking back
+// from its head only the first time the chain turns up; after that the NodeNext index leads from one of its
+// nodes to the next, so a range is exported without decoding anything but the directory blocks and the
+// chain nodes holding the entries.
+func (r *Reader) ExportEntries(w io.Writer, format ExportFormat, fromHeight, toHeight types.BlockHeight) error {
+	if toHeight < fromHeight {
+		return errors.New(fmt.Sprintf("can't export from height %d down to %d", fromHeight, toHeight))
+	}
+	var write func(height types.BlockHeight, chainID, entry types.Hash, seq uint64, ok bool) error
+	var flush func() error
+	switch format {
+	case ExportCSV:
+		out := csv.NewWriter(w)
+		if err := out.Write([]string{"height", "chain_id", "entry_hash", "sequence"}); err != nil {
+			return err
+		}
+		write = func(height types.BlockHeight, chainID, entry types.Hash, seq uint64, ok bool) error {
+			sequence := ""
+			if ok {
+				sequence = strconv.FormatUint(seq, 10)
+			}
+			return out.Write([]string{strconv.FormatUint(uint64(height), 10), hex.EncodeToString(chainID[:]),
+				hex.EncodeToString(entry[:]), sequence})
+		}
+		flush = func() error {
+			out.Flush()
+			return out.Error()
+		}
+	case ExportBinary:
+		write = func(height types.BlockHeight, chainID, entry types.Hash, seq uint64, ok bool) error {
+			if !ok {
+				seq = NoSequence
+			}
+			row := append(height.Bytes(), chainID[:]...)
+			row = append(row, entry[:]...)
+			_, err := w.Write(append(row, types.Uint64Bytes(seq)...))
+			return err
+		}
+		flush = func() error { return nil }
+	default:
+		return errors.New(fmt.Sprintf("unknown export format %d", format))
+	}
+
+	last := make(map[types.Hash][]byte) // Hash of the last node exported for each chain
+	for height := fromHeight; height <= toHeight; height++ {
+		directoryBlock, err := r.GetDirectoryBlock(height)
+		if err != nil {
+			return err
+		}
+		chains, err := r.blockChains(directoryBlock)
+		if err != nil {
+			return err
+		}
+		for _, ne := range chains {
+			hash, chainNode, err := r.nextChainNode(ne.ChainID, height, last[ne.ChainID])
+			if err != nil {
+				return err
+			}
+			last[ne.ChainID] = hash
+			chainNode.LoadEntryList()
+			for _, entry := range chainNode.EntryList {
+				seq, ok := r.GetEntrySequence(ne.ChainID, entry)
+				if err := write(height, ne.ChainID, entry, seq, ok); err != nil {
+					return err
+				}
+			}
+		}
+		if height == toHeight { // Don't wrap around at the top of the heights
+			break
+		}
+	}
+	return flush()
+}
+
+// nextChainNode
+// The hash and header of the chain's node at the height, which follows the node with hash previous if we
+// have one
+func (r *Reader) nextChainNode(chainID types.Hash, height types.BlockHeight, previous []byte) ([]byte, *node.Node, error) {
+	if previous != nil {
+		if hash := r.DB.Get(types.NodeNext, previous); hash != nil {
+			if n, err := r.GetNodeHeader(hash); err == nil && n.BHeight == height {
+				return hash, n, nil
+			}
+		}
+	}
+	return r.chainNodeAt(chainID, height)
+}
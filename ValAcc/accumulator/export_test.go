@@ -0,0 +1,77 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestExportEntries(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.EntrySequences = true
+	chainA := types.Hash(sha256.Sum256([]byte("export a")))
+	chainB := types.Hash(sha256.Sum256([]byte("export b")))
+	inRange := 0
+	for height := 0; height < 5; height++ {
+		for i := 0; i < 3+height; i++ {
+			chainID := chainA
+			if i%2 == 1 && height != 2 { // Chain B sits out the block at height 2
+				chainID = chainB
+			}
+			acc.Submit(GetTestEntry(chainID, height*100+i))
+			if height >= 1 && height <= 3 {
+				inRange++
+			}
+		}
+		runUntilIdle(acc)
+		acc.SealBlock()
+	}
+
+	var out bytes.Buffer
+	if err := acc.Reader().ExportEntries(&out, ExportCSV, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != inRange+1 {
+		t.Fatalf("exported %d rows, not a header and %d entries", len(rows), inRange)
+	}
+	if strings.Join(rows[0], ",") != "height,chain_id,entry_hash,sequence" {
+		t.Errorf("the header is %v", rows[0])
+	}
+	lastHeight := 1
+	nextSeq := map[string]int{}
+	for _, row := range rows[1:] {
+		height, _ := strconv.Atoi(row[0])
+		if height < lastHeight || height > 3 {
+			t.Fatalf("a row at height %d follows one at %d", height, lastHeight)
+		}
+		lastHeight = height
+		seq, err := strconv.Atoi(row[3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, seen := nextSeq[row[1]]; seen && seq != expected {
+			t.Fatalf("chain %s has the sequence %d after %d", row[1][:8], seq, expected-1)
+		}
+		nextSeq[row[1]] = seq + 1
+	}
+
+	out.Reset()
+	if err := acc.Reader().ExportEntries(&out, ExportBinary, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != inRange*(4+32+32+8) {
+		t.Errorf("exported %d bytes, not a row for each of %d entries", out.Len(), inRange)
+	}
+	if err := acc.Reader().ExportEntries(&out, ExportCSV, 3, 9); err == nil {
+		t.Error("exporting heights that haven't been sealed should fail")
+	}
+}
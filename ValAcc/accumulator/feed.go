@@ -0,0 +1,83 @@
+package accumulator
+
+import (
+	"sync"
+)
+
+// FeedStats
+// How full the entryFeed is and has been, and what the accumulator has dropped for want of room on its feeds
+type FeedStats struct {
+	Depth         int // Entries waiting in the entryFeed now
+	Capacity      int // Entries the entryFeed can hold
+	HighWater     int // Most entries waiting at once since the current block was opened
+	LastHighWater int // Most entries waiting at once while the last block sealed was open
+
+	FeedFull          int64 // Entries refused as FeedFull, with DropWhenFeedFull
+	MDRootsDropped    int64 // MD roots dropped with no reader on the mdFeed (with DropOnNoReader)
+	ChainRootsDropped int64 // Chain roots dropped with no reader on the chainRootFeed (with DropOnNoReader)
+}
+
+// feedStats
+// What FeedStats reports, kept as entries are submitted and blocks sealed
+type feedStats struct {
+	mux                           sync.Mutex
+	highWater, lastHighWater      int
+	feedFull, mdRoots, chainRoots int64
+}
+
+// FeedLen
+// The entries waiting in the entryFeed for Run to take them
+func (a *Accumulator) FeedLen() int {
+	return len(a.entryFeed)
+}
+
+// FeedStats
+// Return how full the entryFeed is, the high water marks of this block and the last, and the drops so far.
+// The depth is sampled as entries are submitted, so the high water marks miss entries sent straight to the
+// entryFeed rather than through Submit.  Safe to call from any go routine.
+func (a *Accumulator) FeedStats() FeedStats {
+	a.feed.mux.Lock()
+	defer a.feed.mux.Unlock()
+	return FeedStats{
+		Depth:             len(a.entryFeed),
+		Capacity:          cap(a.entryFeed),
+		HighWater:         a.feed.highWater,
+		LastHighWater:     a.feed.lastHighWater,
+		FeedFull:          a.feed.feedFull,
+		MDRootsDropped:    a.feed.mdRoots,
+		ChainRootsDropped: a.feed.chainRoots,
+	}
+}
+
+// feedSent
+// Note the depth of the entryFeed once an entry has been put on it
+func (a *Accumulator) feedSent() {
+	depth := len(a.entryFeed)
+	a.feed.mux.Lock()
+	defer a.feed.mux.Unlock()
+	if depth > a.feed.highWater {
+		a.feed.highWater = depth
+	}
+}
+
+// feedSealed
+// Keep the block's high water mark as the last block's, and start the next block's from what is waiting now
+func (a *Accumulator) feedSealed() {
+	a.feed.mux.Lock()
+	last := a.feed.lastHighWater
+	a.feed.lastHighWater, a.feed.highWater = a.feed.highWater, len(a.entryFeed)
+	change := a.feed.lastHighWater - last
+	a.feed.mux.Unlock()
+	if change != 0 {
+		a.metrics().Add(MetricFeedHighWater, int64(change))
+	}
+}
+
+// feedDropped
+// Count what was dropped from a feed, in FeedStats and the Metrics
+func (a *Accumulator) feedDropped(count *int64, metric string, n int64) {
+	a.feed.mux.Lock()
+	*count += n
+	a.feed.mux.Unlock()
+	a.metrics().Add(metric, n)
+}
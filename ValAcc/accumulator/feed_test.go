@@ -0,0 +1,125 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestMDFeedNoReader(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MDFeedPolicy = DropOnNoReader
+	chainID := types.Hash(sha256.Sum256([]byte("no reader")))
+
+	// Nobody reads the mdFeed, which has room for one root.  Block production must carry on past that.
+	for i := 0; i < 4; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+		acc.endBlock()
+	}
+	if acc.height != 4 {
+		t.Errorf("expected to have produced 4 blocks, but the next height is %d", acc.height)
+	}
+	if len(acc.mdFeed) != 1 {
+		t.Errorf("expected the first root to be waiting in the mdFeed, found %d", len(acc.mdFeed))
+	}
+}
+
+func TestChainRootFeed(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	feed := acc.ChainRootFeed()
+	var expected []node.NEList
+	for b := 0; b < 3; b++ {
+		for c := 0; c < 5; c++ {
+			chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("fed %d", c))))
+			acc.addEntry(GetTestEntry(chainID, b))
+		}
+		expected = append(expected, acc.endBlock().List...)
+	}
+	if len(feed) != len(expected) {
+		t.Fatalf("expected %d chain roots on the feed, found %d", len(expected), len(feed))
+	}
+	for _, ne := range expected {
+		if got := <-feed; got.ChainID != ne.ChainID || got.MDRoot != ne.MDRoot {
+			t.Errorf("expected the root %x of chain %x, got %x of %x", ne.MDRoot, ne.ChainID, got.MDRoot, got.ChainID)
+		}
+	}
+
+	// Nobody reading a full feed doesn't hold up sealing
+	acc.chainRootFeed = make(chan node.NEList, 2)
+	for c := 0; c < 5; c++ {
+		acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte(fmt.Sprintf("unread %d", c)))), 0))
+	}
+	acc.endBlock()
+	if acc.height != 4 || len(acc.chainRootFeed) != 2 {
+		t.Errorf("the block should be sealed with the extra chain roots dropped, the next height is %d", acc.height)
+	}
+}
+
+func TestFeedStats(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.DropWhenFeedFull = true
+	metrics := countingMetrics{}
+	acc.Metrics = metrics
+	var refused int
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) {
+		if reason == FeedFull {
+			refused++
+		}
+	}
+	chainID := types.Hash(sha256.Sum256([]byte("burst")))
+	capacity := cap(acc.entryFeed)
+	for i := 0; i < capacity+5; i++ { // Nobody is taking entries off the feed, so the last 5 find it full
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	stats := acc.FeedStats()
+	if stats.Depth != capacity || stats.Capacity != capacity || stats.HighWater != capacity || acc.FeedLen() != capacity {
+		t.Errorf("the feed should be full, and so its high water mark: %+v", stats)
+	}
+	if refused != 5 || stats.FeedFull != 5 || metrics[MetricFeedFull] != 5 {
+		t.Errorf("expected 5 entries refused as FeedFull, got %d (%+v, %d counted)", refused, stats, metrics[MetricFeedFull])
+	}
+
+	runUntilIdle(acc)
+	acc.endBlock() // The mdFeed holds one root, which nobody reads
+	acc.Submit(GetTestEntry(chainID, capacity+5))
+	runUntilIdle(acc)
+	acc.endBlock()
+	stats = acc.FeedStats()
+	if stats.LastHighWater != 1 || stats.HighWater != 0 || metrics[MetricFeedHighWater] != 1 {
+		t.Errorf("the high water mark should start again with each block, got %+v (gauge %d)", stats, metrics[MetricFeedHighWater])
+	}
+	if stats.MDRootsDropped != 1 || metrics[MetricMDRootsDropped] != 1 {
+		t.Errorf("the second MD root should be dropped with no reader, got %+v", stats)
+	}
+}
+
+func TestFeedFullRefunds(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.DropWhenFeedFull = true
+	acc.Clock = &testClock{now: time.Unix(1000, 0)} // No tokens are earned back meanwhile
+	capacity := cap(acc.entryFeed)
+	acc.MaxEntriesPerChainPerSecond = capacity + 1
+	acc.TenantResolver = func(chainID types.Hash) string { return "tenant" }
+	acc.TenantQuotas = map[string]int{"tenant": capacity + 1}
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("refunded")))
+	for i := 0; i < capacity+5; i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	if len(rejected) != 5 || rejected[0] != FeedFull {
+		t.Fatalf("expected 5 entries refused as FeedFull, got %v", rejected)
+	}
+	runUntilIdle(acc)
+	rejected = nil
+	if err := acc.SubmitErr(GetTestEntry(chainID, capacity+5)); err != nil {
+		t.Errorf("entries refused as FeedFull shouldn't use up the quota or the rate, got %v (%v)", err, rejected)
+	}
+	if acc.Submit(GetTestEntry(chainID, capacity+6)) || len(rejected) != 1 {
+		t.Errorf("the quota should be used up by the entries queued, got %v", rejected)
+	}
+}
@@ -0,0 +1,38 @@
+package accumulator
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// FileAnchorer
+// An Anchorer that appends each root to a file, one line per block, for development and testing where
+// there is no external chain to anchor to.  The TxID of a record is the offset of its line in the file.
+type FileAnchorer struct {
+	Path string // File the anchors are appended to
+}
+
+// Anchor
+// Append a line with the height and root to the file
+func (f *FileAnchorer) Anchor(height types.BlockHeight, root types.Hash) (*AnchorRecord, error) {
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(file, "%d %x\n", height, root); err != nil {
+		return nil, err
+	}
+	record := new(AnchorRecord)
+	record.Height = height
+	record.Root = root
+	record.Network = "file:" + f.Path
+	record.TxID = types.Uint64Bytes(uint64(info.Size()))
+	return record, nil
+}
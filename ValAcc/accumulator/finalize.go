@@ -0,0 +1,85 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// FinalizedHeight
+// The height of the last block to become final, i.e. with FinalizationDepth blocks sealed on top of it.
+// Zero until the block at height zero is final as well.  May be called from any go routine.
+func (a *Accumulator) FinalizedHeight() types.BlockHeight {
+	if final := a.finalized.Load(); final > 0 {
+		return types.BlockHeight(final - 1)
+	}
+	return 0
+}
+
+// finalize
+// Make final every block the block just committed has buried FinalizationDepth deep, telling OnFinal and
+// then the Anchorer of each in order.  Normally that is just the one block, but it can be several if the
+// FinalizationDepth has been lowered across a restart.
+func (a *Accumulator) finalize(directoryBlock *node.Node) {
+	for next := types.BlockHeight(a.finalized.Load()); next+a.FinalizationDepth <= directoryBlock.BHeight; next++ {
+		a.finalized.Store(int64(next) + 1)
+		block := directoryBlock
+		if next != directoryBlock.BHeight {
+			var err error
+			if block, err = a.Reader().GetDirectoryBlock(next); err != nil {
+				a.logger().Printf("failed to load the block at height %d to make it final: %v", next, err)
+				continue
+			}
+		}
+		a.final(block)
+		a.anchor(block)
+	}
+}
+
+// writeFinalized
+// Write the lowest height that won't yet be final once the block being sealed is committed into its batch,
+// so a restart carries on making blocks final from there whatever the FinalizationDepth is by then.
+func (a *Accumulator) writeFinalized(db *database.DB) {
+	next := types.BlockHeight(a.finalized.Load())
+	if a.height+1 > a.FinalizationDepth && a.height+1-a.FinalizationDepth > next {
+		next = a.height + 1 - a.FinalizationDepth
+	}
+	db.Put(types.FinalizedHeight, a.chainID[:], next.Bytes())
+}
+
+// FinalizedCount
+// The count of blocks that have been made final, i.e. the lowest height not yet final.  Returns false if the
+// database doesn't say, as it was built by a version of ValAcc that didn't record it.
+func (r *Reader) FinalizedCount() (types.BlockHeight, bool, error) {
+	data := r.DB.Get(types.FinalizedHeight, r.ChainID[:])
+	if data == nil {
+		return 0, false, nil
+	}
+	if len(data) != 4 {
+		return 0, false, errors.New(fmt.Sprintf("the finalized height should be 4 bytes, found %d", len(data)))
+	}
+	var next types.BlockHeight
+	next.Extract(data)
+	return next, true, nil
+}
+
+// final
+// Tell OnFinal about a block that has become final.  Like OnCommit, a panic in OnFinal is logged rather than
+// holding up the blocks after it.
+func (a *Accumulator) final(directoryBlock *node.Node) {
+	if a.OnFinal == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger().Printf("recovered from a panic in OnFinal for the block at height %d: %v\n%s",
+				directoryBlock.BHeight, r, debug.Stack())
+			a.metrics().Add(MetricPanics, 1)
+		}
+	}()
+	a.OnFinal(directoryBlock)
+}
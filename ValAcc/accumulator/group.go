@@ -0,0 +1,115 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// GroupChainID
+// The ChainID of the entry in the List of a Grouped directory block that stands for the chains with a single
+// entry in the block.  Its MDRoot is the root of the MD over the roots of those chains, in ChainID order.
+var GroupChainID = types.Hash(sha256.Sum256([]byte("ValAcc group of single entry chains")))
+
+// groupChains
+// Split the roots of the block's chains (given in ChainID order, as are the chains) into the List of the
+// directory block and the group.  Without the Grouped flag, every chain is in the List and there is no group.
+// With it, the List holds the chains with more than one entry in the block, then (if there are any) an entry
+// for the group of those with one, so a block of many small chains has a far shorter List.
+func (a *Accumulator) groupChains(chains []*ChainAcc, chainEntries []node.NEList) (list, group []node.NEList) {
+	if !a.BlockFlags.Has(node.Grouped) {
+		return chainEntries, nil
+	}
+	for i, ne := range chainEntries {
+		if len(chains[i].MD.HashList)-chains[i].Carried == 1 {
+			group = append(group, ne)
+		} else {
+			list = append(list, ne)
+		}
+	}
+	if len(group) > 0 {
+		list = append(list, node.NEList{ChainID: GroupChainID, MDRoot: *a.directoryMD(group).GetMDRoot()})
+	}
+	return list, group
+}
+
+// writeGroup
+// Record the chains grouped in the block, for the Reader to find them
+func (a *Accumulator) writeGroup(db *database.DB, group []node.NEList) {
+	if len(group) == 0 {
+		return
+	}
+	var data []byte
+	for _, ne := range group {
+		data = append(data, ne.ChainID.Bytes()...)
+		data = append(data, ne.MDRoot.Bytes()...)
+	}
+	db.PutInt32(types.BlockGroup, int(a.height), data)
+}
+
+// blockGroup
+// The roots of the chains grouped in a Grouped directory block, checked against the group's entry in its List.
+// Returns nil for a block with no group.
+func (r *Reader) blockGroup(directoryBlock *node.Node) ([]node.NEList, error) {
+	if !directoryBlock.Flags.Has(node.Grouped) {
+		return nil, nil
+	}
+	var entry *node.NEList
+	for i := range directoryBlock.List {
+		if directoryBlock.List[i].ChainID == GroupChainID {
+			entry = &directoryBlock.List[i]
+		}
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	data := r.DB.GetInt32(types.BlockGroup, uint32(directoryBlock.BHeight))
+	if len(data) == 0 || len(data)%64 != 0 {
+		return nil, errors.New(fmt.Sprintf("the group of the directory block at height %d has %d bytes",
+			directoryBlock.BHeight, len(data)))
+	}
+	var group []node.NEList
+	md := r.forFlags(directoryBlock.Flags).newMD()
+	for ; len(data) > 0; data = data[64:] {
+		var ne node.NEList
+		ne.MDRoot.Extract(ne.ChainID.Extract(data))
+		group = append(group, ne)
+		md.AddToChain(ne.MDRoot)
+	}
+	if root := *md.GetMDRoot(); root != entry.MDRoot {
+		return nil, errors.New(fmt.Sprintf("the group of the directory block at height %d has the root %x, not %x",
+			directoryBlock.BHeight, root, entry.MDRoot))
+	}
+	return group, nil
+}
+
+// blockChains
+// The root of every chain in the directory block: its List, with the chains of a Grouped block's group in
+// place of the group's entry
+func (r *Reader) blockChains(directoryBlock *node.Node) ([]node.NEList, error) {
+	group, err := r.blockGroup(directoryBlock)
+	if err != nil || group == nil {
+		return directoryBlock.List, err
+	}
+	var chains []node.NEList
+	for _, ne := range directoryBlock.List {
+		if ne.ChainID != GroupChainID {
+			chains = append(chains, ne)
+		}
+	}
+	return append(chains, group...), nil
+}
+
+// joinPaths
+// The path from the start of first to the root of second, which starts at the root of first: a chain's path
+// up its group, then up the directory block
+func joinPaths(first, second *merkleDag.MDReceipt) *merkleDag.MDReceipt {
+	joined := &merkleDag.MDReceipt{EntryHash: first.EntryHash, MDRoot: second.MDRoot, Hasher: second.Hasher}
+	joined.Nodes = append(append(joined.Nodes, first.Nodes...), second.Nodes...)
+	return joined
+}
@@ -0,0 +1,74 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestGrouping(t *testing.T) {
+	seal := func(grouped, precompute bool) (*Accumulator, *node.Node) {
+		acc := GetTestAccumulator(t)
+		acc.Clock = &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+		acc.PrecomputeReceipts = precompute
+		if grouped {
+			acc.BlockFlags = node.Grouped
+		}
+		for i := 0; i < 20; i++ { // Twenty chains of one entry, and two of five
+			acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte(fmt.Sprintf("single %d", i)))), i))
+		}
+		for i := 0; i < 5; i++ {
+			acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("many 1"))), i))
+			acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("many 2"))), i))
+		}
+		peeked, err := acc.PeekRoot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := acc.sealBlock()
+		if block.ListMDRoot != peeked {
+			t.Errorf("peeked the root %x but sealed %x", peeked, block.ListMDRoot)
+		}
+		return acc, block
+	}
+
+	_, plain := seal(false, false)
+	for _, precompute := range []bool{false, true} {
+		acc, block := seal(true, precompute)
+		if len(block.List) != 3 || len(plain.List) != 22 {
+			t.Errorf("the grouped block should list 3 entries and the plain one 22, not %d and %d",
+				len(block.List), len(plain.List))
+		}
+		if err := acc.Reader().VerifyDirectoryBlock(block); err != nil {
+			t.Error(err)
+		}
+		for _, name := range []string{"single 7", "many 2"} {
+			chainID := types.Hash(sha256.Sum256([]byte(name)))
+			entry := GetTestEntry(chainID, 4)
+			if name == "single 7" {
+				entry = GetTestEntry(chainID, 7)
+			}
+			receipt, err := acc.Reader().GetReceipt(chainID, entry.EntryHash, block.BHeight)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !receipt.VerifyAgainstRoot(block.ListMDRoot) {
+				t.Errorf("the receipt for %s should verify against the directory block (precomputed %v)", name, precompute)
+			}
+		}
+		_, again := seal(true, precompute)
+		if *again.GetHash() != *block.GetHash() {
+			t.Error("the same entries should give the same grouped block")
+		}
+	}
+
+	acc, block := seal(true, false)
+	acc.DB.Delete(types.BlockGroup, types.Uint32Bytes(uint32(block.BHeight)))
+	if err := acc.Reader().VerifyDirectoryBlock(block); err == nil {
+		t.Error("a grouped block whose group is missing shouldn't verify")
+	}
+}
@@ -0,0 +1,105 @@
+package accumulator
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// HeadInconsistent
+// What SelfCheck returns, and Init panics with unless RepairOnInit is set, when the head of the directory
+// blocks isn't the highest block in the height index
+type HeadInconsistent struct {
+	Detail string // How the two disagree
+}
+
+func (e *HeadInconsistent) Error() string {
+	return "the head of the directory blocks is not the highest block sealed; " + e.Detail
+}
+
+// SelfCheck
+// Check the head of the directory blocks is the highest block in the height index.  They can only disagree
+// if we crashed part way through writing a block to a store that can't write a batch all at once.  Returns
+// nil if they agree, or a *HeadInconsistent.  Don't call it while Run is running.
+func (a *Accumulator) SelfCheck() error {
+	_, err := a.Reader().CheckHead()
+	return err
+}
+
+// repairHead
+// Point the head of the directory blocks at the highest block that can be read back, and say where it went
+func (a *Accumulator) repairHead() error {
+	highest, err := a.Reader().CheckHead()
+	if _, inconsistent := err.(*HeadInconsistent); !inconsistent {
+		return err
+	}
+	if highest == nil {
+		err = a.DB.Delete(types.NodeHead, a.chainID[:])
+	} else {
+		err = a.DB.Put(types.NodeHead, a.chainID[:], highest.GetHash()[:])
+	}
+	if err != nil {
+		return err
+	}
+	if highest == nil {
+		a.logger().Printf("repaired the head of the directory blocks, which now has no blocks")
+	} else {
+		a.logger().Printf("repaired the head of the directory blocks to the block at height %d", highest.BHeight)
+	}
+	return nil
+}
+
+// CheckHead
+// Find the highest directory block in the height index that can be read back, and check the head points at
+// it.  Returns the block (or nil if there is none), and a *HeadInconsistent if the head
+// points anywhere else.  Heights are probed up and down from the head's, so this reads only a few blocks.
+func (r *Reader) CheckHead() (*node.Node, error) {
+	headHash := r.DB.Get(types.NodeHead, r.ChainID[:])
+	var head *node.Node
+	var start types.BlockHeight
+	if headHash != nil {
+		if n, err := r.GetNodeHeader(headHash); err == nil {
+			head, start = n, n.BHeight
+		}
+	}
+
+	indexed := func(height types.BlockHeight) bool {
+		return r.DB.GetInt32(types.DirectoryBlockHeight, uint32(height)) != nil
+	}
+	top, found := start, indexed(start)
+	for found && indexed(top+1) { // Up past the head, if blocks were indexed after it
+		top++
+	}
+	for !found && top > 0 { // Down below it, if the head's block never made it into the index
+		top--
+		found = indexed(top)
+	}
+	var highest *node.Node
+	for found {
+		if n, err := r.GetDirectoryBlock(top); err == nil {
+			highest = n
+			break
+		}
+		if top == 0 {
+			break
+		}
+		top--
+	}
+
+	switch {
+	case headHash == nil && highest == nil:
+		return nil, nil
+	case headHash == nil:
+		return highest, &HeadInconsistent{fmt.Sprintf("no head, but blocks up to height %d", highest.BHeight)}
+	case head == nil:
+		return highest, &HeadInconsistent{fmt.Sprintf("the head %x can't be read", headHash)}
+	case highest == nil:
+		return nil, &HeadInconsistent{fmt.Sprintf("the head is at height %d, but no block is indexed", head.BHeight)}
+	case !bytes.Equal(headHash, highest.GetHash()[:]):
+		return highest, &HeadInconsistent{fmt.Sprintf("the head is at height %d, but the highest block is at %d",
+			head.BHeight, highest.BHeight)}
+	}
+	return highest, nil
+}
@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestHeadConsistency(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("head check")))
+	var blocks []*node.Node
+	for i := 0; i < 3; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+		blocks = append(blocks, acc.SealBlock())
+	}
+	if err := acc.SelfCheck(); err != nil {
+		t.Fatalf("a clean database should check out, got %v", err)
+	}
+
+	restart := func(repair bool) (restarted *Accumulator, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err, _ = r.(error)
+			}
+		}()
+		restarted = new(Accumulator)
+		restarted.Logger = new(recordingLogger)
+		restarted.RepairOnInit = repair
+		restarted.Init(acc.DB, acc.chainID)
+		return restarted, nil
+	}
+
+	// A crash after the last block was indexed, but with the head left on the block before
+	acc.DB.Put(types.NodeHead, acc.chainID[:], blocks[1].GetHash()[:])
+	if _, ok := acc.SelfCheck().(*HeadInconsistent); !ok {
+		t.Errorf("a head behind the height index should be caught, got %v", acc.SelfCheck())
+	}
+	_, err := restart(false)
+	if _, ok := err.(*HeadInconsistent); !ok {
+		t.Errorf("Init should refuse an inconsistent head without RepairOnInit, got %v", err)
+	}
+	restarted, err := restart(true)
+	if err != nil || restarted.height != 3 || acc.SelfCheck() != nil {
+		t.Fatalf("RepairOnInit should move the head up to the last block (%v)", err)
+	}
+
+	// A crash after the head moved to a block whose index entry was never written
+	acc.DB.Delete(types.DirectoryBlockHeight, types.Uint32Bytes(2))
+	if _, ok := acc.SelfCheck().(*HeadInconsistent); !ok {
+		t.Errorf("a head ahead of the height index should be caught, got %v", acc.SelfCheck())
+	}
+	restarted, err = restart(true)
+	if err != nil || restarted.height != 2 || acc.SelfCheck() != nil {
+		t.Fatalf("RepairOnInit should move the head back to the last block indexed (%v)", err)
+	}
+}
@@ -0,0 +1,119 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ErrHeightExhausted
+// Why sealBlock refuses to seal the block at MaxHeight: the height after it can't be represented, and wrapping
+// around to 0 would overwrite the first blocks.  Reported by Health once it has happened.
+var ErrHeightExhausted = errors.New("the accumulator has run out of block heights")
+
+// ErrAlreadySealed
+// Why sealBlock refuses to seal a block at a height that already has a directory block committed, rather
+// than writing a second one over the height index.  Only an accumulator out of step with its database (say
+// two sealing into the same one) gets there, as the height moves on with each block committed.
+var ErrAlreadySealed = errors.New("a directory block has already been committed at this height")
+
+// ErrPrevCorrupt
+// Why sealBlock refuses to seal a block, with VerifyPrevious set, when the block it would follow doesn't read
+// back from the database as the block we hold: its stored bytes are missing or don't hash to it, or the head
+// of the directory blocks points elsewhere.  Sealing on would chain the new block off something no reader
+// can verify.
+var ErrPrevCorrupt = errors.New("the previous directory block is corrupt in the database")
+
+// ErrNotSynced
+// Why sealBlock holds a block back while the current height is short of the RequireSyncHeight
+var ErrNotSynced = errors.New("the accumulator hasn't reached the height it has to sync to")
+
+// maxHeight
+// The height of the block that can't be sealed; the head never goes past the height before it
+func (a *Accumulator) maxHeight() types.BlockHeight {
+	if a.MaxHeight == 0 {
+		return types.MaxBlockHeight
+	}
+	return a.MaxHeight
+}
+
+// checkHeight
+// Returns ErrHeightExhausted, and trips the breaker with it so entries are rejected rather than held for a
+// block that will never be sealed, if the current block is at MaxHeight.  Past the last tenth of the heights,
+// each block is counted as a warning, and the first logged.
+func (a *Accumulator) checkHeight() error {
+	max := a.maxHeight()
+	if a.height >= max {
+		a.healthMux.Lock()
+		defer a.healthMux.Unlock()
+		if !a.unhealthy.Load() {
+			a.logger().Printf("not sealing the block at height %d: %v", a.height, ErrHeightExhausted)
+		}
+		a.health = ErrHeightExhausted
+		a.unhealthy.Store(true)
+		return ErrHeightExhausted
+	}
+	if a.height >= max-max/10 {
+		if !a.heightWarned {
+			a.logger().Printf("the block at height %d is within %d blocks of the last height", a.height, max-a.height)
+			a.heightWarned = true
+		}
+		a.metrics().Add(MetricHeightWarnings, 1)
+	}
+	return nil
+}
+
+// SetSyncHeight
+// Set the RequireSyncHeight, say as the operator learns the network's height.  Lowering it to the current height
+// (or zero) lets sealing resume with the next block ended.  May be called from any go routine.
+func (a *Accumulator) SetSyncHeight(height types.BlockHeight) {
+	a.syncMux.Lock()
+	defer a.syncMux.Unlock()
+	a.RequireSyncHeight = height
+	a.syncLogged = false
+}
+
+// checkSynced
+// Returns ErrNotSynced, logging it once for each RequireSyncHeight, if the current block is below the
+// RequireSyncHeight.  The block is left open, so its entries are sealed once the height is reached.
+func (a *Accumulator) checkSynced() error {
+	a.syncMux.Lock()
+	defer a.syncMux.Unlock()
+	if a.height >= a.RequireSyncHeight {
+		return nil
+	}
+	if !a.syncLogged {
+		a.logger().Printf("not sealing the block at height %d until synced to height %d", a.height, a.RequireSyncHeight)
+		a.syncLogged = true
+	}
+	return ErrNotSynced
+}
+
+// checkSealed
+// Returns ErrAlreadySealed, and logs it, if the database already holds a directory block at the current height
+func (a *Accumulator) checkSealed() error {
+	if a.DB.GetInt32(types.DirectoryBlockHeight, uint32(a.height)) == nil {
+		return nil
+	}
+	a.logger().Printf("not sealing the block at height %d: %v", a.height, ErrAlreadySealed)
+	return ErrAlreadySealed
+}
+
+// checkPrevious
+// With VerifyPrevious set, returns ErrPrevCorrupt, and logs it, unless the previous block's stored bytes hash to
+// the block we are about to follow, and the head of the directory blocks is that block
+func (a *Accumulator) checkPrevious() error {
+	if !a.VerifyPrevious || a.previous == nil {
+		return nil
+	}
+	hash := a.previous.GetHash()
+	data := a.DB.Get(types.Node, hash[:])
+	if data != nil && types.Hash(sha256.Sum256(data)) == *hash && bytes.Equal(a.DB.Get(types.NodeHead, a.chainID[:]), hash[:]) {
+		return nil
+	}
+	a.logger().Printf("not sealing the block at height %d on the block at %d, %x: %v",
+		a.height, a.previous.BHeight, *hash, ErrPrevCorrupt)
+	return ErrPrevCorrupt
+}
@@ -0,0 +1,153 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestHeightExhausted(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MaxHeight = 20
+	metrics := countingMetrics{}
+	acc.Metrics = metrics
+	chainID := types.Hash(sha256.Sum256([]byte("long lived")))
+	for i := 0; i < 20; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+		if acc.SealBlock() == nil {
+			t.Fatalf("the block at height %d should seal", i)
+		}
+	}
+	if metrics[MetricHeightWarnings] != 2 {
+		t.Errorf("the last two blocks should be warned of, not %d", metrics[MetricHeightWarnings])
+	}
+
+	acc.addEntry(GetTestEntry(chainID, 20))
+	if acc.SealBlock() != nil || acc.Health() != ErrHeightExhausted {
+		t.Errorf("the block at the max height shouldn't seal, and Health should say why, not %v", acc.Health())
+	}
+	if acc.Submit(GetTestEntry(chainID, 21)) {
+		t.Error("entries shouldn't be taken for a block that can't be sealed")
+	}
+	acc.Reset()
+	if acc.sealBlock() != nil || acc.Health() != ErrHeightExhausted {
+		t.Error("the block at the max height shouldn't seal after a Reset either")
+	}
+	head, err := acc.Reader().GetHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.BHeight != 19 || acc.height != 20 {
+		t.Errorf("the head should stay at height 19, not %d, and the next block at 20, not %d", head.BHeight, acc.height)
+	}
+}
+
+func TestAlreadySealed(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("sealed twice")))
+	for i := 0; i < 3; i++ {
+		acc.entryFeed <- GetTestEntry(chainID, i)
+	}
+	runUntilIdle(acc)
+	for i := 0; i < 2; i++ { // Told twice in quick succession, with no entries in between
+		acc.control <- true
+		runUntilIdle(acc)
+	}
+	first, err := acc.Reader().GetDirectoryBlock(0)
+	if err != nil || len(first.List) != 1 {
+		t.Fatalf("the block at height 0 should hold the entries (%v)", err)
+	}
+	head, err := acc.Reader().GetHead()
+	if err != nil || head.BHeight != 1 || len(head.List) != 0 {
+		t.Fatal("the second signal should seal an empty block at the next height")
+	}
+
+	acc.height = 1 // Out of step with the database, as if another accumulator sealed the block
+	acc.addEntry(GetTestEntry(chainID, 3))
+	if acc.checkSealed() != ErrAlreadySealed || acc.SealBlock() != nil {
+		t.Fatal("a height already sealed shouldn't be sealed again")
+	}
+	if again, err := acc.Reader().GetDirectoryBlock(1); err != nil || *again.GetHash() != *head.GetHash() {
+		t.Error("the block at height 1 shouldn't be replaced")
+	}
+}
+
+func TestVerifyPrevious(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.VerifyPrevious = true
+	chainID := types.Hash(sha256.Sum256([]byte("chained")))
+	for height := 0; height < 2; height++ {
+		acc.addEntry(GetTestEntry(chainID, height))
+		if acc.sealBlock() == nil {
+			t.Fatalf("the block at height %d should be sealed", height)
+		}
+	}
+	previous := acc.previous.GetHash()
+	stored := acc.DB.Get(types.Node, previous[:])
+	corrupt := append([]byte{}, stored...)
+	corrupt[len(corrupt)-1] ^= 1
+	acc.DB.Put(types.Node, previous[:], corrupt)
+	acc.addEntry(GetTestEntry(chainID, 2))
+	if acc.checkPrevious() != ErrPrevCorrupt || acc.sealBlock() != nil {
+		t.Fatal("the next block shouldn't be chained off a corrupt previous block")
+	}
+	if _, err := acc.Reader().GetDirectoryBlock(2); err == nil {
+		t.Error("nothing should be written at height 2")
+	}
+
+	acc.DB.Put(types.Node, previous[:], stored) // Once repaired, the block held back is sealed
+	block := acc.sealBlock()
+	if block == nil || block.BHeight != 2 || block.Previous != *previous {
+		t.Fatal("the block at height 2 should be sealed on the repaired block")
+	}
+}
+
+func TestRequireSyncHeight(t *testing.T) {
+	primary := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("synced")))
+	for height := 0; height < 3; height++ {
+		primary.addEntry(GetTestEntry(chainID, height))
+		primary.sealBlock()
+	}
+
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	follower := new(Accumulator)
+	follower.RequireSyncHeight = 3
+	follower.Init(db, primary.chainID)
+	if follower.endBlock() != nil {
+		t.Fatal("nothing should be sealed short of the sync height")
+	}
+	for height := types.BlockHeight(0); height < 3; height++ { // Catch up with the network
+		b, err := primary.Reader().GetReplicaBlock(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := follower.ApplyBlock(b.Block, b.ChainNodes, b.ChainEntries); err != nil {
+			t.Fatal(err)
+		}
+	}
+	follower.addEntry(GetTestEntry(chainID, 3))
+	if block := follower.endBlock(); block == nil || block.BHeight != 3 {
+		t.Fatal("the block at the sync height should be sealed")
+	}
+
+	follower.SetSyncHeight(10) // The network turns out to be further on
+	follower.Submit(GetTestEntry(chainID, 4))
+	follower.control <- true
+	runUntilIdle(follower)
+	if follower.height != 4 || follower.blockEntries != 1 {
+		t.Fatalf("the block at height 4 should be held open with its entry, at height %d with %d entries",
+			follower.height, follower.blockEntries)
+	}
+	follower.SetSyncHeight(4)
+	follower.control <- true
+	runUntilIdle(follower)
+	if block, err := follower.Reader().GetDirectoryBlock(4); err != nil || follower.height != 5 {
+		t.Fatalf("sealing should resume once the sync height is met (%v)", err)
+	} else if count, _ := follower.Reader().GetBlockEntryCount(block.BHeight); count != 1 {
+		t.Errorf("the entry held back should be sealed in the block, found %d entries", count)
+	}
+}
@@ -0,0 +1,58 @@
+package accumulator
+
+import (
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// hotChain
+// The chain that had (almost) all of the last block's entries.  Workloads that put nearly everything into one
+// chain build it again in the next block without the churn of a new chain: the index of its entries is emptied
+// and reused rather than grown afresh, and addEntry finds the chain without a map lookup.  MD roots are
+// the same either way.
+type hotChain struct {
+	chainID types.Hash
+	found   bool               // A chain was hot in the last block
+	entries map[types.Hash]int // The chain's index of entries in the last block, to reuse
+	chain   *ChainAcc          // The chain in the current block, once it turns up
+}
+
+// chain
+// Look up the chain of an entry in the current block, going straight to the hot chain if it is that
+func (a *Accumulator) chain(chainID types.Hash) *ChainAcc {
+	if c := a.hot.chain; c != nil && c.Node.ChainID == chainID {
+		return c
+	}
+	return a.chains[chainID]
+}
+
+// heatChain
+// Have a chain new to this block reuse the hot chain's index of entries, if it is the hot chain
+func (a *Accumulator) heatChain(chain *ChainAcc) {
+	if !a.hot.found || chain.Node.ChainID != a.hot.chainID {
+		return
+	}
+	if entries := a.hot.entries; entries != nil {
+		for h := range entries {
+			delete(entries, h)
+		}
+		chain.entries = entries
+		a.hot.entries = nil
+	}
+	a.hot.chain = chain
+}
+
+// keepHot
+// Note the chain holding nine in ten or more of the entries of the block just committed, if there is one, for
+// the next block.  With InternChains, the chain's buffers are recycled with the rest instead.
+func (a *Accumulator) keepHot() {
+	a.hot = hotChain{}
+	for chainID, chain := range a.chains {
+		if entries := len(chain.MD.HashList) - chain.Carried; entries*10 >= a.blockEntries*9 && entries > 0 {
+			a.hot = hotChain{chainID: chainID, found: true}
+			if !a.InternChains {
+				a.hot.entries = chain.entries
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestHotChain(t *testing.T) {
+	hot := types.Hash(sha256.Sum256([]byte("hot")))
+	cold := types.Hash(sha256.Sum256([]byte("cold")))
+	build := func(general bool) (blocks []*node.Node) {
+		acc := GetTestAccumulator(t)
+		acc.Clock = &testClock{now: time.Unix(1000, 0)} // Blocks sealed at the same time hash the same
+		for b := 0; b < 4; b++ {
+			for i := 0; i < 50; i++ {
+				if general { // Forget the hot chain, as if no chain ever dominated
+					acc.hot = hotChain{}
+				}
+				acc.addEntry(GetTestEntry(hot, b*50+i))
+				acc.addEntry(GetTestEntry(hot, b*50+i)) // Dropped as a duplicate, from the reused index too
+				if b == 2 && i%10 == 0 {
+					acc.addEntry(GetTestEntry(cold, i))
+				}
+			}
+			blocks = append(blocks, acc.sealBlock())
+			if !general && acc.hot.chainID != hot {
+				t.Fatalf("the hot chain should be found after the block at height %d", b)
+			}
+		}
+		return blocks
+	}
+	fast, general := build(false), build(true)
+	for i := range fast {
+		if *fast[i].GetHash() != *general[i].GetHash() {
+			t.Errorf("the block at height %d is %x on the fast path, not %x", i, fast[i].GetHash(), general[i].GetHash())
+		}
+	}
+	if len(fast[1].List) != 1 || len(fast[2].List) != 2 {
+		t.Error("the hot chain's blocks should hold it, and the cold chain when it turns up")
+	}
+}
+
+func BenchmarkHotChain(b *testing.B) {
+	chainID := types.Hash(sha256.Sum256([]byte("benchmarked hot")))
+	for _, general := range []bool{true, false} {
+		b.Run(fmt.Sprintf("general=%v", general), func(b *testing.B) {
+			acc := GetTestAccumulator(nil)
+			entries := make([]node.EntryHash, b.N)
+			for i := range entries {
+				entries[i] = GetTestEntry(chainID, i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i, entry := range entries {
+				if general {
+					acc.hot = hotChain{}
+				}
+				acc.addEntry(entry)
+				if i%4096 == 4095 {
+					acc.sealBlock()
+				}
+			}
+			acc.sealBlock()
+		})
+	}
+}
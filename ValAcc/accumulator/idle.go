@@ -0,0 +1,41 @@
+package accumulator
+
+import (
+	"time"
+)
+
+// Idle
+// True once Run has caught up: the entry feed has been found empty for IdleWindow, and no entry has been taken
+// from it since.  Unlike a sealed block, this says nothing about the block, only that there is nothing left
+// to add to it.  May be called from any go routine.
+func (a *Accumulator) Idle() bool {
+	return a.idle.Load()
+}
+
+// noteBusy
+// Run has taken an entry from the feed, so it is no longer idle
+func (a *Accumulator) noteBusy() {
+	a.idleSince = time.Time{}
+	a.idle.Store(false)
+}
+
+// noteEmpty
+// Run has found the entry feed empty.  Once it has stayed empty for IdleWindow, by the Clock, we are idle,
+// and OnIdle is called, once until the next entry is taken.  The window keeps a feed emptied for a moment
+// in the middle of a burst from counting.
+func (a *Accumulator) noteEmpty() {
+	if a.idle.Load() {
+		return
+	}
+	now := a.clock().Now()
+	if a.idleSince.IsZero() {
+		a.idleSince = now
+	}
+	if now.Sub(a.idleSince) < a.IdleWindow {
+		return
+	}
+	a.idle.Store(true)
+	if a.OnIdle != nil {
+		a.OnIdle()
+	}
+}
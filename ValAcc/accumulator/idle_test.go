@@ -0,0 +1,44 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestOnIdle(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	acc.Clock = clock
+	acc.IdleWindow = time.Second
+	fired := 0
+	acc.OnIdle = func() { fired++ }
+	chainID := types.Hash(sha256.Sum256([]byte("burst")))
+
+	for i := 0; i < 10; i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	runUntilIdle(acc)
+	acc.step() // The feed is empty, but only for a moment
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	acc.Submit(GetTestEntry(chainID, 10))
+	acc.step()
+	acc.step()
+	if fired != 0 || acc.Idle() {
+		t.Fatal("a feed empty for less than the IdleWindow shouldn't count as idle")
+	}
+	clock.now = clock.now.Add(time.Second)
+	for i := 0; i < 3; i++ {
+		acc.step()
+	}
+	if fired != 1 || !acc.Idle() {
+		t.Errorf("OnIdle should fire once after the burst drains, not %d times", fired)
+	}
+	acc.Submit(GetTestEntry(chainID, 11))
+	acc.step()
+	if acc.Idle() {
+		t.Error("taking an entry should end the idle")
+	}
+}
@@ -0,0 +1,40 @@
+package accumulator
+
+import (
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// chainBuffers
+// What a chain's ChainAcc allocated in one block, for the chain to build on in the next
+type chainBuffers struct {
+	entries  map[types.Hash]int // The chain's entries, emptied for the next block
+	hashList []types.Hash       // Backing array of the chain's MD HashList
+}
+
+// internChain
+// Have a chain new to this block reuse the buffers it had in the last block, if it had any
+func (a *Accumulator) internChain(chain *ChainAcc) {
+	buffers, ok := a.interned[chain.Node.ChainID]
+	if !ok {
+		return
+	}
+	delete(a.interned, chain.Node.ChainID)
+	for h := range buffers.entries {
+		delete(buffers.entries, h)
+	}
+	chain.entries = buffers.entries
+	chain.MD.HashList = buffers.hashList[:0]
+}
+
+// recycleChains
+// Keep the buffers of the chains in the block just committed, for those chains turning up again in the next
+// block.  Only the last block's chains are kept, so the pool is never bigger than a block.  Continuous chains
+// carry their MD into the next block, so have nothing to give back.
+func (a *Accumulator) recycleChains() {
+	a.interned = make(map[types.Hash]chainBuffers, len(a.chains))
+	for chainID, chain := range a.chains {
+		if chain.Carried == 0 && !a.ContinuousChains[chainID] {
+			a.interned[chainID] = chainBuffers{entries: chain.entries, hashList: chain.MD.HashList}
+		}
+	}
+}
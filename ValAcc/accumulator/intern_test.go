@@ -0,0 +1,73 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestInternChains(t *testing.T) {
+	var chains []types.Hash
+	for c := 0; c < 4; c++ {
+		chains = append(chains, types.Hash(sha256.Sum256([]byte(fmt.Sprintf("interned %d", c)))))
+	}
+	build := func(intern bool) []*node.Node {
+		acc := GetTestAccumulator(t)
+		acc.Clock = &testClock{now: time.Unix(1000, 0)}
+		acc.InternChains = intern
+		acc.ContinuousChains = map[types.Hash]bool{chains[3]: true}
+		var blocks []*node.Node
+		for height := 0; height < 5; height++ {
+			for i := 0; i < 40; i++ {
+				chainID := chains[(i+height)%len(chains)]
+				if height%2 == 1 && chainID == chains[0] { // A chain that sits out every other block
+					continue
+				}
+				acc.Submit(GetTestEntry(chainID, height*100+i))
+				if i == 20 {
+					acc.Submit(GetTestEntry(chainID, height*100)) // A duplicate, caught by the chain's entries
+				}
+			}
+			runUntilIdle(acc)
+			blocks = append(blocks, acc.SealBlock())
+		}
+		return blocks
+	}
+	plain, interned := build(false), build(true)
+	for i := range plain {
+		if *plain[i].GetHash() != *interned[i].GetHash() {
+			t.Errorf("the block at height %d is %x interning its chains, not %x",
+				i, interned[i].GetHash(), plain[i].GetHash())
+		}
+	}
+}
+
+func BenchmarkInternChains(b *testing.B) {
+	var chains []types.Hash
+	for c := 0; c < 16; c++ {
+		chains = append(chains, types.Hash(sha256.Sum256([]byte(fmt.Sprintf("benchmarked %d", c)))))
+	}
+	for _, intern := range []bool{false, true} {
+		b.Run(fmt.Sprintf("intern=%v", intern), func(b *testing.B) {
+			acc := GetTestAccumulator(nil)
+			acc.InternChains = intern
+			entries := make([]node.EntryHash, b.N)
+			for i := range entries {
+				entries[i] = GetTestEntry(chains[i%len(chains)], i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i, entry := range entries {
+				acc.addEntry(entry)
+				if i%4096 == 4095 {
+					acc.sealBlock()
+				}
+			}
+			acc.sealBlock()
+		})
+	}
+}
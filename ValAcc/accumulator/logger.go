@@ -0,0 +1,23 @@
+package accumulator
+
+import (
+	"log"
+	"os"
+)
+
+// Logger
+// Where the accumulator reports what it is up to.  A *log.Logger will do.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var defaultLogger = log.New(os.Stdout, "", log.LstdFlags)
+
+// logger
+// The Logger to use; if none was set, we log to stdout
+func (a *Accumulator) logger() Logger {
+	if a.Logger == nil {
+		return defaultLogger
+	}
+	return a.Logger
+}
@@ -0,0 +1,192 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ManifestBlock
+// A chain's entries in one directory block, and the proof of the chain's root in the block
+type ManifestBlock struct {
+	Height       types.BlockHeight   // Height of the directory block
+	Flags        node.Flags          // Flags of the directory block, which say how its hashes are combined
+	Entries      []types.Hash        // The chain's entries in the block, in the order they were added
+	ChainReceipt merkleDag.MDReceipt // chain ListMDRoot -> directory block ListMDRoot
+}
+
+// ChainManifest
+// Every entry of a chain, block by block, with what proves them: each block's entries give the chain's root
+// in that block (along with the entries before them, for a continuous chain), and the ChainReceipt takes that
+// root to the ListMDRoot of the directory block.  Verify checks it all without a database, so a chain can be
+// carried off and checked on its own; check the directory roots it ends at against ones trusted to be sure
+// of more than the manifest's word for them.
+type ChainManifest struct {
+	ChainID types.Hash
+	Blocks  []ManifestBlock // In height order
+}
+
+// manifestVersion
+// Version of the marshaled ChainManifest
+const manifestVersion = types.VersionField(1)
+
+// ExportChainManifest
+// Write the ChainManifest of every entry of the chain.  Returns an error if the chain has no nodes, or any of
+// them have been pruned, since the manifest would then be missing entries.
+func (r *Reader) ExportChainManifest(chainID types.Hash, w io.Writer) error {
+	m := &ChainManifest{ChainID: chainID}
+	for hash := r.DB.Get(types.NodeFirst, chainID[:]); hash != nil; hash = r.DB.Get(types.NodeNext, hash) {
+		chainNode, err := r.GetNode(hash)
+		if err != nil {
+			return errors.New(fmt.Sprintf("can't export the manifest of chain %x: %v", chainID, err))
+		}
+		directoryBlock, err := r.GetDirectoryBlock(chainNode.BHeight)
+		if err != nil {
+			return err
+		}
+		chainReceipt, _, flags, err := r.proveChain(directoryBlock, chainID, chainNode)
+		if err != nil {
+			return err
+		}
+		m.Blocks = append(m.Blocks, ManifestBlock{
+			Height:       chainNode.BHeight,
+			Flags:        flags,
+			Entries:      chainNode.EntryList,
+			ChainReceipt: *chainReceipt,
+		})
+	}
+	if len(m.Blocks) == 0 {
+		return errors.New(fmt.Sprintf("chain %x has no entries to export", chainID))
+	}
+	_, err := w.Write(m.Marshal())
+	return err
+}
+
+// VerifyChainManifest
+// Read a ChainManifest written by ExportChainManifest, and Verify it
+func VerifyChainManifest(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m := new(ChainManifest)
+	if err := m.Unmarshal(data); err != nil {
+		return err
+	}
+	return m.Verify()
+}
+
+// Verify
+// Check the blocks are in height order, that each block's entries give the root its ChainReceipt starts from,
+// and that each ChainReceipt validates.  Blocks built with a custom Hasher can't be checked, as the Hasher
+// isn't carried in the manifest; DomainSeparated blocks are hashed as their Flags say.
+func (m *ChainManifest) Verify() error {
+	if len(m.Blocks) == 0 {
+		return errors.New(fmt.Sprintf("the manifest of chain %x holds no blocks", m.ChainID))
+	}
+	var history []types.Hash // Every entry so far, for the root of a continuous chain
+	for i, block := range m.Blocks {
+		if i > 0 && block.Height <= m.Blocks[i-1].Height {
+			return errors.New(fmt.Sprintf("the block at height %d follows the one at height %d", block.Height,
+				m.Blocks[i-1].Height))
+		}
+		hasher := receiptHasher(block.Flags, nil)
+		history = append(history, block.Entries...)
+		root := manifestRoot(hasher, block.Entries)
+		if root != block.ChainReceipt.EntryHash {
+			root = manifestRoot(hasher, history)
+		}
+		if root != block.ChainReceipt.EntryHash {
+			return errors.New(fmt.Sprintf("the entries of chain %x at height %d give the root %x, but its receipt starts from %x",
+				m.ChainID, block.Height, root, block.ChainReceipt.EntryHash))
+		}
+		chainReceipt := block.ChainReceipt
+		chainReceipt.Hasher = hasher
+		if err := chainReceipt.Check(); err != nil {
+			return errors.New(fmt.Sprintf("the receipt of chain %x at height %d fails: %v", m.ChainID, block.Height, err))
+		}
+	}
+	return nil
+}
+
+// manifestRoot
+// The root of an MD over the entries
+func manifestRoot(hasher merkleDag.Hasher, entries []types.Hash) types.Hash {
+	md := new(merkleDag.MD)
+	md.Hasher = hasher
+	for _, h := range entries {
+		md.AddToChain(h)
+	}
+	return *md.GetMDRoot()
+}
+
+// Marshal
+// Version, ChainID, the count of blocks, then each block's height, flags, count of entries, entries and
+// ChainReceipt
+func (m *ChainManifest) Marshal() (data []byte) {
+	data = append(data, manifestVersion.Bytes()...)
+	data = append(data, m.ChainID.Bytes()...)
+	data = append(data, types.Uint32Bytes(uint32(len(m.Blocks)))...)
+	for _, block := range m.Blocks {
+		data = append(data, block.Height.Bytes()...)
+		data = append(data, types.Uint32Bytes(uint32(block.Flags))...)
+		data = append(data, types.Uint32Bytes(uint32(len(block.Entries)))...)
+		for _, h := range block.Entries {
+			data = append(data, h.Bytes()...)
+		}
+		data = append(data, block.ChainReceipt.Bytes()...)
+	}
+	return data
+}
+
+// Unmarshal
+// Extract a manifest from a byte slice.  Returns an error if the unmarshal fails.
+func (m *ChainManifest) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if tooLong, ok := rec.(merkleDag.ErrProofTooLong); ok {
+				err = tooLong
+				return
+			}
+			err = errors.New(fmt.Sprintf("ChainManifest failed to unmarshal %v", rec))
+		}
+	}()
+	var version types.VersionField
+	data = version.Extract(data)
+	if version > manifestVersion {
+		return errors.New(fmt.Sprintf("unknown manifest version %d", version))
+	}
+	data = m.ChainID.Extract(data)
+	var blocks uint32
+	blocks, data = types.BytesUint32(data)
+	if int(blocks) > len(data)/(4+4+4+32) { // Each block takes at least its header and an entry hash
+		return errors.New(fmt.Sprintf("the manifest claims %d blocks in %d bytes", blocks, len(data)))
+	}
+	m.Blocks = make([]ManifestBlock, blocks)
+	for i := range m.Blocks {
+		block := &m.Blocks[i]
+		data = block.Height.Extract(data)
+		var flags, entries uint32
+		flags, data = types.BytesUint32(data)
+		block.Flags = node.Flags(flags)
+		entries, data = types.BytesUint32(data)
+		if int(entries) > len(data)/32 {
+			return errors.New(fmt.Sprintf("the manifest claims %d entries at height %d in %d bytes", entries,
+				block.Height, len(data)))
+		}
+		block.Entries = make([]types.Hash, entries)
+		for j := range block.Entries {
+			data = block.Entries[j].Extract(data)
+		}
+		data = block.ChainReceipt.Extract(data)
+	}
+	if len(data) != 0 {
+		return errors.New(fmt.Sprintf("%d bytes left over after the manifest", len(data)))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestChainManifest(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("manifest")))
+	continuousID := types.Hash(sha256.Sum256([]byte("continuous manifest")))
+	acc.ContinuousChains = map[types.Hash]bool{continuousID: true}
+	for height := 0; height < 5; height++ {
+		if height != 2 { // The chains are active in all but one of the blocks
+			for i := 0; i < height+1; i++ {
+				acc.addEntry(GetTestEntry(chainID, height*10+i))
+				acc.addEntry(GetTestEntry(continuousID, height*10+i))
+			}
+		}
+		acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte{byte(height)})), 0))
+		acc.sealBlock()
+	}
+
+	for _, id := range []types.Hash{chainID, continuousID} {
+		var buf bytes.Buffer
+		if err := acc.Reader().ExportChainManifest(id, &buf); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if err := VerifyChainManifest(bytes.NewReader(data)); err != nil {
+			t.Errorf("the manifest of chain %x should verify: %v", id[:4], err)
+		}
+		m := new(ChainManifest)
+		if err := m.Unmarshal(data); err != nil {
+			t.Fatal(err)
+		}
+		entries := 0
+		for _, block := range m.Blocks {
+			entries += len(block.Entries)
+		}
+		if len(m.Blocks) != 4 || entries != 1+2+4+5 || m.Blocks[2].Height != 3 {
+			t.Errorf("expected 12 entries over 4 blocks, got %d over %d", entries, len(m.Blocks))
+		}
+		m.Blocks[1].Entries[0] = sha256.Sum256([]byte("forged"))
+		if VerifyChainManifest(bytes.NewReader(m.Marshal())) == nil {
+			t.Error("a manifest with a forged entry should not verify")
+		}
+		if VerifyChainManifest(bytes.NewReader(data[:len(data)-1])) == nil {
+			t.Error("a truncated manifest should not verify")
+		}
+	}
+	if acc.Reader().ExportChainManifest(types.Hash(sha256.Sum256([]byte("no such chain"))), new(bytes.Buffer)) == nil {
+		t.Error("a chain with no entries has no manifest")
+	}
+}
@@ -0,0 +1,113 @@
+package accumulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// RootLocation
+// A chain and height whose node has a given ListMDRoot
+type RootLocation struct {
+	ChainID types.Hash        // Chain of the node
+	Height  types.BlockHeight // Height of the directory block the node was sealed in
+}
+
+// Bytes
+// The ChainID then the height
+func (l RootLocation) Bytes() []byte {
+	return append(l.ChainID.Bytes(), l.Height.Bytes()...)
+}
+
+// indexRoots
+// Add the ListMDRoot of every chain in this block to the MDRoot index.  A root already indexed (the same
+// entries sealed into another chain, say) keeps its old locations, and gets the new ones after them, in
+// ChainID order.
+func (a *Accumulator) indexRoots(db *database.DB, chains []*ChainAcc) {
+	locations := make(map[types.Hash][]byte)
+	for _, v := range chains {
+		root := v.Node.ListMDRoot
+		if locations[root] == nil {
+			locations[root] = a.DB.Get(types.MDRootIndex, root[:])
+		}
+		location := RootLocation{ChainID: v.Node.ChainID, Height: a.height}.Bytes()
+		if hasLocation(locations[root], location) { // Written by an earlier attempt to commit this block
+			continue
+		}
+		locations[root] = append(locations[root], location...)
+	}
+	for root, value := range locations {
+		db.Put(types.MDRootIndex, root[:], value)
+	}
+}
+
+// hasLocation
+// True if the index entry for a root already holds the location
+func hasLocation(index []byte, location []byte) bool {
+	for ; len(index) >= len(location); index = index[len(location):] {
+		if bytes.Equal(index[:len(location)], location) {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupAllByMDRoot
+// Return every chain and height whose node has the given ListMDRoot, oldest first.  Returns an empty
+// list if no chain ever had the root.
+func (r *Reader) LookupAllByMDRoot(root types.Hash) ([]RootLocation, error) {
+	data := r.DB.Get(types.MDRootIndex, root[:])
+	const size = 32 + 4 // A ChainID and a height
+	if len(data)%size != 0 {
+		return nil, errors.New(fmt.Sprintf("the MDRoot index of %x has %d bytes, not a multiple of %d",
+			root, len(data), size))
+	}
+	var found []RootLocation
+	for ; len(data) > 0; data = data[size:] {
+		var l RootLocation
+		l.Height.Extract(l.ChainID.Extract(data))
+		found = append(found, l)
+	}
+	return found, nil
+}
+
+// LookupByMDRoot
+// Return the chain and height that first produced the given ListMDRoot, and false if none did.  Use
+// LookupAllByMDRoot to find every chain that has produced it.
+func (r *Reader) LookupByMDRoot(root types.Hash) (chainID types.Hash, height types.BlockHeight, found bool) {
+	locations, err := r.LookupAllByMDRoot(root)
+	if err != nil || len(locations) == 0 {
+		return chainID, 0, false
+	}
+	return locations[0].ChainID, locations[0].Height, true
+}
+
+// indexDirectoryRoots
+// Index the directory block's MD root (the root sent on the mdFeed and anchored) and its ListMDRoot to its
+// height.  A root already indexed (the ListMDRoot of every empty block is the same) keeps its first height.
+func (a *Accumulator) indexDirectoryRoots(db *database.DB, directoryBlock *node.Node) {
+	for _, root := range []types.Hash{*directoryBlock.GetMDRoot(), directoryBlock.ListMDRoot} {
+		if a.DB.Get(types.DirectoryRootIndex, root[:]) == nil {
+			db.Put(types.DirectoryRootIndex, root[:], a.height.Bytes())
+		}
+	}
+}
+
+// IsKnownDirectoryRoot
+// Return the height of the first directory block whose MD root or ListMDRoot is the given root, and false if
+// none of our blocks has it.  This is the directory block's counterpart of LookupByMDRoot, for checking a root
+// someone shows us (from an anchor, say) was really produced by this accumulator.  Blocks sealed before the
+// index was kept aren't found.
+func (r *Reader) IsKnownDirectoryRoot(root types.Hash) (types.BlockHeight, bool) {
+	data := r.DB.Get(types.DirectoryRootIndex, root[:])
+	if len(data) != 4 {
+		return 0, false
+	}
+	var height types.BlockHeight
+	height.Extract(data)
+	return height, true
+}
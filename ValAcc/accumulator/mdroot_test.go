@@ -0,0 +1,88 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestLookupByMDRoot(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chain1 := types.Hash(sha256.Sum256([]byte("rooted 1")))
+	chain2 := types.Hash(sha256.Sum256([]byte("rooted 2")))
+	roots := map[RootLocation]types.Hash{}
+	for b := 0; b < 3; b++ {
+		for i := 0; i < 4; i++ {
+			acc.addEntry(GetTestEntry(chain1, b*4+i))
+			acc.addEntry(GetTestEntry(chain2, b*4+i))
+		}
+		block := acc.sealBlock()
+		for _, chainID := range []types.Hash{chain1, chain2} {
+			chain, err := acc.Reader().GetChainNode(chainID, block.BHeight)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roots[RootLocation{ChainID: chainID, Height: block.BHeight}] = chain.ListMDRoot
+		}
+	}
+	for location, root := range roots {
+		chainID, height, found := acc.Reader().LookupByMDRoot(root)
+		if !found || chainID != location.ChainID || height != location.Height {
+			t.Errorf("the root of chain %x at height %d should resolve back to it", location.ChainID, location.Height)
+		}
+	}
+	if _, _, found := acc.Reader().LookupByMDRoot(chain1); found {
+		t.Error("a root no chain produced should not be found")
+	}
+
+	// The same entry in two chains of a block gives them the same root
+	shared := node.EntryHash{ChainID: chain1, EntryHash: sha256.Sum256([]byte("shared entry"))}
+	acc.addEntry(shared)
+	shared.ChainID = chain2
+	acc.addEntry(shared)
+	acc.sealBlock()
+	md := acc.Reader().newMD()
+	md.AddToChain(shared.EntryHash)
+	locations, err := acc.Reader().LookupAllByMDRoot(*md.GetMDRoot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 2 || locations[0].Height != 3 || locations[1].Height != 3 ||
+		locations[0].ChainID == locations[1].ChainID {
+		t.Errorf("expected both chains holding the shared entry at height 3, got %v", locations)
+	}
+}
+
+func TestIsKnownDirectoryRoot(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("directory roots")))
+	var blocks []*node.Node
+	for height := 0; height < 4; height++ {
+		for i := 0; i < height+1; i++ {
+			acc.Submit(GetTestEntry(chainID, height*10+i))
+		}
+		runUntilIdle(acc)
+		blocks = append(blocks, acc.SealBlock())
+	}
+	blocks = append(blocks, acc.SealBlock(), acc.SealBlock()) // Two empty blocks, with the same ListMDRoot
+
+	r := acc.Reader()
+	for _, block := range blocks {
+		if height, found := r.IsKnownDirectoryRoot(*block.GetMDRoot()); !found || height != block.BHeight {
+			t.Errorf("the MD root of the block at height %d resolves to %d (%v)", block.BHeight, height, found)
+		}
+		expected := block.BHeight
+		if block.BHeight == 5 {
+			expected = 4 // The first block with the root
+		}
+		if height, found := r.IsKnownDirectoryRoot(block.ListMDRoot); !found || height != expected {
+			t.Errorf("the ListMDRoot of the block at height %d resolves to %d (%v), not %d",
+				block.BHeight, height, found, expected)
+		}
+	}
+	if _, found := r.IsKnownDirectoryRoot(sha256.Sum256([]byte("never a root"))); found {
+		t.Error("a random hash shouldn't be a directory root")
+	}
+}
@@ -0,0 +1,35 @@
+package accumulator
+
+// Names of the counters the accumulator keeps through the Metrics interface
+const (
+	MetricPanics         = "accumulator.panics"          // Panics recovered in Run
+	MetricRejected       = "accumulator.rejected"        // Entries refused by Submit
+	MetricHeightWarnings = "accumulator.height_warnings" // Blocks sealed in the last tenth of the heights
+
+	MetricFeedFull          = "accumulator.feed_full"           // Entries refused as the entryFeed was full
+	MetricMDRootsDropped    = "accumulator.md_roots_dropped"    // MD roots dropped with no reader on the mdFeed
+	MetricChainRootsDropped = "accumulator.chain_roots_dropped" // Chain roots dropped with no reader on the chainRootFeed
+
+	// MetricFeedHighWater is a gauge rather than a counter: the most entries waiting in the entryFeed at once
+	// while the last block sealed was open.  Each block moves it by the difference from the block before.
+	MetricFeedHighWater = "accumulator.feed_high_water"
+)
+
+// Metrics
+// Counters the accumulator bumps as it runs, so they can be exported to whatever monitoring is in use.
+type Metrics interface {
+	Add(name string, delta int64) // Add delta to the named counter
+}
+
+type noMetrics struct{}
+
+func (noMetrics) Add(string, int64) {}
+
+// metrics
+// The Metrics to use; if none were set, the counts go nowhere
+func (a *Accumulator) metrics() Metrics {
+	if a.Metrics == nil {
+		return noMetrics{}
+	}
+	return a.Metrics
+}
@@ -0,0 +1,91 @@
+package accumulator
+
+import (
+	"sync"
+
+	"github.com/FactomProject/factomd/util/atomic"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockEvent
+// What the accumulator publishes to its Observers as each block is committed
+type BlockEvent struct {
+	Block   *node.Node // The directory block
+	MDRoot  types.Hash // Its MD root, as sent on the mdFeed
+	Entries int        // Entries sealed in the block
+}
+
+// eventBus
+// The Observers of an accumulator
+type eventBus struct {
+	mux       sync.Mutex
+	observers map[*Observer]bool
+}
+
+// Observer
+// Tails the blocks a running accumulator commits, and reads what it has written, without being able to write:
+// an Observer has no methods that write, and the Reader it hands out is over a read only view of the Store,
+// whose writes panic with database.ErrReadOnly.  For attaching to a live accumulator to debug it.
+type Observer struct {
+	Events  <-chan BlockEvent // A BlockEvent for each block committed, in height order
+	events  chan BlockEvent
+	dropped atomic.AtomicInt64 // Events dropped because Events was full
+	a       *Accumulator
+}
+
+// Observe
+// Attach an Observer, which gets a BlockEvent for each block committed from now on.  Events are buffered
+// this many deep; the accumulator never waits on an Observer, so an event that finds the buffer full is
+// dropped (and counted by Dropped) rather than holding up the next block.  May be called from any go routine.
+func (a *Accumulator) Observe(buffer int) *Observer {
+	events := make(chan BlockEvent, buffer)
+	o := &Observer{Events: events, events: events, a: a}
+	a.bus.mux.Lock()
+	defer a.bus.mux.Unlock()
+	if a.bus.observers == nil {
+		a.bus.observers = make(map[*Observer]bool)
+	}
+	a.bus.observers[o] = true
+	return o
+}
+
+// Reader
+// Get a Reader over a read only view of the accumulator's database.  Anything that writes through it panics.
+func (o *Observer) Reader() *Reader {
+	r := o.a.Reader()
+	db := new(database.DB)
+	db.InitStore(database.ReadOnly(r.DB.GetStore()))
+	r.DB = db
+	return r
+}
+
+// Dropped
+// The number of events dropped because the Observer wasn't keeping up
+func (o *Observer) Dropped() int64 {
+	return o.dropped.Load()
+}
+
+// Close
+// Detach the Observer.  Events is not closed, as an event may be in flight.
+func (o *Observer) Close() {
+	o.a.bus.mux.Lock()
+	defer o.a.bus.mux.Unlock()
+	delete(o.a.bus.observers, o)
+}
+
+// publish
+// Give every Observer the event, dropping it for those with no room for it
+func (b *eventBus) publish(event BlockEvent) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for o := range b.observers {
+		select {
+		case o.events <- event:
+		default:
+			o.dropped.Add(1)
+		}
+	}
+}
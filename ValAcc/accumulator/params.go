@@ -0,0 +1,240 @@
+package accumulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ParamMismatch
+// What Init panics with when the accumulator is configured to build roots that wouldn't match those of the
+// blocks already in the database
+type ParamMismatch struct {
+	Param      string // Which differs: "hasher", "hash length", "version" or "continuous chain"
+	Stored     string // What the database was built with
+	Configured string // What the accumulator is configured with
+}
+
+func (e *ParamMismatch) Error() string {
+	return fmt.Sprintf("the accumulator's parameters don't match those the database was built with; the %s is %s "+
+		"in the database, but %s here", e.Param, e.Stored, e.Configured)
+}
+
+// ChainParams
+// The parameters that decide the roots an accumulator builds.  They are written with the genesis block (the
+// block at height zero) and never changed, but for new chains joining the ContinuousChains, so a restart
+// against the database can check it is configured to build the same roots.
+type ChainParams struct {
+	Version types.VersionField // Version of ValAcc that built the genesis block
+	Hasher  string             // Names the Hasher the Merkle DAGs are built with; see hasherName
+	HashLen uint16             // Length of the hashes
+	Flags   node.Flags         // The BlockFlags of the genesis block; not checked, as only the Hasher they pick matters
+
+	// Continuous holds the ContinuousChains, in ChainID order.  Params written before they were recorded have
+	// none, and leave ContinuousRecorded unset.
+	Continuous         []types.Hash
+	ContinuousRecorded bool
+}
+
+// Marshal
+// Version, hash length, flags, the hasher's name led by its length, then the continuous chains led by their count.
+func (p *ChainParams) Marshal() (data []byte) {
+	data = append(data, p.Version.Bytes()...)
+	data = append(data, types.Uint16Bytes(p.HashLen)...)
+	data = append(data, types.Uint32Bytes(uint32(p.Flags))...)
+	data = append(data, types.Uint16Bytes(uint16(len(p.Hasher)))...)
+	data = append(data, p.Hasher...)
+	data = append(data, types.Uint32Bytes(uint32(len(p.Continuous)))...)
+	for _, chainID := range p.Continuous {
+		data = append(data, chainID.Bytes()...)
+	}
+	return data
+}
+
+// Unmarshal
+// Extract chain parameters from a byte slice.  Returns an error if the unmarshal fails.
+func (p *ChainParams) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.New(fmt.Sprintf("ChainParams failed to unmarshal %v", rec))
+		}
+	}()
+	data = p.Version.Extract(data)
+	p.HashLen, data = types.BytesUint16(data)
+	var flags uint32
+	flags, data = types.BytesUint32(data)
+	p.Flags = node.Flags(flags)
+	var hasher types.DataField
+	var length uint16
+	length, data = types.BytesUint16(data)
+	data = hasher.Extract(length, data)
+	p.Hasher = string(hasher)
+	p.Continuous, p.ContinuousRecorded = nil, len(data) > 0
+	if p.ContinuousRecorded { // Older params end with the hasher
+		var count uint32
+		count, data = types.BytesUint32(data)
+		if int(count) > len(data)/32 {
+			return errors.New(fmt.Sprintf("ChainParams claims %d continuous chains in %d bytes", count, len(data)))
+		}
+		p.Continuous = make([]types.Hash, count)
+		for i := range p.Continuous {
+			data = p.Continuous[i].Extract(data)
+		}
+	}
+	return nil
+}
+
+// params
+// The ChainParams the accumulator is configured with
+func (a *Accumulator) params() ChainParams {
+	params := ChainParams{
+		Version:            types.Version,
+		Hasher:             hasherName(a.hasher()),
+		HashLen:            uint16(len(types.Hash{})),
+		Flags:              a.BlockFlags,
+		ContinuousRecorded: true,
+	}
+	for chainID, continuous := range a.ContinuousChains {
+		if continuous {
+			params.Continuous = append(params.Continuous, chainID)
+		}
+	}
+	sort.Slice(params.Continuous, func(i, j int) bool {
+		return bytes.Compare(params.Continuous[i][:], params.Continuous[j][:]) < 0
+	})
+	return params
+}
+
+// IsContinuous
+// True if the chain is one of the Continuous chains
+func (p *ChainParams) IsContinuous(chainID types.Hash) bool {
+	i := sort.Search(len(p.Continuous), func(i int) bool { return bytes.Compare(p.Continuous[i][:], chainID[:]) >= 0 })
+	return i < len(p.Continuous) && p.Continuous[i] == chainID
+}
+
+// hasherName
+// Name a Hasher by its type, or "sha256" for the default.  Two hashers of the same type are taken to be the
+// same, so a Hasher shouldn't have fields that change the way it combines hashes.
+func hasherName(hasher interface{}) string {
+	if hasher == nil {
+		return "sha256"
+	}
+	return fmt.Sprintf("%T", hasher)
+}
+
+// writeParams
+// Write the accumulator's ChainParams into the batch of the block being sealed: all of them with the genesis
+// block, and after that the stored params with the ContinuousChains, once they have changed.  Returns the
+// params written, to be kept once the batch commits, or nil if there was nothing to write.
+func (a *Accumulator) writeParams(db *database.DB) *ChainParams {
+	configured := a.params()
+	params := &configured
+	if a.height > 0 {
+		if a.chainParams == nil || sameHashes(a.chainParams.Continuous, configured.Continuous) {
+			return nil // Older than ChainParams, or nothing has changed
+		}
+		params = new(ChainParams)
+		*params = *a.chainParams
+		params.Continuous, params.ContinuousRecorded = configured.Continuous, true
+	}
+	db.Put(types.ChainParams, a.chainID[:], params.Marshal())
+	return params
+}
+
+// sameHashes
+// True if the two lists hold the same hashes in the same order
+func sameHashes(x, y []types.Hash) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkParams
+// Compare the accumulator's ChainParams with those written with the genesis block.  Returns a *ParamMismatch
+// if they differ, or nil if they match or the database has none (it is new, or older than ChainParams).  A
+// database built by an older version of ValAcc still checks out.  The BlockFlags are free to change from
+// block to block, so long as they pick the same Hasher (see hasher), as each block's flags are what its
+// receipts are verified by.
+//
+// A chain recorded as continuous has to stay in the ContinuousChains, as its roots cover its history (and Prune
+// keeps that history only while it is continuous).  A chain can join them only while it has no nodes, as its
+// roots would otherwise change meaning part way; it is then recorded, as the ContinuousChains of a database
+// older than the record are.
+func (a *Accumulator) checkParams() error {
+	stored, err := a.Reader().GetChainParams()
+	if err != nil || stored == nil {
+		return err
+	}
+	configured := a.params()
+	switch {
+	case stored.Hasher != configured.Hasher:
+		return &ParamMismatch{Param: "hasher", Stored: stored.Hasher, Configured: configured.Hasher}
+	case stored.HashLen != configured.HashLen:
+		return &ParamMismatch{Param: "hash length", Stored: fmt.Sprint(stored.HashLen), Configured: fmt.Sprint(configured.HashLen)}
+	case stored.Version > configured.Version:
+		return &ParamMismatch{Param: "version", Stored: fmt.Sprint(stored.Version), Configured: fmt.Sprint(configured.Version)}
+	}
+	a.chainParams = stored
+	if !stored.ContinuousRecorded {
+		return a.recordContinuous(stored, configured)
+	}
+	for _, chainID := range stored.Continuous {
+		if !configured.IsContinuous(chainID) {
+			return &ParamMismatch{Param: "continuous chain", Stored: fmt.Sprintf("%x continuous", chainID),
+				Configured: "not"}
+		}
+	}
+	if sameHashes(configured.Continuous, stored.Continuous) {
+		return nil
+	}
+	for _, chainID := range configured.Continuous {
+		if !stored.IsContinuous(chainID) && a.DB.Get(types.NodeHead, chainID[:]) != nil {
+			return &ParamMismatch{Param: "continuous chain", Stored: fmt.Sprintf("%x not continuous", chainID),
+				Configured: "continuous"}
+		}
+	}
+	return a.recordContinuous(stored, configured)
+}
+
+// recordContinuous
+// Write the stored params back with the configured ContinuousChains
+func (a *Accumulator) recordContinuous(stored *ChainParams, configured ChainParams) error {
+	stored.Continuous, stored.ContinuousRecorded = configured.Continuous, true
+	return a.DB.Put(types.ChainParams, a.chainID[:], stored.Marshal())
+}
+
+// continuousChain
+// Whether the chain is continuous, by the ChainParams, and false for recorded if the params don't say (the
+// database is older than the record of the ContinuousChains, or has no params)
+func (r *Reader) continuousChain(chainID types.Hash) (continuous, recorded bool, err error) {
+	params, err := r.GetChainParams()
+	if err != nil || params == nil || !params.ContinuousRecorded {
+		return false, false, err
+	}
+	return params.IsContinuous(chainID), true, nil
+}
+
+// GetChainParams
+// Return the ChainParams written with the genesis block, or nil if there are none
+func (r *Reader) GetChainParams() (*ChainParams, error) {
+	data := r.DB.Get(types.ChainParams, r.ChainID[:])
+	if data == nil {
+		return nil, nil
+	}
+	params := new(ChainParams)
+	if err := params.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
@@ -0,0 +1,126 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestChainParams(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.Hasher = merkleDag.DomainHasher{}
+	acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("params"))), 0))
+	acc.SealBlock()
+	acc.SealBlock()
+	params, err := acc.Reader().GetChainParams()
+	if err != nil || params == nil {
+		t.Fatalf("the genesis block should write the ChainParams (%v)", err)
+	}
+	if configured := acc.params(); !bytes.Equal(params.Marshal(), configured.Marshal()) {
+		t.Errorf("expected %+v, read back %+v", configured, *params)
+	}
+
+	restart := func(configure func(restarted *Accumulator)) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err, _ = r.(error)
+			}
+		}()
+		restarted := new(Accumulator)
+		configure(restarted)
+		restarted.Init(acc.DB, acc.chainID)
+		return nil
+	}
+	if err := restart(func(r *Accumulator) { r.Hasher = merkleDag.DomainHasher{} }); err != nil {
+		t.Errorf("restarting with the same parameters should work, got %v", err)
+	}
+	if mismatch, ok := restart(func(r *Accumulator) {}).(*ParamMismatch); !ok || mismatch.Param != "hasher" {
+		t.Errorf("restarting with a different hasher should fail with a ParamMismatch, got %v", mismatch)
+	}
+	if err := restart(func(r *Accumulator) {
+		r.Hasher = merkleDag.DomainHasher{}
+		r.BlockFlags = node.Signed
+	}); err != nil {
+		t.Errorf("restarting with flags that build the same roots should work, got %v", err)
+	}
+	if err := restart(func(r *Accumulator) { r.BlockFlags = node.DomainSeparated }); err != nil {
+		t.Errorf("restarting with flags that pick the same hasher should work, got %v", err)
+	}
+}
+
+func TestContinuousParams(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	continuous := types.Hash(sha256.Sum256([]byte("recorded continuous")))
+	other := types.Hash(sha256.Sum256([]byte("recorded other")))
+	acc.ContinuousChains = map[types.Hash]bool{continuous: true}
+	for block := 0; block < 3; block++ {
+		for i := 0; i < 4; i++ {
+			acc.addEntry(GetTestEntry(continuous, block*10+i))
+			acc.addEntry(GetTestEntry(other, block*10+i))
+		}
+		acc.sealBlock()
+	}
+	params, err := acc.Reader().GetChainParams()
+	if err != nil || !params.ContinuousRecorded || !params.IsContinuous(continuous) || params.IsContinuous(other) {
+		t.Fatalf("the ChainParams should record %x alone as continuous, read %+v (%v)", continuous, params, err)
+	}
+	if _, err := acc.Reader().GetReceipt(continuous, GetTestEntry(continuous, 21).EntryHash, 2); err != nil {
+		t.Errorf("the continuous chain should prove its entries, got %v", err)
+	}
+
+	restart := func(continuousChains ...types.Hash) (restarted *Accumulator, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err, _ = r.(error)
+			}
+		}()
+		restarted = new(Accumulator)
+		restarted.ContinuousChains = map[types.Hash]bool{}
+		for _, chainID := range continuousChains {
+			restarted.ContinuousChains[chainID] = true
+		}
+		restarted.Init(acc.DB, acc.chainID)
+		return restarted, nil
+	}
+	if _, err := restart(); !isMismatch(err, "continuous chain") {
+		t.Errorf("dropping a continuous chain should fail with a ParamMismatch, got %v", err)
+	}
+	if _, err := restart(continuous, other); !isMismatch(err, "continuous chain") {
+		t.Error("a chain with nodes shouldn't be able to become continuous")
+	}
+	joined := types.Hash(sha256.Sum256([]byte("recorded joined")))
+	if _, err := restart(continuous, joined); err != nil {
+		t.Errorf("a chain with no nodes should be able to become continuous, got %v", err)
+	}
+	if params, _ := acc.Reader().GetChainParams(); !params.IsContinuous(joined) {
+		t.Error("the chain that joined the continuous chains should be recorded")
+	}
+
+	// A continuous chain that can't be rebuilt drops its entry, rather than panicking
+	restarted, err := restart(continuous, joined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := new(recordingLogger)
+	restarted.Logger = logger
+	restarted.PanicPolicy = PropagatePanics
+	restarted.DB.Delete(types.Node, restarted.DB.Get(types.NodeFirst, continuous[:]))
+	restarted.processEntry(GetTestEntry(continuous, 100))
+	if restarted.chains[continuous] != nil {
+		t.Error("an entry for a continuous chain that can't be rebuilt should be dropped")
+	}
+	if len(logger.lines) != 1 {
+		t.Errorf("expected the dropped entry to be logged, got %q", logger.lines)
+	}
+}
+
+// isMismatch
+// True if the error is a ParamMismatch of the given parameter
+func isMismatch(err error, param string) bool {
+	mismatch, ok := err.(*ParamMismatch)
+	return ok && mismatch.Param == param
+}
@@ -0,0 +1,171 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// partitionBatch
+// Hashes are handed to a partition this many at a time, so the cost of the hand off is spread out
+const partitionBatch = 256
+
+// partitionWork
+// A hash to add to a chain's MD
+type partitionWork struct {
+	md   *merkleDag.MD
+	hash types.Hash
+}
+
+// partitions
+// Go routines that build the MDs of the chains in disjoint ranges of ChainIDs.  The Run loop still
+// decides what goes into each chain, in order, and hands the hashing to the partition owning the chain.
+// Since every entry of a chain goes to the same partition, and each partition works in order, each chain's
+// MD is built exactly as it would be by the Run loop.
+type partitions struct {
+	work    []chan []partitionWork // Batches of work for each partition
+	batch   [][]partitionWork      // Work collected for each partition, not yet handed over
+	pending sync.WaitGroup         // Batches handed over but not yet done
+
+	failMux  sync.Mutex               // Guards failures
+	failures map[*merkleDag.MD]string // The MDs whose hashing panicked, with what the panic said
+}
+
+// partitionOf
+// The partition holding a chain.  Each partition holds a contiguous range of ChainIDs, by their first byte.
+func (a *Accumulator) partitionOf(chainID types.Hash) int {
+	return int(chainID[0]) * a.Partitions / 256
+}
+
+// addHash
+// Add a hash to a chain's MD, in the chain's partition if we have partitions
+func (a *Accumulator) addHash(chain *ChainAcc, hash types.Hash) {
+	if a.Partitions <= 1 {
+		chain.MD.AddToChain(hash)
+		return
+	}
+	p := &a.partitions
+	if p.work == nil {
+		a.startPartitions()
+	}
+	i := a.partitionOf(chain.Node.ChainID)
+	p.batch[i] = append(p.batch[i], partitionWork{md: chain.MD, hash: hash})
+	if len(p.batch[i]) >= partitionBatch {
+		p.pending.Add(1)
+		p.work[i] <- p.batch[i]
+		p.batch[i] = nil
+	}
+}
+
+// startPartitions
+// Start a go routine for each partition
+func (a *Accumulator) startPartitions() {
+	p := &a.partitions
+	p.work = make([]chan []partitionWork, a.Partitions)
+	p.batch = make([][]partitionWork, a.Partitions)
+	for i := range p.work {
+		work := make(chan []partitionWork, 16)
+		p.work[i] = work
+		go func() {
+			for batch := range work {
+				for _, w := range batch {
+					p.add(w)
+				}
+				p.pending.Done()
+			}
+		}()
+	}
+}
+
+// add
+// Add a hash to its MD in a partition's go routine.  A panic (from a Hasher, say) is recovered and recorded
+// against the MD, as there is nobody in this go routine to tell, and the MD is left alone from then on.  The
+// Run loop finds out when it next checks the partitions have settled.
+func (p *partitions) add(w partitionWork) {
+	p.failMux.Lock()
+	failed := p.failures[w.md] != ""
+	p.failMux.Unlock()
+	if failed {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			p.failMux.Lock()
+			defer p.failMux.Unlock()
+			if p.failures == nil {
+				p.failures = make(map[*merkleDag.MD]string)
+			}
+			p.failures[w.md] = fmt.Sprintf("%v\n%s", r, debug.Stack())
+		}
+	}()
+	w.md.AddToChain(w.hash)
+}
+
+// failing
+// True if hashing has panicked in the partitions since failed was last called
+func (p *partitions) failing() bool {
+	p.failMux.Lock()
+	defer p.failMux.Unlock()
+	return len(p.failures) > 0
+}
+
+// failed
+// Return an error describing the hashing that panicked in the partitions since the last call, or nil if none
+// did, and forget the failures
+func (p *partitions) failed() error {
+	p.failMux.Lock()
+	defer p.failMux.Unlock()
+	if len(p.failures) == 0 {
+		return nil
+	}
+	var first string
+	for _, failure := range p.failures {
+		first = failure
+		break
+	}
+	err := errors.New(fmt.Sprintf("hashing panicked in the partitions for %d chains: %s", len(p.failures), first))
+	p.failures = nil
+	return err
+}
+
+// settle
+// Wait for the partitions to finish all the hashing handed to them, so the chains' MDs are complete.
+// Call before anything looks at or replaces a chain's MD.  What panicked in the partitions is left for
+// checkPartitions to report.
+func (a *Accumulator) settle() {
+	p := &a.partitions
+	for i, batch := range p.batch {
+		if len(batch) > 0 {
+			p.pending.Add(1)
+			p.work[i] <- batch
+			p.batch[i] = nil
+		}
+	}
+	p.pending.Wait()
+}
+
+// checkPartitions
+// Settle the partitions, then panic if any hashing panicked in them, so the block is dropped (by safely) as it
+// would be had the hashing panicked in the Run loop
+func (a *Accumulator) checkPartitions() {
+	a.settle()
+	if err := a.partitions.failed(); err != nil {
+		panic(err)
+	}
+}
+
+// stopPartitions
+// Let the partition go routines finish
+func (a *Accumulator) stopPartitions() {
+	a.settle()
+	a.partitions.failed() // Nothing is left to be sealed with the MDs that failed
+	for _, work := range a.partitions.work {
+		close(work)
+	}
+	a.partitions.work = nil
+	a.partitions.batch = nil
+}
@@ -0,0 +1,97 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// partitionedBlocks
+// Seal the same blocks of entries over many chains with the given number of partitions, returning the
+// directory blocks' ListMDRoots
+func partitionedBlocks(partitions int) (roots []types.Hash) {
+	acc := GetTestAccumulator(nil)
+	acc.Partitions = partitions
+	for b := 0; b < 3; b++ {
+		for i := 0; i < 2000; i++ {
+			chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("partitioned %d", i%97))))
+			acc.addEntry(GetTestEntry(chainID, b*2000+i))
+		}
+		roots = append(roots, acc.sealBlock().ListMDRoot)
+	}
+	acc.stopPartitions()
+	return roots
+}
+
+func TestPartitions(t *testing.T) {
+	expected := partitionedBlocks(1)
+	for _, partitions := range []int{2, 4, 7} {
+		for b, root := range partitionedBlocks(partitions) {
+			if root != expected[b] {
+				t.Errorf("block %d has a different root with %d partitions", b, partitions)
+			}
+		}
+	}
+}
+
+func TestPartitionPanic(t *testing.T) {
+	before := runtime.NumGoroutine()
+	acc := GetTestAccumulator(t)
+	acc.Partitions = 4
+	metrics := countingMetrics{}
+	acc.Metrics = metrics
+	chainID := types.Hash(sha256.Sum256([]byte("panics in a partition")))
+	acc.Hasher = panicHasher{bad: GetTestEntry(chainID, 1).EntryHash}
+	other := types.Hash(sha256.Sum256([]byte("hashed in another partition")))
+
+	for i := 0; i < 3; i++ {
+		acc.processEntry(GetTestEntry(chainID, i))
+		acc.processEntry(GetTestEntry(other, i))
+	}
+	if acc.SealBlock() != nil || acc.height != 0 || metrics[MetricPanics] != 1 {
+		t.Fatalf("a panic in a partition should drop the block, recovered from %d panics", metrics[MetricPanics])
+	}
+	acc.processEntry(GetTestEntry(other, 3))
+	block := acc.SealBlock()
+	if block == nil || block.BHeight != 0 || len(block.List) != 1 {
+		t.Fatal("the partitions should carry on hashing after a panic")
+	}
+
+	if err := acc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); runtime.NumGoroutine() > before; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("Close should stop the partition go routines, %d are still running", runtime.NumGoroutine()-before)
+		}
+	}
+}
+
+func BenchmarkPartitions(b *testing.B) {
+	var chains []types.Hash
+	for c := 0; c < 256; c++ {
+		chains = append(chains, types.Hash(sha256.Sum256([]byte(fmt.Sprintf("benchmarked %d", c)))))
+	}
+	for _, partitions := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("partitions=%d", partitions), func(b *testing.B) {
+			acc := GetTestAccumulator(nil)
+			acc.Partitions = partitions
+			acc.BlockFlags = node.DomainSeparated
+			entries := make([]node.EntryHash, b.N)
+			for i := range entries {
+				entries[i] = GetTestEntry(chains[i%len(chains)], i)
+			}
+			b.ResetTimer()
+			for _, entry := range entries {
+				acc.addEntry(entry)
+			}
+			acc.sealBlock()
+			acc.stopPartitions()
+		})
+	}
+}
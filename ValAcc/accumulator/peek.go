@@ -0,0 +1,38 @@
+package accumulator
+
+import (
+	"errors"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ErrEntriesHeld
+// Returned by PeekRoot when entries are held back for the current block, to be added only as it is sealed
+var ErrEntriesHeld = errors.New("entries are held for the current block until it is sealed")
+
+// PeekRoot
+// Return the ListMDRoot the directory block would have if the current block were sealed right now, leaving
+// the block open.  The roots are computed just as sealBlock computes them, so with no more entries added the
+// block sealed gets the root peeked.  The block's MD root can't be peeked, since it covers the header and so
+// the time the block is sealed.
+//
+// Entries queued by SubmitAtHeight or held by the Sequencer are only added as the block is sealed, so while
+// there are any we can't say what the root will be, and return ErrEntriesHeld.  Nor can we know what
+// BeforeSeal will add; the root peeked is the block's without them.  Don't call it while Run is running.
+func (a *Accumulator) PeekRoot() (types.Hash, error) {
+	if len(a.sequenced) > 0 || a.schedule.has(a.height) {
+		return types.Hash{}, ErrEntriesHeld
+	}
+	a.settle()
+	if a.partitions.failing() {
+		return types.Hash{}, errors.New("hashing panicked in the partitions, so the block will be dropped when sealed")
+	}
+	chains := a.chainsInOrder()
+	var chainEntries []node.NEList
+	for _, v := range chains {
+		chainEntries = append(chainEntries, node.NEList{ChainID: v.Node.ChainID, MDRoot: *v.MD.GetMDRoot()})
+	}
+	list, _ := a.groupChains(chains, chainEntries)
+	return *a.directoryMD(list).GetMDRoot(), nil
+}
@@ -0,0 +1,47 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestPeekRoot(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.Partitions = 4
+	defer acc.stopPartitions()
+	continuous := types.Hash(sha256.Sum256([]byte("continuous")))
+	acc.ContinuousChains = map[types.Hash]bool{continuous: true}
+
+	if root, err := acc.PeekRoot(); err != nil || root != merkleDag.EmptyMDRoot {
+		t.Errorf("an empty block should peek the EmptyMDRoot, got %x (%v)", root, err)
+	}
+	for block := 0; block < 3; block++ {
+		for i := 0; i < 50; i++ {
+			chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("peek %d", i%7))))
+			if i%5 == 0 {
+				chainID = continuous
+			}
+			acc.Submit(GetTestEntry(chainID, block*100+i))
+		}
+		acc.ProcessPending()
+		peeked, err := acc.PeekRoot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := acc.PeekRoot(); again != peeked {
+			t.Error("peeking shouldn't change the block")
+		}
+		if sealed := acc.SealBlock(); sealed.ListMDRoot != peeked {
+			t.Errorf("block %d peeked root %x but sealed %x", block, peeked, sealed.ListMDRoot)
+		}
+	}
+
+	acc.SubmitAtHeight(GetTestEntry(continuous, 1000), acc.height)
+	if _, err := acc.PeekRoot(); err != ErrEntriesHeld {
+		t.Errorf("expected ErrEntriesHeld with an entry queued for the block, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package accumulator
+
+import (
+	"errors"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// PendingBlock
+// Return a copy of the entries added to each chain of the current block so far, for showing what is pending
+// before the block is sealed.  The copy is taken by Run between entries, so it is the block as it stood at one
+// moment, but the block can still change until it is sealed: more entries get added, entries held for the block
+// (by SubmitAtHeight, the Sequencer or BeforeSeal) only show up as it is sealed, and a block whose hashing
+// panics is dropped.  Entries submitted but not yet taken by Run aren't in it.  Run has to be running, and
+// PendingBlock can't be called from the go routine running it.
+func (a *Accumulator) PendingBlock() (map[types.Hash][]types.Hash, error) {
+	pending := make(chan map[types.Hash][]types.Hash, 1)
+	query := func() {
+		a.settle() // The partitions add to the chains' MDs
+		block := make(map[types.Hash][]types.Hash, len(a.chains))
+		for chainID, chain := range a.chains {
+			block[chainID] = append([]types.Hash(nil), chain.MD.HashList[chain.Carried:]...)
+		}
+		pending <- block
+	}
+	select {
+	case a.queries <- query:
+	case <-a.stopped:
+		return nil, errors.New("the accumulator has stopped")
+	}
+	return <-pending, nil
+}
@@ -0,0 +1,60 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestPendingBlock(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	sealed := make(chan *node.Node, 1)
+	acc.OnCommit = func(directoryBlock *node.Node) { sealed <- directoryBlock }
+	chainA := types.Hash(sha256.Sum256([]byte("pending a")))
+	chainB := types.Hash(sha256.Sum256([]byte("pending b")))
+	want := map[types.Hash][]types.Hash{}
+	for i := 0; i < 6; i++ {
+		chainID := chainA
+		if i%3 == 0 {
+			chainID = chainB
+		}
+		entry := GetTestEntry(chainID, i)
+		want[chainID] = append(want[chainID], entry.EntryHash)
+		acc.Submit(entry)
+	}
+	go acc.Run()
+	defer acc.Stop()
+
+	var pending map[types.Hash][]types.Hash
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var err error
+		if pending, err = acc.PendingBlock(); err != nil {
+			t.Fatal(err)
+		}
+		if len(pending[chainA])+len(pending[chainB]) == 6 || time.Now().After(deadline) {
+			break
+		}
+	}
+	if len(pending) != len(want) {
+		t.Fatalf("the pending block has %d chains, not %d", len(pending), len(want))
+	}
+	for chainID, entries := range want {
+		if fmt.Sprint(pending[chainID]) != fmt.Sprint(entries) {
+			t.Fatalf("chain %x has the pending entries %x, not %x", chainID[:4], pending[chainID], entries)
+		}
+	}
+	pending[chainA][0] = types.Hash{} // A copy, so changing it leaves the block alone
+	if again, _ := acc.PendingBlock(); again[chainA][0] != want[chainA][0] {
+		t.Error("changing the pending block shouldn't change the block")
+	}
+
+	acc.control <- true
+	<-sealed
+	if pending, err := acc.PendingBlock(); err != nil || len(pending) != 0 {
+		t.Errorf("the pending block should be empty once sealed, not %x (%v)", pending, err)
+	}
+}
@@ -0,0 +1,97 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// PinnedNodeKey
+// The key of a chain's node in a pinned block in the PinnedNode bucket: ChainID then height
+func PinnedNodeKey(chainID types.Hash, height types.BlockHeight) (key []byte) {
+	key = append(key, chainID.Bytes()...)
+	key = append(key, height.Bytes()...)
+	return key
+}
+
+// PinBlock
+// Pin the sealed block at the given height, so Prune (and MaxDBBytes) never prunes it.  Everything pruning
+// would delete is kept, and the block's chain nodes are indexed so they can still be read once the nodes
+// around them are pruned.  Returns an error for a block not sealed, or already pruned.  Pinning a pinned
+// block does nothing.  Don't call it alongside Prune.
+func (a *Accumulator) PinBlock(height types.BlockHeight) error {
+	r := a.Reader()
+	if r.Pinned(height) {
+		return nil
+	}
+	pruned, err := r.PrunedHeight()
+	if err != nil {
+		return err
+	}
+	if height < pruned {
+		return errors.New(fmt.Sprintf("the block at height %d has already been pruned", height))
+	}
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return err
+	}
+	chains, err := r.blockChains(directoryBlock)
+	if err != nil {
+		return err
+	}
+	batch := a.DB.NewBatch()
+	for _, ne := range chains {
+		hash, _, err := r.chainNodeAt(ne.ChainID, height)
+		if err != nil {
+			return err
+		}
+		batch.Put(types.PinnedNode, PinnedNodeKey(ne.ChainID, height), hash)
+	}
+	batch.Put(types.PinnedBlock, height.Bytes(), []byte{1})
+	return batch.Commit()
+}
+
+// UnpinBlock
+// Let the block at the given height be pruned again.  A block Prune has already passed over is pruned then
+// and there, as Prune would have pruned it.  Unpinning a block that isn't pinned does nothing.  Don't call it
+// alongside Prune.
+func (a *Accumulator) UnpinBlock(height types.BlockHeight) error {
+	r := a.Reader()
+	if !r.Pinned(height) {
+		return nil
+	}
+	pruned, err := r.PrunedHeight()
+	if err != nil {
+		return err
+	}
+	if height < pruned { // While the pins still find its nodes
+		batch := a.DB.NewBatch()
+		if _, err := a.pruneBlock(r, batch, newChainWalks(r, height+1), height); err != nil {
+			return err
+		}
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+	}
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return err
+	}
+	chains, err := r.blockChains(directoryBlock)
+	if err != nil {
+		return err
+	}
+	batch := a.DB.NewBatch()
+	for _, ne := range chains {
+		batch.Delete(types.PinnedNode, PinnedNodeKey(ne.ChainID, height))
+	}
+	batch.Delete(types.PinnedBlock, height.Bytes())
+	return batch.Commit()
+}
+
+// Pinned
+// Whether the block at the given height is pinned against pruning
+func (r *Reader) Pinned(height types.BlockHeight) bool {
+	return r.DB.Get(types.PinnedBlock, height.Bytes()) != nil
+}
@@ -0,0 +1,52 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FeedPolicy
+// What the accumulator does when it has something to send on a feed, but the feed is full because
+// nobody is reading it.
+type FeedPolicy int
+
+const (
+	DropOnNoReader FeedPolicy = iota // Log and drop what we were going to send, and keep producing blocks
+	BlockUntilRead                   // Wait until the reader takes it, stalling block production meanwhile
+)
+
+// PanicPolicy
+// What Run does if adding an entry or sealing a block panics.
+type PanicPolicy int
+
+const (
+	RecoverAndContinue PanicPolicy = iota // Log the panic, drop the entry or block, and keep running
+	PropagatePanics                       // Let the panic take down the accumulator
+)
+
+// BlockPolicy
+// The limits that decide when Run ends a block.  Each field works as the Accumulator field of the same
+// name; see UpdatePolicy.
+type BlockPolicy struct {
+	MaxEntriesPerBlock int           // Seal the block once it holds this many entries; zero for no limit
+	BlockInterval      time.Duration // End the block this long after opening it; zero to wait to be told
+	MaxBlockDuration   time.Duration // Seal the block this long after opening it, even if paused; zero for no limit
+}
+
+// Check
+// Return why the policy can't be used, or nil if it can
+func (p BlockPolicy) Check() error {
+	switch {
+	case p.MaxEntriesPerBlock < 0:
+		return errors.New(fmt.Sprintf("MaxEntriesPerBlock can't be negative, found %d", p.MaxEntriesPerBlock))
+	case p.BlockInterval < 0:
+		return errors.New(fmt.Sprintf("BlockInterval can't be negative, found %v", p.BlockInterval))
+	case p.MaxBlockDuration < 0:
+		return errors.New(fmt.Sprintf("MaxBlockDuration can't be negative, found %v", p.MaxBlockDuration))
+	case p.MaxBlockDuration > 0 && p.MaxBlockDuration < p.BlockInterval:
+		return errors.New(fmt.Sprintf("MaxBlockDuration %v is shorter than the BlockInterval %v",
+			p.MaxBlockDuration, p.BlockInterval))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestRecoverFromPanic(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("panics")))
+	bad := GetTestEntry(chainID, 1)
+	acc.Hasher = panicHasher{bad: bad.EntryHash}
+	metrics := countingMetrics{}
+	acc.Metrics = metrics
+
+	good := new(merkleDag.MD) // What we expect the chain to hold, without the bad entry
+	for i := 0; i < 5; i++ {
+		entry := GetTestEntry(chainID, i)
+		acc.entryFeed <- entry
+		if entry.EntryHash != bad.EntryHash {
+			good.AddToChain(entry.EntryHash)
+		}
+	}
+	runUntilIdle(acc)
+	acc.control <- true
+	runUntilIdle(acc)
+	if metrics[MetricPanics] != 1 {
+		t.Errorf("expected to recover from one panic, recovered from %d", metrics[MetricPanics])
+	}
+
+	// The accumulator should keep on producing blocks
+	acc.entryFeed <- GetTestEntry(chainID, 5)
+	runUntilIdle(acc)
+	acc.control <- true
+	runUntilIdle(acc)
+	if acc.height != 2 {
+		t.Fatalf("expected two blocks, the next height is %d", acc.height)
+	}
+	block, err := acc.Reader().GetDirectoryBlock(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.List) != 1 || block.List[0].MDRoot != *good.GetMDRoot() {
+		t.Error("the first block should hold every entry but the one that panicked")
+	}
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	acc.MaxEntriesPerBlock = 5
+	chainID := types.Hash(sha256.Sum256([]byte("reconfigured")))
+	next := 0
+	feed := func(n int) {
+		for i := 0; i < n; i++ {
+			acc.entryFeed <- GetTestEntry(chainID, next)
+			next++
+		}
+		runUntilIdle(acc)
+	}
+	entriesAt := func(height types.BlockHeight) int {
+		count, err := acc.Reader().GetBlockEntryCount(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	for _, bad := range []BlockPolicy{{MaxEntriesPerBlock: -1}, {BlockInterval: -time.Second},
+		{BlockInterval: time.Minute, MaxBlockDuration: time.Second}} {
+		if acc.UpdatePolicy(bad) == nil {
+			t.Errorf("the policy %+v should be rejected", bad)
+		}
+	}
+
+	// The block in flight keeps the old limit
+	feed(2)
+	if err := acc.UpdatePolicy(BlockPolicy{MaxEntriesPerBlock: 3, BlockInterval: 10 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	feed(3)
+	if acc.height != 1 || entriesAt(0) != 5 {
+		t.Fatalf("the block in flight should be sealed at the old limit of 5 entries")
+	}
+
+	// Later blocks honor the new limits
+	feed(7)
+	if acc.height != 3 || entriesAt(1) != 3 || entriesAt(2) != 3 || acc.blockEntries != 1 {
+		t.Fatalf("expected blocks of 3 entries under the new policy, the next height is %d", acc.height)
+	}
+	clock.now = clock.now.Add(9 * time.Second)
+	acc.step()
+	if acc.height != 3 {
+		t.Error("the block should stay open until the BlockInterval is up")
+	}
+	clock.now = clock.now.Add(time.Second)
+	acc.step()
+	if acc.height != 4 || entriesAt(3) != 1 {
+		t.Error("the block should be ended once the BlockInterval is up")
+	}
+
+	// Paused, only MaxBlockDuration ends a block
+	if err := acc.UpdatePolicy(BlockPolicy{BlockInterval: 10 * time.Second, MaxBlockDuration: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(10 * time.Second)
+	acc.step() // The interval ends the empty block at height 4 under the old policy
+	acc.Pause()
+	feed(1)
+	for i := 0; i < 5; i++ {
+		clock.now = clock.now.Add(10 * time.Second)
+		acc.step()
+	}
+	if acc.height != 5 {
+		t.Errorf("a paused block should stay open until MaxBlockDuration, the next height is %d", acc.height)
+	}
+	clock.now = clock.now.Add(10 * time.Second)
+	acc.step()
+	if acc.height != 6 || entriesAt(5) != 1 {
+		t.Errorf("a paused block should be sealed at MaxBlockDuration, the next height is %d", acc.height)
+	}
+}
@@ -0,0 +1,198 @@
+package accumulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Prune
+// Delete the chain nodes, precomputed receipts, entry sequences, entry timestamps and entry type counts of
+// every block below the given height.  The directory blocks are kept, so the chain of directory block roots
+// can still be walked and verified, and so is the MDRoot index, but receipts can no longer be built for
+// entries in pruned blocks.  Continuous chains are never pruned, since every later node of the chain depends
+// on their history.  Nor is the head of any chain, which its next node follows on from, and the entry index is
+// kept so entries in pruned blocks are still dropped as duplicates.  Blocks pinned with PinBlock are passed
+// over, and keep everything.  Each block is pruned in one batch, along with the raising of the PrunedHeight
+// past it.  Pruning leaves dead space in the database; Compact gives it back.
+//
+// Only sealed blocks are touched, so Prune can run alongside Run, but below must be at or under the height
+// of the block being built.
+func (a *Accumulator) Prune(below types.BlockHeight) error {
+	_, err := a.prune(below)
+	return err
+}
+
+// prune
+// Prune below the given height, returning roughly how many bytes of keys and values were deleted
+func (a *Accumulator) prune(below types.BlockHeight) (freed int64, err error) {
+	r := a.Reader()
+	from, err := r.PrunedHeight()
+	if err != nil {
+		return 0, err
+	}
+	walks := newChainWalks(r, below)
+	// Go up from the oldest block, so walking back from a chain's head never runs into a pruned node
+	for height := from; height < below; height++ {
+		batch := a.DB.NewBatch()
+		if !r.Pinned(height) {
+			deleted, err := a.pruneBlock(r, batch, walks, height)
+			if err != nil {
+				return freed, err
+			}
+			freed += deleted
+		}
+		if err := batch.Put(types.PrunedHeight, a.chainID[:], (height + 1).Bytes()); err != nil {
+			return freed, err
+		}
+		if err := batch.Commit(); err != nil {
+			return freed, err
+		}
+	}
+	return freed, nil
+}
+
+// pruneBlock
+// Add the deletes of the chain nodes of the block at the given height, and what goes with them, as Prune
+// does, to the batch.  The nodes are found by the walks.  Returns the bytes of the keys and values deleted.
+func (a *Accumulator) pruneBlock(r *Reader, batch *database.Batch, walks *chainWalks, height types.BlockHeight) (freed int64, err error) {
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return 0, err
+	}
+	chains, err := r.blockChains(directoryBlock)
+	if err != nil {
+		return 0, err
+	}
+	drop := func(bucket types.Bucket, key []byte) error {
+		if value := a.DB.Get(bucket, key); value != nil {
+			freed += int64(len(key) + len(value))
+		}
+		return batch.Delete(bucket, key)
+	}
+	for _, ne := range chains {
+		if a.ContinuousChains[ne.ChainID] {
+			continue
+		}
+		hash, err := walks.nodeAt(ne.ChainID, height)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(a.DB.Get(types.NodeHead, ne.ChainID[:]), hash) {
+			continue
+		}
+		chainNode, err := r.GetNode(hash)
+		if err != nil {
+			return 0, err
+		}
+		for _, h := range chainNode.EntryList {
+			if err := drop(types.Receipt, ReceiptKey(ne.ChainID, h, height)); err != nil {
+				return 0, err
+			}
+			if err := drop(types.EntrySequence, EntrySequenceKey(ne.ChainID, h)); err != nil {
+				return 0, err
+			}
+			if err := drop(types.EntryTimeStamp, h[:]); err != nil {
+				return 0, err
+			}
+		}
+		if err := drop(types.EntryTypeCount, EntryTypeCountKey(ne.ChainID, height)); err != nil {
+			return 0, err
+		}
+		if err := drop(types.Node, hash); err != nil {
+			return 0, err
+		}
+	}
+	return freed, nil
+}
+
+// chainWalks
+// Finds the nodes of chains in the blocks below a height.  Each chain is walked back from its head once, down
+// to the first of its nodes asked for, and the hashes of its nodes below the height kept, rather than walking
+// back again for every block; so ask for them in height order, as Prune does.  The node of a pinned block is
+// looked up instead, as chainNodeAt does.
+type chainWalks struct {
+	r     *Reader
+	below types.BlockHeight
+	nodes map[types.Hash]map[types.BlockHeight][]byte
+}
+
+// newChainWalks
+// Walks for the nodes in the blocks below the given height
+func newChainWalks(r *Reader, below types.BlockHeight) *chainWalks {
+	return &chainWalks{r: r, below: below, nodes: make(map[types.Hash]map[types.BlockHeight][]byte)}
+}
+
+// nodeAt
+// The hash of the chain's node in the block at the given height
+func (w *chainWalks) nodeAt(chainID types.Hash, height types.BlockHeight) ([]byte, error) {
+	if hash := w.r.DB.Get(types.PinnedNode, PinnedNodeKey(chainID, height)); hash != nil {
+		return hash, nil
+	}
+	nodes, walked := w.nodes[chainID]
+	if !walked {
+		var err error
+		if nodes, err = w.walk(chainID, height); err != nil {
+			return nil, err
+		}
+		w.nodes[chainID] = nodes
+	}
+	hash := nodes[height]
+	if hash == nil {
+		return nil, errors.New(fmt.Sprintf("chain %x has no node at height %d", chainID, height))
+	}
+	return hash, nil
+}
+
+// walk
+// Walk back from the chain's head to its node at the given height, keeping the hashes of those below the
+// height the walks are for.  The walk goes no further, as the nodes further back may be pruned.
+func (w *chainWalks) walk(chainID types.Hash, down types.BlockHeight) (map[types.BlockHeight][]byte, error) {
+	nodes := make(map[types.BlockHeight][]byte)
+	hash, err := w.r.headHash(chainID)
+	if err != nil {
+		return nil, err
+	}
+	for hash != nil {
+		n, err := w.r.GetNodeHeader(hash)
+		if err != nil {
+			return nil, err
+		}
+		if n.BHeight < w.below {
+			nodes[n.BHeight] = hash
+		}
+		if n.BHeight <= down || n.SequenceNum == 0 { // Walked down to the height, or no further back to go
+			break
+		}
+		hash = n.Previous[:]
+	}
+	return nodes, nil
+}
+
+// Compact
+// Give back the space left by pruning, returning the bytes reclaimed.  Safe to run alongside Run and readers.
+func (a *Accumulator) Compact() (reclaimed int64, err error) {
+	if reclaimed, err = a.DB.Compact(); err != nil {
+		return 0, err
+	}
+	a.logger().Printf("compacted the database, reclaiming %d bytes", reclaimed)
+	return reclaimed, nil
+}
+
+// PrunedHeight
+// The lowest height whose chain nodes have not been pruned
+func (r *Reader) PrunedHeight() (types.BlockHeight, error) {
+	data := r.DB.Get(types.PrunedHeight, r.ChainID[:])
+	if data == nil {
+		return 0, nil
+	}
+	if len(data) != 4 {
+		return 0, errors.New(fmt.Sprintf("pruned height should be 4 bytes, found %d", len(data)))
+	}
+	var height types.BlockHeight
+	height.Extract(data)
+	return height, nil
+}
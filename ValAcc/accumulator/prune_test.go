@@ -0,0 +1,180 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestPruneAndCompact(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.PrecomputeReceipts = true
+	chain1 := types.Hash(sha256.Sum256([]byte("pruned 1")))
+	chain2 := types.Hash(sha256.Sum256([]byte("pruned 2")))
+	quiet := types.Hash(sha256.Sum256([]byte("only in the first block")))
+	acc.addEntry(GetTestEntry(quiet, 0))
+	for b := 0; b < 6; b++ {
+		for i := 0; i < 10; i++ {
+			acc.addEntry(GetTestEntry(chain1, b*10+i))
+			acc.addEntry(GetTestEntry(chain2, b*10+i))
+		}
+		acc.sealBlock()
+	}
+
+	if err := acc.Prune(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := acc.Prune(4); err != nil { // Picks up where the last prune stopped
+		t.Fatal(err)
+	}
+	if height, _ := acc.Reader().PrunedHeight(); height != 4 {
+		t.Errorf("expected to have pruned below 4, have pruned below %d", height)
+	}
+	reclaimed, err := acc.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed <= 0 {
+		t.Errorf("expected compaction to reclaim space, reclaimed %d bytes", reclaimed)
+	}
+
+	r := acc.Reader()
+	for height := types.BlockHeight(0); height < 6; height++ {
+		if _, err := r.GetDirectoryBlock(height); err != nil {
+			t.Errorf("directory block %d should be kept: %v", height, err)
+		}
+		entry := GetTestEntry(chain2, int(height)*10+3).EntryHash
+		receipt, err := r.GetReceipt(chain2, entry, height)
+		if height < 4 && err == nil {
+			t.Errorf("the receipt at height %d should be gone", height)
+		}
+		if height >= 4 && (err != nil || !receipt.Verify()) {
+			t.Errorf("the receipt at height %d should still verify: %v", height, err)
+		}
+		if _, err := r.GetChainNode(chain1, height); (err == nil) != (height >= 4) {
+			t.Errorf("chain node at height %d: %v", height, err)
+		}
+	}
+	walker := r.WalkBack(5)
+	for _, ok := walker.Next(); ok; _, ok = walker.Next() {
+	}
+	if walker.Err() != nil {
+		t.Errorf("the directory blocks should still walk back to genesis: %v", walker.Err())
+	}
+
+	// The head of a chain is kept, however old, so the chain can carry on from it
+	head, err := r.GetChainNode(quiet, 0)
+	if err != nil {
+		t.Fatalf("the head of a chain should never be pruned: %v", err)
+	}
+	acc.addEntry(GetTestEntry(quiet, 1))
+	acc.addEntry(GetTestEntry(chain2, 13)) // Sealed in block 1, which has been pruned
+	acc.sealBlock()
+	next, err := r.GetChainNode(quiet, 6)
+	if err != nil || next.Previous != *head.GetHash() || next.SequenceNum != 1 {
+		t.Fatalf("the chain should carry on from its old head (%v)", err)
+	}
+	if _, err := r.GetChainNode(chain2, 6); err == nil {
+		t.Error("an entry sealed in a pruned block should still be dropped as a duplicate")
+	}
+}
+
+func TestPruneSparseChain(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	busy := types.Hash(sha256.Sum256([]byte("in every block")))
+	sparse := types.Hash(sha256.Sum256([]byte("in every third block")))
+	for b := 0; b < 10; b++ {
+		acc.addEntry(GetTestEntry(busy, b))
+		if b%3 == 0 {
+			acc.addEntry(GetTestEntry(sparse, b))
+		}
+		acc.sealBlock()
+	}
+	for _, below := range []types.BlockHeight{2, 5, 8} { // Each prune starts between the sparse chain's nodes
+		if err := acc.Prune(below); err != nil {
+			t.Fatalf("pruning below %d: %v", below, err)
+		}
+	}
+	r := acc.Reader()
+	for height := types.BlockHeight(0); height < 10; height++ {
+		if _, err := r.GetChainNode(busy, height); (err == nil) != (height >= 8) {
+			t.Errorf("busy chain node at height %d: %v", height, err)
+		}
+	}
+	for _, height := range []types.BlockHeight{0, 3, 6} {
+		if _, err := r.GetChainNode(sparse, height); err == nil {
+			t.Errorf("the sparse chain's node at height %d should be pruned", height)
+		}
+	}
+	if _, err := r.GetChainNode(sparse, 9); err != nil {
+		t.Errorf("the sparse chain's head should be kept: %v", err)
+	}
+}
+
+func TestPinBlock(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.PrecomputeReceipts = true
+	chain1 := types.Hash(sha256.Sum256([]byte("pinned 1")))
+	chain2 := types.Hash(sha256.Sum256([]byte("pinned 2")))
+	for b := 0; b < 8; b++ {
+		for i := 0; i < 5; i++ {
+			acc.addEntry(GetTestEntry(chain1, b*10+i))
+			acc.addEntry(GetTestEntry(chain2, b*10+i))
+		}
+		acc.sealBlock()
+	}
+	if err := acc.PinBlock(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := acc.Prune(7); err != nil {
+		t.Fatal(err)
+	}
+	r := acc.Reader()
+	for height := types.BlockHeight(0); height < 8; height++ {
+		kept := height == 3 || height == 7
+		for _, chainID := range []types.Hash{chain1, chain2} {
+			if _, err := r.GetChainNode(chainID, height); (err == nil) != kept {
+				t.Errorf("chain node at height %d: %v", height, err)
+			}
+			receipt, err := r.GetReceipt(chainID, GetTestEntry(chainID, int(height)*10+2).EntryHash, height)
+			if kept && (err != nil || !receipt.Verify()) {
+				t.Errorf("the receipt at height %d should still verify: %v", height, err)
+			}
+		}
+	}
+	if acc.PinBlock(2) == nil {
+		t.Error("a block already pruned can't be pinned")
+	}
+
+	if err := acc.UnpinBlock(3); err != nil { // Pruned once it is let go, as Prune has passed it
+		t.Fatal(err)
+	}
+	if _, err := r.GetChainNode(chain1, 3); err == nil || r.Pinned(3) {
+		t.Error("the unpinned block should be pruned")
+	}
+
+	budgeted := GetTestAccumulator(t)
+	budgeted.MaxDBBytes = 1
+	budgeted.MinKeepBlocks = 2
+	for b := 0; b < 6; b++ {
+		budgeted.addEntry(GetTestEntry(chain1, b))
+		budgeted.addEntry(GetTestEntry(chain2, b))
+		budgeted.sealBlock()
+		if b == 1 {
+			if err := budgeted.PinBlock(1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	br := budgeted.Reader()
+	if height, _ := br.PrunedHeight(); height != 4 {
+		t.Errorf("expected the blocks below 4 to be pruned, not those below %d", height)
+	}
+	if _, err := br.GetChainNode(chain1, 1); err != nil {
+		t.Errorf("MaxDBBytes shouldn't prune a pinned block: %v", err)
+	}
+	if _, err := br.GetChainNode(chain1, 2); err == nil {
+		t.Error("the blocks around the pinned one should be pruned")
+	}
+}
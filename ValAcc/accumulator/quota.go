@@ -0,0 +1,77 @@
+package accumulator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+)
+
+// quotas
+// The entries each tenant has submitted in the current quota period
+type quotas struct {
+	mutex sync.Mutex
+	start time.Time      // When the current period started
+	used  map[string]int // Entries taken for each tenant in the period
+}
+
+// tenantQuota
+// The quota of the tenant; zero means no limit
+func (a *Accumulator) tenantQuota(tenant string) int {
+	if quota, ok := a.TenantQuotas[tenant]; ok {
+		return quota
+	}
+	return a.DefaultTenantQuota
+}
+
+// allowTenant
+// Count the entry against the quota of its chain's tenant.  Returns false if the tenant has used up its quota.
+func (a *Accumulator) allowTenant(entry node.EntryHash) bool {
+	tenant := a.TenantResolver(entry.ChainID)
+	quota := a.tenantQuota(tenant)
+	if quota <= 0 {
+		return true
+	}
+	q := &a.quotas
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if a.QuotaPeriod > 0 {
+		if start := a.clock().Now().Truncate(a.QuotaPeriod); !start.Equal(q.start) {
+			q.start, q.used = start, nil
+		}
+	}
+	if q.used == nil {
+		q.used = make(map[string]int)
+	}
+	if q.used[tenant] >= quota {
+		return false
+	}
+	q.used[tenant]++
+	return true
+}
+
+// refundTenant
+// Take back the count allowTenant made of an entry that was then refused after all
+func (a *Accumulator) refundTenant(entry node.EntryHash) {
+	tenant := a.TenantResolver(entry.ChainID)
+	if a.tenantQuota(tenant) <= 0 {
+		return
+	}
+	q := &a.quotas
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.used[tenant] > 0 {
+		q.used[tenant]--
+	}
+}
+
+// resetQuotas
+// Start the tenants' quotas over, as each block is sealed when they are per block
+func (a *Accumulator) resetQuotas() {
+	if a.TenantResolver == nil || a.QuotaPeriod > 0 {
+		return
+	}
+	a.quotas.mutex.Lock()
+	a.quotas.used = nil
+	a.quotas.mutex.Unlock()
+}
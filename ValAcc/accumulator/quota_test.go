@@ -0,0 +1,68 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestTenantQuotas(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)}
+	acc.Clock = clock
+	chainA1 := types.Hash(sha256.Sum256([]byte("tenant a 1")))
+	chainA2 := types.Hash(sha256.Sum256([]byte("tenant a 2")))
+	chainB := types.Hash(sha256.Sum256([]byte("tenant b")))
+	acc.TenantResolver = func(chainID types.Hash) string {
+		if chainID == chainB {
+			return "b"
+		}
+		return "a"
+	}
+	acc.TenantQuotas = map[string]int{"a": 3, "b": 100}
+	acc.QuotaPeriod = 24 * time.Hour
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+
+	for i := 0; i < 5; i++ { // Tenant a's chains share its quota
+		chainID := chainA1
+		if i%2 == 1 {
+			chainID = chainA2
+		}
+		if accepted := acc.Submit(GetTestEntry(chainID, i)); accepted != (i < 3) {
+			t.Errorf("entry %d of tenant a was accepted %v", i, accepted)
+		}
+		if !acc.Submit(GetTestEntry(chainB, i)) {
+			t.Errorf("entry %d of tenant b should be accepted while tenant a is over its quota", i)
+		}
+	}
+	if len(rejected) != 2 || rejected[0] != QuotaExceeded {
+		t.Errorf("the entries over the quota should be rejected as QuotaExceeded, not %v", rejected)
+	}
+
+	runUntilIdle(acc)
+	acc.SealBlock()
+	if acc.Submit(GetTestEntry(chainA1, 10)) {
+		t.Error("a daily quota shouldn't start over with the block")
+	}
+	clock.now = clock.now.Add(time.Hour) // Midnight
+	if !acc.Submit(GetTestEntry(chainA1, 11)) {
+		t.Error("tenant a's quota should start over with the day")
+	}
+
+	acc.QuotaPeriod = 0 // Per block
+	for i := 12; i < 14; i++ {
+		acc.Submit(GetTestEntry(chainA1, i))
+	}
+	if acc.Submit(GetTestEntry(chainA1, 14)) {
+		t.Error("tenant a should be over its quota for the block")
+	}
+	runUntilIdle(acc)
+	acc.SealBlock()
+	if !acc.Submit(GetTestEntry(chainA1, 15)) {
+		t.Error("a per block quota should start over with the next block")
+	}
+}
@@ -0,0 +1,68 @@
+package accumulator
+
+import (
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// RangeStats
+// Totals over the directory blocks from one height to another, for reporting
+type RangeStats struct {
+	From, To       types.BlockHeight // The heights covered, once clamped to the blocks sealed
+	Blocks         int               // Directory blocks in the range; zero for an empty range
+	Entries        uint64            // Entries sealed in them
+	Chains         int               // Distinct chains with a node in any of them
+	AverageEntries float64           // Entries per block
+	MinTimeStamp   types.TimeStamp   // TimeStamp of the earliest block
+	MaxTimeStamp   types.TimeStamp   // TimeStamp of the latest block
+}
+
+// RangeStats
+// Total up the directory blocks from fromHeight to toHeight (inclusive), clamping toHeight to the head.  A range
+// with no blocks sealed in it (toHeight below fromHeight, or fromHeight past the head) gives zero Blocks rather
+// than an error.  Only the directory blocks and the entry count index are read, not the chain nodes, so the
+// range can take in pruned blocks.
+func (r *Reader) RangeStats(fromHeight, toHeight types.BlockHeight) (*RangeStats, error) {
+	stats := &RangeStats{From: fromHeight, To: toHeight}
+	head, err := r.GetHead()
+	if err != nil {
+		return nil, err
+	}
+	if head != nil && stats.To > head.BHeight {
+		stats.To = head.BHeight
+	}
+	if head == nil || stats.To < stats.From {
+		return stats, nil
+	}
+	chains := make(map[types.Hash]bool)
+	for height := stats.From; ; height++ {
+		directoryBlock, err := r.GetDirectoryBlock(height)
+		if err != nil {
+			return nil, err
+		}
+		count, err := r.GetBlockEntryCount(height)
+		if err != nil {
+			return nil, err
+		}
+		list, err := r.blockChains(directoryBlock)
+		if err != nil {
+			return nil, err
+		}
+		for _, ne := range list {
+			chains[ne.ChainID] = true
+		}
+		if stats.Blocks == 0 || directoryBlock.TimeStamp < stats.MinTimeStamp {
+			stats.MinTimeStamp = directoryBlock.TimeStamp
+		}
+		if stats.Blocks == 0 || directoryBlock.TimeStamp > stats.MaxTimeStamp {
+			stats.MaxTimeStamp = directoryBlock.TimeStamp
+		}
+		stats.Blocks++
+		stats.Entries += uint64(count)
+		if height == stats.To { // Stop here, rather than loop, at the last height there is
+			break
+		}
+	}
+	stats.Chains = len(chains)
+	stats.AverageEntries = float64(stats.Entries) / float64(stats.Blocks)
+	return stats, nil
+}
@@ -0,0 +1,44 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestRangeStats(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	sizes := []int{1, 3, 0, 5, 2, 4}
+	for height, size := range sizes {
+		for i := 0; i < size; i++ { // Chain i has an entry in each block of more than i entries
+			chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprint("ranged ", i))))
+			acc.addEntry(GetTestEntry(chainID, height*10+i))
+		}
+		acc.sealBlock()
+		clock.now = clock.now.Add(time.Second)
+	}
+	r := acc.Reader()
+	stats, err := r.RangeStats(1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RangeStats{From: 1, To: 4, Blocks: 4, Entries: 10, Chains: 5, AverageEntries: 2.5,
+		MinTimeStamp: types.TimeStamp(time.Unix(1001, 0).UnixNano()), MaxTimeStamp: types.TimeStamp(time.Unix(1004, 0).UnixNano())}
+	if *stats != want {
+		t.Errorf("expected %+v over heights 1 to 4, got %+v", want, *stats)
+	}
+
+	if stats, err := r.RangeStats(3, 100); err != nil || stats.To != 5 || stats.Blocks != 3 || stats.Entries != 11 {
+		t.Errorf("the range should be clamped to the head (%v): %+v", err, stats)
+	}
+	for _, empty := range [][2]types.BlockHeight{{4, 2}, {6, 9}} {
+		if stats, err := r.RangeStats(empty[0], empty[1]); err != nil || stats.Blocks != 0 || stats.AverageEntries != 0 {
+			t.Errorf("heights %d to %d hold no blocks (%v): %+v", empty[0], empty[1], err, stats)
+		}
+	}
+}
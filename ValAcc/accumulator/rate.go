@@ -0,0 +1,57 @@
+package accumulator
+
+import (
+	"sort"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockRate
+// The blocks sealed in the trailing window (up to the Clock's now), and the entries in them, for spotting a
+// stalled accumulator (no blocks) or a runaway one.  A failure to read the database is logged, and counts as
+// no blocks.  May be called from any go routine.
+func (a *Accumulator) BlockRate(window time.Duration) (blocks int, entries int) {
+	since := types.TimeStamp(a.clock().Now().Add(-window).UnixNano())
+	blocks, entries, err := a.Reader().BlocksSince(since)
+	if err != nil {
+		a.logger().Printf("failed to count the blocks sealed in the last %v: %v", window, err)
+		return 0, 0
+	}
+	return blocks, entries
+}
+
+// BlocksSince
+// Count the directory blocks stamped at or after the given time, and the entries in them.  Block timestamps
+// only go up with height (so long as the accumulator's Clock doesn't go backwards), so the first block in the
+// window is found by a binary search over the heights, without an index.  Then the entries take a read of
+// each block in the window.
+func (r *Reader) BlocksSince(since types.TimeStamp) (blocks int, entries int, err error) {
+	head, err := r.GetHead()
+	if err != nil || head == nil {
+		return 0, 0, err
+	}
+	count := int(head.BHeight) + 1
+	first := sort.Search(count, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		directoryBlock, e := r.GetDirectoryBlock(types.BlockHeight(i))
+		if e != nil {
+			err = e
+			return true
+		}
+		return directoryBlock.TimeStamp >= since
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	for height := first; height < count; height++ {
+		n, err := r.GetBlockEntryCount(types.BlockHeight(height))
+		if err != nil {
+			return 0, 0, err
+		}
+		entries += n
+	}
+	return count - first, entries, nil
+}
@@ -0,0 +1,41 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestBlockRate(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	if blocks, entries := acc.BlockRate(time.Minute); blocks != 0 || entries != 0 {
+		t.Errorf("expected nothing before the first block, got %d blocks, %d entries", blocks, entries)
+	}
+	chainID := types.Hash(sha256.Sum256([]byte("rate")))
+	for i := 0; i < 10; i++ { // A block every 10 seconds, block i with i entries
+		for j := 0; j < i; j++ {
+			acc.addEntry(GetTestEntry(chainID, i*100+j))
+		}
+		acc.SealBlock()
+		clock.now = clock.now.Add(10 * time.Second)
+	}
+	// Blocks were sealed at 1000, 1010 ... 1090; it is now 1100
+	for _, c := range []struct {
+		window          time.Duration
+		blocks, entries int
+	}{
+		{5 * time.Second, 0, 0},
+		{10 * time.Second, 1, 9},
+		{35 * time.Second, 3, 9 + 8 + 7},
+		{time.Hour, 10, 45},
+	} {
+		if blocks, entries := acc.BlockRate(c.window); blocks != c.blocks || entries != c.entries {
+			t.Errorf("over %v expected %d blocks and %d entries, got %d and %d",
+				c.window, c.blocks, c.entries, blocks, entries)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Reader
+// Read only access to what an Accumulator has written to its database.  A Reader never writes (unless
+// RepairIndex is set), so any number of them can be used alongside a running accumulator.
+type Reader struct {
+	DB      *database.DB     // Database written by the accumulator
+	ChainID types.Hash       // Digital ID of the accumulator, i.e. the ChainID of its directory blocks
+	Hasher  merkleDag.Hasher // Hasher the accumulator builds its Merkle DAGs with; nil for sha256
+
+	// StrictReads has GetChainNode and GetChainHead check the EntryList of the node read gives its ListMDRoot
+	// (see checkNode), returning an error rather than a node corrupted in the database.  It costs rebuilding
+	// the chain's MD for every node read.
+	StrictReads bool
+
+	// RepairIndex has GetDirectoryBlock put back the entry of the height index it found missing (see
+	// walkToHeight), so the walk is taken only the once.
+	RepairIndex bool
+
+	bounded bool              // Set by ReaderAt, to read as of the height at
+	at      types.BlockHeight // Last height a Reader from ReaderAt sees
+	release func()            // Lets go of the snapshot of a Reader from ReaderAt
+}
+
+// NewReader
+// Get a Reader over the directory blocks of the accumulator with the given ChainID
+func NewReader(db *database.DB, chainID types.Hash) *Reader {
+	r := new(Reader)
+	r.DB = db
+	r.ChainID = chainID
+	return r
+}
+
+// Reader
+// Get a Reader over this accumulator's database
+func (a *Accumulator) Reader() *Reader {
+	r := NewReader(a.db(), *a.chainID)
+	r.Hasher = a.hasher()
+	return r
+}
+
+// newMD
+// Get an empty MD that hashes the way the accumulator does
+func (r *Reader) newMD() *merkleDag.MD {
+	md := new(merkleDag.MD)
+	md.Hasher = r.Hasher
+	return md
+}
+
+// forFlags
+// A Reader over the same database that hashes the way a block with the given flags was built
+func (r *Reader) forFlags(flags node.Flags) *Reader {
+	if !flags.Has(node.DomainSeparated) {
+		return r
+	}
+	rf := *r
+	rf.Hasher = merkleDag.DomainHasher{}
+	return &rf
+}
+
+// VerifyDirectoryBlock
+// Check the directory block's ListMDRoot against the chain roots it lists, using the algorithms its Flags
+// say it was built with, and for a Grouped block, the roots of the chains in its group against the group's
+// entry.  A SameRoot block is checked to be empty, with the root of the block before it.  Returns an error for
+// flags we don't know how to verify.
+func (r *Reader) VerifyDirectoryBlock(directoryBlock *node.Node) error {
+	if unsupported := directoryBlock.Flags &^ (node.DomainSeparated | node.Grouped | node.SameRoot); unsupported != 0 {
+		return errors.New(fmt.Sprintf("can't verify the directory block at height %d; %v blocks are not supported",
+			directoryBlock.BHeight, unsupported))
+	}
+	if directoryBlock.Flags.Has(node.SameRoot) {
+		return r.checkSameRoot(directoryBlock)
+	}
+	md := r.forFlags(directoryBlock.Flags).newMD()
+	for _, ne := range directoryBlock.List {
+		md.AddToChain(ne.MDRoot)
+	}
+	if root := *md.GetMDRoot(); root != directoryBlock.ListMDRoot {
+		return errors.New(fmt.Sprintf("the directory block at height %d has the ListMDRoot %x, but its chains give %x",
+			directoryBlock.BHeight, directoryBlock.ListMDRoot, root))
+	}
+	_, err := r.blockGroup(directoryBlock)
+	return err
+}
+
+// GetNode
+// Load and unmarshal the node with the given hash
+func (r *Reader) GetNode(hash []byte) (*node.Node, error) {
+	data := r.DB.Get(types.Node, hash)
+	if data == nil {
+		return nil, errors.New(fmt.Sprintf("node %x not found", hash))
+	}
+	n := new(node.Node)
+	if _, err := n.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GetNodeHeader
+// Load the node with the given hash, leaving its EntryList unpacked (see node.UnmarshalHeader)
+func (r *Reader) GetNodeHeader(hash []byte) (*node.Node, error) {
+	data := r.DB.Get(types.Node, hash)
+	if data == nil {
+		return nil, errors.New(fmt.Sprintf("node %x not found", hash))
+	}
+	n := new(node.Node)
+	if _, err := n.UnmarshalHeader(data); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GetChainHead
+// Return the last node a chain wrote, or nil if the chain has none.  Only the header is unpacked; the node's
+// EntryCount and ListMDRoot are there, but call LoadEntryList for its EntryList.
+func (r *Reader) GetChainHead(chainID types.Hash) (*node.Node, error) {
+	hash, err := r.headHash(chainID)
+	if hash == nil || err != nil {
+		return nil, err
+	}
+	n, err := r.GetNodeHeader(hash)
+	if err != nil || !r.StrictReads {
+		return n, err
+	}
+	if err := r.checkNode(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// checkNode
+// Check a chain node's EntryList gives its ListMDRoot, hashing as its directory block says.  A node whose
+// entries alone don't give it may be a continuous chain's, so is checked again over the chain's history.
+func (r *Reader) checkNode(n *node.Node) error {
+	directoryBlock, err := r.GetDirectoryBlock(n.BHeight)
+	if err != nil {
+		return err
+	}
+	if n.VerifyEntryListRoot(r.forFlags(directoryBlock.Flags).Hasher) == nil {
+		return nil
+	}
+	n.LoadEntryList()
+	return r.checkEntries(n.ChainID, n.BHeight, directoryBlock.Flags, n.EntryList, n.ListMDRoot)
+}
+
+// GetDirectoryBlock
+// Return the directory block at the given height
+func (r *Reader) GetDirectoryBlock(height types.BlockHeight) (*node.Node, error) {
+	if r.bounded && height > r.at {
+		return nil, errors.New(fmt.Sprintf("no directory block at height %d as of height %d", height, r.at))
+	}
+	hash := r.DB.GetInt32(types.DirectoryBlockHeight, uint32(height))
+	if hash == nil {
+		var err error
+		if hash, err = r.walkToHeight(height); err != nil {
+			return nil, err
+		}
+	}
+	return r.directoryBlock(hash)
+}
+
+// walkToHeight
+// Find the hash of the directory block at a height missing from the height index, by walking the Previous
+// hashes back from the head.  Only the headers are unpacked on the way.  With RepairIndex set, the hash found is
+// put back in the index.
+func (r *Reader) walkToHeight(height types.BlockHeight) ([]byte, error) {
+	hash, err := r.headHash(r.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	for hash != nil {
+		n, err := r.GetNodeHeader(hash)
+		if err != nil {
+			return nil, err
+		}
+		if n.BHeight < height {
+			break
+		}
+		if n.BHeight == height {
+			if r.RepairIndex {
+				if err := r.DB.PutInt32(types.DirectoryBlockHeight, int(height), hash); err != nil {
+					return nil, err
+				}
+			}
+			return hash, nil
+		}
+		if n.BHeight == 0 {
+			break
+		}
+		hash = append([]byte{}, n.Previous[:]...)
+	}
+	return nil, errors.New(fmt.Sprintf("no directory block at height %d", height))
+}
+
+// GetHead
+// Return the last directory block sealed, or nil if there are none
+func (r *Reader) GetHead() (*node.Node, error) {
+	hash, err := r.headHash(r.ChainID)
+	if hash == nil || err != nil {
+		return nil, err
+	}
+	return r.directoryBlock(hash)
+}
+
+// directoryBlock
+// Load the directory block with the given hash, with its ListMDRoot filled in if it is a SameRoot block
+func (r *Reader) directoryBlock(hash []byte) (*node.Node, error) {
+	n, err := r.GetNode(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.sameRoot(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GetBlockEntryCount
+// Return the number of entries, over all the chains, in the directory block at the given height
+func (r *Reader) GetBlockEntryCount(height types.BlockHeight) (int, error) {
+	data := r.DB.GetInt32(types.BlockEntryCount, uint32(height))
+	if data == nil {
+		return 0, errors.New(fmt.Sprintf("no entry count for a directory block at height %d", height))
+	}
+	if len(data) != 4 {
+		return 0, errors.New(fmt.Sprintf("entry count should be 4 bytes, found %d", len(data)))
+	}
+	count, _ := types.BytesUint32(data)
+	return int(count), nil
+}
+
+// GetChainNode
+// Return the node a chain wrote in the block at the given height.  We walk back from the chain's head,
+// so this is fastest for recent blocks.  Only the headers of the nodes walked past are unpacked.
+func (r *Reader) GetChainNode(chainID types.Hash, height types.BlockHeight) (*node.Node, error) {
+	_, n, err := r.chainNodeAt(chainID, height)
+	if err != nil {
+		return nil, err
+	}
+	n.LoadEntryList()
+	if r.StrictReads {
+		if err := r.checkNode(n); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// chainNodeAt
+// Walk back from the chain's head to its node at the given height, returning the node's hash and its header.
+// The node of a pinned block is looked up instead, as the nodes after it may have been pruned.
+func (r *Reader) chainNodeAt(chainID types.Hash, height types.BlockHeight) ([]byte, *node.Node, error) {
+	if hash := r.DB.Get(types.PinnedNode, PinnedNodeKey(chainID, height)); hash != nil {
+		n, err := r.GetNodeHeader(hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		return hash, n, nil
+	}
+	hash, err := r.headHash(chainID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for hash != nil {
+		n, err := r.GetNodeHeader(hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n.BHeight == height {
+			return hash, n, nil
+		}
+		if n.BHeight < height || n.SequenceNum == 0 { // Walked past the height, or no further back to go
+			break
+		}
+		hash = n.Previous[:]
+	}
+	return nil, nil, errors.New(fmt.Sprintf("chain %x has no node at height %d", chainID, height))
+}
+
+// GetReceipt
+// Return the receipt proving the entry was added to the chain in the directory block at the given height.
+// If the receipt was precomputed when the block was sealed, it is read straight from the database.
+// Otherwise it is built from the directory block and the chain's node.
+func (r *Reader) GetReceipt(chainID, entry types.Hash, height types.BlockHeight) (*Receipt, error) {
+	if data := r.DB.Get(types.Receipt, ReceiptKey(chainID, entry, height)); data != nil {
+		receipt := new(Receipt)
+		if err := receipt.Unmarshal(data); err != nil {
+			return nil, err
+		}
+		receipt.Hasher = r.Hasher
+		return receipt, nil
+	}
+
+	chainReceipt, entryMD, flags, err := r.chainProof(chainID, height)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildReceipt(chainID, entry, height, chainReceipt, entryMD, flags)
+}
+
+// buildReceipt
+// Put together the receipt for an entry from the proof of its chain's MDRoot given by chainProof
+func (r *Reader) buildReceipt(chainID, entry types.Hash, height types.BlockHeight, chainReceipt *merkleDag.MDReceipt,
+	entryMD *merkleDag.MD, flags node.Flags) (*Receipt, error) {
+	receipt := new(Receipt)
+	receipt.Height = height
+	receipt.ChainID = chainID
+	receipt.Flags = flags
+	receipt.Hasher = r.Hasher
+	receipt.ChainReceipt = *chainReceipt
+	receipt.EntryReceipt.BuildMDReceipt(*entryMD, entry)
+	if len(receipt.EntryReceipt.Nodes) == 0 && receipt.EntryReceipt.MDRoot != entry {
+		return nil, errors.New(fmt.Sprintf("entry %x is not in chain %x at height %d", entry, chainID, height))
+	}
+	return receipt, nil
+}
+
+// StreamReceipts
+// Build a receipt for each entry read from entries, proving it was added to the chain in the directory block
+// at the given height, and send it to out.  Entries not in the chain at that height are skipped.  The chain's
+// entries are loaded once, and each receipt is built and sent before the next entry is read, so memory is
+// bounded by the size of the chain rather than the number of receipts: about 37 bytes per entry in the chain
+// (its hash, an index to find it, and the upper levels of the Merkle DAG), plus the receipt in flight.  out
+// is closed when entries is closed, or on an error, in which case nothing more is read from entries.
+func (r *Reader) StreamReceipts(chainID types.Hash, height types.BlockHeight, entries <-chan types.Hash, out chan<- *Receipt) error {
+	defer close(out)
+	chainReceipt, entryMD, flags, err := r.chainProof(chainID, height)
+	if err != nil {
+		return err
+	}
+	builder := merkleDag.NewReceiptBuilder(*entryMD)
+	for entry := range entries {
+		entryReceipt := builder.Receipt(entry)
+		if entryReceipt == nil {
+			continue
+		}
+		receipt := new(Receipt)
+		receipt.Height = height
+		receipt.ChainID = chainID
+		receipt.Flags = flags
+		receipt.Hasher = r.Hasher
+		receipt.ChainReceipt = *chainReceipt
+		receipt.ChainReceipt.Nodes = append([]*merkleDag.ReceiptNode{}, chainReceipt.Nodes...)
+		receipt.EntryReceipt = *entryReceipt
+		out <- receipt
+	}
+	return nil
+}
+
+// GetBatchReceipt
+// Return one receipt proving all the given entries were added to the chain in the directory block at the given
+// height.  The nodes the entries' paths share are carried once, so for many entries of a chain it is much
+// smaller than a receipt apiece.  Returns an error if any of the entries isn't in the chain at that height.
+func (r *Reader) GetBatchReceipt(chainID types.Hash, entries []types.Hash, height types.BlockHeight) (*BatchReceipt, error) {
+	chainReceipt, entryMD, flags, err := r.chainProof(chainID, height)
+	if err != nil {
+		return nil, err
+	}
+	entryReceipt, err := merkleDag.BuildBatchReceipt(*entryMD, entries)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("can't prove the entries of chain %x at height %d: %v", chainID, height, err))
+	}
+	receipt := new(BatchReceipt)
+	receipt.Height = height
+	receipt.ChainID = chainID
+	receipt.Flags = flags
+	receipt.Hasher = r.Hasher
+	receipt.ChainReceipt = *chainReceipt
+	receipt.EntryReceipt = *entryReceipt
+	return receipt, nil
+}
+
+// chainProof
+// Build the receipt proving the chain's MDRoot is in the directory block at the given height, and the MD
+// of the entries that MDRoot covers (the chain's whole history for a continuous chain), along with the
+// directory block's Flags.
+func (r *Reader) chainProof(chainID types.Hash, height types.BlockHeight) (*merkleDag.MDReceipt, *merkleDag.MD, node.Flags, error) {
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return r.proveChain(directoryBlock, chainID, nil)
+}
+
+// proveChain
+// chainProof, given the directory block, and the chain's node in it if it has already been loaded (nil if
+// not, to have it found once the chain is known to be in the block)
+func (r *Reader) proveChain(directoryBlock *node.Node, chainID types.Hash, chainNode *node.Node) (*merkleDag.MDReceipt, *merkleDag.MD, node.Flags, error) {
+	height := directoryBlock.BHeight
+	built := r.forFlags(directoryBlock.Flags) // Hash the way the block was built
+	chainMD := built.newMD()
+	var chainRoot, groupRoot *types.Hash
+	for _, ne := range directoryBlock.List {
+		chainMD.AddToChain(ne.MDRoot)
+		switch ne.ChainID {
+		case chainID:
+			chainRoot = ne.MDRoot.Copy()
+		case GroupChainID:
+			groupRoot = ne.MDRoot.Copy()
+		}
+	}
+	chainReceipt := new(merkleDag.MDReceipt)
+	if chainRoot != nil {
+		chainReceipt.BuildMDReceipt(*chainMD, *chainRoot)
+	} else if groupRoot != nil { // The chain may be in the group, a level below the List
+		group, err := r.blockGroup(directoryBlock)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		groupMD := built.newMD()
+		for _, ne := range group {
+			groupMD.AddToChain(ne.MDRoot)
+			if ne.ChainID == chainID {
+				chainRoot = ne.MDRoot.Copy()
+			}
+		}
+		if chainRoot != nil {
+			groupReceipt, listReceipt := new(merkleDag.MDReceipt), new(merkleDag.MDReceipt)
+			groupReceipt.BuildMDReceipt(*groupMD, *chainRoot)
+			listReceipt.BuildMDReceipt(*chainMD, *groupRoot)
+			chainReceipt = joinPaths(groupReceipt, listReceipt)
+		}
+	}
+	if chainRoot == nil {
+		return nil, nil, 0, errors.New(fmt.Sprintf("chain %x is not in the directory block at height %d", chainID, height))
+	}
+
+	if chainNode == nil {
+		var err error
+		if chainNode, err = r.GetChainNode(chainID, height); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	entryMD := built.newMD()
+	for _, h := range chainNode.EntryList {
+		entryMD.AddToChain(h)
+	}
+	continuous, recorded, err := r.continuousChain(chainID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if !recorded { // Params older than the record; the node's entries not giving its root says it's continuous
+		continuous = *entryMD.GetMDRoot() != chainNode.ListMDRoot
+	}
+	if continuous { // The root covers the chain's history
+		if entryMD, err = built.chainMDTo(chainID, height); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	return chainReceipt, entryMD, directoryBlock.Flags, nil
+}
+
+// TotalEntries
+// The count of entries in all the blocks the accumulator has sealed
+func (r *Reader) TotalEntries() (uint64, error) {
+	data := r.DB.Get(types.TotalEntries, r.ChainID[:])
+	if data == nil {
+		return 0, nil
+	}
+	if len(data) != 8 {
+		return 0, errors.New(fmt.Sprintf("total entries should be 8 bytes, found %d", len(data)))
+	}
+	total, _ := types.BytesUint64(data)
+	return total, nil
+}
+
+// ChainStorageSize
+// Sum up what a chain takes in the database: the bytes of its nodes, and of each entry's records (the
+// entry itself if a validator stored it, its index, precomputed receipt and sequence), along with the
+// count of its entries.  Nodes that have been pruned take no space, and their entries aren't counted.
+func (r *Reader) ChainStorageSize(chainID types.Hash) (bytes uint64, entries uint64, err error) {
+	size := func(bucket types.Bucket, key []byte) uint64 {
+		if value := r.DB.Get(bucket, key); value != nil {
+			return uint64(len(key) + len(value))
+		}
+		return 0
+	}
+	for hash := r.DB.Get(types.NodeFirst, chainID[:]); hash != nil; hash = r.DB.Get(types.NodeNext, hash) {
+		data := r.DB.Get(types.Node, hash)
+		if data == nil { // Pruned
+			continue
+		}
+		n := new(node.Node)
+		if _, err := n.Unmarshal(data); err != nil {
+			return 0, 0, err
+		}
+		bytes += uint64(len(hash) + len(data))
+		for _, h := range n.EntryList {
+			bytes += size(types.Entry, h[:])
+			bytes += size(types.EntryNode, h[:])
+			bytes += size(types.Receipt, ReceiptKey(chainID, h, n.BHeight))
+			bytes += size(types.EntrySequence, EntrySequenceKey(chainID, h))
+		}
+		entries += uint64(len(n.EntryList))
+	}
+	return bytes, entries, nil
+}
+
+// GetCrossChainProof
+// Return a proof that entryA in chainX and entryB in chainY were both sealed in the directory block at
+// the given height.  Returns an error if either entry isn't in its chain at that height.
+func (r *Reader) GetCrossChainProof(chainX, entryA, chainY, entryB types.Hash, height types.BlockHeight) (*CrossChainProof, error) {
+	a, err := r.GetReceipt(chainX, entryA, height)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.GetReceipt(chainY, entryB, height)
+	if err != nil {
+		return nil, err
+	}
+	return &CrossChainProof{Height: height, A: *a, B: *b}, nil
+}
+
+// chainMDTo
+// Rebuild the MD of a chain over all of its nodes up to and including the node at the given height.  Returns
+// an error if any of them can't be read (as once pruned).
+func (r *Reader) chainMDTo(chainID types.Hash, height types.BlockHeight) (*merkleDag.MD, error) {
+	md := r.newMD()
+	for hash := r.DB.Get(types.NodeFirst, chainID[:]); hash != nil; hash = r.DB.Get(types.NodeNext, hash) {
+		n, err := r.GetNode(hash)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("can't rebuild chain %x to height %d: %v", chainID, height, err))
+		}
+		if n.BHeight > height {
+			break
+		}
+		for _, h := range n.EntryList {
+			md.AddToChain(h)
+		}
+	}
+	return md, nil
+}
@@ -0,0 +1,265 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestTotalEntries(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("counted")))
+	other := types.Hash(sha256.Sum256([]byte("also counted")))
+	n := 0
+	for _, size := range []int{3, 0, 7} {
+		for i := 0; i < size; i++ {
+			acc.addEntry(GetTestEntry(chainID, n))
+			acc.addEntry(GetTestEntry(other, n))
+			n++
+		}
+		acc.sealBlock()
+	}
+	acc.addEntry(GetTestEntry(chainID, n))
+	acc.addEntry(GetTestEntry(chainID, n)) // Duplicates are not accumulated, so don't count
+	n++
+	acc.sealBlock()
+	if total, err := acc.TotalEntries(); err != nil || total != 21 {
+		t.Errorf("expected 21 entries, got %d (%v)", total, err)
+	}
+
+	restarted := new(Accumulator)
+	restarted.Init(acc.DB, acc.chainID)
+	for i := 0; i < 5; i++ {
+		restarted.addEntry(GetTestEntry(chainID, n+i))
+	}
+	restarted.sealBlock()
+	total, err := restarted.TotalEntries()
+	if err != nil || total != 26 {
+		t.Errorf("expected 26 entries after the restart, got %d (%v)", total, err)
+	}
+
+	var sum uint64 // The total has to match the entries in the blocks
+	walker := restarted.Reader().WalkBack(restarted.height - 1)
+	for block, ok := walker.Next(); ok; block, ok = walker.Next() {
+		for _, ne := range block.List {
+			chain, err := restarted.Reader().GetChainNode(ne.ChainID, block.BHeight)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum += uint64(len(chain.EntryList))
+		}
+	}
+	if sum != total {
+		t.Errorf("the blocks hold %d entries, but the total is %d", sum, total)
+	}
+}
+
+func TestGetBlockEntryCount(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.ContinuousChains = map[types.Hash]bool{}
+	var chains []types.Hash
+	for c := 0; c < 5; c++ {
+		chains = append(chains, types.Hash(sha256.Sum256([]byte(fmt.Sprintf("counting %d", c)))))
+	}
+	acc.ContinuousChains[chains[0]] = true // Only the entries added in the block count, not those carried
+	for _, size := range []int{17, 4} {
+		for i := 0; i < size; i++ {
+			acc.addEntry(GetTestEntry(chains[i%len(chains)], i+size*100))
+		}
+		block := acc.sealBlock()
+		count, err := acc.Reader().GetBlockEntryCount(block.BHeight)
+		if err != nil || count != size {
+			t.Errorf("expected %d entries in block %d, got %d (%v)", size, block.BHeight, count, err)
+		}
+	}
+	if _, err := acc.Reader().GetBlockEntryCount(2); err == nil {
+		t.Error("expected an error for a block not yet sealed")
+	}
+}
+
+func TestChainStorageSize(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("sized")))
+	other := types.Hash(sha256.Sum256([]byte("not sized")))
+	for b := 0; b < 3; b++ {
+		for i := 0; i < 5; i++ {
+			acc.addEntry(GetTestEntry(chainID, b*5+i))
+			acc.addEntry(GetTestEntry(other, b*5+i))
+		}
+		acc.sealBlock()
+	}
+	bytes, entries, err := acc.Reader().ChainStorageSize(chainID)
+	if err != nil || entries != 15 || bytes == 0 {
+		t.Fatalf("expected 15 entries in a non zero number of bytes, got %d entries in %d bytes (%v)", entries, bytes, err)
+	}
+	if _, entries, _ := acc.Reader().ChainStorageSize(types.Hash{}); entries != 0 {
+		t.Error("a chain that doesn't exist should have no entries")
+	}
+
+	if err := acc.Prune(1); err != nil {
+		t.Fatal(err)
+	}
+	pruned, entries, err := acc.Reader().ChainStorageSize(chainID)
+	if err != nil || entries != 10 || pruned >= bytes {
+		t.Errorf("pruning a block should leave 10 entries in fewer bytes, got %d entries in %d bytes", entries, pruned)
+	}
+}
+
+func TestStreamReceipts(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("streamed")))
+	other := types.Hash(sha256.Sum256([]byte("not streamed")))
+	const size = 20000
+	for i := 0; i < size; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	acc.addEntry(GetTestEntry(other, 0))
+	block := acc.sealBlock()
+
+	entries := make(chan types.Hash)
+	out := make(chan *Receipt)
+	go func() {
+		for i := 0; i < size; i++ {
+			entries <- GetTestEntry(chainID, i).EntryHash
+			if i == size/2 {
+				entries <- GetTestEntry(other, 0).EntryHash // Not in the chain, so skipped
+			}
+		}
+		close(entries)
+	}()
+	done := make(chan error, 1)
+	go func() { done <- acc.Reader().StreamReceipts(chainID, block.BHeight, entries, out) }()
+
+	count := 0
+	for receipt := range out {
+		if receipt.EntryReceipt.EntryHash != GetTestEntry(chainID, count).EntryHash {
+			t.Fatalf("receipt %d is for the wrong entry", count)
+		}
+		if !receipt.Verify() || receipt.ChainReceipt.MDRoot != block.ListMDRoot {
+			t.Fatalf("receipt %d failed to verify against the directory block", count)
+		}
+		count++
+	}
+	if err := <-done; err != nil || count != size {
+		t.Fatalf("expected %d receipts, got %d (%v)", size, count, err)
+	}
+
+	// A height with no block is an error, and out is still closed
+	out = make(chan *Receipt)
+	go func() { done <- acc.Reader().StreamReceipts(chainID, block.BHeight+1, entries, out) }()
+	for range out {
+		t.Error("got a receipt for a block that doesn't exist")
+	}
+	if err := <-done; err == nil {
+		t.Error("expected an error streaming receipts for a block that doesn't exist")
+	}
+}
+
+func TestGetChainHead(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("head")))
+	if head, err := acc.Reader().GetChainHead(chainID); head != nil || err != nil {
+		t.Errorf("a chain with no nodes has no head, got %v (%v)", head, err)
+	}
+	for i := 0; i < 1000; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	acc.SealBlock()
+	acc.addEntry(GetTestEntry(chainID, 1000))
+	acc.addEntry(GetTestEntry(chainID, 1001))
+	acc.SealBlock()
+
+	r := acc.Reader()
+	head, err := r.GetChainHead(chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := r.GetChainNode(chainID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.EntryList != nil || head.EntryCount() != 2 || head.ListMDRoot != full.ListMDRoot {
+		t.Error("the head should have the node's root and entry count, without its EntryList")
+	}
+	if first, err := r.GetChainNode(chainID, 0); err != nil || len(first.EntryList) != 1000 {
+		t.Errorf("walking back past the head should still give whole nodes (%v)", err)
+	}
+	head.LoadEntryList()
+	if !head.SameAs(*full) {
+		t.Error("the head should be whole once its EntryList is loaded")
+	}
+}
+
+func TestStrictReads(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("read strictly")))
+	continuous := types.Hash(sha256.Sum256([]byte("read strictly, continuously")))
+	acc.ContinuousChains = map[types.Hash]bool{continuous: true}
+	for height := 0; height < 2; height++ {
+		for i := 0; i < 5; i++ {
+			acc.addEntry(GetTestEntry(chainID, height*10+i))
+			acc.addEntry(GetTestEntry(continuous, height*10+i))
+		}
+		acc.sealBlock()
+	}
+	r := acc.Reader()
+	r.StrictReads = true
+	for _, id := range []types.Hash{chainID, continuous} {
+		if _, err := r.GetChainNode(id, 1); err != nil {
+			t.Errorf("a good node should be read: %v", err)
+		}
+		if _, err := r.GetChainHead(id); err != nil {
+			t.Errorf("a good head should be read: %v", err)
+		}
+	}
+
+	hash := r.DB.Get(types.NodeHead, chainID[:])
+	data := r.DB.Get(types.Node, hash)
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-1] ^= 1 // The last byte of the last entry
+	r.DB.Put(types.Node, hash, corrupt)
+	if _, err := r.GetChainNode(chainID, 1); err == nil {
+		t.Error("a node with a flipped entry byte shouldn't be read")
+	}
+	if _, err := r.GetChainHead(chainID); err == nil {
+		t.Error("a head with a flipped entry byte shouldn't be read")
+	}
+	r.StrictReads = false
+	if _, err := r.GetChainNode(chainID, 1); err != nil {
+		t.Errorf("without StrictReads, the node is read as it is stored: %v", err)
+	}
+}
+
+func TestMissingHeightIndex(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	var blocks []*node.Node
+	for height := 0; height < 4; height++ {
+		acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("indexed"))), height))
+		blocks = append(blocks, acc.sealBlock())
+	}
+	acc.DB.Delete(types.DirectoryBlockHeight, types.Uint32Bytes(1))
+
+	r := acc.Reader()
+	block, err := r.GetDirectoryBlock(1)
+	if err != nil || *block.GetHash() != *blocks[1].GetHash() {
+		t.Fatalf("the block missing from the index should be found from the head (%v)", err)
+	}
+	if acc.DB.GetInt32(types.DirectoryBlockHeight, 1) != nil {
+		t.Error("the index should be left alone without RepairIndex")
+	}
+	if _, err := r.GetDirectoryBlock(4); err == nil {
+		t.Error("there is no block past the head to find")
+	}
+
+	r.RepairIndex = true
+	if block, err := r.GetDirectoryBlock(1); err != nil || *block.GetHash() != *blocks[1].GetHash() {
+		t.Fatalf("the block should still be found with RepairIndex (%v)", err)
+	}
+	if hash := acc.DB.GetInt32(types.DirectoryBlockHeight, 1); hash == nil || !bytes.Equal(hash, blocks[1].GetHash()[:]) {
+		t.Error("RepairIndex should put the block back in the index")
+	}
+}
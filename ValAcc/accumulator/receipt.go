@@ -0,0 +1,252 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Receipt
+// Proves an entry was recorded in a chain in a particular directory block.  The EntryReceipt proves
+// the entry hash is in the chain's ListMDRoot, and the ChainReceipt proves the chain's ListMDRoot is
+// in the ListMDRoot of the directory block.
+type Receipt struct {
+	Height       types.BlockHeight   // Height of the directory block
+	ChainID      types.Hash          // Chain holding the entry
+	Flags        node.Flags          // Flags of the directory block, which say how its hashes are combined
+	EntryReceipt merkleDag.MDReceipt // Entry hash -> chain ListMDRoot
+	ChainReceipt merkleDag.MDReceipt // chain ListMDRoot -> directory block ListMDRoot
+
+	// Hasher is the custom Hasher the block was built with, if any; nil for sha256.  The Flags override it for
+	// DomainSeparated blocks.  It isn't marshaled, so a verifier of a block built with a custom Hasher sets it.
+	Hasher merkleDag.Hasher
+}
+
+// receiptVersion
+// Version of the marshaled Receipt.  Version 2 added the Flags.
+const receiptVersion = types.VersionField(2)
+
+// hasher
+// The Hasher the receipt's paths combine hashes with
+func (r *Receipt) hasher() merkleDag.Hasher {
+	return receiptHasher(r.Flags, r.Hasher)
+}
+
+// receiptHasher
+// The Hasher the paths of a receipt of a block with the given flags combine hashes with
+func receiptHasher(flags node.Flags, hasher merkleDag.Hasher) merkleDag.Hasher {
+	if flags.Has(node.DomainSeparated) {
+		return merkleDag.DomainHasher{}
+	}
+	return hasher
+}
+
+// Verify
+// Both paths have to validate, and the root of the entry's path has to be what the chain's path starts from.
+func (r *Receipt) Verify() bool {
+	return r.Check() == nil
+}
+
+// Check
+// Verify the receipt, hashing the way its Flags (and Hasher) say, returning why it fails.  A path longer than
+// merkleDag.MaxProofSteps gets a merkleDag.ErrProofTooLong without any hashing.
+func (r *Receipt) Check() error {
+	entryReceipt, chainReceipt := r.EntryReceipt, r.ChainReceipt
+	entryReceipt.Hasher, chainReceipt.Hasher = r.hasher(), r.hasher()
+	if err := entryReceipt.Check(); err != nil {
+		return err
+	}
+	if err := chainReceipt.Check(); err != nil {
+		return err
+	}
+	if r.EntryReceipt.MDRoot != r.ChainReceipt.EntryHash {
+		return errors.New(fmt.Sprintf("the entry's path ends at %x, but the chain's path starts at %x",
+			r.EntryReceipt.MDRoot, r.ChainReceipt.EntryHash))
+	}
+	return nil
+}
+
+// VerifyAgainstRoot
+// Verify the receipt, and that the directory block root it ends at is trustedRoot, the ListMDRoot of the
+// directory block at the receipt's Height as the verifier knows it.  A receipt only proves the entry is under
+// the root it carries, which whoever built it chose, so a light client holding trusted roots checks against
+// those rather than taking the receipt's word for it.
+func (r *Receipt) VerifyAgainstRoot(trustedRoot types.Hash) bool {
+	return r.ChainReceipt.MDRoot == trustedRoot && r.Verify()
+}
+
+// Marshal
+// Version, height, ChainID, flags, then the entry and chain receipts.  The version leads so the format of
+// stored receipts can change without confusing readers of old ones.
+func (r *Receipt) Marshal() (data []byte) {
+	data = append(data, receiptVersion.Bytes()...)
+	data = append(data, r.Height.Bytes()...)
+	data = append(data, r.ChainID.Bytes()...)
+	data = append(data, types.Uint32Bytes(uint32(r.Flags))...)
+	data = append(data, r.EntryReceipt.Bytes()...)
+	data = append(data, r.ChainReceipt.Bytes()...)
+	return data
+}
+
+// Unmarshal
+// Extract a receipt from a byte slice.  Returns an error if the unmarshal fails.
+func (r *Receipt) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if tooLong, ok := rec.(merkleDag.ErrProofTooLong); ok {
+				err = tooLong
+				return
+			}
+			err = errors.New(fmt.Sprintf("Receipt failed to unmarshal %v", rec))
+		}
+	}()
+	var version types.VersionField
+	data = version.Extract(data)
+	if version > receiptVersion {
+		return errors.New(fmt.Sprintf("unknown receipt version %d", version))
+	}
+	data = r.Height.Extract(data)
+	data = r.ChainID.Extract(data)
+	r.Flags = 0
+	if version >= 2 { // Older receipts are of blocks built with sha256
+		var flags uint32
+		flags, data = types.BytesUint32(data)
+		r.Flags = node.Flags(flags)
+	}
+	data = r.EntryReceipt.Extract(data)
+	r.ChainReceipt.Extract(data)
+	return nil
+}
+
+// ReceiptKey
+// Key of a precomputed receipt in the Receipt bucket
+func ReceiptKey(chainID, entry types.Hash, height types.BlockHeight) (key []byte) {
+	key = append(key, chainID.Bytes()...)
+	key = append(key, entry.Bytes()...)
+	key = append(key, height.Bytes()...)
+	return key
+}
+
+// CrossChainProof
+// Proves two entries, each in its own chain, were sealed in the same directory block.  Each receipt
+// carries the entry to its chain's ListMDRoot, and the chain to the directory block's ListMDRoot,
+// and both have to end at the same directory root.
+type CrossChainProof struct {
+	Height types.BlockHeight // Height of the directory block holding both entries
+	A      Receipt           // Proof of the first entry
+	B      Receipt           // Proof of the second entry
+}
+
+// Verify
+// Both receipts have to verify, be for the proof's height, and end in the same directory block ListMDRoot.
+func (p *CrossChainProof) Verify() bool {
+	return p.A.Verify() &&
+		p.B.Verify() &&
+		p.A.Height == p.Height &&
+		p.B.Height == p.Height &&
+		p.A.ChainReceipt.MDRoot == p.B.ChainReceipt.MDRoot
+}
+
+// ReceiptsConsistent
+// Check a set of receipts, say of entries of one chain collected from a server that isn't trusted, all verify and
+// agree: the same height and block flags, the same root for the chain (for receipts of the same chain) and the
+// same directory block root.  Returns true and the directory block root they share, or false and a zero hash
+// if any receipt fails or disagrees with the rest, as when a server hands out proofs against trees of its own
+// making.  An empty set agrees on nothing, so is false.
+func ReceiptsConsistent(receipts []*Receipt) (bool, types.Hash) {
+	if len(receipts) == 0 {
+		return false, types.Hash{}
+	}
+	first := receipts[0]
+	chainRoots := make(map[types.Hash]types.Hash) // The root each chain's receipts carry its entries to
+	for _, r := range receipts {
+		if !r.Verify() || r.Height != first.Height || r.Flags != first.Flags ||
+			r.ChainReceipt.MDRoot != first.ChainReceipt.MDRoot {
+			return false, types.Hash{}
+		}
+		if root, ok := chainRoots[r.ChainID]; ok && root != r.EntryReceipt.MDRoot {
+			return false, types.Hash{}
+		}
+		chainRoots[r.ChainID] = r.EntryReceipt.MDRoot
+	}
+	return true, first.ChainReceipt.MDRoot
+}
+
+// BatchReceipt
+// Proves a number of entries were recorded in a chain in a particular directory block, as a Receipt for each
+// would, but with the nodes their paths share carried once (see merkleDag.BatchReceipt).
+type BatchReceipt struct {
+	Height       types.BlockHeight      // Height of the directory block
+	ChainID      types.Hash             // Chain holding the entries
+	Flags        node.Flags             // Flags of the directory block, which say how its hashes are combined
+	EntryReceipt merkleDag.BatchReceipt // Entry hashes -> chain ListMDRoot
+	ChainReceipt merkleDag.MDReceipt    // chain ListMDRoot -> directory block ListMDRoot
+	Hasher       merkleDag.Hasher       // As for a Receipt; not marshaled
+}
+
+// Verify
+// Both proofs have to validate, and the root of the entries' proof has to be what the chain's path starts from.
+func (r *BatchReceipt) Verify() bool {
+	return r.Check() == nil
+}
+
+// Check
+// Verify the receipt, hashing the way its Flags (and Hasher) say, returning why it fails
+func (r *BatchReceipt) Check() error {
+	hasher := receiptHasher(r.Flags, r.Hasher)
+	entryReceipt, chainReceipt := r.EntryReceipt, r.ChainReceipt
+	entryReceipt.Hasher, chainReceipt.Hasher = hasher, hasher
+	if err := entryReceipt.Check(); err != nil {
+		return err
+	}
+	if err := chainReceipt.Check(); err != nil {
+		return err
+	}
+	if r.EntryReceipt.MDRoot != r.ChainReceipt.EntryHash {
+		return errors.New(fmt.Sprintf("the entries' proof ends at %x, but the chain's path starts at %x",
+			r.EntryReceipt.MDRoot, r.ChainReceipt.EntryHash))
+	}
+	return nil
+}
+
+// Marshal
+// Version, height, ChainID, flags, then the entries' and chain's receipts, as for a Receipt
+func (r *BatchReceipt) Marshal() (data []byte) {
+	data = append(data, receiptVersion.Bytes()...)
+	data = append(data, r.Height.Bytes()...)
+	data = append(data, r.ChainID.Bytes()...)
+	data = append(data, types.Uint32Bytes(uint32(r.Flags))...)
+	data = append(data, r.EntryReceipt.Bytes()...)
+	data = append(data, r.ChainReceipt.Bytes()...)
+	return data
+}
+
+// Unmarshal
+// Extract a batch receipt from a byte slice.  Returns an error if the unmarshal fails.
+func (r *BatchReceipt) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if tooLong, ok := rec.(merkleDag.ErrProofTooLong); ok {
+				err = tooLong
+				return
+			}
+			err = errors.New(fmt.Sprintf("BatchReceipt failed to unmarshal %v", rec))
+		}
+	}()
+	var version types.VersionField
+	data = version.Extract(data)
+	if version != receiptVersion {
+		return errors.New(fmt.Sprintf("unknown batch receipt version %d", version))
+	}
+	data = r.Height.Extract(data)
+	data = r.ChainID.Extract(data)
+	var flags uint32
+	flags, data = types.BytesUint32(data)
+	r.Flags = node.Flags(flags)
+	data = r.EntryReceipt.Extract(data)
+	r.ChainReceipt.Extract(data)
+	return nil
+}
@@ -0,0 +1,244 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// countingStore
+// Counts the reads made of the Store underneath it
+type countingStore struct {
+	database.Store
+	reads int
+}
+
+func (c *countingStore) Get(key []byte) ([]byte, error) {
+	c.reads++
+	return c.Store.Get(key)
+}
+
+func TestPrecomputeReceipts(t *testing.T) {
+	for _, precompute := range []bool{true, false} {
+		acc := GetTestAccumulator(t)
+		acc.PrecomputeReceipts = precompute
+		chain1 := types.Hash(sha256.Sum256([]byte("chain 1")))
+		chain2 := types.Hash(sha256.Sum256([]byte("chain 2")))
+		for i := 0; i < 13; i++ {
+			acc.addEntry(GetTestEntry(chain1, i))
+			acc.addEntry(GetTestEntry(chain2, i))
+		}
+		directoryBlock := acc.sealBlock()
+
+		counter := &countingStore{Store: acc.DB.GetStore()}
+		acc.DB.InitStore(counter)
+		for i := 0; i < 13; i++ {
+			counter.reads = 0
+			entry := GetTestEntry(chain2, i)
+			receipt, err := acc.Reader().GetReceipt(chain2, entry.EntryHash, directoryBlock.BHeight)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !receipt.Verify() || receipt.ChainReceipt.MDRoot != directoryBlock.ListMDRoot {
+				t.Errorf("receipt for entry %d failed to verify against the directory block", i)
+			}
+			if precompute && counter.reads != 1 {
+				t.Errorf("a precomputed receipt should take one read, took %d", counter.reads)
+			}
+			if !precompute && counter.reads < 2 {
+				t.Errorf("without precomputed receipts we have to build the receipt, but took %d reads", counter.reads)
+			}
+		}
+	}
+}
+
+func TestCrossChainProof(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainX := types.Hash(sha256.Sum256([]byte("chain x")))
+	chainY := types.Hash(sha256.Sum256([]byte("chain y")))
+	for i := 0; i < 6; i++ {
+		acc.addEntry(GetTestEntry(chainX, i))
+		acc.addEntry(GetTestEntry(chainY, i))
+	}
+	block1 := acc.sealBlock()
+	acc.addEntry(GetTestEntry(chainY, 6))
+	block2 := acc.sealBlock()
+
+	entryA, entryB := GetTestEntry(chainX, 2).EntryHash, GetTestEntry(chainY, 5).EntryHash
+	proof, err := acc.Reader().GetCrossChainProof(chainX, entryA, chainY, entryB, block1.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proof.Verify() || proof.A.ChainReceipt.MDRoot != block1.ListMDRoot {
+		t.Error("the cross chain proof should verify against the directory block")
+	}
+
+	// Entries sealed in different blocks don't share a directory root
+	other, err := acc.Reader().GetReceipt(chainY, GetTestEntry(chainY, 6).EntryHash, block2.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof.B = *other
+	if proof.Verify() {
+		t.Error("a proof mixing receipts from two blocks should not verify")
+	}
+
+	if _, err := acc.Reader().GetCrossChainProof(chainX, entryA, chainY, GetTestEntry(chainY, 6).EntryHash, block1.BHeight); err == nil {
+		t.Error("expected an error for an entry not sealed in the block")
+	}
+	if _, err := acc.Reader().GetCrossChainProof(chainX, entryA, chainY, entryB, block2.BHeight); err == nil {
+		t.Error("expected an error for a chain not in the block")
+	}
+}
+
+func TestReceiptTooLong(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("long")))
+	for i := 0; i < 20; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	block := acc.sealBlock()
+	receipt, err := acc.Reader().GetReceipt(chainID, GetTestEntry(chainID, 7).EntryHash, block.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(receipt.EntryReceipt.Nodes) <= merkleDag.MaxProofSteps {
+		receipt.EntryReceipt.Nodes = append(receipt.EntryReceipt.Nodes, receipt.EntryReceipt.Nodes...)
+	}
+	if _, ok := receipt.Check().(merkleDag.ErrProofTooLong); !ok || receipt.Verify() {
+		t.Error("a receipt with too many steps should be refused with ErrProofTooLong")
+	}
+	if _, ok := new(Receipt).Unmarshal(receipt.Marshal()).(merkleDag.ErrProofTooLong); !ok {
+		t.Error("unmarshaling a receipt with too many steps should fail with ErrProofTooLong")
+	}
+}
+
+func TestVerifyAgainstRoot(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("trusted")))
+	for i := 0; i < 5; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("another chain"))), 0))
+	block := acc.sealBlock()
+	receipt, err := acc.Reader().GetReceipt(chainID, GetTestEntry(chainID, 3).EntryHash, block.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !receipt.VerifyAgainstRoot(block.ListMDRoot) {
+		t.Error("the receipt should verify against the root of its directory block")
+	}
+	if receipt.VerifyAgainstRoot(sha256.Sum256([]byte("some other root"))) {
+		t.Error("the receipt shouldn't verify against a root other than its block's")
+	}
+
+	// A prover can build a receipt that verifies on its own, up to a root of its choosing
+	fake := new(merkleDag.MD)
+	fake.AddToChain(receipt.ChainReceipt.EntryHash)
+	fake.AddToChain(sha256.Sum256([]byte("made up")))
+	forged := *receipt
+	forged.ChainReceipt.BuildMDReceipt(*fake, receipt.ChainReceipt.EntryHash)
+	if !forged.Verify() || forged.VerifyAgainstRoot(block.ListMDRoot) {
+		t.Error("a receipt up to a made up root should verify, but not against the trusted root")
+	}
+}
+
+func TestBatchReceipt(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.BlockFlags = node.DomainSeparated
+	chainID := types.Hash(sha256.Sum256([]byte("batched")))
+	for i := 0; i < 1000; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+	}
+	acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("not batched"))), 0))
+	block := acc.sealBlock()
+
+	var entries []types.Hash
+	receiptsSize := 0
+	for i := 0; i < 100; i++ { // A dense run of entries, then some spread out
+		index := 300 + i
+		if i >= 50 {
+			index = 400 + (i-50)*12
+		}
+		entries = append(entries, GetTestEntry(chainID, index).EntryHash)
+		receipt, err := acc.Reader().GetReceipt(chainID, entries[i], block.BHeight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiptsSize += len(receipt.Marshal())
+	}
+	batch, err := acc.Reader().GetBatchReceipt(chainID, entries, block.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !batch.Verify() || batch.ChainReceipt.MDRoot != block.ListMDRoot || len(batch.EntryReceipt.Hashes) != 100 {
+		t.Fatalf("the batch receipt should prove the 100 entries against the directory block (%v)", batch.Check())
+	}
+	if size := len(batch.Marshal()); size*3 > receiptsSize {
+		t.Errorf("the batch receipt is %d bytes, not much smaller than the %d of a receipt apiece", size, receiptsSize)
+	}
+
+	unmarshaled := new(BatchReceipt)
+	if err := unmarshaled.Unmarshal(batch.Marshal()); err != nil || !unmarshaled.Verify() {
+		t.Errorf("the batch receipt should verify once unmarshaled (%v)", err)
+	}
+	unmarshaled.EntryReceipt.Hashes[7][0] ^= 1
+	if unmarshaled.Verify() {
+		t.Error("a batch receipt for a changed entry shouldn't verify")
+	}
+	missing := append(entries[:1:1], GetTestEntry(chainID, 1000).EntryHash)
+	if _, err := acc.Reader().GetBatchReceipt(chainID, missing, block.BHeight); err == nil {
+		t.Error("expected an error for an entry not in the chain")
+	}
+}
+
+func TestReceiptsConsistent(t *testing.T) {
+	chainID := types.Hash(sha256.Sum256([]byte("consistent")))
+	otherID := types.Hash(sha256.Sum256([]byte("consistent other")))
+	seal := func(entries int) (*Accumulator, *node.Node) {
+		acc := GetTestAccumulator(t)
+		for i := 0; i < entries; i++ {
+			acc.addEntry(GetTestEntry(chainID, i))
+		}
+		acc.addEntry(GetTestEntry(otherID, 0))
+		return acc, acc.sealBlock()
+	}
+	acc, block := seal(4)
+	var receipts []*Receipt
+	for i := 0; i < 4; i++ {
+		receipt, err := acc.Reader().GetReceipt(chainID, GetTestEntry(chainID, i).EntryHash, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	other, err := acc.Reader().GetReceipt(otherID, GetTestEntry(otherID, 0).EntryHash, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts = append(receipts, other)
+	if ok, root := ReceiptsConsistent(receipts); !ok || root != block.ListMDRoot {
+		t.Error("receipts of the one block should agree on its root")
+	}
+
+	fake, _ := seal(5) // A tree with an entry more, whose receipts verify on their own
+	forged, err := fake.Reader().GetReceipt(chainID, GetTestEntry(chainID, 0).EntryHash, 0)
+	if err != nil || !forged.Verify() {
+		t.Fatalf("the receipt of the other tree should verify on its own (%v)", err)
+	}
+	if ok, _ := ReceiptsConsistent(append(receipts[:4:4], forged)); ok {
+		t.Error("a receipt against a different tree should be caught")
+	}
+	tampered := *receipts[1]
+	tampered.ChainReceipt.MDRoot = sha256.Sum256([]byte("tampered"))
+	if ok, _ := ReceiptsConsistent([]*Receipt{receipts[0], &tampered}); ok {
+		t.Error("a receipt carrying a different root should be caught")
+	}
+	if ok, _ := ReceiptsConsistent(nil); ok {
+		t.Error("no receipts agree on nothing")
+	}
+}
@@ -0,0 +1,99 @@
+package accumulator
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+const (
+	bloomBitsPerEntry = 10 // With 7 probes, about a 1% false positive rate
+	bloomProbes       = 7
+)
+
+// bloom
+// A bloom filter over entry hashes.  Entry hashes are already uniformly distributed, so the probes are
+// taken straight from the hash's bits.
+type bloom struct {
+	bits []uint64
+}
+
+func newBloom(entries int) *bloom {
+	words := (entries*bloomBitsPerEntry + 63) / 64
+	if words == 0 {
+		words = 1
+	}
+	return &bloom{bits: make([]uint64, words)}
+}
+
+// probes
+// Call fn with the bit for each probe of the hash, by double hashing over two words of the hash
+func (b *bloom) probes(h types.Hash, fn func(word int, mask uint64) bool) bool {
+	h1, h2 := binary.BigEndian.Uint64(h[0:8]), binary.BigEndian.Uint64(h[8:16])|1
+	m := uint64(len(b.bits) * 64)
+	for i := uint64(0); i < bloomProbes; i++ {
+		bit := (h1 + i*h2) % m
+		if !fn(int(bit/64), 1<<(bit%64)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloom) add(h types.Hash) {
+	b.probes(h, func(word int, mask uint64) bool {
+		b.bits[word] |= mask
+		return true
+	})
+}
+
+// mayHave
+// False if the hash was never added; true if it probably was
+func (b *bloom) mayHave(h types.Hash) bool {
+	return b.probes(h, func(word int, mask uint64) bool {
+		return b.bits[word]&mask != 0
+	})
+}
+
+// recentEntries
+// A bloom filter for each of the most recently sealed blocks, oldest first
+type recentEntries struct {
+	mutex   sync.Mutex
+	filters []*bloom
+}
+
+// rememberRecent
+// Add a filter over the block just sealed, and forget the blocks beyond RecentDuplicateBlocks
+func (a *Accumulator) rememberRecent() {
+	var entries []types.Hash
+	for _, v := range a.chains {
+		entries = append(entries, v.Node.EntryList...)
+	}
+	filter := newBloom(len(entries))
+	for _, h := range entries {
+		filter.add(h)
+	}
+	a.recent.mutex.Lock()
+	defer a.recent.mutex.Unlock()
+	a.recent.filters = append(a.recent.filters, filter)
+	if extra := len(a.recent.filters) - a.RecentDuplicateBlocks; extra > 0 {
+		a.recent.filters = a.recent.filters[extra:]
+	}
+}
+
+// recentDuplicate
+// True if the entry is in one of the recent blocks.  The filters can give false positives, so whatever
+// they say is checked against the entry index before we call it a duplicate.
+func (a *Accumulator) recentDuplicate(entry types.Hash) bool {
+	a.recent.mutex.Lock()
+	maybe := false
+	for _, filter := range a.recent.filters {
+		if filter.mayHave(entry) {
+			maybe = true
+			break
+		}
+	}
+	a.recent.mutex.Unlock()
+	return maybe && a.db().Get(types.EntryNode, entry.Bytes()) != nil
+}
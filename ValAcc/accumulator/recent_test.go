@@ -0,0 +1,48 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestRecentDuplicates(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.RecentDuplicateBlocks = 2
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("resubmitted")))
+
+	first := GetTestEntry(chainID, 0)
+	acc.Submit(first)
+	runUntilIdle(acc)
+	acc.sealBlock()
+	if acc.Submit(first) || len(rejected) != 1 || rejected[0] != RecentDuplicate {
+		t.Fatalf("resubmitting an entry a block later should be rejected as a RecentDuplicate, got %v", rejected)
+	}
+	if !acc.Submit(GetTestEntry(chainID, 1)) {
+		t.Error("a new entry should be accepted")
+	}
+	runUntilIdle(acc)
+	acc.sealBlock()
+	acc.sealBlock()
+	if !acc.Submit(first) {
+		t.Error("once the entry's block is no longer recent, Submit shouldn't look for it")
+	}
+	runUntilIdle(acc)
+	if acc.chains[chainID] != nil {
+		t.Error("but the old duplicate still shouldn't make it into the block")
+	}
+
+	// False positives from the filters are caught by checking the entry index
+	saturated := newBloom(1)
+	for i := range saturated.bits {
+		saturated.bits[i] = ^uint64(0)
+	}
+	acc.recent.filters = append(acc.recent.filters, saturated)
+	if !acc.Submit(GetTestEntry(chainID, 2)) {
+		t.Error("an entry the filters only think they have seen should be accepted")
+	}
+}
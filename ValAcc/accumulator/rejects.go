@@ -0,0 +1,69 @@
+package accumulator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+)
+
+// rejectBuffer is how many RejectEvents a subscriber can fall behind before the oldest are dropped
+const rejectBuffer = 256
+
+// RejectEvent
+// An entry Submit (or SubmitAtHeight) rejected, and why, as told to WatchRejects subscribers
+type RejectEvent struct {
+	Entry  node.EntryHash
+	Reason RejectReason
+	Time   time.Time // When it was rejected, by the Clock
+}
+
+// rejectWatchers
+// The subscribers to an accumulator's RejectEvents
+type rejectWatchers struct {
+	mux  sync.Mutex
+	subs map[chan RejectEvent]bool
+}
+
+// WatchRejects
+// Subscribe to a live tail of rejected entries, for debugging misbehaving clients.  Every subscriber gets its
+// own copy of each RejectEvent from now on.  The accumulator never waits on a subscriber: once one has fallen
+// rejectBuffer events behind, its oldest event is dropped to make room for the newest.  Call the returned func
+// to unsubscribe, which closes the channel.  May be called from any go routine.
+func (a *Accumulator) WatchRejects() (<-chan RejectEvent, func()) {
+	events := make(chan RejectEvent, rejectBuffer)
+	a.rejects.mux.Lock()
+	defer a.rejects.mux.Unlock()
+	if a.rejects.subs == nil {
+		a.rejects.subs = make(map[chan RejectEvent]bool)
+	}
+	a.rejects.subs[events] = true
+	return events, func() {
+		a.rejects.mux.Lock()
+		defer a.rejects.mux.Unlock()
+		if a.rejects.subs[events] {
+			delete(a.rejects.subs, events)
+			close(events)
+		}
+	}
+}
+
+// publish
+// Give every subscriber the event, dropping their oldest to make room for it
+func (w *rejectWatchers) publish(event RejectEvent) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for events := range w.subs {
+		for sent := false; !sent; {
+			select {
+			case events <- event:
+				sent = true
+			default:
+				select {
+				case <-events:
+				default: // The subscriber made room first
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package accumulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// RepairChainNode
+// Rebuild the chain's node at the given height from its entries, got from another source (a replica, or the
+// write ahead log), when the EntryList of the node stored there is corrupt.  The node is found by the entry
+// index of its first entry, and its header is kept, since the hash the node is stored and linked under covers
+// the header (the TimeStamp in it can't be got from anywhere else).  Nothing is written unless the entries
+// give the chain root the directory block at the height lists for the chain (over the chain's history, for a
+// continuous chain), and the rebuilt node hashes to the hash it is stored under.  A node whose header is
+// corrupt too can't be repaired this way.  Only sealed blocks are touched, so it can run alongside Run.
+func (a *Accumulator) RepairChainNode(chainID types.Hash, height types.BlockHeight, entries []types.Hash) error {
+	if len(entries) == 0 {
+		return errors.New("a chain node has at least one entry")
+	}
+	r := a.Reader()
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return err
+	}
+	chains, err := r.blockChains(directoryBlock)
+	if err != nil {
+		return err
+	}
+	var chainRoot *types.Hash
+	for _, ne := range chains {
+		if ne.ChainID == chainID {
+			chainRoot = ne.MDRoot.Copy()
+		}
+	}
+	if chainRoot == nil {
+		return errors.New(fmt.Sprintf("chain %x is not in the directory block at height %d", chainID, height))
+	}
+
+	if err := r.checkEntries(chainID, height, directoryBlock.Flags, entries, *chainRoot); err != nil {
+		return err
+	}
+
+	hash := r.DB.Get(types.EntryNode, entries[0][:])
+	if hash == nil {
+		return errors.New(fmt.Sprintf("entry %x isn't indexed to a node", entries[0]))
+	}
+	stored, err := r.GetNodeHeader(hash)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the header of node %x is corrupt, so it can't be rebuilt: %v", hash, err))
+	}
+	if stored.ChainID != chainID || stored.BHeight != height {
+		return errors.New(fmt.Sprintf("entry %x is indexed to the node of chain %x at height %d",
+			entries[0], stored.ChainID, stored.BHeight))
+	}
+	repaired := node.Node{
+		Version:     stored.Version,
+		Flags:       stored.Flags,
+		BHeight:     stored.BHeight,
+		SequenceNum: stored.SequenceNum,
+		TimeStamp:   stored.TimeStamp,
+		ChainID:     stored.ChainID,
+		SubChainIDs: stored.SubChainIDs,
+		Previous:    stored.Previous,
+		IsNode:      stored.IsNode,
+		ListMDRoot:  *chainRoot,
+		EntryList:   append([]types.Hash(nil), entries...),
+	}
+	if rebuilt := repaired.GetHash(); rebuilt == nil || !bytes.Equal(rebuilt[:], hash) {
+		return errors.New(fmt.Sprintf("the rebuilt node of chain %x at height %d doesn't hash to %x", chainID, height, hash))
+	}
+	if err := r.DB.Put(types.Node, hash, repaired.Marshal()); err != nil {
+		return err
+	}
+	a.logger().Printf("repaired the node of chain %x at height %d", chainID, height)
+	return nil
+}
+
+// checkEntries
+// Check the entries of the chain's node at the given height give the chain root the directory block lists for
+// it (over the chain's history, for a continuous chain), hashing as the block's flags say
+func (r *Reader) checkEntries(chainID types.Hash, height types.BlockHeight, flags node.Flags, entries []types.Hash, chainRoot types.Hash) error {
+	built := r.forFlags(flags) // Hash the way the block was built
+	md := built.newMD()
+	for _, h := range entries {
+		md.AddToChain(h)
+	}
+	continuous, recorded, err := r.continuousChain(chainID)
+	if err != nil {
+		return err
+	}
+	if !recorded { // Params older than the record; perhaps a continuous chain, whose root covers its history
+		continuous = *md.GetMDRoot() != chainRoot
+	}
+	if continuous && height > 0 {
+		if md, err = built.chainMDTo(chainID, height-1); err != nil {
+			return err
+		}
+		for _, h := range entries {
+			md.AddToChain(h)
+		}
+	}
+	if root := *md.GetMDRoot(); root != chainRoot {
+		return errors.New(fmt.Sprintf("the entries give chain %x the root %x at height %d, but the directory block has %x",
+			chainID, root, height, chainRoot))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestRepairChainNode(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	plain := types.Hash(sha256.Sum256([]byte("repaired")))
+	continuous := types.Hash(sha256.Sum256([]byte("repaired continuous")))
+	acc.ContinuousChains = map[types.Hash]bool{continuous: true}
+	entries := map[types.Hash][][]types.Hash{} // The entries of each chain, block by block
+	for height := 0; height < 3; height++ {
+		for _, chainID := range []types.Hash{plain, continuous} {
+			var block []types.Hash
+			for i := 0; i < 4; i++ {
+				entry := GetTestEntry(chainID, height*10+i)
+				acc.Submit(entry)
+				block = append(block, entry.EntryHash)
+			}
+			entries[chainID] = append(entries[chainID], block)
+		}
+		runUntilIdle(acc)
+		acc.SealBlock()
+	}
+
+	r := acc.Reader()
+	for _, chainID := range []types.Hash{plain, continuous} {
+		hash, _, err := r.chainNodeAt(chainID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		good := append([]byte(nil), r.DB.Get(types.Node, hash)...)
+		corrupt := append([]byte(nil), good...)
+		corrupt[len(corrupt)-1] ^= 0xff // The last entry of the EntryList
+		r.DB.Put(types.Node, hash, corrupt)
+
+		wrong := append([]types.Hash(nil), entries[chainID][1]...)
+		wrong[0], wrong[1] = wrong[1], wrong[0]
+		if err := acc.RepairChainNode(chainID, 1, wrong); err == nil {
+			t.Error("entries that don't give the chain's root shouldn't repair the node")
+		}
+		if !bytes.Equal(r.DB.Get(types.Node, hash), corrupt) {
+			t.Fatal("a repair that failed shouldn't write the node")
+		}
+		if err := acc.RepairChainNode(chainID, 1, entries[chainID][1]); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(r.DB.Get(types.Node, hash), good) {
+			t.Errorf("the repaired node of chain %x isn't the node that was sealed", chainID[:4])
+		}
+		receipt, err := r.GetReceipt(chainID, entries[chainID][1][3], 1)
+		if err != nil || !receipt.Verify() {
+			t.Errorf("the repaired node of chain %x should give receipts again (%v)", chainID[:4], err)
+		}
+	}
+}
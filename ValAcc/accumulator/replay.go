@@ -0,0 +1,91 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// replayClock
+// The Clock of a replay, set to the timestamps of the blocks being replayed
+type replayClock struct {
+	now types.TimeStamp
+}
+
+func (c *replayClock) Now() time.Time { return time.Unix(0, int64(c.now)) }
+
+// ReplayVerify
+// Rebuild every block this accumulator has sealed from a log of its entries, in a fresh accumulator over an
+// in memory database, and check the replayed head is expectedHead (the head of a live node, say).  What the
+// log can't say is taken from our own blocks: each block is sealed once the log has given it as many entries
+// as ours has, with our block's flags and the timestamps of our block and its chain nodes.  Everything else that
+// decides the roots (the Hasher, ContinuousChains, MaxChainsPerBlock) is taken from this accumulator.
+//
+// The log has to hold the entries in the order they were added to the blocks, which is the order they were
+// submitted only when nothing reorders them: with a Sequencer the log has to be in sequence order, and the
+// entries of BeforeSeal and SubmitAtHeight logged where they were added, at the end of their block.  Pruned
+// blocks can't be replayed.  Returns nil if the heads match, or an error saying where the replay went astray.
+// Don't call it while Run is running.
+func (a *Accumulator) ReplayVerify(entries []node.EntryHash, expectedHead types.Hash) error {
+	live := a.Reader()
+	head, err := live.GetHead()
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		return errors.New("no blocks have been sealed to replay")
+	}
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	clock := new(replayClock)
+	replay := new(Accumulator)
+	replay.Hasher = a.Hasher
+	replay.ContinuousChains = a.ContinuousChains
+	replay.MaxChainsPerBlock = a.MaxChainsPerBlock
+	replay.Logger = a.Logger
+	replay.Clock = clock
+	replay.Init(db, a.chainID)
+
+	next := 0
+	for height := types.BlockHeight(0); height <= head.BHeight; height++ {
+		block, err := live.GetDirectoryBlock(height)
+		if err != nil {
+			return err
+		}
+		count, err := live.GetBlockEntryCount(height)
+		if err != nil {
+			return err
+		}
+		replay.BlockFlags = block.Flags
+		for replay.blockEntries < count {
+			if next == len(entries) {
+				return errors.New(fmt.Sprintf("the log ran out of entries replaying the block at height %d", height))
+			}
+			entry := entries[next]
+			next++
+			if replay.chains[entry.ChainID] == nil { // The chain's node is stamped as its first entry is added
+				if chainNode, err := live.GetChainNode(entry.ChainID, height); err == nil {
+					clock.now = chainNode.TimeStamp
+				}
+			}
+			replay.processEntry(entry)
+		}
+		clock.now = block.TimeStamp
+		replayed := replay.SealBlock()
+		if replayed == nil {
+			return errors.New(fmt.Sprintf("failed to seal the replayed block at height %d", height))
+		}
+		if *replayed.GetHash() != *block.GetHash() {
+			return errors.New(fmt.Sprintf("the replayed block at height %d is %x, but ours is %x",
+				height, replayed.GetHash(), block.GetHash()))
+		}
+	}
+	if replayed := replay.previous.GetHash(); *replayed != expectedHead {
+		return errors.New(fmt.Sprintf("the replay ends at the head %x, not %x", replayed, expectedHead))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestReplayVerify(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	acc.ContinuousChains = map[types.Hash]bool{}
+	var chains []types.Hash
+	for c := 0; c < 4; c++ {
+		chains = append(chains, types.Hash(sha256.Sum256([]byte(fmt.Sprintf("replayed %d", c)))))
+	}
+	acc.ContinuousChains[chains[3]] = true
+	var log []node.EntryHash
+	for b := 0; b < 5; b++ {
+		acc.BlockFlags = node.Flags(0)
+		if b >= 3 {
+			acc.BlockFlags = node.DomainSeparated
+		}
+		for i := 0; i < 7; i++ {
+			entry := GetTestEntry(chains[(b+i)%len(chains)], b*7+i)
+			if i == 6 && b > 0 {
+				entry = GetTestEntry(chains[(b+i-7)%len(chains)], b*7-7) // Already sealed, so dropped
+			}
+			log = append(log, entry)
+			acc.processEntry(entry)
+			clock.now = clock.now.Add(time.Millisecond)
+		}
+		acc.sealBlock()
+	}
+	acc.processEntry(GetTestEntry(chains[0], 100)) // In the open block, so beyond what is replayed
+	log = append(log, GetTestEntry(chains[0], 100))
+	head, _ := acc.Reader().GetHead()
+
+	if err := acc.ReplayVerify(log, *head.GetHash()); err != nil {
+		t.Fatalf("replaying the log should give the head, got %v", err)
+	}
+	tampered := append([]node.EntryHash{}, log...)
+	tampered[17].EntryHash[0] ^= 1
+	if err := acc.ReplayVerify(tampered, *head.GetHash()); err == nil || !strings.Contains(err.Error(), "height 2") {
+		t.Errorf("a tampered entry should be caught at the height it was sealed, got %v", err)
+	}
+	if err := acc.ReplayVerify(log[:20], *head.GetHash()); err == nil {
+		t.Error("a log that runs out before the head should fail")
+	}
+	if err := acc.ReplayVerify(log, head.ListMDRoot); err == nil {
+		t.Error("replaying to a head other than the one expected should fail")
+	}
+}
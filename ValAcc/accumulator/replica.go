@@ -0,0 +1,328 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ReplicaBlock
+// A committed block as streamed to a replica: the directory block, the node of each chain in it, and the root
+// of every chain (as blockChains gives them, the chains of a Grouped block's group after the rest)
+type ReplicaBlock struct {
+	Block        *node.Node
+	ChainNodes   []*node.Node
+	ChainEntries []node.NEList
+}
+
+// Marshal
+// The directory block, the number of chain nodes, each led by its length, then the number of chain roots and
+// each ChainID and MDRoot.
+func (b *ReplicaBlock) Marshal() (data []byte) {
+	data = appendFrame(data, b.Block.Marshal())
+	data = append(data, types.Uint32Bytes(uint32(len(b.ChainNodes)))...)
+	for _, n := range b.ChainNodes {
+		data = appendFrame(data, n.Marshal())
+	}
+	data = append(data, types.Uint32Bytes(uint32(len(b.ChainEntries)))...)
+	for _, ne := range b.ChainEntries {
+		data = append(data, ne.ChainID.Bytes()...)
+		data = append(data, ne.MDRoot.Bytes()...)
+	}
+	return data
+}
+
+// Unmarshal
+// Extract a ReplicaBlock from a byte slice.  Returns an error if the unmarshal fails.
+func (b *ReplicaBlock) Unmarshal(data []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.New(fmt.Sprintf("ReplicaBlock failed to unmarshal %v", rec))
+		}
+	}()
+	var frame []byte
+	var count uint32
+	frame, data = extractFrame(data)
+	b.Block = new(node.Node)
+	if _, err := b.Block.Unmarshal(frame); err != nil {
+		return err
+	}
+	count, data = types.BytesUint32(data)
+	b.ChainNodes = nil
+	for i := uint32(0); i < count; i++ {
+		frame, data = extractFrame(data)
+		n := new(node.Node)
+		if _, err := n.Unmarshal(frame); err != nil {
+			return err
+		}
+		b.ChainNodes = append(b.ChainNodes, n)
+	}
+	count, data = types.BytesUint32(data)
+	b.ChainEntries = nil
+	for i := uint32(0); i < count; i++ {
+		var ne node.NEList
+		data = ne.ChainID.Extract(data)
+		data = ne.MDRoot.Extract(data)
+		b.ChainEntries = append(b.ChainEntries, ne)
+	}
+	return nil
+}
+
+// appendFrame
+// Append the data to out, led by its length
+func appendFrame(out, data []byte) []byte {
+	return append(append(out, types.Uint32Bytes(uint32(len(data)))...), data...)
+}
+
+// extractFrame
+// Take data led by its length off the front of a byte slice, returning it and what follows.  Panics if the
+// slice is too short.
+func extractFrame(data []byte) (frame []byte, rest []byte) {
+	length, data := types.BytesUint32(data)
+	return data[:length], data[length:]
+}
+
+// GetReplicaBlock
+// Gather the committed block at the given height, with what a replica needs to apply it
+func (r *Reader) GetReplicaBlock(height types.BlockHeight) (*ReplicaBlock, error) {
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	chains, err := r.blockChains(directoryBlock)
+	if err != nil {
+		return nil, err
+	}
+	b := &ReplicaBlock{Block: directoryBlock, ChainEntries: chains}
+	for _, ne := range chains {
+		chainNode, err := r.GetChainNode(ne.ChainID, height)
+		if err != nil {
+			return nil, err
+		}
+		b.ChainNodes = append(b.ChainNodes, chainNode)
+	}
+	return b, nil
+}
+
+// ApplyBlock
+// Write a block committed by a primary into this accumulator, a replica of it, once it has checked the block
+// follows our head, its List gives its ListMDRoot, the chain roots given are the ones it lists (those of a
+// Grouped block's group giving its group's root), and each chain node's entries give its chain's root.  The
+// block is written as the primary wrote it, so the replica's head hash ends up the same as the primary's
+// (and the replica can take over, carrying on from there, set up as the primary was).  The indexes kept by
+// the options a block is only sealed with (EntrySequences, KeepChainStats and the like), and precomputed
+// receipts, aren't written.  Observers and BlockWatchers hear of the block; OnCommit, OnFinal and the
+// Anchorer don't, as the primary sees to those.  Don't call it while Run is running.
+func (a *Accumulator) ApplyBlock(block *node.Node, chainNodes []*node.Node, chainEntries []node.NEList) error {
+	if len(a.chains) > 0 {
+		return errors.New("a replica can't apply blocks with entries of its own in its current block")
+	}
+	if block.BHeight != a.height {
+		return errors.New(fmt.Sprintf("the replica needs the block at height %d, not %d", a.height, block.BHeight))
+	}
+	if !block.IsNode || block.ChainID != *a.chainID {
+		return errors.New(fmt.Sprintf("the block at height %d isn't a directory block of chain %x", block.BHeight, *a.chainID))
+	}
+	var previous types.Hash
+	if a.previous != nil {
+		previous = *a.previous.GetHash()
+	}
+	if block.Previous != previous {
+		return errors.New(fmt.Sprintf("the block at height %d follows %x, not our head %x", block.BHeight, block.Previous, previous))
+	}
+	r := a.Reader()
+	if block.Flags.Has(node.SameRoot) { // An empty block, whose root is our head's
+		if len(block.List) != 0 || a.previous == nil {
+			return errors.New(fmt.Sprintf("the block at height %d can't take the root of the block before it", block.BHeight))
+		}
+		block.ListMDRoot = a.previous.ListMDRoot
+	} else {
+		md := r.forFlags(block.Flags).newMD()
+		for _, ne := range block.List {
+			md.AddToChain(ne.MDRoot)
+		}
+		if root := *md.GetMDRoot(); root != block.ListMDRoot {
+			return errors.New(fmt.Sprintf("the block at height %d has the ListMDRoot %x, but its List gives %x",
+				block.BHeight, block.ListMDRoot, root))
+		}
+	}
+	group, err := a.checkChainEntries(block, chainNodes, chainEntries)
+	if err != nil {
+		return err
+	}
+
+	batch := a.DB.NewBatch()
+	var blockEntries uint32
+	chains := make([]*ChainAcc, len(chainNodes))
+	for i, chainNode := range chainNodes {
+		if err := r.checkEntries(chainNode.ChainID, block.BHeight, block.Flags, chainNode.EntryList, chainNode.ListMDRoot); err != nil {
+			return err
+		}
+		if err := chainNode.Put(&batch.DB); err != nil {
+			return err
+		}
+		nodeHash := chainNode.GetHash()
+		for _, h := range chainNode.EntryList {
+			batch.Put(types.EntryNode, h.Bytes(), nodeHash.Bytes())
+		}
+		blockEntries += uint32(len(chainNode.EntryList))
+		chains[i] = &ChainAcc{Node: *chainNode}
+	}
+	a.writeGroup(&batch.DB, group)
+	a.indexRoots(&batch.DB, chains)
+	a.indexChainHeights(&batch.DB, chains)
+	a.indexDirectoryRoots(&batch.DB, block)
+	block.Put(&batch.DB)
+	sealedEntries := a.sealedEntries + uint64(blockEntries)
+	batch.PutInt32(types.BlockEntryCount, int(a.height), types.Uint32Bytes(blockEntries))
+	batch.Put(types.TotalEntries, a.chainID[:], types.Uint64Bytes(sealedEntries))
+	a.writeFinalized(&batch.DB)
+	params := a.writeParams(&batch.DB)
+	if err := batch.Commit(); err != nil {
+		return err
+	}
+	if params != nil {
+		a.chainParams = params
+	}
+
+	a.previous = block
+	a.sealedEntries = sealedEntries
+	for _, chainNode := range chainNodes { // Rebuilt from the database when next needed
+		delete(a.continuous, chainNode.ChainID)
+	}
+	a.height++
+	a.nextBlock()
+	a.signalSealed()
+	a.bus.publish(BlockEvent{Block: block, MDRoot: *block.GetMDRoot(), Entries: int(blockEntries)})
+	return nil
+}
+
+// checkChainEntries
+// Check the chain roots given with a block are those it lists, and each of the chain nodes is the node of one
+// of those chains in the block, with its root.  Returns the roots of the chains in the block's group.
+func (a *Accumulator) checkChainEntries(block *node.Node, chainNodes []*node.Node, chainEntries []node.NEList) ([]node.NEList, error) {
+	if len(chainNodes) != len(chainEntries) {
+		return nil, errors.New(fmt.Sprintf("the block at height %d comes with %d chain nodes for %d chains",
+			block.BHeight, len(chainNodes), len(chainEntries)))
+	}
+	listed := make(map[types.Hash]types.Hash, len(block.List))
+	var groupRoot *types.Hash
+	for _, ne := range block.List {
+		if ne.ChainID == GroupChainID && block.Flags.Has(node.Grouped) {
+			groupRoot = ne.MDRoot.Copy()
+			continue
+		}
+		listed[ne.ChainID] = ne.MDRoot
+	}
+	var group []node.NEList
+	groupMD := a.Reader().forFlags(block.Flags).newMD()
+	for i, ne := range chainEntries {
+		chainNode := chainNodes[i]
+		if chainNode.ChainID != ne.ChainID || chainNode.ListMDRoot != ne.MDRoot || chainNode.BHeight != block.BHeight || chainNode.IsNode {
+			return nil, errors.New(fmt.Sprintf("the node given for chain %x isn't its node in the block at height %d",
+				ne.ChainID, block.BHeight))
+		}
+		if root, ok := listed[ne.ChainID]; ok {
+			if root != ne.MDRoot {
+				return nil, errors.New(fmt.Sprintf("chain %x has the root %x, but the block at height %d lists %x",
+					ne.ChainID, ne.MDRoot, block.BHeight, root))
+			}
+			delete(listed, ne.ChainID)
+			continue
+		}
+		if groupRoot == nil || len(chainNode.EntryList) != 1 {
+			return nil, errors.New(fmt.Sprintf("chain %x isn't in the block at height %d", ne.ChainID, block.BHeight))
+		}
+		group = append(group, ne)
+		groupMD.AddToChain(ne.MDRoot)
+	}
+	if len(listed) > 0 {
+		return nil, errors.New(fmt.Sprintf("the block at height %d lists %d chains not given", block.BHeight, len(listed)))
+	}
+	if groupRoot != nil && *groupMD.GetMDRoot() != *groupRoot {
+		return nil, errors.New(fmt.Sprintf("the group of the block at height %d has the root %x, not %x",
+			block.BHeight, groupMD.GetMDRoot(), *groupRoot))
+	}
+	return group, nil
+}
+
+// ServeReplica
+// Stream committed blocks to a replica over conn (a network connection, say).  The replica first says the
+// height it needs, as ReplicateFrom does, and is sent every block from there up to our head, then each block
+// as it is committed, as a ReplicaBlock led by its length, until stop is closed or a write fails.  Run may be
+// running; ServeReplica only reads what has been committed.
+func (a *Accumulator) ServeReplica(conn io.ReadWriter, stop <-chan struct{}) error {
+	var wanted [4]byte
+	if _, err := io.ReadFull(conn, wanted[:]); err != nil {
+		return err
+	}
+	var next types.BlockHeight
+	next.Extract(wanted[:])
+	observer := a.Observe(64) // Attached before reading the head, so no block is missed in between
+	defer observer.Close()
+	send := func(to types.BlockHeight) error {
+		for ; next <= to; next++ {
+			b, err := a.Reader().GetReplicaBlock(next)
+			if err != nil {
+				return err
+			}
+			if _, err := conn.Write(appendFrame(nil, b.Marshal())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	head, err := a.Reader().GetHead()
+	if err != nil {
+		return err
+	}
+	if head != nil {
+		if err := send(head.BHeight); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case event := <-observer.Events: // Events dropped are caught up on with the next one
+			if err := send(event.Block.BHeight); err != nil {
+				return err
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// ReplicateFrom
+// Follow a primary's ServeReplica over conn, asking for the blocks from the one after our head (so a replica
+// restarted with Init carries on from where it was, catching up on what it missed), and applying each with
+// ApplyBlock.  Returns nil once conn is closed between blocks, or the first error.  Don't call it while Run is
+// running.
+func (a *Accumulator) ReplicateFrom(conn io.ReadWriter) error {
+	if _, err := conn.Write(a.height.Bytes()); err != nil {
+		return err
+	}
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(conn, length[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		size, _ := types.BytesUint32(length[:])
+		data := make([]byte, size)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return err
+		}
+		b := new(ReplicaBlock)
+		if err := b.Unmarshal(data); err != nil {
+			return err
+		}
+		if err := a.ApplyBlock(b.Block, b.ChainNodes, b.ChainEntries); err != nil {
+			return err
+		}
+	}
+}
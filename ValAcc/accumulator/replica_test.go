@@ -0,0 +1,123 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// newReplica
+// An accumulator set up as the primary is, over the given database
+func newReplica(db *database.DB) *Accumulator {
+	replica := new(Accumulator)
+	replica.BlockFlags = node.Grouped
+	replica.ContinuousChains = map[types.Hash]bool{types.Hash(sha256.Sum256([]byte("replicated continuous"))): true}
+	chainID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	replica.Init(db, &chainID)
+	return replica
+}
+
+// sealReplicated
+// Seal a block on the primary with a chain of many entries, the continuous chain, and a couple of chains of one
+func sealReplicated(t *testing.T, primary *Accumulator, height int) {
+	for i := 0; i < 4; i++ {
+		primary.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("replicated"))), height*10+i))
+		primary.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("replicated continuous"))), height*10+i))
+	}
+	for i := 0; i < 2; i++ {
+		primary.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte{byte(height), byte(i)})), 0))
+	}
+	if primary.sealBlock() == nil {
+		t.Fatalf("failed to seal the block at height %d", height)
+	}
+}
+
+// replicate
+// Connect a replica to the primary, and wait for the replica to reach the primary's head
+func replicate(t *testing.T, primary, replica *Accumulator, seal func()) {
+	server, client := net.Pipe()
+	stop := make(chan struct{})
+	served, replicated := make(chan error, 1), make(chan error, 1)
+	go func() { served <- primary.ServeReplica(server, stop) }()
+	go func() { replicated <- replica.ReplicateFrom(client) }()
+	seal()
+	head, _ := primary.Reader().GetHead()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if got, _ := replica.Reader().GetHead(); got != nil && *got.GetHash() == *head.GetHash() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the replica never caught up with the primary")
+		}
+	}
+	close(stop)
+	if err := <-served; err != nil {
+		t.Error(err)
+	}
+	server.Close()
+	if err := <-replicated; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	primary := newReplica(db)
+	for height := 0; height < 3; height++ {
+		sealReplicated(t, primary, height)
+	}
+
+	replicaDB := new(database.DB)
+	replicaDB.InitStore(database.NewMemStore())
+	replica := newReplica(replicaDB)
+	replicate(t, primary, replica, func() { // Catch up, then follow the blocks as they are committed
+		for height := 3; height < 5; height++ {
+			sealReplicated(t, primary, height)
+		}
+	})
+
+	replica = newReplica(replicaDB) // Restarted, it carries on from its own head
+	if replica.height != 5 {
+		t.Fatalf("the restarted replica should want height 5, not %d", replica.height)
+	}
+	for height := 5; height < 7; height++ {
+		sealReplicated(t, primary, height)
+	}
+	replicate(t, primary, replica, func() {})
+	primaryState, _ := primary.Reader().StateHash()
+	replicaState, _ := replica.Reader().StateHash()
+	if primaryState != replicaState {
+		t.Error("the replica's chains should end up as the primary's")
+	}
+	chainID := types.Hash(sha256.Sum256([]byte("replicated continuous")))
+	receipt, err := replica.Reader().GetReceipt(chainID, GetTestEntry(chainID, 62).EntryHash, 6)
+	if err != nil || !receipt.Verify() {
+		t.Errorf("the replica should prove the entries of a continuous chain (%v)", err)
+	}
+
+	sealReplicated(t, primary, 7)
+	b, err := primary.Reader().GetReplicaBlock(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := *b.ChainNodes[0]
+	forged.EntryList = append([]types.Hash{sha256.Sum256([]byte("forged"))}, forged.EntryList[1:]...)
+	if replica.ApplyBlock(b.Block, append([]*node.Node{&forged}, b.ChainNodes[1:]...), b.ChainEntries) == nil {
+		t.Error("a chain node whose entries don't give its root should be refused")
+	}
+	if replica.ApplyBlock(b.Block, b.ChainNodes[1:], b.ChainEntries[1:]) == nil {
+		t.Error("a block missing one of its chains should be refused")
+	}
+	if err := replica.ApplyBlock(b.Block, b.ChainNodes, b.ChainEntries); err != nil {
+		t.Error(err)
+	}
+	if replica.ApplyBlock(b.Block, b.ChainNodes, b.ChainEntries) == nil {
+		t.Error("a block applied already should be refused")
+	}
+}
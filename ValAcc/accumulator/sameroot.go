@@ -0,0 +1,51 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+)
+
+// sameRoot
+// Fill in the ListMDRoot of a directory block flagged node.SameRoot, which isn't stored with it, from the block
+// before it (or the last one before that with a root of its own, after a run of empty blocks).  Blocks with
+// roots of their own are left alone.
+func (r *Reader) sameRoot(directoryBlock *node.Node) error {
+	if !directoryBlock.Flags.Has(node.SameRoot) {
+		return nil
+	}
+	for n := directoryBlock; ; {
+		if n.BHeight == 0 {
+			return errors.New(fmt.Sprintf("the directory block at height %d takes its root from a block before the first",
+				directoryBlock.BHeight))
+		}
+		previous, err := r.GetNodeHeader(n.Previous[:])
+		if err != nil {
+			return err
+		}
+		if !previous.Flags.Has(node.SameRoot) {
+			directoryBlock.ListMDRoot = previous.ListMDRoot
+			return nil
+		}
+		n = previous
+	}
+}
+
+// checkSameRoot
+// A directory block flagged node.SameRoot can't list any chains, and has to carry the root of the block before it
+func (r *Reader) checkSameRoot(directoryBlock *node.Node) error {
+	if len(directoryBlock.List) != 0 {
+		return errors.New(fmt.Sprintf("the directory block at height %d lists %d chains, but takes the previous block's root",
+			directoryBlock.BHeight, len(directoryBlock.List)))
+	}
+	previous := *directoryBlock
+	if err := r.sameRoot(&previous); err != nil {
+		return err
+	}
+	if directoryBlock.ListMDRoot != previous.ListMDRoot {
+		return errors.New(fmt.Sprintf("the directory block at height %d has the ListMDRoot %x, but the block before it has %x",
+			directoryBlock.BHeight, directoryBlock.ListMDRoot, previous.ListMDRoot))
+	}
+	return nil
+}
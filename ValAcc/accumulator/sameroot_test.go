@@ -0,0 +1,76 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestReuseEmptyRoots(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.ReuseEmptyRoots = true
+	acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("before the idle blocks"))), 0))
+	first := acc.sealBlock()
+	for i := 0; i < 3; i++ {
+		acc.sealBlock()
+	}
+	acc = func() *Accumulator { // Restarted, with an empty block at its head
+		restarted := new(Accumulator)
+		restarted.ReuseEmptyRoots = true
+		restarted.Init(acc.DB, acc.chainID)
+		return restarted
+	}()
+	acc.sealBlock()
+
+	r := acc.Reader()
+	for height := types.BlockHeight(1); height <= 4; height++ {
+		block, err := r.GetDirectoryBlock(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, _ := r.GetDirectoryBlock(height - 1)
+		if !block.Flags.Has(node.SameRoot) || block.BHeight != height || block.Previous != *previous.GetHash() {
+			t.Errorf("the empty block at height %d should be flagged SameRoot and follow the one before it", height)
+		}
+		if block.ListMDRoot != first.ListMDRoot {
+			t.Errorf("the empty block at height %d should carry the root of the block at height 0", height)
+		}
+		if err := r.VerifyDirectoryBlock(block); err != nil {
+			t.Error(err)
+		}
+		stored := acc.DB.Get(types.Node, block.GetHash()[:])
+		if types.Hash(sha256.Sum256(stored)) != *block.GetHash() || bytes.Contains(stored, first.ListMDRoot[:]) {
+			t.Errorf("the empty block at height %d should be stored without the root", height)
+		}
+	}
+	acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("after the idle blocks"))), 0))
+	if block := acc.sealBlock(); block.Flags.Has(node.SameRoot) || block.ListMDRoot == first.ListMDRoot {
+		t.Error("a block with entries should have a root of its own")
+	}
+
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	replica := new(Accumulator)
+	replica.Init(db, acc.chainID)
+	for height := types.BlockHeight(0); height <= 5; height++ {
+		b, err := r.GetReplicaBlock(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wire := new(node.Node) // As a replica reads it, without the root
+		wire.Unmarshal(b.Block.Marshal())
+		if err := replica.ApplyBlock(wire, b.ChainNodes, b.ChainEntries); err != nil {
+			t.Fatal(err)
+		}
+	}
+	primaryState, _ := r.StateHash()
+	replicaState, _ := replica.Reader().StateHash()
+	if block, err := replica.Reader().GetDirectoryBlock(3); err != nil || primaryState != replicaState ||
+		block.ListMDRoot != first.ListMDRoot {
+		t.Errorf("the replica should end up with the same blocks (%v)", err)
+	}
+}
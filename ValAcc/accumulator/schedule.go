@@ -0,0 +1,98 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// schedule
+// Entries submitted for a particular block height, held until that block is sealed.  open is the height
+// of the lowest block still taking entries; anything below it has been (or is being) sealed.
+type schedule struct {
+	mutex   sync.Mutex
+	open    types.BlockHeight
+	pending map[types.BlockHeight][]node.EntryHash
+}
+
+// add
+// Hold an entry for the block at the given height.  Returns false if that block is no longer open.
+func (s *schedule) add(entry node.EntryHash, height types.BlockHeight) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if height < s.open {
+		return false
+	}
+	if s.pending == nil {
+		s.pending = make(map[types.BlockHeight][]node.EntryHash)
+	}
+	s.pending[height] = append(s.pending[height], entry)
+	return true
+}
+
+// close
+// Stop taking entries for the block at the given height, and return those held for it
+func (s *schedule) close(height types.BlockHeight) []node.EntryHash {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.open = height + 1
+	entries := s.pending[height]
+	delete(s.pending, height)
+	return entries
+}
+
+// has
+// True if entries are held for the block at the given height
+func (s *schedule) has(height types.BlockHeight) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.pending[height]) > 0
+}
+
+// reopen
+// Take entries for the given height again, as the block there was dropped rather than sealed
+func (s *schedule) reopen(height types.BlockHeight) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.open = height
+}
+
+// SubmitAtHeight
+// Queue an entry for the block at the given height rather than the current block.  The entry is added to
+// that block just before it is sealed.  Returns an error (and tells OnReject) if the block at that height
+// has already been sealed, or Submit would refuse the entry; a malformed entry gets validateEntry's error.
+// May be called from any go routine.
+func (a *Accumulator) SubmitAtHeight(entry node.EntryHash, height types.BlockHeight) error {
+	a.submitMux.RLock()
+	defer a.submitMux.RUnlock()
+	if a.stopping.Load() {
+		a.reject(entry, ShuttingDown)
+		return ErrShuttingDown
+	}
+	if err := a.validateEntry(entry); err != nil {
+		a.reject(entry, Malformed)
+		return err
+	}
+	if reason := a.admit(entry); reason != 0 {
+		a.reject(entry, reason)
+		return errors.New(fmt.Sprintf("entry %x for chain %x was rejected as %v", entry.EntryHash, entry.ChainID, reason))
+	}
+	if !a.schedule.add(entry, height) {
+		a.unadmit(entry)
+		a.reject(entry, HeightSealed)
+		return errors.New(fmt.Sprintf("entry %x for chain %x targets block %d, which is already sealed",
+			entry.EntryHash, entry.ChainID, height))
+	}
+	return nil
+}
+
+// addScheduled
+// Close the current block to SubmitAtHeight, and add the entries that were queued for it
+func (a *Accumulator) addScheduled() {
+	for _, entry := range a.schedule.close(a.height) {
+		a.processEntry(entry)
+	}
+}
@@ -0,0 +1,45 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestSubmitAtHeight(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	var reasons []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { reasons = append(reasons, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("scheduled")))
+	now := types.Hash(sha256.Sum256([]byte("now")))
+
+	for i := 0; i < 3; i++ {
+		if err := acc.SubmitAtHeight(GetTestEntry(chainID, i), acc.height+2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var blocks []*node.Node
+	for b := 0; b < 4; b++ {
+		acc.addEntry(GetTestEntry(now, b))
+		blocks = append(blocks, acc.sealBlock())
+	}
+	for b, block := range blocks {
+		chain, err := acc.Reader().GetChainNode(chainID, block.BHeight)
+		if b == 2 {
+			if err != nil || chain.BHeight != 2 || len(chain.EntryList) != 3 {
+				t.Error("the scheduled entries should all be in block 2")
+			}
+		} else if err == nil && chain.BHeight == block.BHeight {
+			t.Errorf("block %d should hold none of the scheduled entries", b)
+		}
+	}
+
+	if err := acc.SubmitAtHeight(GetTestEntry(chainID, 10), 3); err == nil {
+		t.Error("expected an error targeting a sealed block")
+	}
+	if len(reasons) != 1 || reasons[0] != HeightSealed {
+		t.Errorf("expected the entry rejected as HeightSealed, got %v", reasons)
+	}
+}
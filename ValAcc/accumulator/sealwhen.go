@@ -0,0 +1,95 @@
+package accumulator
+
+import (
+	"time"
+)
+
+// BlockState
+// What a SealCondition looks at to decide whether to seal the current block
+type BlockState struct {
+	Entries  int           // Entries added to the block, or held for it by the Sequencer
+	Chains   int           // Distinct chains with entries in the block
+	Duration time.Duration // How long the block has been open, by the Clock
+	Told     bool          // Run has been told to end the block on the control channel
+}
+
+// SealCondition
+// Decides from the state of the current block whether it is time to seal it.  Build them with SealOnEntries,
+// SealOnDuration, SealOnChains and SealOnControl, and combine them with SealAny and SealAll.
+type SealCondition func(state BlockState) bool
+
+// SealOnEntries
+// Seal once the block holds n entries
+func SealOnEntries(n int) SealCondition {
+	return func(state BlockState) bool { return state.Entries >= n }
+}
+
+// SealOnDuration
+// Seal once the block has been open for d
+func SealOnDuration(d time.Duration) SealCondition {
+	return func(state BlockState) bool { return state.Duration >= d }
+}
+
+// SealOnChains
+// Seal once the block has entries for n distinct chains
+func SealOnChains(n int) SealCondition {
+	return func(state BlockState) bool { return state.Chains >= n }
+}
+
+// SealOnControl
+// Seal once Run has been told to end the block on the control channel
+func SealOnControl() SealCondition {
+	return func(state BlockState) bool { return state.Told }
+}
+
+// SealAny
+// Seal once any of the conditions holds
+func SealAny(conditions ...SealCondition) SealCondition {
+	return func(state BlockState) bool {
+		for _, condition := range conditions {
+			if condition(state) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SealAll
+// Seal once all of the conditions hold at the same time
+func SealAll(conditions ...SealCondition) SealCondition {
+	return func(state BlockState) bool {
+		for _, condition := range conditions {
+			if !condition(state) {
+				return false
+			}
+		}
+		return len(conditions) > 0
+	}
+}
+
+// blockState
+// The state of the current block, for the SealWhen condition
+func (a *Accumulator) blockState() BlockState {
+	return BlockState{
+		Entries:  a.blockEntries + len(a.sequenced),
+		Chains:   len(a.chains),
+		Duration: a.clock().Now().Sub(a.blockStart),
+		Told:     a.told,
+	}
+}
+
+// sealDue
+// End the current block if the SealWhen condition holds.  Returns true if it did, whether or not the block was
+// sealed.  A block left open (because it is empty and SkipEmptyBlocks is set) is timed, and has to be told to
+// end, afresh.  Nothing is checked while paused.
+func (a *Accumulator) sealDue() bool {
+	if a.SealWhen == nil || a.paused.Load() || !a.SealWhen(a.blockState()) {
+		return false
+	}
+	if !a.endOfBlock(false) {
+		a.blockStart = a.clock().Now()
+		a.told = false
+	}
+	return true
+}
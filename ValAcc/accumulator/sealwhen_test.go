@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestSealWhen(t *testing.T) {
+	chainID := types.Hash(sha256.Sum256([]byte("sealed when")))
+	other := types.Hash(sha256.Sum256([]byte("sealed when too")))
+	either := SealAny(SealOnEntries(5), SealOnDuration(2*time.Second))
+	both := SealAll(SealOnEntries(3), SealOnDuration(time.Second))
+	for _, c := range []struct {
+		name    string
+		when    SealCondition
+		entries int           // Entries queued for chainID
+		chains  bool          // Whether an entry for the other chain is queued after them
+		wait    time.Duration // How long the clock moves on once the entries are added
+		told    bool          // Whether Run is then told to end the block
+		sealed  int           // Entries in the block sealed, or zero if none should be
+	}{
+		{"any, on entries", either, 7, false, 0, false, 5},
+		{"any, on duration", either, 2, false, 2 * time.Second, false, 2},
+		{"any, on neither", either, 4, false, time.Second, false, 0},
+		{"all, on entries alone", both, 4, false, 0, false, 0},
+		{"all, on duration alone", both, 2, false, time.Second, false, 0},
+		{"all, on both", both, 3, false, time.Second, false, 3},
+		{"control alone", SealAll(SealOnControl(), SealOnChains(2)), 3, false, 0, true, 0},
+		{"control and chains", SealAll(SealOnControl(), SealOnChains(2)), 3, true, 0, true, 4},
+	} {
+		acc := GetTestAccumulator(t)
+		clock := &testClock{now: time.Unix(1000, 0)}
+		acc.Clock = clock
+		acc.SealWhen = c.when
+		for i := 0; i < c.entries; i++ {
+			acc.entryFeed <- GetTestEntry(chainID, i)
+		}
+		if c.chains {
+			acc.entryFeed <- GetTestEntry(other, 0)
+		}
+		runUntilIdle(acc)
+		clock.now = clock.now.Add(c.wait)
+		if c.told {
+			acc.control <- true
+			runUntilIdle(acc)
+		}
+		acc.step() // Check the condition as a timer tick would
+		count, err := acc.Reader().GetBlockEntryCount(0)
+		switch {
+		case c.sealed == 0 && err == nil:
+			t.Errorf("%s: the block shouldn't be sealed", c.name)
+		case c.sealed != 0 && (err != nil || count != c.sealed):
+			t.Errorf("%s: expected a block of %d entries, got %d (%v)", c.name, c.sealed, count, err)
+		}
+	}
+}
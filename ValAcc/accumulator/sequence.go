@@ -0,0 +1,55 @@
+package accumulator
+
+import (
+	"sync"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// EntrySequenceKey
+// Key of an entry's sequence number in the EntrySequence bucket
+func EntrySequenceKey(chainID, entry types.Hash) (key []byte) {
+	key = append(key, chainID.Bytes()...)
+	key = append(key, entry.Bytes()...)
+	return key
+}
+
+// nextSequence
+// The sequence number the next entry added to the chain will get.  Chains start at zero.
+func nextSequence(r *Reader, chainID types.Hash) uint64 {
+	data := r.DB.Get(types.ChainSequence, chainID[:])
+	if len(data) < 8 {
+		return 0
+	}
+	seq, _ := types.BytesUint64(data)
+	return seq
+}
+
+// writeSequences
+// Record the sequence number of every entry the chain added in this block, and where the chain's
+// sequence picks up in the next block.
+func (a *Accumulator) writeSequences(db *database.DB, writes *sync.WaitGroup, chain *ChainAcc) {
+	writes.Add(1)
+	go func() {
+		seq := chain.FirstSequence
+		for _, h := range chain.Node.EntryList {
+			db.Put(types.EntrySequence, EntrySequenceKey(chain.Node.ChainID, h), types.Uint64Bytes(seq))
+			seq++
+		}
+		db.Put(types.ChainSequence, chain.Node.ChainID[:], types.Uint64Bytes(seq))
+		writes.Done()
+	}()
+}
+
+// GetEntrySequence
+// Return the sequence number of an entry in its chain, if the accumulator recorded one.  Sequence numbers
+// are assigned as entries are added, but can only be read once the block holding the entry is sealed.
+func (r *Reader) GetEntrySequence(chainID, entry types.Hash) (uint64, bool) {
+	data := r.DB.Get(types.EntrySequence, EntrySequenceKey(chainID, entry))
+	if len(data) < 8 {
+		return 0, false
+	}
+	seq, _ := types.BytesUint64(data)
+	return seq, true
+}
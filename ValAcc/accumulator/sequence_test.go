@@ -0,0 +1,40 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestEntrySequences(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.EntrySequences = true
+	chainID := types.Hash(sha256.Sum256([]byte("sequenced")))
+	for i := 0; i < 10; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+		if i == 3 {
+			acc.sealBlock()
+		}
+	}
+	acc.sealBlock()
+
+	// Restart over the same database, and the chain should carry on counting where it left off
+	restarted := new(Accumulator)
+	restarted.Init(acc.DB, acc.chainID)
+	restarted.EntrySequences = true
+	for i := 10; i < 15; i++ {
+		restarted.addEntry(GetTestEntry(chainID, i))
+	}
+	restarted.sealBlock()
+
+	for i := 0; i < 15; i++ {
+		seq, ok := restarted.Reader().GetEntrySequence(chainID, GetTestEntry(chainID, i).EntryHash)
+		if !ok || seq != uint64(i) {
+			t.Errorf("entry %d should have sequence %d, got %d (found %v)", i, i, seq, ok)
+		}
+	}
+	if _, ok := restarted.Reader().GetEntrySequence(chainID, GetTestEntry(chainID, 15).EntryHash); ok {
+		t.Error("an entry never added should have no sequence")
+	}
+}
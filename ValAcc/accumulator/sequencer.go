@@ -0,0 +1,49 @@
+package accumulator
+
+import (
+	"sort"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+)
+
+// Sequencer
+// Decides the order of the entries in a block, so every replica fed by the same sequencer builds the same
+// roots whatever order the entries reach it in.  AssignSequence is called for each entry as it is pulled off
+// the entryFeed, before it is added to a chain.
+type Sequencer interface {
+	AssignSequence(entry node.EntryHash) (uint64, error)
+}
+
+// sequencedEntry
+// An entry held for the current block, with the sequence the Sequencer gave it
+type sequencedEntry struct {
+	sequence uint64
+	entry    node.EntryHash
+}
+
+// sequenceEntry
+// Ask the Sequencer where an entry goes, and hold it until the block is sealed.  An entry the Sequencer
+// refuses is rejected as Unsequenced.
+func (a *Accumulator) sequenceEntry(entry node.EntryHash) {
+	sequence, err := a.Sequencer.AssignSequence(entry)
+	if err != nil {
+		a.logger().Printf("no sequence for entry %x for chain %x: %v", entry.EntryHash, entry.ChainID, err)
+		a.reject(entry, Unsequenced)
+		return
+	}
+	a.sequenced = append(a.sequenced, sequencedEntry{sequence: sequence, entry: entry})
+}
+
+// addSequenced
+// Add the entries held for this block in the order of their sequences.  Entries with the same sequence
+// keep the order they arrived in.
+func (a *Accumulator) addSequenced() {
+	held := a.sequenced
+	a.sequenced = nil
+	sort.SliceStable(held, func(i, j int) bool { return held[i].sequence < held[j].sequence })
+	for _, s := range held {
+		a.safely("adding an entry",
+			func() { a.addEntry(s.entry) },
+			func() { a.dropEntry(s.entry) })
+	}
+}
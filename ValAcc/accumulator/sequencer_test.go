@@ -0,0 +1,75 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// testSequencer
+// Assigns each entry the sequence it was given in the map, and refuses entries it doesn't know
+type testSequencer map[types.Hash]uint64
+
+func (s testSequencer) AssignSequence(entry node.EntryHash) (uint64, error) {
+	sequence, ok := s[entry.EntryHash]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("entry %x was never sequenced", entry.EntryHash))
+	}
+	return sequence, nil
+}
+
+func TestSequencer(t *testing.T) {
+	chainA := types.Hash(sha256.Sum256([]byte("sequenced A")))
+	chainB := types.Hash(sha256.Sum256([]byte("sequenced B")))
+	var entries []node.EntryHash
+	sequencer := make(testSequencer)
+	for i := 0; i < 10; i++ {
+		for _, chainID := range []types.Hash{chainA, chainB} {
+			entry := GetTestEntry(chainID, i)
+			sequencer[entry.EntryHash] = uint64(len(entries))
+			entries = append(entries, entry)
+		}
+	}
+
+	// One accumulator gets the entries in order, the other gets them backwards but has the sequencer
+	inOrder := GetTestAccumulator(t)
+	for _, entry := range entries {
+		inOrder.Submit(entry)
+	}
+	runUntilIdle(inOrder)
+	expected := inOrder.sealBlock()
+
+	acc := GetTestAccumulator(t)
+	acc.Sequencer = sequencer
+	rejected := 0
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) {
+		if reason == Unsequenced {
+			rejected++
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		acc.Submit(entries[i])
+	}
+	acc.Submit(GetTestEntry(chainA, 100)) // The sequencer knows nothing of this one
+	runUntilIdle(acc)
+	if rejected != 1 {
+		t.Errorf("expected the unsequenced entry to be rejected, got %d rejected", rejected)
+	}
+	block := acc.sealBlock()
+	if block.ListMDRoot != expected.ListMDRoot {
+		t.Error("the sealed root should follow the sequence, not the order the entries arrived in")
+	}
+	chain, err := acc.Reader().GetChainNode(chainA, block.BHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, h := range chain.EntryList {
+		if h != GetTestEntry(chainA, i).EntryHash {
+			t.Errorf("entry %d of the chain is out of sequence", i)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ReaderAt
+// Return a Reader over a snapshot of the database (see database.Snapshotter) that reads as of the given height:
+// its head is the directory block at that height, directory blocks above it aren't there, and each chain's head
+// is its last node at or below it.  A query spanning many reads sees one consistent state, however many blocks
+// are sealed meanwhile.  The other indexes (entry counts, stats and the like) are read from the snapshot as is,
+// so may know of blocks sealed after the height, before ReaderAt was called.  Returns an error if the Store
+// can't take snapshots or the height hasn't been sealed.  Close the Reader once done with it.
+func (r *Reader) ReaderAt(height types.BlockHeight) (*Reader, error) {
+	db, release, err := r.DB.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	at := *r
+	at.DB = db
+	at.RepairIndex = false // The snapshot can't be written to
+	at.release = release
+	head, err := at.GetHead()
+	if err == nil && (head == nil || head.BHeight < height) {
+		err = errors.New(fmt.Sprintf("no directory block at height %d to read as of", height))
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+	at.bounded, at.at = true, height
+	return &at, nil
+}
+
+// Close
+// Let go of the snapshot of a Reader given by ReaderAt.  Nothing to do for any other Reader.
+func (r *Reader) Close() {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+}
+
+// headHash
+// The hash of the chain's head node, or nil if it has none.  For a Reader from ReaderAt, that is its last node
+// at or below the Reader's height, found walking back from its head.
+func (r *Reader) headHash(chainID types.Hash) ([]byte, error) {
+	hash := r.DB.Get(types.NodeHead, chainID[:])
+	for r.bounded && hash != nil {
+		n, err := r.GetNodeHeader(hash)
+		if err != nil {
+			return nil, err
+		}
+		if n.BHeight <= r.at {
+			break
+		}
+		if n.SequenceNum == 0 { // No node until after the height
+			return nil, nil
+		}
+		hash = append([]byte{}, n.Previous[:]...)
+	}
+	return hash, nil
+}
@@ -0,0 +1,66 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestReaderAt(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("snapshot")))
+	seal := func(height int) *node.Node {
+		acc.addEntry(GetTestEntry(chainID, height))
+		if height > 3 { // A chain the snapshot should never see
+			acc.addEntry(GetTestEntry(types.Hash(sha256.Sum256([]byte("after the snapshot"))), height))
+		}
+		return acc.sealBlock()
+	}
+	var head *node.Node
+	for height := 0; height <= 3; height++ {
+		head = seal(height)
+	}
+	state, _ := acc.Reader().StateHash()
+	chainHead, _ := acc.Reader().GetChainHead(chainID)
+
+	at, err := acc.Reader().ReaderAt(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer at.Close()
+	for height := 4; height < 7; height++ {
+		seal(height)
+	}
+	if got, err := at.GetHead(); err != nil || *got.GetHash() != *head.GetHash() {
+		t.Errorf("the head should be the block at height 3 (%v)", err)
+	}
+	if _, err := at.GetDirectoryBlock(4); err == nil {
+		t.Error("blocks sealed after height 3 should not be read")
+	}
+	if got, err := at.GetChainHead(chainID); err != nil || *got.GetHash() != *chainHead.GetHash() {
+		t.Errorf("the chain's head should be its node at height 3 (%v)", err)
+	}
+	if got, err := at.GetChainHead(types.Hash(sha256.Sum256([]byte("after the snapshot")))); got != nil || err != nil {
+		t.Errorf("a chain started after height 3 should have no head (%v)", err)
+	}
+	if got, err := at.StateHash(); err != nil || got != state {
+		t.Errorf("the state should be as it was at height 3 (%v)", err)
+	}
+	if _, err := at.GetChainNode(chainID, 3); err != nil {
+		t.Error(err)
+	}
+
+	earlier, err := acc.Reader().ReaderAt(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer earlier.Close()
+	if got, err := earlier.GetChainHead(chainID); err != nil || got.BHeight != 1 {
+		t.Errorf("a Reader at height 1 should see the chain's node at height 1 as its head (%v)", err)
+	}
+	if _, err := acc.Reader().ReaderAt(7); err == nil {
+		t.Error("a height not yet sealed can't be read as of")
+	}
+}
@@ -0,0 +1,43 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// StateHash
+// Return one hash committing to the whole state of the accumulator, so replicas can be compared at a glance:
+// the sha256 of the height and hash of the head directory block, followed by the ChainID and head node hash
+// of every chain, in ChainID order.  Replicas that have sealed the same blocks get the same StateHash, and
+// any difference in the blocks or in any chain's head gives a different one.  Returns an error if no blocks
+// have been sealed.
+func (r *Reader) StateHash() (types.Hash, error) {
+	head, err := r.GetHead()
+	if err != nil {
+		return types.Hash{}, err
+	}
+	if head == nil {
+		return types.Hash{}, errors.New("no blocks have been sealed")
+	}
+	data := append(head.BHeight.Bytes(), head.GetHash().Bytes()...)
+	err = r.DB.IterateBucket(types.NodeHead, func(key, value []byte) error { // In key, so ChainID, order
+		if r.bounded { // The chain's head as of the Reader's height, if it had one by then
+			var chainID types.Hash
+			copy(chainID[:], key)
+			hash, err := r.headHash(chainID)
+			if hash == nil || err != nil {
+				return err
+			}
+			value = hash
+		}
+		data = append(data, key...)
+		data = append(data, value...)
+		return nil
+	})
+	if err != nil {
+		return types.Hash{}, err
+	}
+	return sha256.Sum256(data), nil
+}
@@ -0,0 +1,45 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestStateHash(t *testing.T) {
+	replica := func(entries int) *Reader {
+		acc := GetTestAccumulator(t)
+		acc.Clock = &testClock{now: time.Unix(1000, 0)} // Node hashes cover the time they were built
+		for i := 0; i < entries; i++ {
+			chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("state %d", i%3))))
+			acc.Submit(GetTestEntry(chainID, i))
+			if i%4 == 3 {
+				runUntilIdle(acc)
+				acc.SealBlock()
+			}
+		}
+		runUntilIdle(acc)
+		acc.SealBlock()
+		return acc.Reader()
+	}
+	a, err := replica(10).StateHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := replica(10).StateHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("replicas fed the same entries have the state hashes %x and %x", a, b)
+	}
+	if c, err := replica(11).StateHash(); err != nil || c == a {
+		t.Errorf("a replica with another entry should have another state hash (%v)", err)
+	}
+	if _, err := GetTestAccumulator(t).Reader().StateHash(); err == nil {
+		t.Error("an accumulator with no blocks sealed shouldn't have a state hash")
+	}
+}
@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockStub
+// The parts of a directory block a light client needs to check the chain of directory blocks, without
+// the list of chains in the block
+type BlockStub struct {
+	Height     types.BlockHeight // Height of the directory block
+	Hash       types.Hash        // Hash of the directory block
+	Previous   types.Hash        // Hash of the directory block before it
+	ListMDRoot types.Hash        // Root of the chains in the block
+	TimeStamp  types.TimeStamp   // When the block was sealed
+}
+
+// GetBlockStub
+// Return the stub of the directory block at the given height
+func (r *Reader) GetBlockStub(height types.BlockHeight) (*BlockStub, error) {
+	directoryBlock, err := r.GetDirectoryBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	stub := new(BlockStub)
+	stub.Height = directoryBlock.BHeight
+	stub.Hash = *directoryBlock.GetHash()
+	stub.Previous = directoryBlock.Previous
+	stub.ListMDRoot = directoryBlock.ListMDRoot
+	stub.TimeStamp = directoryBlock.TimeStamp
+	return stub, nil
+}
+
+// VerifyStubChain
+// Check the stubs, in height order, form an unbroken chain: each is at the height after the one before it,
+// and its Previous is that block's Hash.  A chain starting at genesis has to start with no Previous.
+// The Hash of each stub is taken on trust; only the full directory block can show it is right.
+func VerifyStubChain(stubs []BlockStub) error {
+	for i, stub := range stubs {
+		if i == 0 {
+			if stub.Height == 0 && stub.Previous != (types.Hash{}) {
+				return errors.New("the genesis block should have no previous block")
+			}
+			continue
+		}
+		prior := stubs[i-1]
+		if stub.Height != prior.Height+1 {
+			return errors.New(fmt.Sprintf("the block at height %d follows the block at height %d", stub.Height, prior.Height))
+		}
+		if stub.Previous != prior.Hash {
+			return errors.New(fmt.Sprintf("the block at height %d has the previous hash %x, but the block before it is %x",
+				stub.Height, stub.Previous, prior.Hash))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestStubChain(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("stubbed")))
+	var stubs []BlockStub
+	for b := 0; b < 100; b++ {
+		acc.addEntry(GetTestEntry(chainID, b))
+		block := acc.sealBlock()
+		stub, err := acc.Reader().GetBlockStub(block.BHeight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stub.Hash != *block.GetHash() || stub.ListMDRoot != block.ListMDRoot {
+			t.Fatalf("the stub at height %d doesn't match its block", b)
+		}
+		stubs = append(stubs, *stub)
+	}
+	if err := VerifyStubChain(stubs); err != nil {
+		t.Errorf("the stubs of the blocks sealed should verify: %v", err)
+	}
+	if err := VerifyStubChain(stubs[40:60]); err != nil {
+		t.Errorf("a run of stubs from the middle should verify: %v", err)
+	}
+
+	broken := append([]BlockStub{}, stubs...)
+	broken[50].Previous[0] ^= 1
+	if VerifyStubChain(broken) == nil {
+		t.Error("a broken link should fail to verify")
+	}
+	if VerifyStubChain(append(append([]BlockStub{}, stubs[:50]...), stubs[51:]...)) == nil {
+		t.Error("a missing block should fail to verify")
+	}
+	if _, err := acc.Reader().GetBlockStub(100); err == nil {
+		t.Error("there is no block at height 100 to stub")
+	}
+}
@@ -0,0 +1,234 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// ErrShuttingDown
+// Returned by SubmitAtHeight once Stop has been called
+var ErrShuttingDown = errors.New("the accumulator is shutting down")
+
+// Errors validateEntry returns for a malformed entry
+var (
+	ErrZeroEntryHash    = errors.New("the entry hash is all zeros")
+	ErrZeroChainID      = errors.New("the ChainID is all zeros")
+	ErrDirectoryChainID = errors.New("the ChainID is the accumulator's own, which only its directory blocks use")
+)
+
+// PayloadResolver
+// Fetch the payload of the entry with the given hash, for an EntryValidator
+type PayloadResolver func(entry types.Hash) ([]byte, error)
+
+// RejectReason
+// Why the accumulator refused an entry submitted to it
+type RejectReason int
+
+const (
+	RateLimited     RejectReason = iota + 1 // The entry's chain is over MaxEntriesPerChainPerSecond
+	HeightSealed                            // SubmitAtHeight targeted a block that has already been sealed
+	RecentDuplicate                         // The entry is already in one of the last RecentDuplicateBlocks blocks
+	TooManyChains                           // The entry would add a chain to a block already at MaxChainsPerBlock
+	Unsequenced                             // The Sequencer couldn't assign the entry a sequence
+	NotLogged                               // The entry couldn't be written to the write ahead log
+	ShuttingDown                            // Stop has been called
+	FilteredType                            // AcceptEntryType refused the entry's type
+	AlreadyRecorded                         // With PermanentDedup, the entry was sealed in its chain before
+	Malformed                               // With ValidateEntries, validateEntry (or the EntryValidator) refused it
+	Unhealthy                               // MaxCommitFailures commits in a row failed; see Health
+	QuotaExceeded                           // The tenant of the entry's chain has used up its quota
+	TimeSkew                                // The submitter's TimeStamp is further than MaxTimeSkew from the Clock
+	FeedFull                                // With DropWhenFeedFull, the entryFeed had no room for the entry
+)
+
+func (r RejectReason) String() string {
+	switch r {
+	case RateLimited:
+		return "rate limited"
+	case HeightSealed:
+		return "height sealed"
+	case RecentDuplicate:
+		return "recent duplicate"
+	case TooManyChains:
+		return "too many chains"
+	case Unsequenced:
+		return "unsequenced"
+	case NotLogged:
+		return "not logged"
+	case ShuttingDown:
+		return "shutting down"
+	case FilteredType:
+		return "filtered type"
+	case AlreadyRecorded:
+		return "already recorded"
+	case Malformed:
+		return "malformed"
+	case Unhealthy:
+		return "unhealthy"
+	case QuotaExceeded:
+		return "quota exceeded"
+	case TimeSkew:
+		return "time skew"
+	case FeedFull:
+		return "feed full"
+	}
+	return "unknown"
+}
+
+// Submit
+// Queue an entry for the accumulator, subject to the limits set on the accumulator.  Returns false if the
+// entry was rejected, in which case OnReject (if set) is told why (and the logger, for a Malformed entry, told
+// what is wrong with it).  With a WALPath, the entry is on disk
+// before Submit returns true.  Submit may be called from any go routine.
+func (a *Accumulator) Submit(entry node.EntryHash) bool {
+	reason, _ := a.submit(entry)
+	return reason == 0
+}
+
+// SubmitErr
+// Submit an entry as Submit does, but return why it was rejected as an error, or nil if it was queued:
+// ErrShuttingDown once Stop has been called, validateEntry's error (ErrZeroChainID, say) for a Malformed
+// entry, or an error naming the RejectReason for the rest.  May be called from any go routine.
+func (a *Accumulator) SubmitErr(entry node.EntryHash) error {
+	reason, err := a.submit(entry)
+	return rejectError(entry, reason, err)
+}
+
+// rejectError
+// The error SubmitErr returns for an entry submit rejected, given the reason and the error behind it
+func rejectError(entry node.EntryHash, reason RejectReason, err error) error {
+	switch {
+	case reason == 0:
+		return nil
+	case reason == ShuttingDown:
+		return ErrShuttingDown
+	case reason == Malformed:
+		return err
+	case err != nil:
+		return errors.New(fmt.Sprintf("entry %x for chain %x was rejected as %v: %v", entry.EntryHash, entry.ChainID, reason, err))
+	}
+	return errors.New(fmt.Sprintf("entry %x for chain %x was rejected as %v", entry.EntryHash, entry.ChainID, reason))
+}
+
+// submit
+// Submit the entry, returning why it was rejected, or zero if it was queued, along with the error behind a
+// Malformed or NotLogged entry
+func (a *Accumulator) submit(entry node.EntryHash) (RejectReason, error) {
+	a.submitMux.RLock()
+	defer a.submitMux.RUnlock()
+	if a.stopping.Load() {
+		a.reject(entry, ShuttingDown)
+		return ShuttingDown, nil
+	}
+	if err := a.validateEntry(entry); err != nil {
+		a.logger().Printf("malformed entry %x for chain %x: %v", entry.EntryHash, entry.ChainID, err)
+		a.reject(entry, Malformed)
+		return Malformed, err
+	}
+	if reason := a.admit(entry); reason != 0 {
+		a.reject(entry, reason)
+		return reason, nil
+	}
+	if a.WALPath != "" {
+		if err := a.wal.submit(entry, a.entryFeed); err != nil {
+			a.logger().Printf("failed to log entry %x for chain %x: %v", entry.EntryHash, entry.ChainID, err)
+			a.unadmit(entry)
+			a.reject(entry, NotLogged)
+			return NotLogged, err
+		}
+		a.feedSent()
+		return 0, nil
+	}
+	if a.DropWhenFeedFull {
+		select {
+		case a.entryFeed <- entry:
+		default:
+			a.feedDropped(&a.feed.feedFull, MetricFeedFull, 1)
+			a.unadmit(entry)
+			a.reject(entry, FeedFull)
+			return FeedFull, nil
+		}
+	} else {
+		a.entryFeed <- entry
+	}
+	a.feedSent()
+	return 0, nil
+}
+
+// validateEntry
+// With ValidateEntries set, check the entry is well formed: neither its hash nor its ChainID may be all zeros,
+// and its ChainID can't be the accumulator's own.  (Both are fixed length Hashes, so the length can't be
+// wrong.)  Then the EntryValidator, if set, has its say.  Returns nil for a good entry, or without
+// ValidateEntries.
+func (a *Accumulator) validateEntry(entry node.EntryHash) error {
+	if !a.ValidateEntries {
+		return nil
+	}
+	switch {
+	case entry.EntryHash == types.Hash{}:
+		return ErrZeroEntryHash
+	case entry.ChainID == types.Hash{}:
+		return ErrZeroChainID
+	case a.chainID != nil && entry.ChainID == *a.chainID:
+		return ErrDirectoryChainID
+	}
+	if a.EntryValidator != nil {
+		return a.EntryValidator(entry, a.PayloadResolver)
+	}
+	return nil
+}
+
+// admit
+// Check an entry against the limits set on the accumulator.  Returns why the entry is refused, or zero.
+func (a *Accumulator) admit(entry node.EntryHash) RejectReason {
+	if a.unhealthy.Load() {
+		return Unhealthy
+	}
+	if a.AcceptEntryType != nil && !a.AcceptEntryType(node.EntryType(entry)) {
+		return FilteredType
+	}
+	if a.skewed(entry) {
+		return TimeSkew
+	}
+	if a.MaxEntriesPerChainPerSecond > 0 && !a.throttle.allow(entry.ChainID, a.MaxEntriesPerChainPerSecond, a.clock().Now()) {
+		return RateLimited
+	}
+	reason := RejectReason(0)
+	switch {
+	case a.RecentDuplicateBlocks > 0 && a.recentDuplicate(entry.EntryHash):
+		reason = RecentDuplicate
+	case a.PermanentDedup && a.Reader().Recorded(entry)[0]:
+		reason = AlreadyRecorded
+	case a.TenantResolver != nil && !a.allowTenant(entry): // Last, so only entries otherwise admitted are counted
+		reason = QuotaExceeded
+	}
+	if reason != 0 && a.MaxEntriesPerChainPerSecond > 0 { // The entry doesn't cost its chain a token after all
+		a.throttle.giveBack(entry.ChainID, a.MaxEntriesPerChainPerSecond)
+	}
+	return reason
+}
+
+// unadmit
+// Give back what admit took for an entry that was then refused anyway (as FeedFull, say): its chain's token,
+// and its count against its tenant's quota
+func (a *Accumulator) unadmit(entry node.EntryHash) {
+	if a.MaxEntriesPerChainPerSecond > 0 {
+		a.throttle.giveBack(entry.ChainID, a.MaxEntriesPerChainPerSecond)
+	}
+	if a.TenantResolver != nil {
+		a.refundTenant(entry)
+	}
+}
+
+// reject
+// Tell whoever cares that an entry was refused
+func (a *Accumulator) reject(entry node.EntryHash, reason RejectReason) {
+	a.metrics().Add(MetricRejected, 1)
+	if a.OnReject != nil {
+		a.OnReject(entry, reason)
+	}
+	a.rejects.publish(RejectEvent{Entry: entry, Reason: reason, Time: a.clock().Now()})
+}
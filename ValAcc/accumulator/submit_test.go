@@ -0,0 +1,97 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestValidateEntries(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("validated")))
+
+	zeroHash := node.EntryHash{ChainID: chainID}
+	if !acc.Submit(zeroHash) {
+		t.Fatal("without ValidateEntries, even an all zero entry hash is accepted")
+	}
+	runUntilIdle(acc)
+
+	acc.ValidateEntries = true
+	good := GetTestEntry(chainID, 0)
+	for _, malformed := range []struct {
+		entry node.EntryHash
+		err   error
+	}{
+		{zeroHash, ErrZeroEntryHash},
+		{GetTestEntry(types.Hash{}, 1), ErrZeroChainID},
+		{GetTestEntry(*acc.chainID, 2), ErrDirectoryChainID},
+	} {
+		rejected = nil
+		if err := acc.SubmitErr(malformed.entry); err != malformed.err || len(rejected) != 1 || rejected[0] != Malformed {
+			t.Errorf("SubmitErr should reject the entry as Malformed with %v, got %v (%v)", malformed.err, err, rejected)
+		}
+		if acc.Submit(malformed.entry) {
+			t.Errorf("Submit should reject the entry (%v)", malformed.err)
+		}
+		if err := acc.SubmitAtHeight(malformed.entry, acc.height+1); err != malformed.err {
+			t.Errorf("SubmitAtHeight should return %v, got %v", malformed.err, err)
+		}
+	}
+	if len(acc.entryFeed) != 0 {
+		t.Error("no malformed entry should reach the entry feed")
+	}
+	if err := acc.SubmitErr(good); err != nil {
+		t.Errorf("a good entry should be accepted (%v)", err)
+	}
+}
+
+func TestEntryValidator(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	var rejected []RejectReason
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) { rejected = append(rejected, reason) }
+	chainID := types.Hash(sha256.Sum256([]byte("resolved")))
+	payloads := map[types.Hash][]byte{} // The validator's own store
+	entry := func(payload string) node.EntryHash {
+		e := node.EntryHash{ChainID: chainID, EntryHash: sha256.Sum256([]byte(payload))}
+		payloads[e.EntryHash] = []byte(payload)
+		return e
+	}
+	acc.ValidateEntries = true
+	acc.PayloadResolver = func(entry types.Hash) ([]byte, error) {
+		payload, ok := payloads[entry]
+		if !ok {
+			return nil, errors.New("no such payload")
+		}
+		return payload, nil
+	}
+	acc.EntryValidator = func(entry node.EntryHash, resolve PayloadResolver) error {
+		payload, err := resolve(entry.EntryHash)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(string(payload), "{") {
+			return errors.New("the payload isn't an object")
+		}
+		return nil
+	}
+
+	if !acc.Submit(entry(`{"amount": 5}`)) {
+		t.Error("an entry whose payload passes the rule should be accepted")
+	}
+	if acc.Submit(entry("amount: 5")) {
+		t.Error("an entry whose payload fails the rule should be refused")
+	}
+	if acc.Submit(GetTestEntry(chainID, 1)) {
+		t.Error("an entry whose payload can't be resolved should be refused")
+	}
+	if fmt.Sprint(rejected) != "[malformed malformed]" || len(acc.entryFeed) != 1 {
+		t.Errorf("the refused entries should be Malformed, and kept off the feed, not %v", rejected)
+	}
+}
@@ -0,0 +1,62 @@
+package accumulator
+
+import (
+	"errors"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+)
+
+// storeSwap
+// A Store for Run to move the accumulator onto, and where to say how it went
+type storeSwap struct {
+	store database.Store
+	done  chan error
+}
+
+// SwapStore
+// Move a paused accumulator onto another Store, say one MigrateStore has been copying the accumulator's Store
+// into while it ran.  Run brings the new Store up to date with a last MigrateStore, then points DB at a new
+// database.DB over it, without sealing a block in between.  Entries are still taken while the Store is swapped;
+// Resume once SwapStore returns.  Readers got before the swap go on reading the old Store, which is left as it
+// was.  Run has to be running, SwapStore can't be called from the go routine running it, and Prune shouldn't
+// run at the same time.
+func (a *Accumulator) SwapStore(store database.Store) error {
+	if !a.paused.Load() {
+		return errors.New("the accumulator has to be paused to swap its store")
+	}
+	swap := storeSwap{store: store, done: make(chan error, 1)}
+	select {
+	case a.swaps <- swap:
+	case <-a.stopped:
+		return errors.New("the accumulator has stopped")
+	}
+	return <-swap.done
+}
+
+// swapStore
+// Copy what is left to copy into the store, and move the accumulator onto it.  Called by Run.
+func (a *Accumulator) swapStore(store database.Store) error {
+	if !a.paused.Load() { // Resumed while the swap was waiting for Run
+		return errors.New("the accumulator has to be paused to swap its store")
+	}
+	a.watchMux.Lock() // AckRoot writes to the store from other go routines
+	defer a.watchMux.Unlock()
+	if err := database.MigrateStore(a.DB.GetStore(), store); err != nil {
+		return err
+	}
+	db := new(database.DB)
+	db.InitStore(store)
+	a.dbMux.Lock()
+	a.DB = db
+	a.dbMux.Unlock()
+	a.logger().Printf("swapped the store of the accumulator at height %d", a.height)
+	return nil
+}
+
+// db
+// The accumulator's database, for use off the go routine running Run, which is the only one to change it
+func (a *Accumulator) db() *database.DB {
+	a.dbMux.RLock()
+	defer a.dbMux.RUnlock()
+	return a.DB
+}
@@ -0,0 +1,70 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestSwapStore(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.MaxEntriesPerBlock = 5
+	sealed := make(chan *node.Node, 10)
+	acc.OnCommit = func(directoryBlock *node.Node) { sealed <- directoryBlock }
+	chainID := types.Hash(sha256.Sum256([]byte("migrated")))
+	submitBlocks := func(from, blocks int) {
+		for i := from * 5; i < (from+blocks)*5; i++ {
+			acc.Submit(GetTestEntry(chainID, i))
+		}
+		for b := 0; b < blocks; b++ {
+			<-sealed
+		}
+	}
+	go acc.Run()
+	submitBlocks(0, 3)
+
+	store := database.NewMemStore()
+	if err := database.MigrateStore(acc.DB.GetStore(), store); err != nil { // The bulk of it, while running
+		t.Fatal(err)
+	}
+	old := acc.DB
+	submitBlocks(3, 2)
+	if err := acc.SwapStore(store); err == nil {
+		t.Error("an accumulator that isn't paused shouldn't swap its store")
+	}
+	acc.Pause()
+	if err := acc.SwapStore(store); err != nil {
+		t.Fatal(err)
+	}
+	acc.Resume()
+	submitBlocks(5, 1)
+	acc.Stop()
+	if _, err := NewReader(old, *acc.chainID).GetDirectoryBlock(5); err == nil {
+		t.Error("the block sealed after the swap shouldn't be written to the old store")
+	}
+
+	db := new(database.DB)
+	db.InitStore(store)
+	restarted := new(Accumulator)
+	restarted.Init(db, acc.chainID)
+	if err := restarted.SelfCheck(); err != nil || restarted.height != 7 {
+		t.Fatalf("expected to restart at height 7 on the new store, got %d (%v)", restarted.height, err)
+	}
+	reader := restarted.Reader()
+	for h := 0; h < 6; h++ {
+		block, err := reader.GetDirectoryBlock(types.BlockHeight(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.VerifyDirectoryBlock(block); err != nil {
+			t.Error(err)
+		}
+		receipt, err := reader.GetReceipt(chainID, GetTestEntry(chainID, h*5+2).EntryHash, block.BHeight)
+		if err != nil || !receipt.Verify() {
+			t.Errorf("the receipt of an entry in block %d should verify on the new store (%v)", h, err)
+		}
+	}
+}
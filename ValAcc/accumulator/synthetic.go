@@ -0,0 +1,45 @@
+package accumulator
+
+import (
+	"runtime/debug"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockBuilder
+// Handed to BeforeSeal so it can add entries of its own (heartbeats, epoch markers ...) to the block about
+// to be sealed.  Only good for the duration of the call.
+type BlockBuilder struct {
+	acc *Accumulator
+}
+
+// Height
+// The height of the block about to be sealed
+func (b *BlockBuilder) Height() types.BlockHeight {
+	return b.acc.height
+}
+
+// Add
+// Add an entry to the block about to be sealed.  It goes into its chain's MD like any entry pulled off the
+// entryFeed (through the Sequencer, if there is one), but isn't subject to the limits Submit checks.
+func (b *BlockBuilder) Add(entry node.EntryHash) {
+	b.acc.processEntry(entry)
+}
+
+// beforeSeal
+// Give BeforeSeal its chance to add entries to the block being sealed.  Like OnCommit, a panic in BeforeSeal
+// is logged, and the block is sealed with whatever was added before it panicked.
+func (a *Accumulator) beforeSeal() {
+	if a.BeforeSeal == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger().Printf("recovered from a panic in BeforeSeal for the block at height %d: %v\n%s",
+				a.height, r, debug.Stack())
+			a.metrics().Add(MetricPanics, 1)
+		}
+	}()
+	a.BeforeSeal(&BlockBuilder{acc: a})
+}
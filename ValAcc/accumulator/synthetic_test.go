@@ -0,0 +1,42 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestBeforeSeal(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	heartbeat := types.Hash(sha256.Sum256([]byte("heartbeat")))
+	acc.BeforeSeal = func(builder *BlockBuilder) {
+		builder.Add(GetTestEntry(heartbeat, int(builder.Height())))
+	}
+	chainID := types.Hash(sha256.Sum256([]byte("ordinary")))
+	acc.addEntry(GetTestEntry(chainID, 0))
+	acc.SealBlock()
+	acc.SealBlock() // Nothing but the heartbeat
+	acc.addEntry(GetTestEntry(chainID, 1))
+	acc.SealBlock()
+
+	r := acc.Reader()
+	for height := types.BlockHeight(0); height < 3; height++ {
+		chain, err := r.GetChainNode(heartbeat, height)
+		if err != nil {
+			t.Fatalf("block %d should hold a heartbeat: %v", height, err)
+		}
+		md := new(merkleDag.MD)
+		md.AddToChain(GetTestEntry(heartbeat, int(height)).EntryHash)
+		if len(chain.EntryList) != 1 || chain.ListMDRoot != *md.GetMDRoot() {
+			t.Errorf("block %d should hold just its heartbeat entry in the heartbeat chain's MD", height)
+		}
+	}
+	if block, _ := r.GetDirectoryBlock(1); len(block.List) != 1 {
+		t.Error("the block with no other entries should hold only the heartbeat chain")
+	}
+	if total, _ := r.TotalEntries(); total != 5 {
+		t.Errorf("the heartbeats should count as entries, expected 5 in all, got %d", total)
+	}
+}
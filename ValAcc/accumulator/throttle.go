@@ -0,0 +1,87 @@
+package accumulator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// How often the throttle forgets about chains that have gone idle
+const throttleSweep = 10 * time.Second
+
+// bucket
+// A token bucket for one chain.  It holds up to a second's worth of entries, and refills at the rate limit.
+type bucket struct {
+	tokens float64   // Entries the chain can submit right now
+	last   time.Time // When tokens was last brought up to date
+}
+
+// throttle
+// Rate limits submissions per chain.  Chains that haven't submitted for long enough to have refilled their
+// bucket are dropped in a periodic sweep, so idle chains don't cost us memory.
+type throttle struct {
+	mutex     sync.Mutex
+	buckets   map[types.Hash]*bucket
+	lastSweep time.Time
+}
+
+// allow
+// Take a token from the chain's bucket if it has one.  Returns false if the chain is over its rate.
+func (t *throttle) allow(chainID types.Hash, rate int, now time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.buckets == nil {
+		t.buckets = make(map[types.Hash]*bucket)
+		t.lastSweep = now
+	}
+	if now.Sub(t.lastSweep) >= throttleSweep {
+		t.sweep(rate, now)
+	}
+
+	b := t.buckets[chainID]
+	if b == nil {
+		b = &bucket{tokens: float64(rate), last: now}
+		t.buckets[chainID] = b
+	}
+	b.refill(rate, now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// giveBack
+// Return the token allow took for an entry that was then refused after all
+func (t *throttle) giveBack(chainID types.Hash, rate int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if b := t.buckets[chainID]; b != nil && b.tokens < float64(rate) { // Swept away once full anyway
+		b.tokens++
+	}
+}
+
+// refill
+// Add the tokens earned since the bucket was last updated
+func (b *bucket) refill(rate int, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * float64(rate)
+		if b.tokens > float64(rate) {
+			b.tokens = float64(rate)
+		}
+		b.last = now
+	}
+}
+
+// sweep
+// Drop the buckets that would be full by now; a new full bucket is made if the chain comes back.
+func (t *throttle) sweep(rate int, now time.Time) {
+	for chainID, b := range t.buckets {
+		b.refill(rate, now)
+		if b.tokens >= float64(rate) {
+			delete(t.buckets, chainID)
+		}
+	}
+	t.lastSweep = now
+}
@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestRateLimit(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	acc.MaxEntriesPerChainPerSecond = 10
+	rejected := map[types.Hash]int{}
+	acc.OnReject = func(entry node.EntryHash, reason RejectReason) {
+		if reason != RateLimited {
+			t.Errorf("unexpected reject reason %v", reason)
+		}
+		rejected[entry.ChainID]++
+	}
+	noisy := types.Hash(sha256.Sum256([]byte("noisy")))
+	quiet := types.Hash(sha256.Sum256([]byte("quiet")))
+
+	// Over two seconds the noisy chain submits 30 a second, and the quiet chain 5 a second
+	for second := 0; second < 2; second++ {
+		for i := 0; i < 30; i++ {
+			acc.Submit(GetTestEntry(noisy, second*30+i))
+			if i%6 == 0 {
+				acc.Submit(GetTestEntry(quiet, second*5+i/6))
+			}
+			clock.now = clock.now.Add(time.Second / 30)
+		}
+	}
+	runUntilIdle(acc)
+	acc.sealBlock()
+
+	noisyNode, _ := acc.Reader().GetChainNode(noisy, 0)
+	quietNode, _ := acc.Reader().GetChainNode(quiet, 0)
+	if quietNode == nil || len(quietNode.EntryList) != 10 || rejected[quiet] != 0 {
+		t.Error("all of the quiet chain's entries should land")
+	}
+	// A full bucket of 10, then 10 more a second for the (just under) 2 seconds that follow
+	if noisyNode == nil || len(noisyNode.EntryList) > 30 || len(noisyNode.EntryList) < 28 {
+		t.Errorf("the noisy chain should be held to its rate")
+	}
+	if noisyNode != nil && len(noisyNode.EntryList)+rejected[noisy] != 60 {
+		t.Errorf("every noisy entry should land or be rejected")
+	}
+
+	// Once idle, the chains are forgotten
+	clock.now = clock.now.Add(time.Minute)
+	acc.Submit(GetTestEntry(quiet, 100))
+	if len(acc.throttle.buckets) != 1 {
+		t.Errorf("expected the idle chains to be swept, have %d buckets", len(acc.throttle.buckets))
+	}
+}
@@ -0,0 +1,122 @@
+package accumulator
+
+import (
+	"sync"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Name of the span the accumulator starts around sealing each block, and the attributes set on it
+const (
+	SpanSeal         = "accumulator.seal"
+	AttrHeight       = "accumulator.height"        // types.BlockHeight of the block
+	AttrEntryCount   = "accumulator.entry_count"   // int, entries in the block
+	AttrChainCount   = "accumulator.chain_count"   // int, chains with entries in the block
+	AttrSealDuration = "accumulator.seal_duration" // time.Duration, by the Clock
+	AttrSealed       = "accumulator.sealed"        // bool, false if the block was dropped or left open
+)
+
+// TraceContext
+// Whatever identifies a span to the Tracer (an OpenTelemetry SpanContext, say).  The accumulator never looks
+// inside it, only hands it back to the Tracer as a link.
+type TraceContext interface{}
+
+// Tracer
+// Starts spans around the work the accumulator does, so block production shows up in whatever tracing is in
+// use.  An adapter over OpenTelemetry (or anything else) keeps the accumulator from depending on it.
+type Tracer interface {
+	StartSpan(name string) Span
+}
+
+// Span
+// A span started by the Tracer.  The accumulator ends every span it starts.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	AddLink(link TraceContext) // Link the span to another, such as that of an entry submitted into the block
+	End()
+}
+
+type noSpan struct{}
+
+func (noSpan) SetAttribute(string, interface{}) {}
+func (noSpan) AddLink(TraceContext)             {}
+func (noSpan) End()                             {}
+
+// traces
+// The trace contexts of entries submitted by SubmitTraced, held until the entry is added to a block, then
+// until the block is sealed
+type traces struct {
+	mutex   sync.Mutex
+	pending map[types.Hash]TraceContext // Entries submitted, but not yet added
+	block   map[types.Hash]TraceContext // Entries added to the current block; only touched by Run
+}
+
+// startSpan
+// Start a span with the Tracer; if none was set, the span goes nowhere
+func (a *Accumulator) startSpan(name string) Span {
+	if a.Tracer == nil {
+		return noSpan{}
+	}
+	return a.Tracer.StartSpan(name)
+}
+
+// SubmitTraced
+// Submit an entry that carries a trace context, so the span of the block it is sealed in is linked to it.
+// Without a Tracer this is just Submit.
+func (a *Accumulator) SubmitTraced(entry node.EntryHash, trace TraceContext) bool {
+	if a.Tracer == nil || trace == nil {
+		return a.Submit(entry)
+	}
+	a.traces.mutex.Lock() // Held before Submit, as Run may add the entry before Submit returns
+	if a.traces.pending == nil {
+		a.traces.pending = make(map[types.Hash]TraceContext)
+	}
+	a.traces.pending[entry.EntryHash] = trace
+	a.traces.mutex.Unlock()
+	if a.Submit(entry) {
+		return true
+	}
+	a.traces.mutex.Lock()
+	delete(a.traces.pending, entry.EntryHash)
+	a.traces.mutex.Unlock()
+	return false
+}
+
+// takeTrace
+// Remove and return the trace context an entry was submitted with, or nil
+func (a *Accumulator) takeTrace(entry node.EntryHash) TraceContext {
+	if a.Tracer == nil {
+		return nil
+	}
+	a.traces.mutex.Lock()
+	defer a.traces.mutex.Unlock()
+	trace := a.traces.pending[entry.EntryHash]
+	delete(a.traces.pending, entry.EntryHash)
+	return trace
+}
+
+// traceAdded
+// Hold the trace context of an entry added to the current block, for the block's span
+func (a *Accumulator) traceAdded(entry node.EntryHash, trace TraceContext) {
+	if trace == nil {
+		return
+	}
+	if a.traces.block == nil {
+		a.traces.block = make(map[types.Hash]TraceContext)
+	}
+	a.traces.block[entry.EntryHash] = trace
+}
+
+// traceSealed
+// Set the attributes of a block's span once the block is committed, and link it to the entries submitted
+// with a trace context
+func (a *Accumulator) traceSealed(span Span, height types.BlockHeight, entries, chains int) {
+	span.SetAttribute(AttrHeight, height)
+	span.SetAttribute(AttrEntryCount, entries)
+	span.SetAttribute(AttrChainCount, chains)
+	for _, trace := range a.traces.block {
+		span.AddLink(trace)
+	}
+	a.traces.block = nil
+}
@@ -0,0 +1,90 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// testSpan
+// What a testTracer's span was given
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	links      []TraceContext
+	ended      int
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *testSpan) AddLink(link TraceContext)                  { s.links = append(s.links, link) }
+func (s *testSpan) End()                                       { s.ended++ }
+
+// testTracer
+// Keeps every span started
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) StartSpan(name string) Span {
+	span := &testSpan{name: name, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func TestTracer(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Unix(1000, 0)}
+	acc.Clock = clock
+	tracer := new(testTracer)
+	acc.Tracer = tracer
+	chains := []types.Hash{sha256.Sum256([]byte("traced")), sha256.Sum256([]byte("also traced"))}
+
+	for i := 0; i < 3; i++ { // Entries 1, 3 and 5 come with a trace context
+		acc.SubmitTraced(GetTestEntry(chains[0], i*2), nil)
+		acc.SubmitTraced(GetTestEntry(chains[1], i*2+1), fmt.Sprint("trace ", i*2+1))
+	}
+	runUntilIdle(acc)
+	acc.SubmitTraced(GetTestEntry(chains[0], 0), "trace of a duplicate") // Never added, so never linked
+	runUntilIdle(acc)
+	acc.control <- true
+	runUntilIdle(acc)
+	acc.SubmitTraced(GetTestEntry(chains[0], 7), "trace 7")
+	runUntilIdle(acc)
+	acc.control <- true
+	runUntilIdle(acc)
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected a span for each of 2 blocks, got %d", len(tracer.spans))
+	}
+	expected := []struct {
+		entries, chains int
+		links           []string
+	}{{6, 2, []string{"trace 1", "trace 3", "trace 5"}}, {1, 1, []string{"trace 7"}}}
+	for h, span := range tracer.spans {
+		if span.name != SpanSeal || span.ended != 1 {
+			t.Errorf("span %d should be a %s span ended once, got %s ended %d times", h, SpanSeal, span.name, span.ended)
+		}
+		if span.attributes[AttrHeight] != types.BlockHeight(h) || span.attributes[AttrSealed] != true ||
+			span.attributes[AttrEntryCount] != expected[h].entries || span.attributes[AttrChainCount] != expected[h].chains {
+			t.Errorf("block %d has the wrong attributes: %v", h, span.attributes)
+		}
+		if _, ok := span.attributes[AttrSealDuration].(time.Duration); !ok {
+			t.Errorf("block %d has no seal duration", h)
+		}
+		var links []string
+		for _, link := range span.links {
+			links = append(links, link.(string))
+		}
+		sort.Strings(links)
+		if fmt.Sprint(links) != fmt.Sprint(expected[h].links) {
+			t.Errorf("block %d should be linked to %v, got %v", h, expected[h].links, links)
+		}
+	}
+	if len(acc.traces.pending) != 0 || len(acc.traces.block) != 0 {
+		t.Error("the trace contexts should be let go once their blocks are sealed")
+	}
+}
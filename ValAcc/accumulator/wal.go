@@ -0,0 +1,130 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+)
+
+// walRecordSize
+// Each record in the write ahead log is an entry's ChainID followed by its EntryHash
+const walRecordSize = 64
+
+// wal
+// The write ahead log of the entries Submit has accepted that are not yet in a sealed block.  Entries are
+// logged in the order they go onto the entryFeed, so the first taken records of the log are the entries Run
+// has pulled off the feed into the current block.  Sealing the block trims them off.
+type wal struct {
+	order sync.Mutex // Held from logging an entry until it is on the entryFeed, so the two are in the same order
+	mux   sync.Mutex // Guards the file
+	path  string
+	file  *os.File
+	taken int // Records at the front of the log pulled off the entryFeed since it was last trimmed
+}
+
+// openWAL
+// Open the write ahead log at WALPath, returning the entries in it.  Those are the entries accepted before
+// a restart that didn't make it into a sealed block.  A partial record, left by a crash while it was being
+// written, is dropped.
+func (a *Accumulator) openWAL() ([]node.EntryHash, error) {
+	data, err := ioutil.ReadFile(a.WALPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	records := len(data) / walRecordSize
+	if len(data)%walRecordSize != 0 { // Rewrite the log without the partial record
+		if err := ioutil.WriteFile(a.WALPath, data[:records*walRecordSize], 0644); err != nil {
+			return nil, err
+		}
+	}
+	var entries []node.EntryHash
+	for i := 0; i < records; i++ {
+		var entry node.EntryHash
+		record := data[i*walRecordSize:]
+		record = entry.ChainID.Extract(record)
+		entry.EntryHash.Extract(record)
+		entries = append(entries, entry)
+	}
+	a.wal.path = a.WALPath
+	if a.wal.file, err = os.OpenFile(a.WALPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// submit
+// Log the entry, and only once it is safely on disk put it on the feed.  The file is let go before we wait on
+// the feed, since if the feed is full only Run can make room, and it may be trimming the log.  Anything still
+// to go onto the feed was logged after the entries Run has taken, so a trim meanwhile leaves it in the log.
+func (w *wal) submit(entry node.EntryHash, feed chan node.EntryHash) error {
+	w.order.Lock()
+	defer w.order.Unlock()
+	if err := w.write(entry); err != nil {
+		return err
+	}
+	feed <- entry
+	return nil
+}
+
+// write
+// Append an entry's record to the log, and sync it to disk
+func (w *wal) write(entry node.EntryHash) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.file == nil {
+		return errors.New("the write ahead log is not open")
+	}
+	if _, err := w.file.Write(append(entry.ChainID.Bytes(), entry.EntryHash.Bytes()...)); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// trim
+// Drop the records of the entries now sealed in a block from the front of the log.  The rest of the log is
+// written to a new file that replaces the old one, so a crash leaves one log or the other.
+func (w *wal) trim() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.file == nil || w.taken == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	if len(data) < w.taken*walRecordSize {
+		return errors.New(fmt.Sprintf("the write ahead log holds %d records, but %d were sealed",
+			len(data)/walRecordSize, w.taken))
+	}
+	tmp := w.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data[w.taken*walRecordSize:], 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return err
+	}
+	w.file.Close()
+	if w.file, err = os.OpenFile(w.path, os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+		return err
+	}
+	w.taken = 0
+	return nil
+}
+
+// close
+// Close the log's file, if it is open
+func (w *wal) close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,124 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestWALRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	walPath := filepath.Join(dir, "entries.wal")
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	accID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	chainID := types.Hash(sha256.Sum256([]byte("logged")))
+
+	acc := new(Accumulator)
+	acc.WALPath = walPath
+	acc.Init(db, &accID)
+	for i := 0; i < 5; i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	runUntilIdle(acc)
+	acc.sealBlock()
+	if info, err := os.Stat(walPath); err != nil || info.Size() != 0 {
+		t.Fatal("sealing the block should empty the write ahead log")
+	}
+	for i := 5; i < 12; i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	runUntilIdle(acc)
+	acc.Submit(GetTestEntry(chainID, 12)) // Still on the feed when we crash
+	acc.wal.file.Close()                  // Crash before the block is sealed
+
+	// A half written record at the end of the log is dropped
+	file, _ := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0644)
+	file.Write([]byte{1, 2, 3})
+	file.Close()
+
+	restarted := new(Accumulator)
+	restarted.WALPath = walPath
+	restarted.Init(db, &accID)
+	block := restarted.sealBlock()
+	if block.BHeight != 1 {
+		t.Fatalf("the restart should pick up at height 1, not %d", block.BHeight)
+	}
+	chain, err := restarted.Reader().GetChainNode(chainID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.EntryList) != 8 {
+		t.Fatalf("expected the 8 unsealed entries in the first block after the restart, got %d", len(chain.EntryList))
+	}
+	for i, h := range chain.EntryList {
+		if h != GetTestEntry(chainID, i+5).EntryHash {
+			t.Errorf("entry %d of the recovered block is out of order", i)
+		}
+	}
+	if info, err := os.Stat(walPath); err != nil || info.Size() != 0 {
+		t.Error("sealing the recovered block should empty the write ahead log")
+	}
+	restarted.wal.file.Close()
+}
+
+func TestWALFullFeed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	accID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	chainID := types.Hash(sha256.Sum256([]byte("full feed")))
+
+	acc := new(Accumulator)
+	acc.WALPath = filepath.Join(dir, "entries.wal")
+	acc.Init(db, &accID)
+	defer acc.wal.file.Close()
+	for i := 0; i < cap(acc.entryFeed); i++ {
+		acc.Submit(GetTestEntry(chainID, i))
+	}
+	acc.ProcessPending()
+	for i := 0; i < cap(acc.entryFeed); i++ { // Fill the feed again
+		acc.Submit(GetTestEntry(chainID, cap(acc.entryFeed)+i))
+	}
+	waiting := make(chan bool)
+	go func() { waiting <- acc.Submit(GetTestEntry(chainID, -1)) }() // Waits on the full feed
+	time.Sleep(100 * time.Millisecond)
+
+	sealed := make(chan *node.Node)
+	go func() { sealed <- acc.SealBlock() }()
+	select {
+	case block := <-sealed:
+		if block == nil {
+			t.Fatal("the block should be sealed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sealing the block deadlocked with a Submit waiting on the full feed")
+	}
+	acc.ProcessPending()
+	if !<-waiting {
+		t.Fatal("the Submit waiting on the feed should go through")
+	}
+	acc.SealBlock()
+	data, err := ioutil.ReadFile(acc.WALPath)
+	if err != nil || len(data) != 0 {
+		t.Errorf("the log should be empty once every entry is sealed, holds %d bytes (%v)", len(data), err)
+	}
+	if total, _ := acc.TotalEntries(); total != uint64(2*cap(acc.entryFeed)+1) {
+		t.Errorf("expected every entry sealed, got %d", total)
+	}
+}
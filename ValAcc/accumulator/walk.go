@@ -0,0 +1,59 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockWalker
+// Walks the directory blocks from some height back to genesis by following the Previous hash of each
+// block.  Call Next until it returns false, then check Err to see if the walk ended at genesis or at a
+// broken link.
+//
+//	walker := reader.WalkBack(height)
+//	for block, ok := walker.Next(); ok; block, ok = walker.Next() {
+//	    ...
+//	}
+//	if walker.Err() != nil { ... }
+type BlockWalker struct {
+	reader *Reader
+	next   *node.Node // The next block to return, nil when we are done
+	err    error      // Why the walk ended early, nil if we reached genesis
+}
+
+// WalkBack
+// Return a BlockWalker starting at the directory block at fromHeight.
+func (r *Reader) WalkBack(fromHeight types.BlockHeight) *BlockWalker {
+	w := new(BlockWalker)
+	w.reader = r
+	w.next, w.err = r.GetDirectoryBlock(fromHeight)
+	return w
+}
+
+// Next
+// Return the next directory block in the walk, or false if there are no more.
+func (w *BlockWalker) Next() (*node.Node, bool) {
+	block := w.next
+	if block == nil {
+		return nil, false
+	}
+	w.next = nil
+	if block.Previous != (types.Hash{}) { // An all zero Previous is genesis, and the end of the walk
+		previous, err := w.reader.directoryBlock(block.Previous[:])
+		if err != nil {
+			w.err = errors.New(fmt.Sprintf("broken link from the directory block at height %d: %v", block.BHeight, err))
+		} else {
+			w.next = previous
+		}
+	}
+	return block, true
+}
+
+// Err
+// Return the error that ended the walk, or nil if the walk reached genesis.
+func (w *BlockWalker) Err() error {
+	return w.err
+}
@@ -0,0 +1,46 @@
+package accumulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestWalkBack(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("walk")))
+	var hashes []types.Hash
+	for i := 0; i < 6; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+		hashes = append(hashes, *acc.sealBlock().GetHash())
+	}
+
+	walker := acc.Reader().WalkBack(5)
+	count := 0
+	var last types.BlockHeight
+	for block, ok := walker.Next(); ok; block, ok = walker.Next() {
+		if *block.GetHash() != hashes[5-count] {
+			t.Errorf("block %d from the walk isn't the block sealed at height %d", count, 5-count)
+		}
+		last = block.BHeight
+		count++
+	}
+	if walker.Err() != nil {
+		t.Fatal(walker.Err())
+	}
+	if count != 6 || last != 0 {
+		t.Errorf("expected to walk 6 blocks ending at genesis, walked %d ending at %d", count, last)
+	}
+
+	// Break the link to the block at height 2
+	acc.DB.Put(types.Node, hashes[2][:], []byte{1, 2, 3})
+	walker = acc.Reader().WalkBack(5)
+	count = 0
+	for _, ok := walker.Next(); ok; _, ok = walker.Next() {
+		count++
+	}
+	if walker.Err() == nil || count != 3 {
+		t.Errorf("expected the walk to fail after 3 blocks, walked %d with error %v", count, walker.Err())
+	}
+}
@@ -0,0 +1,161 @@
+package accumulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockWatcher
+// Delivers directory blocks, in height order, from the first block not yet acknowledged with AckRoot.
+// Unlike the mdFeed, nothing is lost if the consumer goes away: a new BlockWatcher picks up again from
+// the first unacknowledged block, so every root is delivered at least once.
+type BlockWatcher struct {
+	Blocks <-chan *node.Node // The directory blocks; the MD root of each is block.GetMDRoot()
+	stop   chan struct{}
+}
+
+// Stop
+// Stop delivering blocks.  Blocks is not closed, as a block may be in flight.
+func (w *BlockWatcher) Stop() {
+	close(w.stop)
+}
+
+// WatchBlocks
+// Watch the directory blocks, starting with the first block not acknowledged, and carrying on with each
+// block as it is committed.
+func (a *Accumulator) WatchBlocks() (*BlockWatcher, error) {
+	r := a.Reader()
+	next, err := r.AckedHeight()
+	if err != nil {
+		return nil, err
+	}
+	blocks := make(chan *node.Node)
+	w := &BlockWatcher{Blocks: blocks, stop: make(chan struct{})}
+	go func() {
+		for {
+			committed := a.sealedSignal() // Get the signal first, so we can't miss a block committed meanwhile
+			if block, err := r.GetDirectoryBlock(next); err == nil {
+				select {
+				case blocks <- block:
+					next++
+				case <-w.stop:
+					return
+				}
+				continue
+			}
+			select {
+			case <-committed:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	return w, nil
+}
+
+// SubmitAndWait
+// Submit an entry, then wait for the block it is added to to be committed.  Returns the height of the block
+// and the ListMDRoot of the entry's chain in it, which GetReceipt proves the entry against.  An entry sealed
+// before is found where it was sealed.  Returns an error if the entry is rejected, if ctx is done first, or
+// if Run stops without sealing the entry.  An entry dropped after Submit took it (as TooManyChains, say) is
+// only given up on when ctx is done.  Nothing is left waiting once SubmitAndWait returns.  May be called from
+// any go routine but the one running Run.
+func (a *Accumulator) SubmitAndWait(ctx context.Context, entry node.EntryHash) (height types.BlockHeight, root types.Hash, err error) {
+	if err := a.SubmitErr(entry); err != nil {
+		return 0, root, err
+	}
+	return a.awaitSealed(ctx, entry)
+}
+
+// awaitSealed
+// Wait for the block a submitted entry is added to to be committed, as SubmitAndWait does
+func (a *Accumulator) awaitSealed(ctx context.Context, entry node.EntryHash) (height types.BlockHeight, root types.Hash, err error) {
+	for stopped := false; ; {
+		committed := a.sealedSignal() // Get the signal first, so we can't miss a block committed meanwhile
+		chainNode, err := a.Reader().sealedIn(entry.EntryHash)
+		if err != nil {
+			return 0, root, err
+		}
+		if chainNode != nil {
+			return chainNode.BHeight, chainNode.ListMDRoot, nil
+		}
+		if stopped {
+			return 0, root, errors.New(fmt.Sprintf("the accumulator stopped without sealing entry %x for chain %x",
+				entry.EntryHash, entry.ChainID))
+		}
+		select {
+		case <-committed:
+		case <-a.stopped: // Look once more, as Run seals a last block on the way out
+			stopped = true
+		case <-ctx.Done():
+			return 0, root, ctx.Err()
+		}
+	}
+}
+
+// AckRoot
+// Acknowledge every block up to and including the given height, so they won't be delivered to another
+// BlockWatcher.  Acknowledging a height at or below one already acknowledged does nothing.
+func (a *Accumulator) AckRoot(height types.BlockHeight) error {
+	a.watchMux.Lock()
+	defer a.watchMux.Unlock()
+	next, err := a.Reader().AckedHeight()
+	if err != nil {
+		return err
+	}
+	if height < next {
+		return nil
+	}
+	return a.db().Put(types.AckedHeight, a.chainID[:], (height + 1).Bytes())
+}
+
+// AckedHeight
+// The lowest height not yet acknowledged with AckRoot
+func (r *Reader) AckedHeight() (types.BlockHeight, error) {
+	data := r.DB.Get(types.AckedHeight, r.ChainID[:])
+	if data == nil {
+		return 0, nil
+	}
+	if len(data) != 4 {
+		return 0, errors.New(fmt.Sprintf("acknowledged height should be 4 bytes, found %d", len(data)))
+	}
+	var height types.BlockHeight
+	height.Extract(data)
+	return height, nil
+}
+
+// sealedIn
+// The header of the chain node an entry was sealed in, or nil if the entry hasn't been sealed
+func (r *Reader) sealedIn(entry types.Hash) (*node.Node, error) {
+	nodeHash := r.DB.Get(types.EntryNode, entry[:])
+	if nodeHash == nil {
+		return nil, nil
+	}
+	return r.GetNodeHeader(nodeHash)
+}
+
+// sealedSignal
+// A channel that is closed when the next block is committed
+func (a *Accumulator) sealedSignal() chan struct{} {
+	a.watchMux.Lock()
+	defer a.watchMux.Unlock()
+	if a.sealed == nil {
+		a.sealed = make(chan struct{})
+	}
+	return a.sealed
+}
+
+// signalSealed
+// Wake up the BlockWatchers waiting on a block to be committed
+func (a *Accumulator) signalSealed() {
+	a.watchMux.Lock()
+	defer a.watchMux.Unlock()
+	if a.sealed != nil {
+		close(a.sealed)
+		a.sealed = nil
+	}
+}
@@ -0,0 +1,285 @@
+package accumulator
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// nextBlock
+// Wait a while for the watcher to deliver a block
+func nextBlock(t *testing.T, w *BlockWatcher) *node.Node {
+	select {
+	case block := <-w.Blocks:
+		return block
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a block")
+	}
+	return nil
+}
+
+func TestWatchBlocks(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("watched")))
+	for i := 0; i < 6; i++ {
+		acc.addEntry(GetTestEntry(chainID, i))
+		acc.sealBlock()
+	}
+
+	w, err := acc.WatchBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for height := 0; height < 6; height++ {
+		if block := nextBlock(t, w); block.BHeight != types.BlockHeight(height) {
+			t.Fatalf("expected block %d, got %d", height, block.BHeight)
+		}
+	}
+	if err := acc.AckRoot(3); err != nil {
+		t.Fatal(err)
+	}
+	acc.AckRoot(1) // Going backwards does nothing
+	w.Stop()
+
+	// Restart, and only the blocks after the last acknowledged are delivered again
+	restarted := new(Accumulator)
+	restarted.Init(acc.DB, acc.chainID)
+	w, err = restarted.WatchBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	for height := 4; height < 6; height++ {
+		if block := nextBlock(t, w); block.BHeight != types.BlockHeight(height) {
+			t.Fatalf("expected block %d to be redelivered, got %d", height, block.BHeight)
+		}
+	}
+	restarted.addEntry(GetTestEntry(chainID, 6))
+	sealed := restarted.sealBlock()
+	if block := nextBlock(t, w); *block.GetMDRoot() != *sealed.GetMDRoot() {
+		t.Error("expected the new block to be delivered once committed")
+	}
+}
+
+func TestSubmitAndWait(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("awaited")))
+	go acc.Run()
+	type included struct {
+		height types.BlockHeight
+		root   types.Hash
+		err    error
+	}
+	results := make(chan included, 1)
+	go func() {
+		height, root, err := acc.SubmitAndWait(context.Background(), GetTestEntry(chainID, 1))
+		results <- included{height, root, err}
+	}()
+	var result included
+	timeout := time.After(5 * time.Second)
+	for waiting := true; waiting; {
+		acc.control <- true // Blocks may be sealed before the entry is added; it is in whichever comes next
+		select {
+		case result = <-results:
+			waiting = false
+		case <-timeout:
+			t.Fatal("timed out waiting for the entry to be sealed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	chainNode, err := acc.Reader().GetChainNode(chainID, result.height)
+	if err != nil || chainNode.ListMDRoot != result.root || chainNode.EntryList[0] != GetTestEntry(chainID, 1).EntryHash {
+		t.Fatalf("expected the height and root of the block the entry was sealed in (%v)", err)
+	}
+	if height, _, err := acc.SubmitAndWait(context.Background(), GetTestEntry(chainID, 1)); err != nil || height != result.height {
+		t.Errorf("an entry sealed before should be found where it was sealed (%v)", err)
+	}
+
+	acc.Pause() // Nothing is sealed, so the caller gives up
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := acc.SubmitAndWait(ctx, GetTestEntry(chainID, 2)); err != context.DeadlineExceeded {
+		t.Errorf("expected to give up once the context is done, got %v", err)
+	}
+	if runtime.NumGoroutine() > before {
+		t.Error("nothing should be left waiting once SubmitAndWait gives up")
+	}
+	acc.Stop()
+	if _, _, err := acc.SubmitAndWait(context.Background(), GetTestEntry(chainID, 3)); err != ErrShuttingDown {
+		t.Errorf("expected ErrShuttingDown once stopped, got %v", err)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	chainID := types.Hash(sha256.Sum256([]byte("observed")))
+	observer := acc.Observe(2)
+	var sealed []*node.Node
+	for height := 0; height < 3; height++ {
+		for i := 0; i <= height; i++ {
+			acc.addEntry(GetTestEntry(chainID, height*10+i))
+		}
+		sealed = append(sealed, acc.sealBlock())
+	}
+	for _, block := range sealed[:2] {
+		event := <-observer.Events
+		if *event.Block.GetHash() != *block.GetHash() || event.MDRoot != *block.GetMDRoot() ||
+			event.Entries != int(block.BHeight)+1 {
+			t.Errorf("the event for the block at height %d is for the block at height %d", block.BHeight, event.Block.BHeight)
+		}
+	}
+	if observer.Dropped() != 1 {
+		t.Errorf("the event that found the buffer full should be dropped, not %d", observer.Dropped())
+	}
+
+	r := observer.Reader()
+	if head, err := r.GetHead(); err != nil || *head.GetHash() != *sealed[2].GetHash() {
+		t.Errorf("the observer should read the head (%v)", err)
+	}
+	writes := map[string]func(){
+		"Put":    func() { r.DB.Put(types.AckedHeight, chainID[:], types.Uint32Bytes(1)) },
+		"Delete": func() { r.DB.Delete(types.NodeHead, chainID[:]) },
+		"Commit": func() {
+			batch := r.DB.NewBatch()
+			batch.Put(types.AckedHeight, chainID[:], types.Uint32Bytes(1))
+			batch.Commit()
+		},
+	}
+	for name, write := range writes {
+		func() {
+			defer func() {
+				if rec := recover(); rec != database.ErrReadOnly {
+					t.Errorf("%s through an observer should panic with ErrReadOnly, not %v", name, rec)
+				}
+			}()
+			write()
+		}()
+	}
+	if acc.Reader().DB.Get(types.NodeHead, chainID[:]) == nil {
+		t.Error("writes through the observer shouldn't reach the store")
+	}
+
+	observer.Close()
+	acc.addEntry(GetTestEntry(chainID, 100))
+	acc.sealBlock()
+	if len(observer.Events) != 0 {
+		t.Error("a closed observer shouldn't get events")
+	}
+}
+
+func TestWatchRejects(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	acc.Clock = clock
+	acc.MaxEntriesPerChainPerSecond = 1
+	acc.ValidateEntries = true
+	chainID := types.Hash(sha256.Sum256([]byte("misbehaving")))
+	acc.addEntry(GetTestEntry(chainID, 0))
+	acc.sealBlock()
+
+	first, stopFirst := acc.WatchRejects()
+	second, stopSecond := acc.WatchRejects()
+	acc.Submit(GetTestEntry(chainID, 1))
+	acc.Submit(GetTestEntry(chainID, 2)) // Over the rate
+	acc.Submit(node.EntryHash{ChainID: chainID})
+	acc.SubmitAtHeight(GetTestEntry(types.Hash(sha256.Sum256([]byte("late"))), 3), 0)
+	want := []RejectReason{RateLimited, Malformed, HeightSealed}
+	for name, events := range map[string]<-chan RejectEvent{"first": first, "second": second} {
+		for _, reason := range want {
+			event := <-events
+			if event.Reason != reason || !event.Time.Equal(clock.now) {
+				t.Errorf("the %s subscriber got %v at %v, not %v", name, event.Reason, event.Time, reason)
+			}
+		}
+	}
+	stopSecond()
+	if _, ok := <-second; ok {
+		t.Error("unsubscribing should close the channel")
+	}
+
+	for i := 0; i < rejectBuffer+2; i++ { // A slow subscriber loses the oldest events
+		acc.Submit(GetTestEntry(chainID, 100+i))
+	}
+	if len(first) != rejectBuffer {
+		t.Fatalf("the buffer should hold %d events, not %d", rejectBuffer, len(first))
+	}
+	if event := <-first; event.Entry.EntryHash != GetTestEntry(chainID, 102).EntryHash {
+		t.Error("the two oldest events should have been dropped")
+	}
+	stopFirst()
+	stopFirst()
+}
+
+func TestSubmitWithAck(t *testing.T) {
+	chainID := types.Hash(sha256.Sum256([]byte("acknowledged")))
+	acc := GetTestAccumulator(t)
+	if _, _, err := acc.SubmitWithAck(context.Background(), GetTestEntry(chainID, 1), AckAccepted); err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.entryFeed) != 1 {
+		t.Error("an accepted entry should be on the feed when SubmitWithAck returns")
+	}
+	if _, _, err := acc.SubmitWithAck(context.Background(), GetTestEntry(chainID, 2), AckWALDurable); err != ErrNoWAL {
+		t.Errorf("expected ErrNoWAL without a WALPath, got %v", err)
+	}
+	if len(acc.entryFeed) != 1 {
+		t.Error("an entry that can't be made durable shouldn't be submitted")
+	}
+
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db := new(database.DB)
+	db.InitStore(database.NewMemStore())
+	accID := types.Hash(sha256.Sum256([]byte("Test Accumulator")))
+	logged := new(Accumulator)
+	logged.WALPath = filepath.Join(dir, "entries.wal")
+	logged.Init(db, &accID)
+	defer logged.wal.close()
+	if _, _, err := logged.SubmitWithAck(context.Background(), GetTestEntry(chainID, 3), AckWALDurable); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(logged.WALPath); err != nil || info.Size() != walRecordSize || len(logged.entryFeed) != 1 {
+		t.Error("a durable entry should be in the write ahead log, and on the feed, when SubmitWithAck returns")
+	}
+
+	acc = GetTestAccumulator(t)
+	results := make(chan error, 1)
+	go func() {
+		_, _, err := acc.SubmitWithAck(context.Background(), GetTestEntry(chainID, 4), AckSealed)
+		results <- err
+	}()
+	acc.addEntry(<-acc.entryFeed) // Once the entry is submitted, only sealing its block lets the caller go
+	select {
+	case <-results:
+		t.Fatal("a sealed ack shouldn't return before the entry's block is committed")
+	default:
+	}
+	acc.sealBlock()
+	select {
+	case err := <-results:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the sealed ack")
+	}
+	if _, _, err := acc.SubmitWithAck(context.Background(), GetTestEntry(chainID, 5), AckLevel(9)); err == nil {
+		t.Error("an unknown ack level should be refused")
+	}
+}
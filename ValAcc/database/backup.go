@@ -0,0 +1,156 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// The stream written by Backup is the backupMagic, then each key/value as
+//
+//	len(key) uint32, key, len(value) uint32, value
+//
+// then a key length of backupEnd, the count of key/values as a uint64, and the sha256 of everything before it.
+var backupMagic = []byte("ValAcc backup 1\n")
+
+const backupEnd = 0xFFFFFFFF
+
+// Backup
+// Stream every key/value in the database to w.  The Store is read as of a single point in time, so a backup
+// taken while an accumulator runs holds whole blocks; blocks are committed in one write.  Nothing is held in
+// memory but the key/value being written.
+func (d *DB) Backup(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	sum := sha256.New()
+	out := io.MultiWriter(buf, sum)
+	if _, err := out.Write(backupMagic); err != nil {
+		return err
+	}
+	var count uint64
+	err := d.store.Iterate(func(key, value []byte) error {
+		count++
+		for _, data := range [][]byte{types.Uint32Bytes(uint32(len(key))), key, types.Uint32Bytes(uint32(len(value))), value} {
+			if _, err := out.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(types.Uint32Bytes(backupEnd), types.Uint64Bytes(count)...)); err != nil {
+		return err
+	}
+	if _, err := buf.Write(sum.Sum(nil)); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// Restore
+// Load a stream written by Backup into this database, which must be empty.  The key/values are written
+// as they are read.  If the stream turns out to be truncated or corrupt an error is returned, and the
+// database holds whatever was restored before the problem was found, so it should be thrown away.
+func (d *DB) Restore(r io.Reader) error {
+	empty := errors.New("not empty")
+	if err := d.store.Iterate(func(key, value []byte) error { return empty }); err == empty {
+		return errors.New("can only restore into an empty database")
+	} else if err != nil {
+		return err
+	}
+
+	sum := sha256.New()
+	in := io.TeeReader(bufio.NewReader(r), sum)
+	magic := make([]byte, len(backupMagic))
+	if _, err := io.ReadFull(in, magic); err != nil || !bytes.Equal(magic, backupMagic) {
+		return errors.New("not a ValAcc backup")
+	}
+	var count uint64
+	for {
+		keyLen, err := readUint32(in)
+		if err != nil {
+			return err
+		}
+		if keyLen == backupEnd {
+			break
+		}
+		key, err := readBytes(in, keyLen)
+		if err != nil {
+			return err
+		}
+		valueLen, err := readUint32(in)
+		if err != nil {
+			return err
+		}
+		value, err := readBytes(in, valueLen)
+		if err != nil {
+			return err
+		}
+		if err := d.store.Put(key, value); err != nil {
+			return err
+		}
+		count++
+	}
+	return checkBackupEnd(in, sum, count)
+}
+
+// checkBackupEnd
+// Check the count and sha256 that close off a backup
+func checkBackupEnd(in io.Reader, sum hash.Hash, count uint64) error {
+	data, err := readBytes(in, 8)
+	if err != nil {
+		return err
+	}
+	if expected, _ := types.BytesUint64(data); expected != count {
+		return errors.New(fmt.Sprintf("backup should hold %d key/values, found %d", expected, count))
+	}
+	computed := sum.Sum(nil)
+	stored := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(in, stored); err != nil {
+		return errors.New(fmt.Sprintf("backup is truncated: %v", err))
+	}
+	if !bytes.Equal(stored, computed) {
+		return errors.New("backup is corrupt; the checksum doesn't match")
+	}
+	return nil
+}
+
+func readUint32(in io.Reader) (uint32, error) {
+	data, err := readBytes(in, 4)
+	if err != nil {
+		return 0, err
+	}
+	v, _ := types.BytesUint32(data)
+	return v, nil
+}
+
+// readChunk
+// readBytes reads this much at a time, so a corrupt length can't have it allocate much more than the stream
+// actually holds
+const readChunk = 1 << 20
+
+func readBytes(in io.Reader, n uint32) ([]byte, error) {
+	size := int(n)
+	if size > readChunk {
+		size = readChunk
+	}
+	data := make([]byte, 0, size)
+	for remaining := int(n); remaining > 0; remaining -= size {
+		if size > remaining {
+			size = remaining
+		}
+		start := len(data)
+		data = append(data, make([]byte, size)...)
+		if _, err := io.ReadFull(in, data[start:]); err != nil {
+			return nil, errors.New(fmt.Sprintf("backup is truncated: %v", err))
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+// badgerStore
+// Store implementation backed by Badger.  This is what DB.Init opens.
+type badgerStore struct {
+	badgerDB *badger.DB
+}
+
+func (b *badgerStore) Get(key []byte) (value []byte, err error) {
+	// Go look up the key, and return any error we might find.
+	err = b.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		err = item.Value(func(val []byte) error {
+			value = append(value, val...)
+			return nil
+		})
+		return err
+	})
+	// Not finding the key isn't an error, just a nil value
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (b *badgerStore) Put(key []byte, value []byte) error {
+	// Update the key/value in the database
+	return b.badgerDB.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (b *badgerStore) Delete(key []byte) error {
+	return b.badgerDB.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// Iterate
+// Run through a read transaction, so fn sees a snapshot of the database
+func (b *badgerStore) Iterate(fn func(key, value []byte) error) error {
+	return b.badgerDB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := fn(item.KeyCopy(nil), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Size
+// The size of the LSM tree and value log.  Badger only measures them every minute or so, so the size lags
+// behind recent writes and compactions.
+func (b *badgerStore) Size() (bytes int64, err error) {
+	lsm, vlog := b.badgerDB.Size()
+	return lsm + vlog, nil
+}
+
+// Compact
+// Have Badger garbage collect its value log until there is nothing left worth rewriting.  Badger runs
+// this alongside reads and writes.  The bytes reclaimed is how much the LSM tree and value log shrank.
+func (b *badgerStore) Compact() (reclaimed int64, err error) {
+	lsm, vlog := b.badgerDB.Size()
+	for err == nil {
+		err = b.badgerDB.RunValueLogGC(0.5)
+	}
+	if err != badger.ErrNoRewrite {
+		return 0, err
+	}
+	if err = b.badgerDB.Flatten(1); err != nil {
+		return 0, err
+	}
+	lsmAfter, vlogAfter := b.badgerDB.Size()
+	if reclaimed = lsm + vlog - lsmAfter - vlogAfter; reclaimed < 0 {
+		reclaimed = 0
+	}
+	return reclaimed, nil
+}
+
+// Snapshot
+// Hold a read transaction open, so reads through the snapshot see the database as it was when it was taken.
+// Badger keeps the versions the transaction can see until it is released, so don't hold one for long.
+func (b *badgerStore) Snapshot() (snapshot Store, release func(), err error) {
+	s := &badgerSnapshot{txn: b.badgerDB.NewTransaction(false)}
+	return ReadOnly(s), s.release, nil
+}
+
+// badgerSnapshot
+// The read transaction of a Snapshot.  A Badger transaction isn't safe to use from several goroutines at once,
+// so reads take turns on it.
+type badgerSnapshot struct {
+	mux sync.Mutex
+	txn *badger.Txn
+}
+
+func (s *badgerSnapshot) Get(key []byte) (value []byte, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	item, err := s.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (s *badgerSnapshot) Put(key []byte, value []byte) error { return ErrReadOnly }
+func (s *badgerSnapshot) Delete(key []byte) error            { return ErrReadOnly }
+
+func (s *badgerSnapshot) Iterate(fn func(key, value []byte) error) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	it := s.txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(item.KeyCopy(nil), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *badgerSnapshot) release() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.txn.Discard()
+}
+
+// writeBatch
+// Write the batch in one transaction, so readers (and Iterate) see all of it or none of it.  A batch too big
+// for one transaction is refused, rather than written over several, since a crash part way through those
+// would leave a block half written.  Keep blocks small enough (see MaxEntriesPerBlock) to fit in one.
+func (b *badgerStore) writeBatch(ops []batchOp) error {
+	err := b.badgerDB.Update(func(txn *badger.Txn) error {
+		for _, op := range ops {
+			var err error
+			if op.value == nil {
+				err = txn.Delete(op.key)
+			} else {
+				err = txn.Set(op.key, op.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == badger.ErrTxnTooBig {
+		return errors.New(fmt.Sprintf("a batch of %d writes is too big to commit in one transaction: %v", len(ops), err))
+	}
+	return err
+}
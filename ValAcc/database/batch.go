@@ -0,0 +1,83 @@
+package database
+
+import (
+	"sync"
+)
+
+// batchOp
+// One write held in a Batch.  A nil value is a delete.
+type batchOp struct {
+	key   []byte
+	value []byte
+}
+
+// batchWriter
+// A Store that can write a whole batch in one go, rather than a Put or Delete at a time.
+type batchWriter interface {
+	writeBatch(ops []batchOp) error
+}
+
+// batchStore
+// Holds the Puts and Deletes made to a Batch until it is committed.  Gets (and Iterate) go straight to
+// the Store underneath, so they don't see what has been written to the batch.
+type batchStore struct {
+	under Store
+	mux   sync.Mutex
+	ops   []batchOp
+}
+
+func (b *batchStore) Get(key []byte) (value []byte, err error) {
+	return b.under.Get(key)
+}
+
+func (b *batchStore) Iterate(fn func(key, value []byte) error) error {
+	return b.under.Iterate(fn)
+}
+
+func (b *batchStore) Put(key []byte, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.ops = append(b.ops, batchOp{key: append([]byte{}, key...), value: append([]byte{}, value...)})
+	return nil
+}
+
+func (b *batchStore) Delete(key []byte) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.ops = append(b.ops, batchOp{key: append([]byte{}, key...)})
+	return nil
+}
+
+// Batch
+// Collects writes to a DB so they can all be written by Commit.  A Batch is itself a DB, so anything that
+// writes to a DB can write into a Batch; reads from it see the DB as it was before the batch.  Writes to
+// a Batch may be made from any number of go routines.
+type Batch struct {
+	DB
+	batch *batchStore
+}
+
+// NewBatch
+// Start a batch of writes to this DB
+func (d *DB) NewBatch() *Batch {
+	b := new(Batch)
+	b.batch = &batchStore{under: d.store}
+	b.DB.DBHome = d.DBHome
+	b.DB.store = b.batch
+	return b
+}
+
+// Commit
+// Write everything in the batch to the DB, in the order it was written to the batch.  Stores that can
+// (Badger and the MemStore) write the whole batch at once; others get a Put or Delete at a time.
+func (b *Batch) Commit() error {
+	b.batch.mux.Lock()
+	ops := b.batch.ops
+	b.batch.ops = nil
+	b.batch.mux.Unlock()
+
+	return writeOps(b.batch.under, ops)
+}
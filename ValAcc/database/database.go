@@ -5,15 +5,29 @@ package database
 // allows.
 //
 //To use this DB interface, you must allocate a DB
-// Then call DB.Init(int)
+// Then call DB.Init(int) to open Badger, or DB.InitStore(Store) to use some other Store
+// (i.e. a MemStore for testing)
 //
-// To set a value in the database, call DB.Put(bucket string, key []byte, value []byte) error
+// To set a value in the database, call DB.Put(bucket types.Bucket, key []byte, value []byte) error
 //
-// To get a value from the database, call DB.Get(bucket string, key []byte) (value []byte)_
+// To get a value from the database, call DB.Get(bucket types.Bucket, key []byte) (value []byte)_
 //
-// see ValAcc/types/types.go for the constants for bucket names
+// To remove a value, call DB.Delete(bucket types.Bucket, key []byte) error, and to give the space back,
+// DB.Compact() (reclaimed int64, err error)
+//
+// To walk the keys of a bucket, call DB.IterateBucket(bucket types.Bucket, fn func(key, value []byte) error) error
+//
+// DB.Backup(w io.Writer) error streams the whole database out, and DB.Restore(r io.Reader) error loads
+// such a stream into an empty database.  MigrateStore(src, dst Store) error copies one Store into another,
+// say to move from one backend to another
+//
+// DB.Snapshot() (*DB, func(), error) gives a read only DB frozen as of the call, for Stores that can take one
+//
+// see ValAcc/types/database.go for the constants for bucket names
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 
@@ -23,8 +37,8 @@ import (
 )
 
 type DB struct {
-	DBHome   string
-	badgerDB *badger.DB
+	DBHome string
+	store  Store
 }
 
 // We take an instance of the database, because we anticipate sometime in the future,
@@ -43,36 +57,50 @@ func (d *DB) Init(instance int) {
 	if err != nil { // Panic if we can't open Badger
 		panic(err)
 	}
-	d.badgerDB = db // And all is good.
+	d.store = &badgerStore{badgerDB: db} // And all is good.
+}
+
+// InitStore
+// Use the given Store rather than opening Badger.
+func (d *DB) InitStore(store Store) {
+	d.store = store
+}
+
+// GetStore
+// Return the Store underneath this DB
+func (d *DB) GetStore() Store {
+	return d.store
 }
 
 // GetKey
 // Given a bucket and a key, return the combined key
-func GetKey(bucket string, key []byte) (CKey []byte) {
+func GetKey(bucket types.Bucket, key []byte) (CKey []byte) {
 	CKey = append(CKey, []byte(bucket)...)
 	CKey = append(CKey, key...)
 	return CKey
 }
 
+// checkKey
+// Keys in the accumulator's buckets have to be the bucket's KeyLen, or they might collide with the keys of
+// another bucket (see types.Bucket)
+func checkKey(bucket types.Bucket, key []byte) error {
+	if keyLen := bucket.KeyLen(); keyLen != 0 && len(key) != keyLen {
+		return errors.New(fmt.Sprintf("keys in the %q bucket are %d bytes, not %d", bucket, keyLen, len(key)))
+	}
+	return nil
+}
+
 // Get
 // Look in the given bucket, and return the key found.  Returns nil if no value
-// is found for the given key
-func (d *DB) Get(bucket string, key []byte) (value []byte) {
+// is found for the given key, or the key is the wrong length for the bucket
+func (d *DB) Get(bucket types.Bucket, key []byte) (value []byte) {
+	if checkKey(bucket, key) != nil {
+		return nil
+	}
 	CKey := GetKey(bucket, key) // combine the bucket and the key
 
-	// Go look up the CKey, and return any error we might find.
-	err := d.badgerDB.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(CKey)
-		if err != nil {
-			return err
-		}
-		err = item.Value(func(val []byte) error {
-			value = append(value, val...)
-			return nil
-		})
-		return err
-	})
-	// If anything goes wrong, return nil
+	// Go look up the CKey, and if anything goes wrong, return nil
+	value, err := d.store.Get(CKey)
 	if err != nil {
 		return nil
 	}
@@ -80,29 +108,105 @@ func (d *DB) Get(bucket string, key []byte) (value []byte) {
 	return value
 }
 
-func (d *DB) GetInt32(bucket string, ikey uint32) (value []byte) {
+func (d *DB) GetInt32(bucket types.Bucket, ikey uint32) (value []byte) {
 	key := types.Uint32Bytes(ikey)
 	return d.Get(bucket, key)
 }
 
 // Put
 // Put a key/value in the database.  We return an error if there was a problem
-// writing the key/value pair to the database.
-func (d *DB) Put(bucket string, key []byte, value []byte) error {
+// writing the key/value pair to the database, or the key is the wrong length for the bucket.
+func (d *DB) Put(bucket types.Bucket, key []byte, value []byte) error {
+	if err := checkKey(bucket, key); err != nil {
+		return err
+	}
 	CKey := GetKey(bucket, key)
 
 	// Update the key/value in the database
-	err := d.badgerDB.Update(func(txn *badger.Txn) error {
-		err := txn.Set([]byte(CKey), value)
+	return d.store.Put(CKey, value)
+}
+
+// Delete
+// Remove a key/value from the database.  Deleting a key that isn't there is not an error.
+func (d *DB) Delete(bucket types.Bucket, key []byte) error {
+	if err := checkKey(bucket, key); err != nil {
 		return err
+	}
+	return d.store.Delete(GetKey(bucket, key))
+}
+
+// IterateBucket
+// Call fn with the key (without the bucket name) and value of every key/value in the bucket, in key order.
+// Iteration stops at the first error from fn, which is returned.
+func (d *DB) IterateBucket(bucket types.Bucket, fn func(key, value []byte) error) error {
+	prefix := []byte(bucket)
+	found := false
+	err := d.store.Iterate(func(key, value []byte) error {
+		if !bytes.HasPrefix(key, prefix) {
+			if found { // The bucket's keys all come together, so we are past them
+				return errBucketDone
+			}
+			return nil
+		}
+		found = true
+		if checkKey(bucket, key[len(prefix):]) != nil { // A key of a bucket whose name starts with this one's
+			return nil
+		}
+		return fn(key[len(prefix):], value)
 	})
+	if err == errBucketDone {
+		return nil
+	}
 	return err
 }
 
+// errBucketDone
+// Stops the iteration of IterateBucket once it is past the bucket
+var errBucketDone = errors.New("past the end of the bucket")
+
+// Compact
+// Reclaim the space of deleted and overwritten values, if the Store knows how.  Returns the bytes reclaimed.
+// Safe to call while the database is in use.
+func (d *DB) Compact() (reclaimed int64, err error) {
+	compacter, ok := d.store.(Compacter)
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("a %T store does not support compaction", d.store))
+	}
+	return compacter.Compact()
+}
+
+// Size
+// The bytes the Store takes up, if the Store knows, dead space not yet compacted included
+func (d *DB) Size() (bytes int64, err error) {
+	sizer, ok := d.store.(Sizer)
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("a %T store can't say how big it is", d.store))
+	}
+	return sizer.Size()
+}
+
+// Snapshot
+// A DB over a read only snapshot of the Store, if the Store can take one (see Snapshotter).  Call release once
+// done with the snapshot.
+func (d *DB) Snapshot() (snapshot *DB, release func(), err error) {
+	snapshotter, ok := d.store.(Snapshotter)
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("a %T store can't take snapshots", d.store))
+	}
+	store, release, err := snapshotter.Snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot = new(DB)
+	snapshot.DBHome = d.DBHome
+	snapshot.InitStore(store)
+	return snapshot, release, nil
+}
+
 // PutInt
 // Put a key/value in the database, where the key is an index.  We return an error if there was a problem
 // writing the key/value pair to the database.
-func (d *DB) PutInt32(bucket string, ikey int, value []byte) error {
+func (d *DB) PutInt32(bucket types.Bucket, ikey int, value []byte) error {
 	key := types.Uint32Bytes(uint32(ikey))
 	return d.Put(bucket, key, value)
 }
@@ -1,11 +1,17 @@
 package database
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+
 	"github.com/dgraph-io/badger/v2"
 )
 
@@ -52,3 +58,271 @@ func TestDatabase2(t *testing.T) {
 	answer := db.Get("test", []byte("answer"))
 	fmt.Println("The Answer is ", answer)
 }
+
+func TestBatch(t *testing.T) {
+	db := new(DB)
+	db.InitStore(NewMemStore())
+	db.Put("test", []byte("old"), []byte("gone"))
+
+	batch := db.NewBatch()
+	batch.Put("test", []byte("answer"), []byte("42"))
+	batch.Delete("test", []byte("old"))
+	if db.Get("test", []byte("answer")) != nil || batch.Get("test", []byte("old")) == nil {
+		t.Error("nothing should be written before the batch is committed")
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if string(db.Get("test", []byte("answer"))) != "42" || db.Get("test", []byte("old")) != nil {
+		t.Error("the batch should be written once committed")
+	}
+}
+
+func TestBadgerBackup(t *testing.T) {
+	dname, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dname)
+	db := new(DB)
+	db.DBHome = dname
+	db.Init(0)
+	batch := db.NewBatch()
+	for i := 0; i < 100; i++ {
+		batch.Put("test", []byte(fmt.Sprint("key ", i)), []byte(fmt.Sprint("value ", i)))
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var backup bytes.Buffer
+	if err := db.Backup(&backup); err != nil {
+		t.Fatal(err)
+	}
+	restored := new(DB)
+	restored.InitStore(NewMemStore())
+	if err := restored.Restore(&backup); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if v := restored.Get("test", []byte(fmt.Sprint("key ", i))); string(v) != fmt.Sprint("value ", i) {
+			t.Errorf("key %d restored as %q", i, v)
+		}
+	}
+}
+
+func TestBadgerBatchTooBig(t *testing.T) {
+	dname, err := ioutil.TempDir("", "toobig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dname)
+	db := new(DB)
+	db.DBHome = dname
+	db.Init(0)
+	batch := db.NewBatch()
+	for i := 0; i < 200000; i++ { // Far more writes than one transaction takes
+		batch.Put("test", []byte(fmt.Sprint("key ", i)), []byte("value"))
+	}
+	if err := batch.Commit(); err == nil {
+		t.Fatal("a batch too big for one transaction should fail to commit")
+	}
+	if db.Get("test", []byte("key 0")) != nil {
+		t.Error("nothing of a batch that failed to commit should be written")
+	}
+}
+
+func TestRestoreHugeLength(t *testing.T) {
+	// A key claiming to be nearly 4GB long, in a stream that ends right after it
+	stream := append(append([]byte{}, backupMagic...), types.Uint32Bytes(backupEnd-1)...)
+	stream = append(stream, "short"...)
+	db := new(DB)
+	db.InitStore(NewMemStore())
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := db.Restore(bytes.NewReader(stream)); err == nil {
+		t.Fatal("expected an error restoring a key longer than the backup")
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("a corrupt length shouldn't allocate what it claims, allocated %d bytes", allocated)
+	}
+}
+
+func TestMemStoreIterateWrites(t *testing.T) {
+	m := NewMemStore()
+	for i := 0; i < 10; i++ {
+		m.Put([]byte(fmt.Sprint("key ", i)), []byte("value"))
+	}
+	seen := 0
+	err := m.Iterate(func(key, value []byte) error { // Writes don't wait for the iteration, nor show up in it
+		seen++
+		return m.Put(append([]byte("copy of "), key...), value)
+	})
+	if err != nil || seen != 10 {
+		t.Errorf("expected to iterate over the 10 keys there were at the start, saw %d (%v)", seen, err)
+	}
+	if v, _ := m.Get([]byte("copy of key 3")); string(v) != "value" {
+		t.Error("the writes made while iterating should be kept")
+	}
+}
+
+func TestBucketsDontCollide(t *testing.T) {
+	db := new(DB)
+	db.InitStore(NewMemStore())
+	// Buckets like "node" and "node head" share a prefix, so try keys that start with the rest of the other
+	// bucket's name, as well as plain keys
+	keys := func(b types.Bucket) (keys [][]byte) {
+		keys = append(keys, make([]byte, b.KeyLen()))
+		for _, other := range types.Buckets {
+			if strings.HasPrefix(string(other), string(b)) && other != b && len(other)-len(b) <= b.KeyLen() {
+				key := append([]byte(other[len(b):]), make([]byte, b.KeyLen())...)
+				keys = append(keys, key[:b.KeyLen()])
+			}
+		}
+		return keys
+	}
+	for i, b := range types.Buckets {
+		for _, key := range keys(b) {
+			if err := db.Put(b, key, []byte{byte(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i, b := range types.Buckets {
+		for _, key := range keys(b) {
+			if v := db.Get(b, key); len(v) != 1 || v[0] != byte(i) {
+				t.Errorf("bucket %q key %x reads back %x, expected %x", b, key, v, i)
+			}
+		}
+	}
+	if db.Put(types.Node, make([]byte, 31), []byte{1}) == nil || db.Get(types.Node, make([]byte, 31)) != nil {
+		t.Error("a key the wrong length for its bucket should be refused")
+	}
+}
+
+// countingStore
+// Counts the writes made to the Store it wraps, one at a time, since it hides the Store's writeBatch
+type countingStore struct {
+	Store
+	puts, deletes int
+}
+
+func (c *countingStore) Put(key []byte, value []byte) error {
+	c.puts++
+	return c.Store.Put(key, value)
+}
+
+func (c *countingStore) Delete(key []byte) error {
+	c.deletes++
+	return c.Store.Delete(key)
+}
+
+func TestMigrateStore(t *testing.T) {
+	src := NewMemStore()
+	for i := 0; i < 100; i++ {
+		src.Put([]byte(fmt.Sprint("key ", i)), []byte(fmt.Sprint("value ", i)))
+	}
+	src.Put([]byte("empty"), []byte{})
+	dst := &countingStore{Store: NewMemStore()}
+	for i := 0; i < 40; i++ { // As if an earlier migration was interrupted
+		dst.Store.Put([]byte(fmt.Sprint("key ", i)), []byte(fmt.Sprint("value ", i)))
+	}
+	dst.Store.Put([]byte("key 7"), []byte("changed since"))
+	dst.Store.Put([]byte("deleted since"), []byte("value"))
+
+	if err := MigrateStore(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.puts != 62 || dst.deletes != 1 {
+		t.Errorf("expected to write just the 62 keys missing or changed, and delete 1, got %d and %d", dst.puts, dst.deletes)
+	}
+	var srcBackup, dstBackup bytes.Buffer
+	srcDB, dstDB := new(DB), new(DB)
+	srcDB.InitStore(src)
+	dstDB.InitStore(dst)
+	if err := srcDB.Backup(&srcBackup); err != nil {
+		t.Fatal(err)
+	}
+	if err := dstDB.Backup(&dstBackup); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(srcBackup.Bytes(), dstBackup.Bytes()) {
+		t.Error("the migrated store should hold exactly what the source does")
+	}
+	if MigrateStore(src, src) == nil {
+		t.Error("migrating a store onto itself should fail")
+	}
+}
+
+func TestIterateBucket(t *testing.T) {
+	db := new(DB)
+	db.InitStore(NewMemStore())
+	for i := 0; i < 5; i++ { // "node head" sorts among the keys of "node", whose name it starts with
+		key := make([]byte, 32)
+		key[0] = byte(i * 50)
+		db.Put(types.Node, key, []byte{byte(i)})
+		db.Put(types.NodeHead, key, []byte{byte(i + 10)})
+	}
+	db.Put(types.NodeNext, make([]byte, 32), []byte{99})
+	var values []byte
+	err := db.IterateBucket(types.Node, func(key, value []byte) error {
+		if len(key) != 32 || key[0] != byte(len(values)*50) {
+			t.Errorf("expected the keys in order, got %x", key)
+		}
+		values = append(values, value...)
+		return nil
+	})
+	if err != nil || !bytes.Equal(values, []byte{0, 1, 2, 3, 4}) {
+		t.Errorf("expected the values of just the node bucket, got %v (%v)", values, err)
+	}
+	stop := errors.New("stop")
+	if err := db.IterateBucket(types.NodeHead, func(key, value []byte) error { return stop }); err != stop {
+		t.Errorf("expected the iteration to stop with fn's error, got %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dname, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dname)
+	badgerDB := new(DB)
+	badgerDB.DBHome = dname
+	badgerDB.Init(0)
+	memDB := new(DB)
+	memDB.InitStore(NewMemStore())
+
+	for _, db := range []*DB{memDB, badgerDB} {
+		db.Put("test", []byte("kept"), []byte("before"))
+		db.Put("test", []byte("deleted"), []byte("before"))
+		snapshot, release, err := db.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Put("test", []byte("kept"), []byte("after"))
+		db.Delete("test", []byte("deleted"))
+		db.Put("test", []byte("added"), []byte("after"))
+		if v := snapshot.Get("test", []byte("kept")); string(v) != "before" {
+			t.Errorf("%T: the snapshot should read the value as it was, not %q", db.GetStore(), v)
+		}
+		if v := snapshot.Get("test", []byte("deleted")); string(v) != "before" {
+			t.Errorf("%T: the snapshot should still hold the deleted key", db.GetStore())
+		}
+		keys := 0
+		snapshot.IterateBucket("test", func(key, value []byte) error { keys++; return nil })
+		if keys != 2 {
+			t.Errorf("%T: the snapshot should iterate over the 2 keys there were, not %d", db.GetStore(), keys)
+		}
+		func() {
+			defer func() {
+				if recover() != ErrReadOnly {
+					t.Errorf("%T: writing to a snapshot should panic with ErrReadOnly", db.GetStore())
+				}
+			}()
+			snapshot.Put("test", []byte("kept"), []byte("written"))
+		}()
+		release()
+	}
+}
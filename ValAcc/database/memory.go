@@ -0,0 +1,134 @@
+package database
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemStore
+// Store implementation that keeps everything in a map.  Nothing survives the process, so this is
+// for testing and for tools that build throw away accumulators.
+type MemStore struct {
+	mux    sync.RWMutex
+	values map[string][]byte
+	dead   int64 // Bytes of deleted and overwritten values still held by the map
+}
+
+func NewMemStore() *MemStore {
+	m := new(MemStore)
+	m.values = make(map[string][]byte)
+	return m
+}
+
+func (m *MemStore) Get(key []byte) (value []byte, err error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	v, ok := m.values[string(key)]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte{}, v...), nil
+}
+
+func (m *MemStore) Put(key []byte, value []byte) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if old, ok := m.values[string(key)]; ok {
+		m.dead += int64(len(old))
+	}
+	m.values[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (m *MemStore) Delete(key []byte) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if old, ok := m.values[string(key)]; ok {
+		m.dead += int64(len(key) + len(old))
+		delete(m.values, string(key))
+	}
+	return nil
+}
+
+// Iterate
+// The keys and values are snapshot under the lock, so fn sees a single point in time, but is called without
+// it, so writers carry on meanwhile (and fn may write to the MemStore).  Values are never changed in place,
+// so the snapshot holds only the keys and references to the values, not copies of them.
+func (m *MemStore) Iterate(fn func(key, value []byte) error) error {
+	m.mux.RLock()
+	keys := make([]string, 0, len(m.values))
+	values := make(map[string][]byte, len(m.values))
+	for k, v := range m.values {
+		keys = append(keys, k)
+		values[k] = v
+	}
+	m.mux.RUnlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fn([]byte(k), append([]byte{}, values[k]...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size
+// The bytes of every key and value in the map, and of the deleted and overwritten values it still holds
+func (m *MemStore) Size() (bytes int64, err error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	for k, v := range m.values {
+		bytes += int64(len(k) + len(v))
+	}
+	return bytes + m.dead, nil
+}
+
+// Snapshot
+// A read only MemStore holding the values as they are now.  As with Iterate, only the references to the values
+// are copied, so the snapshot costs the map and not the data.  Nothing needs releasing.
+func (m *MemStore) Snapshot() (snapshot Store, release func(), err error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	frozen := NewMemStore()
+	for k, v := range m.values {
+		frozen.values[k] = v
+	}
+	return ReadOnly(frozen), func() {}, nil
+}
+
+// Compact
+// Go maps don't shrink as keys are deleted, so copy what is live into a fresh map.  Readers and writers
+// wait while the copy is made.
+func (m *MemStore) Compact() (reclaimed int64, err error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	values := make(map[string][]byte, len(m.values))
+	for k, v := range m.values {
+		values[k] = v
+	}
+	m.values = values
+	reclaimed, m.dead = m.dead, 0
+	return reclaimed, nil
+}
+
+// writeBatch
+// Write the batch under one lock, so readers see all of it or none of it
+func (m *MemStore) writeBatch(ops []batchOp) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, op := range ops {
+		old, ok := m.values[string(op.key)]
+		if ok {
+			m.dead += int64(len(old))
+		}
+		if op.value == nil {
+			if ok {
+				m.dead += int64(len(op.key))
+				delete(m.values, string(op.key))
+			}
+			continue
+		}
+		m.values[string(op.key)] = op.value
+	}
+	return nil
+}
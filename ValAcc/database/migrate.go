@@ -0,0 +1,96 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+)
+
+// migrateBatch
+// MigrateStore writes this many bytes of key/values at a time to a Store that can write a batch at once
+const migrateBatch = 1 << 20
+
+// MigrateStore
+// Copy every key/value in src to dst, byte for byte, so dst holds exactly what src did as of a single point
+// in time (see Store.Iterate), and keys in dst that aren't in src are deleted.  Key/values are streamed a
+// batch at a time, not held in memory.  Keys dst already holds with the same value aren't written again, so
+// an interrupted migration picks up where it left off when run again, and running it again once src has
+// moved on copies just what changed.
+func MigrateStore(src, dst Store) error {
+	if src == dst {
+		return errors.New("can't migrate a store onto itself")
+	}
+	var ops []batchOp
+	var size int
+	flush := func() error {
+		err := writeOps(dst, ops)
+		ops, size = nil, 0
+		return err
+	}
+	write := func(op batchOp) error {
+		ops = append(ops, op)
+		if size += len(op.key) + len(op.value); size >= migrateBatch {
+			return flush()
+		}
+		return nil
+	}
+	err := src.Iterate(func(key, value []byte) error {
+		current, err := dst.Get(key)
+		if err != nil {
+			return err
+		}
+		if current != nil && bytes.Equal(current, value) {
+			return nil
+		}
+		if value == nil {
+			value = []byte{} // A nil value would be a delete
+		}
+		return write(batchOp{key: key, value: value})
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	var stale [][]byte
+	err = dst.Iterate(func(key, value []byte) error {
+		kept, err := src.Get(key)
+		if err == nil && kept == nil {
+			stale = append(stale, key)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := write(batchOp{key: key}); err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+// writeOps
+// Write the ops to the Store, all at once if it can
+func writeOps(store Store, ops []batchOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	if writer, ok := store.(batchWriter); ok {
+		return writer.writeBatch(ops)
+	}
+	for _, op := range ops {
+		var err error
+		if op.value == nil {
+			err = store.Delete(op.key)
+		} else {
+			err = store.Put(op.key, op.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import "errors"
+
+// Store
+// The key/value store underneath a DB.  The DB folds the bucket into the key (see GetKey) before
+// calling the Store, so a Store only deals with flat keys.  Get returns a nil value and a nil error
+// if the key isn't in the Store.  Deleting a key that isn't in the Store isn't an error.
+type Store interface {
+	Get(key []byte) (value []byte, err error)
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+	// Iterate calls fn with every key/value in the Store, in key order, as of a single point in time.
+	// Iteration stops at the first error from fn, which is returned.
+	Iterate(fn func(key, value []byte) error) error
+}
+
+// Compacter
+// A Store that can give back the space held by deleted and overwritten values.  Compact returns the
+// number of bytes reclaimed, and must be safe to call while the Store is being read and written.
+type Compacter interface {
+	Compact() (reclaimed int64, err error)
+}
+
+// Sizer
+// A Store that can say how many bytes it takes up, the space of deleted and overwritten values not yet given
+// back by compaction included.
+type Sizer interface {
+	Size() (bytes int64, err error)
+}
+
+// Snapshotter
+// A Store that can freeze a view of itself.  The Store given by Snapshot reads as this one did when it was taken,
+// whatever is written since, and can't be written to.  Call release once done with it, so the Store can let go
+// of what it keeps for the snapshot.
+type Snapshotter interface {
+	Snapshot() (snapshot Store, release func(), err error)
+}
+
+// ErrReadOnly
+// What a Store wrapped by ReadOnly panics with when it is written to
+var ErrReadOnly = errors.New("the store is read only; observers can't write to it")
+
+// ReadOnly
+// Wrap a Store so it can only be read.  Put and Delete (and so committing a Batch over it) panic with
+// ErrReadOnly, rather than returning an error that could be ignored.
+func ReadOnly(store Store) Store {
+	return readOnlyStore{store}
+}
+
+type readOnlyStore struct {
+	Store
+}
+
+func (readOnlyStore) Put(key []byte, value []byte) error { panic(ErrReadOnly) }
+func (readOnlyStore) Delete(key []byte) error            { panic(ErrReadOnly) }
@@ -0,0 +1,196 @@
+package merkleDag
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// BatchReceipt
+// Proves a number of hashes are in an MD at once.  Rather than a path apiece, it holds the roots of the
+// subtrees of the MD that hold none of the hashes, so the nodes the paths share are only carried once.  For
+// a dense selection of hashes that is far smaller than a receipt for each.
+//
+// The MD's tree splits its hashes at the largest power of two less than their count, the left side being
+// a whole subtree, and the right side split the same way.  That is the tree AddToChain and GetMDRoot build.
+type BatchReceipt struct {
+	Count   uint32       // Hashes in the MD
+	Indexes []uint32     // Where in the MD each hash proved was added, in increasing order
+	Hashes  []types.Hash // The hashes proved, in the order of the Indexes
+	Nodes   []types.Hash // Roots of the subtrees holding none of the hashes, from left to right
+	MDRoot  types.Hash   // Merkle DAG root the hashes are proved against
+	Hasher  Hasher       // Combines hashes as the MD did; nil for sha256.  Not marshaled, so set it after Extract
+}
+
+// split
+// Where the tree over count hashes splits into a left and right side
+func split(count uint32) uint32 {
+	left := uint32(1)
+	for left < count-left {
+		left <<= 1
+	}
+	return left
+}
+
+// BuildBatchReceipt
+// Build the receipt proving the given hashes are in the MD.  Hashes given more than once are proved once.
+// Returns an error if any of them isn't in the MD, or for a wide MD (with an Arity over 2).
+func BuildBatchReceipt(md MD, hashes []types.Hash) (*BatchReceipt, error) {
+	if md.wide() {
+		return nil, errors.New(fmt.Sprintf("batch receipts are only built for binary Merkle DAGs, not arity %d", md.Arity))
+	}
+	wanted := make(map[types.Hash]bool, len(hashes))
+	for _, h := range hashes {
+		wanted[h] = true
+	}
+	br := &BatchReceipt{Count: uint32(len(md.HashList)), MDRoot: *md.GetMDRoot(), Hasher: md.Hasher}
+	proved := make([]uint32, len(md.HashList)+1) // How many of the hashes proved come before each index
+	for i, h := range md.HashList {
+		proved[i+1] = proved[i]
+		if wanted[h] {
+			delete(wanted, h)
+			br.Indexes = append(br.Indexes, uint32(i))
+			br.Hashes = append(br.Hashes, h)
+			proved[i+1]++
+		}
+	}
+	for _, h := range hashes {
+		if wanted[h] {
+			return nil, errors.New(fmt.Sprintf("%x is not in the Merkle DAG", h))
+		}
+	}
+	if len(br.Indexes) == 0 {
+		return nil, errors.New("no hashes to prove")
+	}
+	var walk func(start, count uint32)
+	walk = func(start, count uint32) {
+		switch {
+		case proved[start+count] == proved[start]: // Nothing to prove here, so the subtree's root will do
+			sub := MD{Hasher: md.Hasher}
+			for _, h := range md.HashList[start : start+count] {
+				sub.AddToChain(h)
+			}
+			br.Nodes = append(br.Nodes, *sub.GetMDRoot())
+		case count > 1:
+			left := split(count)
+			walk(start, left)
+			walk(start+left, count-left)
+		}
+	}
+	walk(0, br.Count)
+	return br, nil
+}
+
+// Validate
+// Check the hashes the receipt proves give its MDRoot
+func (br *BatchReceipt) Validate() bool {
+	return br.Check() == nil
+}
+
+// Check
+// Validate the receipt, combining hashes with its Hasher, returning why it doesn't validate.  A receipt
+// with more than MaxProofSteps nodes for each hash proved gets an ErrProofTooLong without any hashing.
+func (br *BatchReceipt) Check() error {
+	if len(br.Indexes) == 0 || len(br.Indexes) != len(br.Hashes) {
+		return errors.New(fmt.Sprintf("batch receipt has %d indexes for %d hashes", len(br.Indexes), len(br.Hashes)))
+	}
+	if len(br.Nodes) > len(br.Indexes)*MaxProofSteps {
+		return ErrProofTooLong{Steps: len(br.Nodes), Max: len(br.Indexes) * MaxProofSteps}
+	}
+	for i, index := range br.Indexes {
+		if index >= br.Count || (i > 0 && index <= br.Indexes[i-1]) {
+			return errors.New(fmt.Sprintf("batch receipt index %d is out of order, or not in the %d hashes", index, br.Count))
+		}
+	}
+	md := MD{Hasher: br.Hasher}
+	next, nodes := 0, br.Nodes // The next hash proved, and the nodes not yet used
+	var root func(start, count uint32) (types.Hash, error)
+	root = func(start, count uint32) (types.Hash, error) {
+		if next == len(br.Indexes) || br.Indexes[next] >= start+count { // None of the hashes are here
+			if len(nodes) == 0 {
+				return types.Hash{}, errors.New("batch receipt has too few nodes")
+			}
+			node := nodes[0]
+			nodes = nodes[1:]
+			return node, nil
+		}
+		if count == 1 {
+			next++
+			return br.Hashes[next-1], nil
+		}
+		left := split(count)
+		l, err := root(start, left)
+		if err != nil {
+			return l, err
+		}
+		r, err := root(start+left, count-left)
+		if err != nil {
+			return r, err
+		}
+		return *md.combine(l, r), nil
+	}
+	computed, err := root(0, br.Count)
+	if err != nil {
+		return err
+	}
+	if len(nodes) != 0 {
+		return errors.New(fmt.Sprintf("batch receipt has %d nodes left over", len(nodes)))
+	}
+	if computed != br.MDRoot {
+		return errors.New(fmt.Sprintf("batch receipt computes the root %x, not %x", computed, br.MDRoot))
+	}
+	return nil
+}
+
+// Bytes
+// Marshal the receipt.  Count, number of hashes, each index and hash, number of nodes, each node, then the
+// MDRoot
+func (br *BatchReceipt) Bytes() (data []byte) {
+	data = append(data, types.Uint32Bytes(br.Count)...)
+	data = append(data, types.Uint32Bytes(uint32(len(br.Indexes)))...)
+	for i, index := range br.Indexes {
+		data = append(data, types.Uint32Bytes(index)...)
+		data = append(data, br.Hashes[i].Bytes()...)
+	}
+	data = append(data, types.Uint32Bytes(uint32(len(br.Nodes)))...)
+	for _, n := range br.Nodes {
+		data = append(data, n.Bytes()...)
+	}
+	data = append(data, br.MDRoot.Bytes()...)
+	return data
+}
+
+// Extract
+// Unmarshal a receipt from the given data, returning the data that follows it.  Like the other Extract
+// methods, it panics on bad data; a receipt with more than MaxProofSteps nodes for each hash proved panics
+// with ErrProofTooLong.
+func (br *BatchReceipt) Extract(data []byte) []byte {
+	var numHashes, numNodes uint32
+	br.Count, data = types.BytesUint32(data)
+	numHashes, data = types.BytesUint32(data)
+	if numHashes > br.Count {
+		panic(fmt.Sprintf("batch receipt proves %d hashes of %d", numHashes, br.Count))
+	}
+	br.Indexes, br.Hashes = br.Indexes[:0], br.Hashes[:0]
+	for i := uint32(0); i < numHashes; i++ {
+		var index uint32
+		var hash types.Hash
+		index, data = types.BytesUint32(data)
+		data = hash.Extract(data)
+		br.Indexes = append(br.Indexes, index)
+		br.Hashes = append(br.Hashes, hash)
+	}
+	numNodes, data = types.BytesUint32(data)
+	if uint64(numNodes) > uint64(numHashes)*uint64(MaxProofSteps) {
+		panic(ErrProofTooLong{Steps: int(numNodes), Max: int(numHashes) * MaxProofSteps})
+	}
+	br.Nodes = br.Nodes[:0]
+	for i := uint32(0); i < numNodes; i++ {
+		var n types.Hash
+		data = n.Extract(data)
+		br.Nodes = append(br.Nodes, n)
+	}
+	data = br.MDRoot.Extract(data)
+	return data
+}
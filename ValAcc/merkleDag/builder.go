@@ -0,0 +1,164 @@
+package merkleDag
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// builderBlockLevels
+// A ReceiptBuilder rebuilds the bottom this many levels of a tree (a block of 64 hashes) for each receipt,
+// rather than holding them
+const builderBlockLevels = 6
+
+// builderTree
+// One of the perfect trees of the MD, with the levels above its blocks
+type builderTree struct {
+	start int            // Index in the HashList of the first hash in the tree
+	size  int            // Hashes in the tree, a power of two
+	block int            // Hashes in each block at the bottom of the tree, a power of two no bigger than size
+	upper [][]types.Hash // upper[0] holds the root of each block, upper[1] the level above, ... up to the root
+}
+
+// ReceiptBuilder
+// Builds receipts for hashes in an MD one at a time, in memory bounded by the size of the MD rather than the
+// number of receipts.  Beyond the MD's HashList, it holds a 4 byte index for each hash (to find it), and the
+// levels of the trees above the bottom 6, which is one hash for every 32 in the MD.  Each receipt rebuilds
+// the 64 hashes around its hash in a buffer that is reused.  For a wide MD (with an Arity over 2), each
+// receipt is built from the whole HashList, as BuildMDReceipt does.
+type ReceiptBuilder struct {
+	md      MD
+	order   []uint32 // Indexes into the HashList, sorted by hash
+	trees   []builderTree
+	peaks   []types.Hash // The root of each tree, left to right
+	bags    []types.Hash // bags[i] is what the trees from i on combine into
+	scratch []types.Hash // Levels of the block being rebuilt
+}
+
+// NewReceiptBuilder
+// Get ready to build receipts for the hashes in the MD
+func NewReceiptBuilder(MerkleDag MD) *ReceiptBuilder {
+	b := new(ReceiptBuilder)
+	b.md = MerkleDag
+	hashes := MerkleDag.HashList
+	b.order = make([]uint32, len(hashes))
+	for i := range b.order {
+		b.order[i] = uint32(i)
+	}
+	sort.Slice(b.order, func(i, j int) bool {
+		// Equal hashes keep their order, so a duplicated hash gets the receipt of its first instance
+		if c := bytes.Compare(hashes[b.order[i]][:], hashes[b.order[j]][:]); c != 0 {
+			return c < 0
+		}
+		return b.order[i] < b.order[j]
+	})
+	if MerkleDag.wide() { // Receipt builds each receipt from the HashList
+		return b
+	}
+
+	for start := 0; start < len(hashes); { // The perfect trees, largest first
+		t := builderTree{start: start, size: 1}
+		for t.size*2 <= len(hashes)-start {
+			t.size *= 2
+		}
+		t.block = t.size
+		if t.block > 1<<builderBlockLevels {
+			t.block = 1 << builderBlockLevels
+		}
+		var level []types.Hash
+		for s := start; s < start+t.size; s += t.block {
+			level = append(level, b.blockRoot(hashes[s:s+t.block]))
+		}
+		t.upper = append(t.upper, level)
+		for len(level) > 1 {
+			next := make([]types.Hash, len(level)/2)
+			for i := range next {
+				next[i] = *MerkleDag.combine(level[2*i], level[2*i+1])
+			}
+			t.upper = append(t.upper, next)
+			level = next
+		}
+		b.trees = append(b.trees, t)
+		b.peaks = append(b.peaks, level[0])
+		start += t.size
+	}
+	if len(b.peaks) > 0 {
+		b.bags = make([]types.Hash, len(b.peaks))
+		b.bags[len(b.peaks)-1] = b.peaks[len(b.peaks)-1]
+		for i := len(b.peaks) - 2; i >= 0; i-- {
+			b.bags[i] = *MerkleDag.combine(b.peaks[i], b.bags[i+1])
+		}
+	}
+	return b
+}
+
+// blockRoot
+// Combine a block of hashes (a power of two of them) up to its root, in the scratch buffer
+func (b *ReceiptBuilder) blockRoot(block []types.Hash) types.Hash {
+	b.scratch = append(b.scratch[:0], block...)
+	for width := len(block); width > 1; width /= 2 {
+		for i := 0; i < width/2; i++ {
+			b.scratch[i] = *b.md.combine(b.scratch[2*i], b.scratch[2*i+1])
+		}
+	}
+	return b.scratch[0]
+}
+
+// Receipt
+// Build the receipt for the given hash, or return nil if it isn't in the MD
+func (b *ReceiptBuilder) Receipt(hash types.Hash) *MDReceipt {
+	hashes := b.md.HashList
+	at := sort.Search(len(b.order), func(i int) bool { return bytes.Compare(hashes[b.order[i]][:], hash[:]) >= 0 })
+	if at == len(b.order) || hashes[b.order[at]] != hash {
+		return nil
+	}
+	if b.md.wide() {
+		mdr := new(MDReceipt)
+		mdr.BuildMDReceipt(b.md, hash)
+		return mdr
+	}
+	leaf := int(b.order[at])
+	p := 0
+	for leaf >= b.trees[p].start+b.trees[p].size {
+		p++
+	}
+	t := b.trees[p]
+
+	mdr := new(MDReceipt)
+	mdr.EntryHash = hash
+	mdr.MDRoot = b.bags[0]
+	mdr.Hasher = b.md.Hasher
+
+	// Up through the block holding the hash
+	i := leaf - t.start
+	blockStart := t.start + i/t.block*t.block
+	b.scratch = append(b.scratch[:0], hashes[blockStart:blockStart+t.block]...)
+	for j, width := i%t.block, t.block; width > 1; j, width = j/2, width/2 {
+		mdr.Nodes = append(mdr.Nodes, sibling(b.scratch, j))
+		for k := 0; k < width/2; k++ {
+			b.scratch[k] = *b.md.combine(b.scratch[2*k], b.scratch[2*k+1])
+		}
+	}
+	// Then up through the levels above the blocks
+	for k, j := 0, i/t.block; k < len(t.upper)-1; k, j = k+1, j/2 {
+		mdr.Nodes = append(mdr.Nodes, sibling(t.upper[k], j))
+	}
+	// Then over to the trees on the right, and up the trees on the left
+	if p < len(b.peaks)-1 {
+		mdr.Nodes = append(mdr.Nodes, &ReceiptNode{Right: true, Hash: b.bags[p+1]})
+	}
+	for j := p - 1; j >= 0; j-- {
+		mdr.Nodes = append(mdr.Nodes, &ReceiptNode{Right: false, Hash: b.peaks[j]})
+	}
+	return mdr
+}
+
+// sibling
+// The receipt node combining the hash at index j of a level with its neighbour
+func sibling(level []types.Hash, j int) *ReceiptNode {
+	if j%2 == 0 { // We are on the left, so the neighbour's hash goes on the right
+		return &ReceiptNode{Right: true, Hash: level[j+1]}
+	}
+	return &ReceiptNode{Right: false, Hash: level[j-1]}
+}
@@ -1,14 +1,93 @@
 package merkleDag
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
 )
 
+// Hasher
+// Combines a left and a right hash into the hash of the node above them in the Merkle DAG.
+type Hasher interface {
+	Combine(left, right types.Hash) types.Hash
+}
+
+// DomainHasher
+// Combines with sha256 over a 0x01 prefix, so an interior node of the MD can never be mistaken for the
+// hash of an entry.
+type DomainHasher struct{}
+
+func (DomainHasher) Combine(left, right types.Hash) types.Hash {
+	data := append([]byte{1}, left.Bytes()...)
+	return sha256.Sum256(append(data, right.Bytes()...))
+}
+
+// EmptyMDRoot
+// The MD root of an MD with no hashes, i.e. the ListMDRoot of an empty block.  It is the sha256 of a fixed
+// string rather than all zeros or the sha256 of nothing, so it can't be mistaken for the root of an MD
+// holding real data.
+var EmptyMDRoot = types.Hash(sha256.Sum256([]byte("ValAcc empty Merkle DAG")))
+
 // MD
 // Collects Hashes from some source, and allows the creation of MD Roots as desired.
 type MD struct {
 	MD       []*types.Hash // Array of hashes that represent the right edge of the Merkle tree
 	HashList []types.Hash  // List of Hashes in the order added to the chain
+	Hasher   Hasher        // Combines hashes in the MD.  If nil, we use sha256 (see types.Hash.Combine)
+
+	// Arity is the number of children each interior node has, 2 (the default, as for zero) up to MaxArity.
+	// Changing it changes every root.  An MD with an Arity over 2 combines its hashes with its Hasher's
+	// CombineAll (see WideHasher), and StrictMode only checks the hashes it combines two at a time.
+	Arity  int
+	levels [][]types.Hash // For an Arity over 2, the hashes at each level waiting for their siblings
+
+	// StrictMode has the MD remember what every hash it combines was combined from, and panic with an
+	// ErrCollision if two different pairs combine to the same hash, which takes a broken hash (or Hasher) to
+	// happen.  It costs a map entry for every hash combined.
+	StrictMode bool
+	combined   map[types.Hash][2]types.Hash // What each hash combined was combined from, in StrictMode
+}
+
+// ErrCollision
+// What an MD in StrictMode panics with when two different pairs of hashes combine to the same hash
+type ErrCollision struct {
+	Hash  types.Hash    // The hash both pairs combined to
+	First [2]types.Hash // The left and right hashes first combined to it
+	Then  [2]types.Hash // The left and right hashes that combined to it again
+}
+
+func (e ErrCollision) Error() string {
+	return fmt.Sprintf("hash collision: %x combined from both %x,%x and %x,%x",
+		e.Hash, e.First[0], e.First[1], e.Then[0], e.Then[1])
+}
+
+// combine
+// Combine the left and right hashes with the MD's Hasher
+func (m *MD) combine(left, right types.Hash) *types.Hash {
+	var combined types.Hash
+	if m.Hasher == nil {
+		combined = *left.Combine(right)
+	} else {
+		combined = m.Hasher.Combine(left, right)
+	}
+	if m.StrictMode {
+		m.checkCollision(combined, [2]types.Hash{left, right})
+	}
+	return &combined
+}
+
+// checkCollision
+// Remember what the hash was combined from, and panic if it was combined from something else before
+func (m *MD) checkCollision(combined types.Hash, from [2]types.Hash) {
+	if m.combined == nil {
+		m.combined = make(map[types.Hash][2]types.Hash)
+	}
+	if first, ok := m.combined[combined]; ok && first != from {
+		panic(ErrCollision{Hash: combined, First: first, Then: from})
+	}
+	m.combined[combined] = from
 }
 
 // GetHashList
@@ -28,6 +107,10 @@ func (m *MD) AddToChain(hash types.Hash) {
 	hash = *hash.Copy() // Get a copy of the hash
 	// We are going through through the MD list and combining hashes, so we have to record the hash first thing
 	m.HashList = append(m.HashList, hash) // before it is combined with other hashes already added to MD[].
+	if m.wide() {
+		m.addWide(hash)
+		return
+	}
 
 	// We make sure m.MD ends with a nil entry, because that cuts out most of the corner cases in adding hashes
 	if len(m.MD) == 0 || m.MD[len(m.MD)-1] != nil { // If this is the first entry, or the last entry isn't nil
@@ -47,16 +130,20 @@ func (m *MD) AddToChain(hash types.Hash) {
 
 		// If teh current spot is NOT open, we need to combine the hash we have with the hash on the "left", i.e.
 		// the hash already in m.MD
-		hash = *v.Combine(hash) // Combine v (left) and hash (right) to get a new combined hash to use forward
-		m.MD[i] = nil           // Now that we have combined v and hash, this spot is now empty, so clear it.
+		hash = *m.combine(*v, hash) // Combine v (left) and hash (right) to get a new combined hash to use forward
+		m.MD[i] = nil               // Now that we have combined v and hash, this spot is now empty, so clear it.
 	}
 }
 
 // GetMDRoot
 // Close off the Merkle Directed Acyclic Graph (Merkle DAG or MD)
 // We take any trailing hashes in MD, hash them up and combine to create the Merkle Dag Root.
-// Getting the closing ListMDRoot is non-destructive, which is useful for some use cases.
+// Getting the closing ListMDRoot is non-destructive, which is useful for some use cases.  An MD with no hashes
+// has the EmptyMDRoot.
 func (m *MD) GetMDRoot() (MDRoot *types.Hash) {
+	if m.wide() {
+		return m.wideRoot()
+	}
 	// We go through m.MD and combine any left over hashes in m.MD with each other and the MR.
 	// If this is a power of two, that's okay because we will pick up the MR (a balanced MD) and
 	// return that, the correct behavior
@@ -64,15 +151,36 @@ func (m *MD) GetMDRoot() (MDRoot *types.Hash) {
 		if MDRoot == nil { // We will pick up the first hash in m.MD no matter what.
 			MDRoot = v // If we assign a nil over a nil, no harm no foul.  Fewer cases to test this way.
 		} else if v != nil { // If MDRoot isn't nil and v isn't nil, we combine them.
-			MDRoot = v.Combine(*MDRoot) // v is on the left, MDRoot candidate is on the right, for a new MDRoot
+			MDRoot = m.combine(*v, *MDRoot) // v is on the left, MDRoot candidate is on the right, for a new MDRoot
 		}
 	}
-	// We drop out with a MDRoot unless m.MD is zero length, in which case we return a nil (correct)
+	// We drop out with a MDRoot unless m.MD is zero length, in which case we return the EmptyMDRoot
 	// If m.MD has the entries for a power of two, then only one hash (the last) is in m.MD, which we return (correct)
 	// If m.MD has a railing nil, we return the trailing entries combined with the last entry in m.MD (correct)
+	if MDRoot == nil {
+		return EmptyMDRoot.Copy()
+	}
 	return MDRoot
 }
 
+// SubRangeRoot
+// The root over the hashes i up to (but not including) j of the HashList.  The range is taken as a chain of
+// its own: the root is that of a fresh MD (with the same Hasher and Arity) the range's hashes are added to, so
+// the trailing hashes of a range that isn't a power of two are closed off as GetMDRoot closes off the full
+// MD, and an empty range has the EmptyMDRoot.  A binary MD's range of a power of two hashes starting at a
+// multiple of that power is a subtree of the full MD, so its root is a node of the full MD as well.  Returns
+// an error for a range outside the HashList.
+func (m *MD) SubRangeRoot(i, j int) (types.Hash, error) {
+	if i < 0 || j < i || j > len(m.HashList) {
+		return types.Hash{}, errors.New(fmt.Sprintf("the range [%d, %d) isn't within the %d hashes of the MD", i, j, len(m.HashList)))
+	}
+	sub := &MD{Hasher: m.Hasher, Arity: m.Arity}
+	for _, h := range m.HashList[i:j] {
+		sub.AddToChain(h)
+	}
+	return *sub.GetMDRoot(), nil
+}
+
 // PrintMR
 // For debugging purposes, it is nice to get a string that shows the nil and non nil entries in c.MD
 // Note that the "low order" entries are first in the string, so the binary is going from low order on the left to
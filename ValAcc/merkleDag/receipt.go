@@ -1,12 +1,20 @@
 package merkleDag
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
 )
 
 type ReceiptNode struct {
 	Right bool       // The given Hash will be on the Right (right==true) or on the left (right==false)
 	Hash  types.Hash // hash to be combined at the next level
+
+	// For a receipt of a wide MD (with an Arity over 2), the siblings to the left and the right of the hash
+	// being proved, in place of Right and Hash
+	Before []types.Hash
+	After  []types.Hash
 }
 
 type MDReceipt struct {
@@ -15,6 +23,8 @@ type MDReceipt struct {
 	MDRoot    types.Hash     // Merkle DAG root from the Accumulator network.
 	// We likely want a struct here provided by the underlying blockchain where we are recording
 	// the MDRoots for the Accumulator
+	Hasher Hasher // Combines hashes as the MD did; nil for sha256.  Not marshaled, so set it after Extract
+	Arity  int    // The Arity of the MD; zero or 2 for a binary MD
 }
 
 // BuildMDReceipt
@@ -26,9 +36,15 @@ type MDReceipt struct {
 func (mdr *MDReceipt) BuildMDReceipt(MerkleDag MD, data types.Hash) {
 	mdr.Nodes = mdr.Nodes[:0] // Throw away any old paths
 	mdr.EntryHash = data      // The Data for which this is a Receipt
-	md := []*types.Hash{nil}  // The intermediate hashes used to compute the Merkle DAG root
-	right := true             // We assume we will be combining from the right
-	idx := -1                 // idx of -1 means not yet found the hash for which we want a receipt in the hash stream
+	mdr.Hasher = MerkleDag.Hasher
+	mdr.Arity = MerkleDag.Arity
+	if MerkleDag.wide() {
+		mdr.buildWide(MerkleDag, data)
+		return
+	}
+	md := []*types.Hash{nil} // The intermediate hashes used to compute the Merkle DAG root
+	right := true            // We assume we will be combining from the right
+	idx := -1                // idx of -1 means not yet found the hash for which we want a receipt in the hash stream
 
 DataLoop: // Loop through the data behind the Merkle DAG and rebuild the MD state
 	for _, h := range MerkleDag.HashList {
@@ -66,8 +82,8 @@ DataLoop: // Loop through the data behind the Merkle DAG and rebuild the MD stat
 				right = false // Regardless, our hash is now in h and will later combine with a hash on the left
 				idx++         // And our hash will "carry" to the next slot in md[]
 			}
-			h = *v.Combine(h) // Combine v (left) and hash (right) to get a new combined hash to use forward
-			md[i] = nil       // Now that we have combined v and hash, this spot is now empty, so clear it.
+			h = *MerkleDag.combine(*v, h) // Combine v (left) and hash (right) to get a new combined hash to use forward
+			md[i] = nil                   // Now that we have combined v and hash, this spot is now empty, so clear it.
 		}
 	}
 	// At this point we have a (possibly) partial merkle tree.
@@ -102,55 +118,241 @@ DataLoop: // Loop through the data behind the Merkle DAG and rebuild the MD stat
 		return
 	}
 	var mdRoot *types.Hash // mdRoot is the merkle DAG root we are building.
-	right = true
+	inRoot := false        // Becomes true once our hash has been combined into mdRoot
 
 	// We close the Merkle DAG
 	for i, v := range md {
-		if mdRoot == nil { // If we have not found a hash yet, try and pick one up here.
-			if i == idx {
-				if v == nil { // This should never happen.
-					panic("found our hash level, but no hash was found.")
-				}
-				right = false // Now our hash is on the right, will be combined with a hash on the left, or none at all.
-				idx++
-			}
-			if v != nil {
-				mdRoot = new(types.Hash)
-				copy(mdRoot[:], v[:]) // Pick up this hash, and look for the next
-			}
+		if v == nil {
 			continue
 		}
-		if v != nil { // If MDRoot isn't nil and v isn't nil, we combine them.
-			if i == idx {
-				rn := new(ReceiptNode)
-				mdr.Nodes = append(mdr.Nodes, rn)
-				rn.Right = right
-				if right {
-					copy(rn.Hash[:], mdRoot[:])
-				} else {
-					copy(rn.Hash[:], v[:])
+		if mdRoot == nil { // Pick up the first hash we find
+			mdRoot = v.Copy()
+			inRoot = i == idx
+			continue
+		}
+		if i == idx { // Our hash is v, and is combined with the mdRoot on the right
+			mdr.Nodes = append(mdr.Nodes, &ReceiptNode{Right: true, Hash: *mdRoot})
+			inRoot = true
+		} else if inRoot { // Our hash is in mdRoot, which is combined with v on the left
+			mdr.Nodes = append(mdr.Nodes, &ReceiptNode{Right: false, Hash: *v})
+		}
+		mdRoot = MerkleDag.combine(*v, *mdRoot) // v is on the left, MDRoot candidate is on the right, for a new MDRoot
+	}
+	copy(mdr.MDRoot[:], mdRoot[:]) // The last one is the one we want (even if we never had to combine)
+	return
+}
+
+// BuildMDReceipts
+// Build the receipts for every hash in the Merkle DAG in one pass, rather than calling BuildMDReceipt
+// (which walks the whole HashList) for each hash.  The hashes in the MD form a set of perfect binary
+// trees (one for each bit set in the count of hashes), largest on the left.  A receipt is the path up
+// through the hash's own tree, then over to the combined trees on the right, then up the trees on the left.
+// Receipts are returned in the order of the HashList.
+func BuildMDReceipts(MerkleDag MD) (receipts []*MDReceipt) {
+	hashes := MerkleDag.HashList
+	if MerkleDag.wide() { // The trees aren't perfect binary trees, so build each receipt on its own
+		for _, h := range hashes {
+			mdr := new(MDReceipt)
+			mdr.BuildMDReceipt(MerkleDag, h)
+			receipts = append(receipts, mdr)
+		}
+		return receipts
+	}
+	var peaks []types.Hash // The roots of each perfect tree, left to right
+	var sizes []int        // The number of hashes under each of the perfect trees
+	var paths [][]*ReceiptNode
+
+	// Walk through the perfect trees, largest first.
+	for start := 0; start < len(hashes); {
+		size := 1
+		for size*2 <= len(hashes)-start {
+			size *= 2
+		}
+		level := append([]types.Hash{}, hashes[start:start+size]...)
+		leafPaths := make([][]*ReceiptNode, size)
+		for width := 1; len(level) > 1; width *= 2 { // Combine up a level at a time
+			next := make([]types.Hash, len(level)/2)
+			for i := range next {
+				left, right := level[2*i], level[2*i+1]
+				for j := 2 * i * width; j < (2*i+1)*width; j++ { // leaves under the left need the right hash
+					leafPaths[j] = append(leafPaths[j], &ReceiptNode{Right: true, Hash: right})
+				}
+				for j := (2*i + 1) * width; j < (2*i+2)*width; j++ { // leaves under the right need the left hash
+					leafPaths[j] = append(leafPaths[j], &ReceiptNode{Right: false, Hash: left})
 				}
-				right = false
-				idx++
+				next[i] = *MerkleDag.combine(left, right)
 			}
-			mdRoot = v.Combine(*mdRoot)    // v is on the left, MDRoot candidate is on the right, for a new MDRoot
-			copy(mdr.MDRoot[:], mdRoot[:]) // The last one is the one we want
+			level = next
 		}
+		peaks = append(peaks, level[0])
+		sizes = append(sizes, size)
+		paths = append(paths, leafPaths...)
+		start += size
 	}
-	return
+	if len(peaks) == 0 {
+		return nil
+	}
+
+	// The MDRoot combines the trees from the right; bags[i] is what the trees from i on combine into
+	bags := make([]types.Hash, len(peaks))
+	bags[len(peaks)-1] = peaks[len(peaks)-1]
+	for i := len(peaks) - 2; i >= 0; i-- {
+		bags[i] = *MerkleDag.combine(peaks[i], bags[i+1])
+	}
+
+	leaf := 0
+	for p := range peaks {
+		for i := 0; i < sizes[p]; i++ {
+			mdr := new(MDReceipt)
+			mdr.EntryHash = hashes[leaf]
+			mdr.MDRoot = bags[0]
+			mdr.Hasher = MerkleDag.Hasher
+			mdr.Nodes = paths[leaf]
+			if p < len(peaks)-1 { // Combine with the trees to our right
+				mdr.Nodes = append(mdr.Nodes, &ReceiptNode{Right: true, Hash: bags[p+1]})
+			}
+			for j := p - 1; j >= 0; j-- { // Then with each tree to our left
+				mdr.Nodes = append(mdr.Nodes, &ReceiptNode{Right: false, Hash: peaks[j]})
+			}
+			receipts = append(receipts, mdr)
+			leaf++
+		}
+	}
+	return receipts
+}
+
+// MaxProofSteps
+// The most steps a receipt may take from its entry hash to its MDRoot.  A path climbs at most one step for
+// each bit of the count of hashes in the MD, then one per peak to its right and left, so 128 covers any MD
+// that could ever be built.  Receipts with more steps are refused before any hashing is done, so a crafted
+// receipt can't make us burn CPU.
+var MaxProofSteps = 128
+
+// ErrProofTooLong
+// Returned for a receipt with more than MaxProofSteps steps
+type ErrProofTooLong struct {
+	Steps int // Steps in the receipt
+	Max   int // MaxProofSteps when the receipt was refused
+}
+
+func (e ErrProofTooLong) Error() string {
+	return fmt.Sprintf("receipt has %d steps, more than the limit of %d", e.Steps, e.Max)
 }
 
 // Validate
 // Run down the Merkle DAG and prove that this receipt self validates
 func (mdr *MDReceipt) Validate() bool {
+	return mdr.Check() == nil
+}
+
+// Check
+// Validate the receipt, combining hashes with its Hasher (and as its Arity says), returning why it doesn't
+// validate.  Receipts longer than MaxProofSteps get an ErrProofTooLong.
+func (mdr *MDReceipt) Check() error {
+	if len(mdr.Nodes) > MaxProofSteps {
+		return ErrProofTooLong{Steps: len(mdr.Nodes), Max: MaxProofSteps}
+	}
+	if mdr.Arity < 0 || mdr.Arity == 1 || mdr.Arity > MaxArity {
+		return errors.New(fmt.Sprintf("receipt has an arity of %d", mdr.Arity))
+	}
+	md := MD{Hasher: mdr.Hasher, Arity: mdr.Arity}
 	hash := mdr.EntryHash
 	for _, n := range mdr.Nodes {
-		node := n.Hash.Copy()
-		if n.Right {
-			hash = *hash.Combine(*node)
+		if mdr.Arity > 2 {
+			if len(n.Before)+len(n.After) >= mdr.Arity {
+				return errors.New(fmt.Sprintf("receipt step has %d siblings in an MD of arity %d", len(n.Before)+len(n.After), mdr.Arity))
+			}
+			children := append(append(append([]types.Hash{}, n.Before...), hash), n.After...)
+			hash = md.combineAll(children)
+		} else if n.Right {
+			hash = *md.combine(hash, n.Hash)
 		} else {
-			hash = *node.Combine(hash)
+			hash = *md.combine(n.Hash, hash)
+		}
+	}
+	if hash != mdr.MDRoot {
+		return errors.New(fmt.Sprintf("receipt for %x computes the root %x, not %x", mdr.EntryHash, hash, mdr.MDRoot))
+	}
+	return nil
+}
+
+// wideReceipt
+// Set in the number of nodes of a marshaled receipt of a wide MD, which is followed by its Arity, and for
+// each node the count and hashes of the siblings before, then after.  No receipt has enough nodes to set it.
+const wideReceipt = 1 << 31
+
+// Bytes
+// Marshal the receipt.  EntryHash, number of nodes, each node (right flag and hash), then the MDRoot.  See
+// wideReceipt for the nodes of a receipt of a wide MD.
+func (mdr *MDReceipt) Bytes() (data []byte) {
+	data = append(data, mdr.EntryHash.Bytes()...)
+	if mdr.Arity > 2 {
+		data = append(data, types.Uint32Bytes(uint32(len(mdr.Nodes))|wideReceipt)...)
+		data = append(data, types.Uint32Bytes(uint32(mdr.Arity))...)
+		for _, n := range mdr.Nodes {
+			for _, siblings := range [][]types.Hash{n.Before, n.After} {
+				data = append(data, types.Uint32Bytes(uint32(len(siblings)))...)
+				for _, h := range siblings {
+					data = append(data, h.Bytes()...)
+				}
+			}
 		}
+		data = append(data, mdr.MDRoot.Bytes()...)
+		return data
+	}
+	data = append(data, types.Uint32Bytes(uint32(len(mdr.Nodes)))...)
+	for _, n := range mdr.Nodes {
+		data = append(data, types.BoolBytes(n.Right)...)
+		data = append(data, n.Hash.Bytes()...)
+	}
+	data = append(data, mdr.MDRoot.Bytes()...)
+	return data
+}
+
+// Extract
+// Unmarshal a receipt from the given data, returning the data that follows it.  Like the other Extract
+// methods, it panics on bad data; a receipt with more than MaxProofSteps nodes panics with ErrProofTooLong.
+func (mdr *MDReceipt) Extract(data []byte) []byte {
+	data = mdr.EntryHash.Extract(data)
+	var numNodes, arity uint32
+	numNodes, data = types.BytesUint32(data)
+	wide := numNodes&wideReceipt != 0
+	numNodes &^= wideReceipt
+	if uint64(numNodes) > uint64(MaxProofSteps) {
+		panic(ErrProofTooLong{Steps: int(numNodes), Max: MaxProofSteps})
+	}
+	mdr.Nodes = mdr.Nodes[:0]
+	mdr.Arity = 0
+	if wide {
+		arity, data = types.BytesUint32(data)
+		if arity <= 2 || arity > MaxArity {
+			panic(fmt.Sprintf("receipt has an arity of %d", arity))
+		}
+		mdr.Arity = int(arity)
+		for i := uint32(0); i < numNodes; i++ {
+			rn := new(ReceiptNode)
+			for _, siblings := range []*[]types.Hash{&rn.Before, &rn.After} {
+				var count uint32
+				count, data = types.BytesUint32(data)
+				if count >= arity {
+					panic(fmt.Sprintf("receipt step has %d siblings in an MD of arity %d", count, arity))
+				}
+				for j := uint32(0); j < count; j++ {
+					var h types.Hash
+					data = h.Extract(data)
+					*siblings = append(*siblings, h)
+				}
+			}
+			mdr.Nodes = append(mdr.Nodes, rn)
+		}
+		return mdr.MDRoot.Extract(data)
+	}
+	for i := uint32(0); i < numNodes; i++ {
+		rn := new(ReceiptNode)
+		rn.Right, data = types.BytesBool(data)
+		data = rn.Hash.Extract(data)
+		mdr.Nodes = append(mdr.Nodes, rn)
 	}
-	return hash == mdr.MDRoot
+	data = mdr.MDRoot.Extract(data)
+	return data
 }
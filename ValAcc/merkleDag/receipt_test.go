@@ -1,10 +1,13 @@
 package merkleDag
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
 )
 
 func TestMD(t *testing.T) {
@@ -179,3 +182,300 @@ func TestMD(t *testing.T) {
 	}
 
 }
+
+func TestBuildMDReceipts(t *testing.T) {
+	// Check every receipt against BuildMDReceipt for a range of sizes, including a single hash
+	for size := 1; size < 70; size++ {
+		md := new(MD)
+		for i := 0; i < size; i++ {
+			md.AddToChain(sha256.Sum256([]byte{byte(i), byte(size)}))
+		}
+		receipts := BuildMDReceipts(*md)
+		if len(receipts) != size {
+			t.Fatalf("expected %d receipts, got %d", size, len(receipts))
+		}
+		for i, r := range receipts {
+			if !r.Validate() || r.MDRoot != *md.GetMDRoot() {
+				t.Fatalf("receipt %d of %d fails to validate against the MDRoot", i, size)
+			}
+			one := new(MDReceipt)
+			one.BuildMDReceipt(*md, md.HashList[i])
+			if !bytes.Equal(one.Bytes(), r.Bytes()) {
+				t.Fatalf("receipt %d of %d doesn't match BuildMDReceipt", i, size)
+			}
+			var back MDReceipt
+			if rest := back.Extract(r.Bytes()); len(rest) != 0 || !bytes.Equal(back.Bytes(), r.Bytes()) {
+				t.Fatalf("receipt %d of %d doesn't survive marshaling", i, size)
+			}
+		}
+	}
+}
+
+func TestMaxProofSteps(t *testing.T) {
+	md := new(MD)
+	for i := 0; i < 1000; i++ {
+		md.AddToChain(sha256.Sum256([]byte(fmt.Sprintf("step %d", i))))
+	}
+	receipt := new(MDReceipt)
+	receipt.BuildMDReceipt(*md, md.HashList[500])
+	if err := receipt.Check(); err != nil {
+		t.Fatalf("a real receipt should be well within the limit: %v", err)
+	}
+
+	// A crafted receipt with a million steps is refused, both when checked and when unmarshaled
+	for len(receipt.Nodes) < 1000000 {
+		receipt.Nodes = append(receipt.Nodes, receipt.Nodes...)
+	}
+	err := receipt.Check()
+	if tooLong, ok := err.(ErrProofTooLong); !ok || tooLong.Steps != len(receipt.Nodes) {
+		t.Errorf("expected ErrProofTooLong, got %v", err)
+	}
+	func() {
+		defer func() {
+			if _, ok := recover().(ErrProofTooLong); !ok {
+				t.Error("expected Extract to refuse the long receipt")
+			}
+		}()
+		new(MDReceipt).Extract(receipt.Bytes())
+	}()
+}
+
+func TestEmptyMDRoot(t *testing.T) {
+	first, second := new(MD).GetMDRoot(), new(MD).GetMDRoot()
+	if first == nil || *first != EmptyMDRoot || *second != EmptyMDRoot {
+		t.Fatal("an empty MD should have the EmptyMDRoot")
+	}
+	first[0] ^= 1 // Changing a root handed out for an empty MD mustn't change the EmptyMDRoot
+	if *second != EmptyMDRoot || *new(MD).GetMDRoot() != EmptyMDRoot {
+		t.Error("the EmptyMDRoot should not be shared")
+	}
+	nothing := sha256.Sum256(nil)
+	if EmptyMDRoot == (types.Hash{}) || EmptyMDRoot == nothing {
+		t.Error("the EmptyMDRoot should not look like a real hash")
+	}
+}
+
+func TestReceiptBuilder(t *testing.T) {
+	// Sizes around a block, and big enough to have levels above the blocks, with both hashers
+	sizes := []int{1, 2, 3, 63, 64, 65, 127, 128, 129, 200, 1000, 4097}
+	for _, hasher := range []Hasher{nil, DomainHasher{}} {
+		for _, size := range sizes {
+			md := new(MD)
+			md.Hasher = hasher
+			for i := 0; i < size; i++ {
+				md.AddToChain(sha256.Sum256([]byte(fmt.Sprintf("builder %d %d", size, i))))
+			}
+			builder := NewReceiptBuilder(*md)
+			for i, want := range BuildMDReceipts(*md) {
+				got := builder.Receipt(md.HashList[i])
+				if got == nil || !bytes.Equal(got.Bytes(), want.Bytes()) {
+					t.Fatalf("receipt %d of %d doesn't match BuildMDReceipts", i, size)
+				}
+			}
+			if builder.Receipt(sha256.Sum256([]byte("not in the MD"))) != nil {
+				t.Fatalf("got a receipt for a hash not in an MD of %d", size)
+			}
+		}
+	}
+	if NewReceiptBuilder(MD{}).Receipt(types.Hash{}) != nil {
+		t.Error("got a receipt from an empty MD")
+	}
+}
+
+// constantHasher
+// A broken Hasher that combines everything to the same hash
+type constantHasher struct{}
+
+func (constantHasher) Combine(left, right types.Hash) types.Hash {
+	return types.Hash{1}
+}
+
+// addAll
+// Add the hashes to the MD, returning what it panicked with, if anything
+func addAll(md *MD, hashes []types.Hash) (panicked interface{}) {
+	defer func() { panicked = recover() }()
+	for _, h := range hashes {
+		md.AddToChain(h)
+	}
+	md.GetMDRoot()
+	return nil
+}
+
+func TestStrictMode(t *testing.T) {
+	var hashes []types.Hash
+	for i := 0; i < 10; i++ {
+		hashes = append(hashes, sha256.Sum256([]byte(fmt.Sprint("strict ", i))))
+	}
+	if panicked := addAll(&MD{Hasher: constantHasher{}}, hashes); panicked != nil {
+		t.Fatalf("without StrictMode, collisions should go unnoticed, got %v", panicked)
+	}
+	collision, ok := addAll(&MD{Hasher: constantHasher{}, StrictMode: true}, hashes).(ErrCollision)
+	if !ok || collision.Hash != (types.Hash{1}) || collision.First == collision.Then {
+		t.Fatalf("StrictMode should panic with an ErrCollision, got %v", collision)
+	}
+	if collision.First != [2]types.Hash{hashes[0], hashes[1]} || collision.Then != [2]types.Hash{hashes[2], hashes[3]} {
+		t.Errorf("expected the first collision to be of 0,1 with 2,3, got %v", collision)
+	}
+
+	for _, hasher := range []Hasher{nil, DomainHasher{}} {
+		strict, plain := &MD{Hasher: hasher, StrictMode: true}, &MD{Hasher: hasher}
+		if panicked := addAll(strict, hashes); panicked != nil {
+			t.Errorf("a real hash shouldn't collide, got %v", panicked)
+		}
+		addAll(plain, hashes)
+		if *strict.GetMDRoot() != *plain.GetMDRoot() {
+			t.Error("StrictMode shouldn't change the root")
+		}
+	}
+}
+
+func TestBatchReceipt(t *testing.T) {
+	for _, hasher := range []Hasher{nil, DomainHasher{}} {
+		for count := 1; count <= 40; count++ {
+			md := MD{Hasher: hasher}
+			for i := 0; i < count; i++ {
+				md.AddToChain(sha256.Sum256([]byte(fmt.Sprint("batched ", i))))
+			}
+			for _, stride := range []int{1, 2, 3, 7, count} {
+				var hashes []types.Hash
+				for i := count - 1; i >= 0; i -= stride { // In any order
+					hashes = append(hashes, md.HashList[i])
+				}
+				br, err := BuildBatchReceipt(md, hashes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if br.MDRoot != *md.GetMDRoot() || br.Check() != nil {
+					t.Fatalf("batch receipt for every %d of %d hashes should validate: %v", stride, count, br.Check())
+				}
+				var extracted BatchReceipt
+				if rest := extracted.Extract(append(br.Bytes(), 9)); len(rest) != 1 || rest[0] != 9 {
+					t.Fatal("Extract should return the data that follows the receipt")
+				}
+				extracted.Hasher = hasher
+				if !extracted.Validate() {
+					t.Fatalf("batch receipt for every %d of %d hashes should validate once extracted", stride, count)
+				}
+				if len(br.Nodes) > 0 {
+					br.Nodes[0][0] ^= 1
+					if br.Validate() {
+						t.Fatalf("a batch receipt with a changed node shouldn't validate")
+					}
+					br.Nodes = br.Nodes[1:]
+					if br.Validate() {
+						t.Fatalf("a batch receipt with a node missing shouldn't validate")
+					}
+				}
+			}
+		}
+	}
+	if _, err := BuildBatchReceipt(MD{}, []types.Hash{{1}}); err == nil {
+		t.Error("expected an error proving a hash not in the MD")
+	}
+}
+
+func TestArity(t *testing.T) {
+	// With an arity of 4, six hashes are a node over the first four, then a node over the last two,
+	// combined at the end as the binary MD combines its trailing hashes
+	//            1   2   3   4   5   6
+	//             \  |   |  /     \ /
+	//               1234          56
+	//                   \        /
+	//                   1234+56
+	hashes := make([]types.Hash, 6)
+	for i := range hashes {
+		hashes[i] = sha256.Sum256([]byte{byte(i + 1)})
+	}
+	concat := func(hs ...types.Hash) types.Hash {
+		var data []byte
+		for _, h := range hs {
+			data = append(data, h[:]...)
+		}
+		return sha256.Sum256(data)
+	}
+	expected := concat(concat(hashes[:4]...), concat(hashes[4:]...))
+	binary, wide := MD{Arity: 2}, MD{Arity: 4}
+	for _, h := range hashes {
+		binary.AddToChain(h)
+		wide.AddToChain(h)
+	}
+	if *wide.GetMDRoot() != expected {
+		t.Errorf("arity 4 gives the root %x, not %x", wide.GetMDRoot(), expected)
+	}
+	plain := new(MD)
+	for _, h := range hashes {
+		plain.AddToChain(h)
+	}
+	if *binary.GetMDRoot() != *plain.GetMDRoot() || *binary.GetMDRoot() == *wide.GetMDRoot() {
+		t.Error("arity 2 should be the default binary MD, and differ from arity 4")
+	}
+
+	for _, arity := range []int{2, 4, 5, 256} {
+		for _, hasher := range []Hasher{nil, DomainHasher{}} {
+			for size := 1; size < 70; size++ {
+				md := &MD{Arity: arity, Hasher: hasher}
+				for i := 0; i < size; i++ {
+					md.AddToChain(sha256.Sum256([]byte{byte(i), byte(size)}))
+				}
+				builder := NewReceiptBuilder(*md)
+				for i, r := range BuildMDReceipts(*md) {
+					if !r.Validate() || r.MDRoot != *md.GetMDRoot() {
+						t.Fatalf("receipt %d of %d fails to validate under arity %d", i, size, arity)
+					}
+					if built := builder.Receipt(md.HashList[i]); !bytes.Equal(built.Bytes(), r.Bytes()) {
+						t.Fatalf("the ReceiptBuilder's receipt %d of %d differs under arity %d", i, size, arity)
+					}
+					var back MDReceipt
+					if rest := back.Extract(r.Bytes()); len(rest) != 0 || !bytes.Equal(back.Bytes(), r.Bytes()) {
+						t.Fatalf("receipt %d of %d doesn't survive marshaling under arity %d", i, size, arity)
+					}
+					if arity > 2 && size > 1 {
+						back.Hasher = hasher
+						back.Arity = 2
+						if back.Validate() {
+							t.Fatalf("receipt %d of %d for arity %d shouldn't validate as binary", i, size, arity)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSubRangeRoot(t *testing.T) {
+	var leaves []types.Hash
+	for i := 0; i < 8; i++ {
+		leaves = append(leaves, sha256.Sum256([]byte(fmt.Sprint("leaf ", i))))
+	}
+	for _, hasher := range []Hasher{nil, DomainHasher{}} {
+		md := &MD{Hasher: hasher}
+		for _, h := range leaves {
+			md.AddToChain(h)
+		}
+		for i := 0; i <= 8; i++ {
+			for j := i; j <= 8; j++ {
+				sub := &MD{Hasher: hasher}
+				for _, h := range leaves[i:j] {
+					sub.AddToChain(h)
+				}
+				root, err := md.SubRangeRoot(i, j)
+				if err != nil || root != *sub.GetMDRoot() {
+					t.Errorf("the root over [%d, %d) should be that of an MD of just those leaves (%v)", i, j, err)
+				}
+			}
+		}
+		combine := func(left, right types.Hash) types.Hash { return *md.combine(left, right) }
+		right := combine(combine(leaves[4], leaves[5]), combine(leaves[6], leaves[7]))
+		if root, _ := md.SubRangeRoot(4, 8); root != right {
+			t.Error("an aligned range of four should be the right subtree of the full MD")
+		}
+		if root, _ := md.SubRangeRoot(0, 8); root != *md.GetMDRoot() {
+			t.Error("the whole range should have the MD's root")
+		}
+		for _, r := range [][2]int{{-1, 2}, {3, 2}, {5, 9}} {
+			if _, err := md.SubRangeRoot(r[0], r[1]); err == nil {
+				t.Errorf("the range [%d, %d) should be refused", r[0], r[1])
+			}
+		}
+	}
+}
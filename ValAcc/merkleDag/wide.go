@@ -0,0 +1,174 @@
+package merkleDag
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// MaxArity
+// The most children an interior node of a wide MD can have
+const MaxArity = 256
+
+// WideHasher
+// A Hasher that can also combine more than two hashes into the hash of the node above them, for an MD with
+// an Arity over 2.  The DomainHasher is one.  For a nil Hasher, the children are hashed with sha256 over
+// their concatenation; a Hasher that isn't a WideHasher combines them one at a time, left to right.
+type WideHasher interface {
+	Hasher
+	CombineAll(children []types.Hash) types.Hash
+}
+
+func (DomainHasher) CombineAll(children []types.Hash) types.Hash {
+	data := []byte{1}
+	for _, c := range children {
+		data = append(data, c.Bytes()...)
+	}
+	return sha256.Sum256(data)
+}
+
+// wide
+// True if the MD's nodes have more than two children.  Panics for an Arity out of range.
+func (m *MD) wide() bool {
+	if m.Arity < 0 || m.Arity == 1 || m.Arity > MaxArity {
+		panic(fmt.Sprintf("a Merkle DAG can't have an arity of %d", m.Arity))
+	}
+	return m.Arity > 2
+}
+
+// combineAll
+// Combine the children of a node of a wide MD with the MD's Hasher
+func (m *MD) combineAll(children []types.Hash) types.Hash {
+	switch h := m.Hasher.(type) {
+	case nil:
+		var data []byte
+		for _, c := range children {
+			data = append(data, c.Bytes()...)
+		}
+		return sha256.Sum256(data)
+	case WideHasher:
+		return h.CombineAll(children)
+	}
+	combined := children[0]
+	for _, c := range children[1:] {
+		combined = m.Hasher.Combine(combined, c)
+	}
+	return combined
+}
+
+// addWide
+// AddToChain for a wide MD.  Each level holds the hashes waiting for their siblings; once a level has
+// Arity of them, they are combined into a hash for the level above.
+func (m *MD) addWide(hash types.Hash) {
+	for level := 0; ; level++ {
+		if level == len(m.levels) {
+			m.levels = append(m.levels, nil)
+		}
+		m.levels[level] = append(m.levels[level], hash)
+		if len(m.levels[level]) < m.Arity {
+			return
+		}
+		hash = m.combineAll(m.levels[level])
+		m.levels[level] = nil
+	}
+}
+
+// wideRoot
+// GetMDRoot for a wide MD.  Like the binary MD, the levels are closed off from the bottom up: the root of
+// the levels below becomes the last child of a node over the hashes waiting at the next level.
+func (m *MD) wideRoot() *types.Hash {
+	var root *types.Hash
+	for _, waiting := range m.levels {
+		children := waiting
+		if root != nil {
+			children = append(append([]types.Hash{}, waiting...), *root)
+		}
+		switch len(children) {
+		case 0:
+		case 1:
+			root = children[0].Copy()
+		default:
+			combined := m.combineAll(children)
+			root = &combined
+		}
+	}
+	if root == nil {
+		return EmptyMDRoot.Copy()
+	}
+	return root
+}
+
+// buildWide
+// BuildMDReceipt for a wide MD.  Each step of the path records the siblings before and after the hash
+// being proved (or the node it is under) as they are combined.
+func (mdr *MDReceipt) buildWide(MerkleDag MD, data types.Hash) {
+	var levels [][]types.Hash
+	level, index := -1, -1 // Where our hash, or the node it is under, waits to be combined
+	step := func(children []types.Hash, at int) {
+		mdr.Nodes = append(mdr.Nodes, &ReceiptNode{
+			Before: append([]types.Hash{}, children[:at]...),
+			After:  append([]types.Hash{}, children[at+1:]...),
+		})
+	}
+	for _, h := range MerkleDag.HashList {
+		if level < 0 && h == data { // Only the first instance of a duplicated hash is proved
+			level, index = 0, 0
+			if len(levels) > 0 {
+				index = len(levels[0])
+			}
+		}
+		for l := 0; ; l++ {
+			if l == len(levels) {
+				levels = append(levels, nil)
+			}
+			levels[l] = append(levels[l], h)
+			if len(levels[l]) < MerkleDag.Arity {
+				break
+			}
+			if l == level {
+				step(levels[l], index)
+				level, index = l+1, 0
+				if l+1 < len(levels) {
+					index = len(levels[l+1])
+				}
+			}
+			h = MerkleDag.combineAll(levels[l])
+			levels[l] = nil
+		}
+	}
+	if level < 0 {
+		mdr.Nodes = mdr.Nodes[:0]
+		return
+	}
+
+	var root *types.Hash
+	inRoot := false // Becomes true once our hash is under the root of the levels closed off so far
+	for l, waiting := range levels {
+		children := waiting
+		if root != nil {
+			children = append(append([]types.Hash{}, waiting...), *root)
+		}
+		at := -1 // Where our hash is in the children, if it is
+		switch {
+		case l == level:
+			at = index
+		case inRoot:
+			at = len(children) - 1
+		}
+		switch len(children) {
+		case 0:
+			continue
+		case 1:
+			root = children[0].Copy()
+		default:
+			if at >= 0 {
+				step(children, at)
+			}
+			combined := MerkleDag.combineAll(children)
+			root = &combined
+		}
+		inRoot = inRoot || at >= 0
+	}
+	copy(mdr.MDRoot[:], root[:])
+}
@@ -165,7 +165,8 @@ func (e *ANode) Unmarshal(data []byte) (dataConsumed int, err error) {
 // EntryHash
 // The accumulator assumes the ANode has already been written to the (a) database.  It is only dealing with EntryHashes
 type EntryHash struct {
-	SubChains []types.Hash // SubChainIDs used to create the ChainID
-	ChainID   types.Hash   // The ChainID
-	EntryHash types.Hash   // The EntryHash
+	SubChains []types.Hash    // SubChainIDs used to create the ChainID
+	ChainID   types.Hash      // The ChainID
+	EntryHash types.Hash      // The EntryHash
+	TimeStamp types.TimeStamp // The submitter's time for the entry (as the accumulator's, in nanoseconds), or zero for none
 }
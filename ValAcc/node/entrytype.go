@@ -0,0 +1,20 @@
+package node
+
+import (
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// EntryType
+// The type tag of an entry.  By convention, applications that tag their entries (transactions, data
+// entries, anchors ...) put the type in the leading byte of the EntryHash; see TagEntryHash.  For an
+// untagged entry this is just the first byte of its hash.
+func EntryType(e EntryHash) byte {
+	return e.EntryHash[0]
+}
+
+// TagEntryHash
+// Return the entry hash with its leading byte replaced by the given type tag
+func TagEntryHash(entryType byte, hash types.Hash) types.Hash {
+	hash[0] = entryType
+	return hash
+}
@@ -0,0 +1,39 @@
+package node
+
+import (
+	"strings"
+)
+
+// Flags
+// The features a node was built with, so whoever verifies it knows which algorithms to use.  Flags are
+// part of the marshaled node (from version 1), so they are covered by the node's hash.
+type Flags uint32
+
+const (
+	Signed          Flags = 1 << iota // The node is signed by the accumulator
+	Compressed                        // The entries behind the node are stored compressed
+	DomainSeparated                   // The Merkle DAGs combine hashes with domain separation (merkleDag.DomainHasher)
+	Grouped                           // The chains with a single entry in the block share one entry of its List
+	SameRoot                          // An empty block whose ListMDRoot is its previous block's, so isn't marshaled
+)
+
+var flagNames = []string{"Signed", "Compressed", "DomainSeparated", "Grouped", "SameRoot"}
+
+// Has
+// True if every one of the given flags is set
+func (f Flags) Has(flags Flags) bool {
+	return f&flags == flags
+}
+
+func (f Flags) String() string {
+	var names []string
+	for i, name := range flagNames {
+		if f.Has(1 << uint(i)) {
+			names = append(names, name)
+		}
+	}
+	if unknown := f &^ (1<<uint(len(flagNames)) - 1); unknown != 0 {
+		names = append(names, "Unknown")
+	}
+	return strings.Join(names, "|")
+}
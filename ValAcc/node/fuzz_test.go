@@ -0,0 +1,49 @@
+//go:build go1.18
+// +build go1.18
+
+// Fuzz targets need go 1.18; the rest of the module builds with go 1.13.
+
+package node
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// FuzzNodeUnmarshal
+// Unmarshal has to reject bad data with an error, never a panic, and whatever it accepts has to
+// marshal back to a node that unmarshals the same way
+func FuzzNodeUnmarshal(f *testing.F) {
+	valid := GetTestNode(nil)
+	f.Add(valid.Marshal())
+	noEntries := *valid
+	noEntries.List = nil
+	f.Add(noEntries.Marshal())
+	huge := noEntries.Marshal()
+	copy(huge[len(huge)-4:], types.Uint32Bytes(0xFFFFFFFF)) // EntryList length prefix, with no entries behind it
+	f.Add(huge)
+	f.Add(huge[:len(huge)-8]) // List length prefix claims 0 but the EntryList prefix is cut off
+	f.Add([]byte{})
+	f.Add([]byte{1})
+	f.Add(valid.Marshal()[:10]) // Truncated header
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var n Node
+		consumed, err := n.Unmarshal(data)
+		if err != nil {
+			if strings.Contains(err.Error(), "panicked") {
+				t.Fatalf("Unmarshal panicked rather than checking its bounds: %v", err)
+			}
+			return
+		}
+		if consumed > len(data) {
+			t.Fatalf("consumed %d bytes of %d", consumed, len(data))
+		}
+		remarshaled := n.Marshal()
+		var n2 Node
+		if consumed, err := n2.Unmarshal(remarshaled); err != nil || consumed != len(remarshaled) || !n.SameAs(n2) {
+			t.Fatalf("an unmarshaled node failed to round trip: %v", err)
+		}
+	})
+}
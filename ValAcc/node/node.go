@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
 )
 
@@ -17,6 +18,7 @@ import (
 
 type Node struct {
 	Version     types.VersionField // Version of this data structure
+	Flags       Flags              // Features used to build the node (version 1 and up)
 	BHeight     types.BlockHeight  // Block Height
 	SequenceNum types.Sequence     // Sequence Number for this chain of nodes
 	TimeStamp   types.TimeStamp    // TimeStamp by Accumulator when the structure was built
@@ -30,6 +32,9 @@ type Node struct {
 
 	MarshalCache []byte // Cache of the marshaled form of the node.  Do NOT marshal a node unless
 	//   the node is completely formed!
+
+	entryCount uint32 // Length of the EntryList UnmarshalHeader left in entryData
+	entryData  []byte // The marshaled EntryList, for LoadEntryList, if UnmarshalHeader left it out
 }
 
 // NEList
@@ -60,8 +65,9 @@ func (n Node) Put(db *database.DB) error {
 
 	// If a node does not have any SubChains to define its ChainID, then its ChainID is really
 	// the DID for the root accumulator, and this is a Directory Block.  So we will index it
-	// against the block height.  Other nodes are not indexed by block height.
-	if len(n.SubChainIDs) == 0 {
+	// against the block height.  Other nodes are not indexed by block height.  Chain nodes
+	// (IsNode is false) may also lack SubChains, so they are excluded too.
+	if n.IsNode && len(n.SubChainIDs) == 0 {
 		db.PutInt32(types.DirectoryBlockHeight, int(n.BHeight), nHash)
 	}
 
@@ -76,6 +82,9 @@ func (n Node) SameAs(n2 Node) bool {
 	if n.Version != n2.Version {
 		return false
 	}
+	if n.Flags != n2.Flags {
+		return false
+	}
 	if n.BHeight != n2.BHeight {
 		return false
 	}
@@ -145,6 +154,9 @@ func (n Node) Marshal() (bytes []byte) {
 	}()
 
 	bytes = append(bytes, n.Version.Bytes()...) // Put the version into the slice
+	if n.Version >= 1 {                         // Version 0 nodes have no flags
+		bytes = append(bytes, types.Uint32Bytes(uint32(n.Flags))...)
+	}
 	bytes = append(bytes, n.BHeight.Bytes()...)
 	bytes = append(bytes, n.SequenceNum.Bytes()...)
 	bytes = append(bytes, n.TimeStamp.Bytes()...)
@@ -155,7 +167,9 @@ func (n Node) Marshal() (bytes []byte) {
 	}
 	bytes = append(bytes, n.Previous.Bytes()...)
 	bytes = append(bytes, types.BoolBytes(n.IsNode)...)
-	bytes = append(bytes, n.ListMDRoot.Bytes()...)
+	if !n.Flags.Has(SameRoot) { // Otherwise the ListMDRoot is the previous block's, which its hash covers
+		bytes = append(bytes, n.ListMDRoot.Bytes()...)
+	}
 	bytes = append(bytes, types.Uint32Bytes(uint32(len(n.List)))...) // Put the number of List Entries
 	for _, list := range n.List {                                    // For each SubChain
 		bytes = append(bytes, list.ChainID.Bytes()...) // ChainsInBlock/SubChain ID
@@ -196,53 +210,165 @@ func (n Node) GetMDRoot() (mdRoot *types.Hash) {
 	return &mdr
 }
 
+// VerifyEntryListRoot
+// Check the EntryList, fed into a fresh MD combining with the given Hasher (nil for sha256), gives the
+// ListMDRoot.  An EntryList UnmarshalHeader left out is unpacked to be checked.  The root of a continuous
+// chain's node covers the chain's history, so only that chain's Reader can check it.
+func (n Node) VerifyEntryListRoot(hasher merkleDag.Hasher) error {
+	n.LoadEntryList() // On our copy of the node
+	md := new(merkleDag.MD)
+	md.Hasher = hasher
+	for _, h := range n.EntryList {
+		md.AddToChain(h)
+	}
+	root := md.GetMDRoot()
+	if root == nil {
+		root = new(types.Hash)
+	}
+	if *root != n.ListMDRoot {
+		return errors.New(fmt.Sprintf("the %d entries of chain %x at height %d give the root %x, not the ListMDRoot %x",
+			len(n.EntryList), n.ChainID, n.BHeight, *root, n.ListMDRoot))
+	}
+	return nil
+}
+
 // Unmarshal
 // Extract an entry from a byte slice.  Returns an error if the unmarshal fails, or the length of the
-// data consumed and a nil.
+// data consumed and a nil.  Every length is checked against the data left before anything is pulled out,
+// so truncated or garbage data gets an error rather than a panic (or a huge allocation), and on an error
+// the node is left as it was.
 func (n *Node) Unmarshal(data []byte) (dataConsumed int, err error) {
+	return n.unmarshal(data, false)
+}
+
+// UnmarshalHeader
+// Extract a node from a byte slice as Unmarshal does, except for the EntryList, which is checked but left
+// unpacked, since a chain node's EntryList can be huge and most readers only want the header.  EntryCount
+// gives the length of the EntryList, and LoadEntryList unpacks it when it is wanted.  The node keeps the
+// data it was unmarshaled from (as its MarshalCache, so GetHash works), so the data mustn't be changed.
+func (n *Node) UnmarshalHeader(data []byte) (dataConsumed int, err error) {
+	return n.unmarshal(data, true)
+}
+
+// EntryCount
+// The number of entries in the EntryList, whether or not it has been unpacked
+func (n *Node) EntryCount() int {
+	if n.entryData != nil {
+		return int(n.entryCount)
+	}
+	return len(n.EntryList)
+}
+
+// LoadEntryList
+// Unpack the EntryList left out by UnmarshalHeader, leaving the node as Unmarshal would have.  Does nothing
+// if the EntryList is already unpacked.
+func (n *Node) LoadEntryList() {
+	if n.entryData == nil {
+		return
+	}
+	n.MarshalCache = nil
+	data := n.entryData
+	n.EntryList = make([]types.Hash, n.entryCount)
+	for i := range n.EntryList {
+		data = n.EntryList[i].Extract(data)
+	}
+	n.entryData = nil
+}
+
+// unmarshal
+// The work of Unmarshal, and with headerOnly, of UnmarshalHeader
+func (n *Node) unmarshal(data []byte, headerOnly bool) (dataConsumed int, err error) {
 
 	// On any error, no data is consumed and return an error as to why unmarshal fails
 	defer func() {
 		if r := recover(); r != nil {
-			err = errors.New(fmt.Sprintf("ANode Failed to unmarshal %v", r))
+			dataConsumed = 0
+			err = errors.New(fmt.Sprintf("ANode panicked while unmarshaling %v", r))
 		}
 	}()
 	d := data // d keeps the original slice
+	var u Node
 
-	data = n.Version.Extract(data)     // Extract the version
-	data = n.BHeight.Extract(data)     // Extract the BlockHeight
-	data = n.SequenceNum.Extract(data) // Extract the BlockHeight
-	data = n.TimeStamp.Extract(data)   // Extract the TimeStamp
-	data = n.ChainID.Extract(data)     // Extract the ChainID
-	n.SubChainIDs = n.SubChainIDs[0:0] // Clear any ExtIDs that might already be in this ANode
+	if err := need(data, 1, "the version"); err != nil {
+		return 0, err
+	}
+	data = u.Version.Extract(data) // Extract the version
+	if u.Version >= 1 {            // Version 0 nodes have no flags
+		if err := need(data, 4, "the flags"); err != nil {
+			return 0, err
+		}
+		var flags uint32
+		flags, data = types.BytesUint32(data)
+		u.Flags = Flags(flags)
+	}
+	if err := need(data, 4+4+8+32+2, "the header"); err != nil {
+		return 0, err
+	}
+	data = u.BHeight.Extract(data)     // Extract the BlockHeight
+	data = u.SequenceNum.Extract(data) // Extract the BlockHeight
+	data = u.TimeStamp.Extract(data)   // Extract the TimeStamp
+	data = u.ChainID.Extract(data)     // Extract the ChainID
 	// Pull out all the subChain IDs
 	var numSubChains uint16
 	numSubChains, data = types.BytesUint16(data) // Get the number of SubChainIDs we should have
-	for i := uint16(0); i < numSubChains; i++ {  // Pull each of them out of the data slice
+	rootLen := uint64(32)
+	if u.Flags.Has(SameRoot) { // Left for the reader to take from the previous block
+		rootLen = 0
+	}
+	if err := need(data, uint64(numSubChains)*32+32+1+rootLen+4, "the SubChainIDs"); err != nil {
+		return 0, err
+	}
+	for i := uint16(0); i < numSubChains; i++ { // Pull each of them out of the data slice
 		sc := types.Hash{}                        // Get a Hash to put the SubChainID in
 		data = sc.Extract(data)                   // Extract the ExtID
-		n.SubChainIDs = append(n.SubChainIDs, sc) // Put it in the ExtID list
+		u.SubChainIDs = append(u.SubChainIDs, sc) // Put it in the ExtID list
+	}
+	data = u.Previous.Extract(data)
+	u.IsNode, data = types.BytesBool(data) // Extract the node/entries flag
+	if rootLen > 0 {
+		data = u.ListMDRoot.Extract(data)
 	}
-	data = n.Previous.Extract(data)
-	n.IsNode, data = types.BytesBool(data) // Extract the node/entries flag
-	data = n.ListMDRoot.Extract(data)
 	// Pull out all the List entries
 	var listLen uint32
 	listLen, data = types.BytesUint32(data)
+	if err := need(data, uint64(listLen)*64+4, "the List"); err != nil {
+		return 0, err
+	}
 	for i := uint32(0); i < listLen; i++ {
 		ne := new(NEList)
 		data = ne.ChainID.Extract(data)
 		data = ne.MDRoot.Extract(data)
-		n.List = append(n.List, *ne)
+		u.List = append(u.List, *ne)
 	}
 	var eListLen uint32
-	listLen, data = types.BytesUint32(data)
-	for i := uint32(0); i < eListLen; i++ {
-		var eHash types.Hash
-		eHash.Extract(data)
-		n.EntryList = append(n.EntryList, eHash)
+	eListLen, data = types.BytesUint32(data)
+	if err := need(data, uint64(eListLen)*32, "the EntryList"); err != nil {
+		return 0, err
+	}
+	if headerOnly {
+		u.entryCount = eListLen
+		u.entryData = data[:uint64(eListLen)*32]
+		data = data[uint64(eListLen)*32:]
+		u.MarshalCache = d[:len(d)-len(data)]
+	} else {
+		for i := uint32(0); i < eListLen; i++ {
+			var eHash types.Hash
+			data = eHash.Extract(data)
+			u.EntryList = append(u.EntryList, eHash)
+		}
 	}
 
+	*n = u
 	return len(d) - len(data), nil // Return the bytes consumed and a nil that all is well for an error
 
 }
+
+// need
+// Return an error if there are fewer than the given number of bytes left to unmarshal what comes next
+func need(data []byte, length uint64, what string) error {
+	if uint64(len(data)) < length {
+		return errors.New(fmt.Sprintf("ANode Failed to unmarshal; %d bytes are needed for %s and what follows, "+
+			"but only %d are left", length, what, len(data)))
+	}
+	return nil
+}
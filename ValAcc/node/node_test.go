@@ -6,11 +6,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/merkleDag"
 
 	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
 )
@@ -71,10 +73,149 @@ func TestNode(t *testing.T) {
 	if !n.SameAs(n2) {
 		t.Error("Did not unmarshal an ANode as expected")
 	}
-	expectedLen := 444
+	expectedLen := 448
 	if nodeLen != expectedLen {
 		t.Errorf("Length of data consumed (%d) not as expected (%d)", nodeLen, expectedLen)
 	}
+
+	// Version 0 nodes have no flags, and are 4 bytes shorter
+	n = GetTestNode(t)
+	n.Version = 0
+	nodeLen, err = n2.Unmarshal(n.Marshal())
+	if err != nil || !n.SameAs(n2) || nodeLen != expectedLen-4 {
+		t.Errorf("Failed to round trip a version 0 ANode; consumed %d bytes", nodeLen)
+	}
+}
+
+func TestNodeFlags(t *testing.T) {
+	n := GetTestNode(t)
+	hash := *n.GetHash()
+	n.Flags = Signed | DomainSeparated
+	if *n.GetHash() == hash {
+		t.Error("the flags should be covered by the node's hash")
+	}
+	var n2 Node
+	if _, err := n2.Unmarshal(n.Marshal()); err != nil || !n2.Flags.Has(Signed|DomainSeparated) || n2.Flags.Has(Compressed) {
+		t.Errorf("expected the flags to round trip, got %v", n2.Flags)
+	}
+	if s := n2.Flags.String(); s != "Signed|DomainSeparated" {
+		t.Errorf("unexpected flags string %q", s)
+	}
+
+	withRoot := len(n.Marshal())
+	n.Flags |= SameRoot
+	if len(n.Marshal()) != withRoot-32 {
+		t.Errorf("a SameRoot node should be marshaled without its ListMDRoot, in %d bytes not %d", withRoot-32, len(n.Marshal()))
+	}
+	if _, err := n2.Unmarshal(n.Marshal()); err != nil || n2.ListMDRoot != (types.Hash{}) || n2.Previous != n.Previous {
+		t.Errorf("a SameRoot node should unmarshal with no ListMDRoot (%v)", err)
+	}
+}
+
+// randomNode
+// Build a node with random contents, for property tests
+func randomNode(rnd *rand.Rand) *Node {
+	hash := func() (h types.Hash) {
+		rnd.Read(h[:])
+		return h
+	}
+	n := new(Node)
+	n.Version = types.VersionField(rnd.Intn(int(types.Version) + 1))
+	if n.Version >= 1 {
+		n.Flags = Flags(rnd.Uint32())
+	}
+	n.BHeight = types.BlockHeight(rnd.Uint32())
+	n.SequenceNum = types.Sequence(rnd.Uint32())
+	n.TimeStamp = types.TimeStamp(rnd.Int63())
+	n.ChainID = hash()
+	for i := rnd.Intn(4); i > 0; i-- {
+		n.SubChainIDs = append(n.SubChainIDs, hash())
+	}
+	n.Previous = hash()
+	n.IsNode = rnd.Intn(2) == 1
+	n.ListMDRoot = hash()
+	if n.Version >= 1 && n.Flags.Has(SameRoot) { // Not marshaled, so can't round trip
+		n.ListMDRoot = types.Hash{}
+	}
+	for i := rnd.Intn(5); i > 0; i-- {
+		n.List = append(n.List, NEList{ChainID: hash(), MDRoot: hash()})
+	}
+	for i := rnd.Intn(5); i > 0; i-- {
+		n.EntryList = append(n.EntryList, hash())
+	}
+	return n
+}
+
+func TestNodeRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		n := randomNode(rnd)
+		data := n.Marshal()
+		var n2 Node
+		consumed, err := n2.Unmarshal(append(data, 0xFF)) // Whatever follows the node is left alone
+		if err != nil || consumed != len(data) || !n.SameAs(n2) {
+			t.Fatalf("node %d failed to round trip; consumed %d of %d bytes, %v", i, consumed, len(data), err)
+		}
+		for cut := 0; cut < len(data); cut++ {
+			if _, err := n2.Unmarshal(data[:cut]); err == nil {
+				t.Fatalf("node %d truncated to %d bytes should fail to unmarshal", i, cut)
+			}
+		}
+	}
+}
+
+func TestVerifyEntryListRoot(t *testing.T) {
+	for _, hasher := range []merkleDag.Hasher{nil, merkleDag.DomainHasher{}} {
+		n := new(Node)
+		n.ChainID = sha256.Sum256([]byte("verified"))
+		md := new(merkleDag.MD)
+		md.Hasher = hasher
+		for i := 0; i < 7; i++ {
+			h := types.Hash(sha256.Sum256([]byte(fmt.Sprint("entry ", i))))
+			n.EntryList = append(n.EntryList, h)
+			md.AddToChain(h)
+		}
+		n.ListMDRoot = *md.GetMDRoot()
+		if err := n.VerifyEntryListRoot(hasher); err != nil {
+			t.Error(err)
+		}
+		var header Node // Left out by UnmarshalHeader, the EntryList is unpacked to be checked
+		if _, err := header.UnmarshalHeader(n.Marshal()); err != nil || header.VerifyEntryListRoot(hasher) != nil {
+			t.Errorf("the header of a good node should verify (%v)", err)
+		}
+		n.EntryList[3][5] ^= 1
+		if n.VerifyEntryListRoot(hasher) == nil {
+			t.Error("a node with a flipped entry byte shouldn't verify")
+		}
+	}
+}
+
+func TestNodeUnmarshalHeader(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		n := randomNode(rnd)
+		data := n.Marshal()
+		var header Node
+		consumed, err := header.UnmarshalHeader(append(data, 0xFF))
+		if err != nil || consumed != len(data) {
+			t.Fatalf("node %d header consumed %d of %d bytes, %v", i, consumed, len(data), err)
+		}
+		if header.EntryList != nil || header.EntryCount() != len(n.EntryList) {
+			t.Fatalf("node %d header should count %d entries without unpacking them", i, len(n.EntryList))
+		}
+		if header.ListMDRoot != n.ListMDRoot || *header.GetHash() != *n.GetHash() {
+			t.Fatalf("node %d header should have the node's root and hash", i)
+		}
+		header.LoadEntryList()
+		if !n.SameAs(header) || header.EntryCount() != len(n.EntryList) {
+			t.Fatalf("node %d should be whole once its EntryList is loaded", i)
+		}
+		for cut := 0; cut < len(data); cut++ {
+			if _, err := header.UnmarshalHeader(data[:cut]); err == nil {
+				t.Fatalf("node %d truncated to %d bytes should fail to unmarshal", i, cut)
+			}
+		}
+	}
 }
 
 // GetTestDB
@@ -74,6 +74,7 @@ func (r *Router) Init(entryHashStream chan node.EntryHash, NumAccumulator int) {
 		db := new(database.DB)
 		r.DBs = append(r.DBs, db)
 		db.Init(i)
+		// Not types.ChainIDFromName; the chainID keys the accumulator's database, so it can never change
 		chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
 		entryFeed, control, mdHashes := acc.Init(db, &chainID)
 		r.EntryFeeds = append(r.EntryFeeds, entryFeed)
@@ -90,6 +91,6 @@ func (r *Router) Run() {
 		entry := <-r.EntryHashStream
 		chainNumber := int(entry.ChainID[0])<<8 + int(entry.ChainID[1])
 		idx := chainNumber % cnt
-		r.ACCs[idx].GetEntryFeed() <- entry
+		r.ACCs[idx].Submit(entry)
 	}
 }
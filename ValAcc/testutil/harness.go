@@ -0,0 +1,116 @@
+// Package testutil
+// Helpers for testing code built on the accumulator.  The Harness runs an accumulator over an in memory
+// database with a clock that only moves when told to, so tests get the same blocks every time they run.
+package testutil
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/database"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/node"
+	"github.com/PaulSnow/ValidatorAccumulator/ValAcc/types"
+)
+
+// Start
+// Where every Harness clock starts
+var Start = time.Unix(1577836800, 0) // 2020-01-01 00:00:00 UTC
+
+// Clock
+// A Clock for the accumulator that stays put until Advance is called
+type Clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewClock
+// A clock set to the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance
+// Move the clock forward
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Harness
+// Drives an accumulator without Run.  Entries are fed straight through, and blocks are sealed when asked,
+// with no sleeps or dependence on real time.
+type Harness struct {
+	Acc   *accumulator.Accumulator // The accumulator under test; set its options before feeding it
+	DB    *database.DB             // The in memory database the accumulator writes to
+	Clock *Clock                   // The accumulator's clock, starting at Start
+}
+
+// NewHarness
+// An accumulator with the given ID over a fresh in memory database
+func NewHarness(name string) *Harness {
+	h := new(Harness)
+	h.DB = new(database.DB)
+	h.DB.InitStore(database.NewMemStore())
+	h.Clock = NewClock(Start)
+	h.Acc = new(accumulator.Accumulator)
+	h.Acc.Clock = h.Clock
+	chainID := types.Hash(sha256.Sum256([]byte(name)))
+	h.Acc.Init(h.DB, &chainID)
+	return h
+}
+
+// Feed
+// Submit the entries and add them to the current block.  Returns how many were accepted.
+func (h *Harness) Feed(entries ...node.EntryHash) (accepted int) {
+	for _, entry := range entries {
+		if h.Acc.Submit(entry) {
+			accepted++
+		}
+		if len(h.Acc.GetEntryFeed()) == cap(h.Acc.GetEntryFeed()) {
+			h.Acc.ProcessPending() // Don't block on a full feed
+		}
+	}
+	h.Acc.ProcessPending()
+	return accepted
+}
+
+// Seal
+// Seal the current block, and return its directory block (nil if the block was dropped, or left open; see
+// accumulator.SealBlock)
+func (h *Harness) Seal() *node.Node {
+	return h.Acc.SealBlock()
+}
+
+// Close
+// Let go of the accumulator's go routines and files once the test is done with it
+func (h *Harness) Close() error {
+	return h.Acc.Close()
+}
+
+// LastBlock
+// The last directory block sealed (by Seal, or by hitting MaxEntriesPerBlock), or nil if none have been
+func (h *Harness) LastBlock() *node.Node {
+	block, err := h.Acc.Reader().GetHead()
+	if err != nil {
+		panic(err)
+	}
+	return block
+}
+
+// Entry
+// A test entry for the named chain, unique for each i
+func Entry(chain string, i int) node.EntryHash {
+	var eh node.EntryHash
+	eh.ChainID = sha256.Sum256([]byte(chain))
+	eh.EntryHash = sha256.Sum256(append(eh.ChainID[:], types.Uint32Bytes(uint32(i))...))
+	return eh
+}
@@ -0,0 +1,53 @@
+package testutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// run
+// Build a few blocks over a handful of chains, and return the hashes of the directory blocks
+func run() (hashes []string) {
+	h := NewHarness("determinism")
+	defer h.Close()
+	for block := 0; block < 5; block++ {
+		for i := 0; i < 20; i++ {
+			h.Feed(Entry(fmt.Sprintf("chain %d", i%7), block*20+i))
+		}
+		h.Clock.Advance(time.Second)
+		h.Seal()
+		hashes = append(hashes, fmt.Sprintf("%x", *h.LastBlock().GetHash()))
+	}
+	return hashes
+}
+
+func TestDeterminism(t *testing.T) {
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("block %d differs between runs: %s and %s", i, first[i], second[i])
+		}
+	}
+}
+
+func TestHarness(t *testing.T) {
+	h := NewHarness("harness")
+	defer h.Close()
+	if h.LastBlock() != nil {
+		t.Error("no blocks have been sealed yet")
+	}
+	if accepted := h.Feed(Entry("a", 0), Entry("a", 1), Entry("b", 0)); accepted != 3 {
+		t.Errorf("expected 3 entries accepted, got %d", accepted)
+	}
+	block := h.Seal()
+	if block == nil || *block.GetHash() != *h.LastBlock().GetHash() || len(block.List) != 2 || block.BHeight != 0 {
+		t.Fatal("expected a block at height 0 holding both chains")
+	}
+	if !time.Unix(0, int64(block.TimeStamp)).Equal(Start) {
+		t.Error("the block should be stamped with the harness clock")
+	}
+	if h.Seal().Previous != *block.GetHash() {
+		t.Error("the second block should follow the first")
+	}
+}
@@ -11,3 +11,8 @@ func (bh *BlockHeight) Extract(data []byte) []byte {
 	*bh = BlockHeight(bhv)
 	return newData
 }
+
+// MaxBlockHeight
+// The highest height a BlockHeight can hold.  A directory block's SequenceNum is its height, so it is the limit
+// of a Sequence too.
+const MaxBlockHeight = BlockHeight(^uint32(0))
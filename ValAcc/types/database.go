@@ -1,14 +1,86 @@
 package types
 
 // ======================= Database Support =======================================
+
+const (
+	Version = VersionField(1) // Version of ValAcc.  Version 1 added node Flags
+)
+
+// Bucket
+// Names a key space in the database.  The database puts the bucket name in front of the key, as it always
+// has.  Every key in a bucket is the same length, so even where one bucket's name starts with another's
+// (like "node" and "node head"), no key in one can be read back under the other, so long as the name and key
+// together come to a different length in each.  See KeyLen.
+type Bucket string
+
 // Bucket Names used by the accumulator and validator
 const (
-	Version              = VersionField(0)          // Version of ValAcc
-	NodeFirst            = "first node"             // Key: node.ChainID      Value:  First node hash with this chainID
-	NodeNext             = "next node"              // Key: node.GetHash()    Value:  next node in sequence with this chainID
-	NodeHead             = "node head"              // Key: node.ChainID      Value:  last node hash for this chainID
-	Entry                = "entry"                  // Key: entry.GetHash()   Value:  Entry
-	EntryNode            = "entry Node"             // Key: entry.GetHash()   Value:  node where this entry is recorded
-	DirectoryBlockHeight = "directory block height" // Key: node.BHeight      Value:  Directory Block node
-	Node                 = "node"                   // Key: node.GetHash()    Value:  nodeHash
+	NodeFirst            Bucket = "first node"             // Key: node.ChainID      Value:  First node hash with this chainID
+	NodeNext             Bucket = "next node"              // Key: node.GetHash()    Value:  next node in sequence with this chainID
+	NodeHead             Bucket = "node head"              // Key: node.ChainID      Value:  last node hash for this chainID
+	Entry                Bucket = "entry"                  // Key: entry.GetHash()   Value:  Entry
+	EntryNode            Bucket = "entry Node"             // Key: entry.GetHash()   Value:  node where this entry is recorded
+	DirectoryBlockHeight Bucket = "directory block height" // Key: node.BHeight      Value:  Directory Block node
+	Node                 Bucket = "node"                   // Key: node.GetHash()    Value:  nodeHash
+	Receipt              Bucket = "receipt"                // Key: ChainID+EntryHash+BHeight  Value: precomputed Receipt
+	EntrySequence        Bucket = "entry sequence"         // Key: ChainID+EntryHash Value:  sequence of the entry in its chain
+	ChainSequence        Bucket = "chain sequence"         // Key: node.ChainID      Value:  next entry sequence for the chain
+	TotalEntries         Bucket = "total entries"          // Key: accumulator ChainID Value: count of entries in all sealed blocks
+	PrunedHeight         Bucket = "pruned height"          // Key: accumulator ChainID Value: lowest height not pruned
+	BlockEntryCount      Bucket = "block entry count"      // Key: node.BHeight      Value:  count of entries in the directory block
+	AckedHeight          Bucket = "acked height"           // Key: accumulator ChainID Value: lowest height not acknowledged
+	Anchor               Bucket = "anchor"                 // Key: node.BHeight      Value:  record of the directory block's external anchor
+	MDRootIndex          Bucket = "md root index"          // Key: node.ListMDRoot   Value:  ChainID+BHeight of every chain node with the root
+	EntryTypeCount       Bucket = "entry type count"       // Key: ChainID+BHeight   Value:  count of each type of entry in the chain's node
+	ChainEntry           Bucket = "chain entry"            // Key: ChainID+EntryHash Value:  BHeight the entry was sealed at; never pruned
+	BlockAnnotation      Bucket = "block annotation"       // Key: node.BHeight      Value:  operator's annotation of the directory block
+	ChainParams          Bucket = "chain params"           // Key: accumulator ChainID Value: parameters written with the genesis block
+	FinalizedHeight      Bucket = "finalized height"       // Key: accumulator ChainID Value: lowest height not final
+	ChainStats           Bucket = "chain stats"            // Key: node.ChainID      Value:  entries and blocks of the chain, and when it was last sealed
+	DirectoryRootIndex   Bucket = "directory root index"   // Key: directory root    Value:  BHeight of the first directory block with the MD root or ListMDRoot
+	BlockGroup           Bucket = "block group"            // Key: node.BHeight      Value:  ChainID+MDRoot of each chain grouped in a Grouped directory block
+	ChainHeights         Bucket = "chain heights"          // Key: node.ChainID      Value:  BHeight of every directory block the chain has a node in
+	PinnedBlock          Bucket = "pinned block"           // Key: node.BHeight      Value:  1 while the block is pinned against pruning
+	PinnedNode           Bucket = "pinned node"            // Key: ChainID+BHeight   Value:  hash of the chain's node in a pinned block
+	EntryTimeStamp       Bucket = "entry timestamp"        // Key: entry.GetHash()   Value:  TimeStamp of the entry, the submitter's or the accumulator's
 )
+
+// Buckets
+// Every bucket used by the accumulator and validator.  Add new buckets here, as well as above.
+var Buckets = []Bucket{
+	NodeFirst, NodeNext, NodeHead, Entry, EntryNode, DirectoryBlockHeight, Node, Receipt,
+	EntrySequence, ChainSequence, TotalEntries, PrunedHeight, BlockEntryCount, AckedHeight,
+	Anchor, MDRootIndex, EntryTypeCount, ChainEntry, BlockAnnotation, ChainParams,
+	FinalizedHeight, ChainStats, DirectoryRootIndex, BlockGroup, ChainHeights,
+	PinnedBlock, PinnedNode, EntryTimeStamp,
+}
+
+// Valid
+// True for the buckets in Buckets
+func (b Bucket) Valid() bool {
+	for _, bucket := range Buckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// keyLengths
+// The length of every key in each of the Buckets
+var keyLengths = map[Bucket]int{
+	NodeFirst: 32, NodeNext: 32, NodeHead: 32, Entry: 32, EntryNode: 32, DirectoryBlockHeight: 4, Node: 32,
+	Receipt: 68, EntrySequence: 64, ChainSequence: 32, TotalEntries: 32, PrunedHeight: 32, BlockEntryCount: 4,
+	AckedHeight: 32, Anchor: 4, MDRootIndex: 32, EntryTypeCount: 36, ChainEntry: 64, BlockAnnotation: 4,
+	ChainParams: 32, FinalizedHeight: 32, ChainStats: 32, DirectoryRootIndex: 32,
+	BlockGroup: 4, ChainHeights: 32, PinnedBlock: 4, PinnedNode: 36,
+	EntryTimeStamp: 32,
+}
+
+// KeyLen
+// The length every key in the bucket has to be, or zero for a bucket not in Buckets, whose keys can be any
+// length.  Add the length of each new bucket's keys to keyLengths; TestBuckets checks that no two buckets
+// can collide.
+func (b Bucket) KeyLen() int {
+	return keyLengths[b]
+}
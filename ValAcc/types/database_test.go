@@ -0,0 +1,35 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuckets(t *testing.T) {
+	seen := map[Bucket]bool{}
+	for _, b := range Buckets {
+		if seen[b] {
+			t.Errorf("bucket %q is used twice", b)
+		}
+		seen[b] = true
+		if !b.Valid() || len(b) == 0 || strings.ContainsRune(string(b), 0) {
+			t.Errorf("bucket %q is not a valid bucket name", b)
+		}
+	}
+	if Bucket("no such bucket").Valid() {
+		t.Error("only the buckets in Buckets are valid")
+	}
+}
+
+func TestBucketKeysDontCollide(t *testing.T) {
+	for _, b := range Buckets {
+		if b.KeyLen() == 0 {
+			t.Errorf("bucket %q has no key length", b)
+		}
+		for _, other := range Buckets {
+			if other != b && strings.HasPrefix(string(other), string(b)) && len(b)+b.KeyLen() == len(other)+other.KeyLen() {
+				t.Errorf("the keys of bucket %q can collide with those of %q", b, other)
+			}
+		}
+	}
+}
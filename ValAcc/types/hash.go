@@ -55,3 +55,22 @@ func GetChainID(AccumulatorDID Hash, SubChainIDs []Hash) (chainID Hash) {
 	copy(chainID[:], sum.Sum(nil))
 	return chainID
 }
+
+// ChainIDFromName
+// The canonical way to derive a ChainID from a name, a public key, or any other set of byte strings.
+// Each part is prefixed with its length as a 4 byte big endian integer, and the ChainID is the sha256
+// of the concatenation of the prefixed parts:
+//
+//	ChainID = sha256( len(part[0]) + part[0] + len(part[1]) + part[1] + .. + len(part[n]) + part[n] )
+//
+// The length prefixes keep ("ab", "c") and ("a", "bc") from producing the same ChainID.  With no parts,
+// the ChainID is the sha256 of nothing.
+func ChainIDFromName(parts ...[]byte) (chainID Hash) {
+	sum := sha256.New()
+	for _, part := range parts {
+		sum.Write(Uint32Bytes(uint32(len(part))))
+		sum.Write(part)
+	}
+	copy(chainID[:], sum.Sum(nil))
+	return chainID
+}
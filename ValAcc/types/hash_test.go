@@ -0,0 +1,28 @@
+package types
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestChainIDFromName(t *testing.T) {
+	vectors := []struct {
+		parts    [][]byte
+		expected string
+	}{
+		{nil, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{[][]byte{[]byte("Accumulator")}, "e3c89c9fb63b6eb6c8778501ac1d5b9bb9545574d01fa8c1fa3b7f1016f41ab9"},
+		{[][]byte{[]byte("Accumulator"), Uint32Bytes(0)}, "d08df482849ddca35abfeebcbc0efd3c0cef5cfbf16488c9d1c50c42d8e17c40"},
+		{[][]byte{[]byte("ab"), []byte("c")}, "f2939f903016e5bb29b1e4a61cdbd376220ca03a24180b39995f2d50f2e0a647"},
+		{[][]byte{[]byte("a"), []byte("bc")}, "b534ce16ac9c8b36823f39a395ce8e0e3c7ad9605b82b5444f18cadacd217a5d"},
+	}
+	for i, v := range vectors {
+		chainID := ChainIDFromName(v.parts...)
+		if hex.EncodeToString(chainID[:]) != v.expected {
+			t.Errorf("vector %d: got %x expected %s", i, chainID, v.expected)
+		}
+		if ChainIDFromName(v.parts...) != chainID {
+			t.Errorf("vector %d: the same parts must always give the same ChainID", i)
+		}
+	}
+}
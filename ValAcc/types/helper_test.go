@@ -44,4 +44,18 @@ func TestHelperFunc(t *testing.T) {
 			t.Error("v2 r2 didn't match")
 		}
 	}
+	{
+		v1 := uint64(0x1122334455667788)
+		b := Uint64Bytes(v1)
+		v2 := uint64(0x99aabbccddeeff00)
+		b = append(b, Uint64Bytes(v2)...)
+		r1, b := BytesUint64(b)
+		r2, b := BytesUint64(b)
+		if v1 != r1 {
+			t.Error("v1, r1 didn't match")
+		}
+		if v2 != r2 || len(b) != 0 {
+			t.Error("v2 r2 didn't match")
+		}
+	}
 }